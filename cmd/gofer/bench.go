@@ -0,0 +1,195 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+)
+
+// benchAggregation is the name of the benchmark of the full aggregation.
+const benchAggregation = "*"
+
+var tableBenchColumns = []tableColumn{
+	{name: "NAME"},
+	{name: "PAIRS", right: true},
+	{name: "RUNS", right: true},
+	{name: "ERRORS", right: true},
+	{name: "P50", right: true},
+	{name: "P95", right: true},
+	{name: "P99", right: true},
+}
+
+// benchResult describes latencies of repeated requests to an origin, or of
+// the full aggregation, for which the name is "*".
+type benchResult struct {
+	Name   string   `json:"name"`
+	Pairs  []string `json:"pairs"`
+	Runs   int      `json:"runs"`
+	Errors int      `json:"errors"`
+
+	P50   time.Duration `json:"-"`
+	P95   time.Duration `json:"-"`
+	P99   time.Duration `json:"-"`
+	P50MS int64         `json:"p50_ms"`
+	P95MS int64         `json:"p95_ms"`
+	P99MS int64         `json:"p99_ms"`
+}
+
+// bench calls f n times and returns the latency percentiles. A run fails if
+// f returns false.
+func bench(name string, pairs []provider.Pair, n int, f func() bool) benchResult {
+	res := benchResult{Name: name, Pairs: make([]string, len(pairs)), Runs: n}
+	for i, p := range pairs {
+		res.Pairs[i] = p.String()
+	}
+	latencies := make([]time.Duration, n)
+	for i := range latencies {
+		start := time.Now()
+		if !f() {
+			res.Errors++
+		}
+		latencies[i] = time.Since(start)
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	res.P50 = percentile(latencies, 50)
+	res.P95 = percentile(latencies, 95)
+	res.P99 = percentile(latencies, 99)
+	res.P50MS = res.P50.Milliseconds()
+	res.P95MS = res.P95.Milliseconds()
+	res.P99MS = res.P99.Milliseconds()
+	return res
+}
+
+// benchProvider measures how long it takes to calculate prices of the pairs.
+// A run fails if any price could not be calculated.
+func benchProvider(p provider.Provider, pairs []provider.Pair, n int) benchResult {
+	return bench(benchAggregation, pairs, n, func() bool {
+		prices, err := p.Prices(pairs...)
+		if err != nil {
+			return false
+		}
+		for _, price := range prices {
+			if price.Error != "" {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// benchOrigin measures how long it takes to fetch prices of the pairs from
+// the origin. A run fails if any price could not be fetched.
+func benchOrigin(name string, h origins.Handler, pairs []provider.Pair, n int) benchResult {
+	originPairs := make([]origins.Pair, len(pairs))
+	for i, p := range pairs {
+		originPairs[i] = origins.Pair{Base: p.Base, Quote: p.Quote}
+	}
+	return bench(name, pairs, n, func() bool {
+		res := h.Fetch(originPairs)
+		if len(res) != len(pairs) {
+			return false
+		}
+		for _, r := range res {
+			if r.Error != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// percentile returns the p-th percentile of sorted latencies using the
+// nearest-rank method.
+func percentile(latencies []time.Duration, p int) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := (p*len(latencies) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+// modelOrigins returns the origins used by the models, and the pairs for
+// which they are used, sorted by pair.
+func modelOrigins(models map[provider.Pair]*provider.Model) map[string][]provider.Pair {
+	seen := make(map[string]map[provider.Pair]bool)
+	var queue []*provider.Model
+	for _, m := range models {
+		queue = append(queue, m)
+	}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		queue = append(queue, m.Models...)
+		name := m.Parameters["origin"]
+		if m.Type != "origin" || name == "" {
+			continue
+		}
+		if seen[name] == nil {
+			seen[name] = make(map[provider.Pair]bool)
+		}
+		seen[name][m.Pair] = true
+	}
+	res := make(map[string][]provider.Pair, len(seen))
+	for name, pairs := range seen {
+		for p := range pairs {
+			res[name] = append(res[name], p)
+		}
+		sort.Slice(res[name], func(i, j int) bool {
+			return res[name][i].String() < res[name][j].String()
+		})
+	}
+	return res
+}
+
+// sortBenchResults sorts the results of origins from the slowest to the
+// fastest by the 95th percentile. The result of the full aggregation is
+// always first.
+func sortBenchResults(results []benchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Name == benchAggregation || results[j].Name == benchAggregation {
+			return results[i].Name == benchAggregation && results[j].Name != benchAggregation
+		}
+		return results[i].P95 > results[j].P95
+	})
+}
+
+// writeBenchResults writes the results as JSON, or NDJSON, if one of these
+// formats is used, and as a table otherwise.
+func writeBenchResults(w io.Writer, format marshal.FormatType, results []benchResult) error {
+	return writeList(w, format, results, tableBenchColumns, func(r benchResult) ([]string, error) {
+		return []string{
+			r.Name,
+			strconv.Itoa(len(r.Pairs)),
+			strconv.Itoa(r.Runs),
+			strconv.Itoa(r.Errors),
+			r.P50.Round(time.Millisecond).String(),
+			r.P95.Round(time.Millisecond).String(),
+			r.P99.Round(time.Millisecond).String(),
+		}, nil
+	})
+}
@@ -0,0 +1,116 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+)
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 95*time.Millisecond, percentile(latencies, 95))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 2*time.Millisecond, percentile(latencies[:3], 50))
+	assert.Equal(t, 3*time.Millisecond, percentile(latencies[:3], 99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
+
+func TestModelOrigins(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	btceth := provider.Pair{Base: "BTC", Quote: "ETH"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	models := map[provider.Pair]*provider.Model{
+		btcusd: {Type: "median", Pair: btcusd, Models: []*provider.Model{
+			{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "bitstamp"}},
+			{Type: "indirect", Pair: btcusd, Models: []*provider.Model{
+				{Type: "origin", Pair: btceth, Parameters: map[string]string{"origin": "kraken"}},
+				{Type: "origin", Pair: ethusd, Parameters: map[string]string{"origin": "bitstamp"}},
+			}},
+		}},
+		ethusd: {Type: "origin", Pair: ethusd, Parameters: map[string]string{"origin": "bitstamp"}},
+	}
+	assert.Equal(t, map[string][]provider.Pair{
+		"bitstamp": {btcusd, ethusd},
+		"kraken":   {btceth},
+	}, modelOrigins(models))
+}
+
+func TestBenchProvider(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: {Pair: btcusd, Price: 1}}, nil).Once()
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: {Pair: btcusd, Error: "failed"}}, nil).Once()
+	p.On("Prices", btcusd).Return((map[provider.Pair]*provider.Price)(nil), errors.New("failed")).Once()
+
+	res := benchProvider(p, []provider.Pair{btcusd}, 3)
+	assert.Equal(t, benchAggregation, res.Name)
+	assert.Equal(t, []string{"BTC/USD"}, res.Pairs)
+	assert.Equal(t, 3, res.Runs)
+	assert.Equal(t, 2, res.Errors)
+	p.AssertExpectations(t)
+}
+
+func TestBenchOrigin(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	calls := 0
+	h := handlerFunc(func(pairs []origins.Pair) []origins.FetchResult {
+		calls++
+		time.Sleep(time.Duration(calls) * time.Millisecond)
+		if calls == 1 {
+			return []origins.FetchResult{{Error: errors.New("failed")}}
+		}
+		return []origins.FetchResult{{Price: origins.Price{Pair: pairs[0], Price: 1}}}
+	})
+	res := benchOrigin("bitstamp", h, []provider.Pair{btcusd}, 4)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, 1, res.Errors)
+	assert.GreaterOrEqual(t, res.P50, 2*time.Millisecond)
+	assert.GreaterOrEqual(t, res.P99, 4*time.Millisecond)
+	assert.LessOrEqual(t, res.P50, res.P95)
+	assert.LessOrEqual(t, res.P95, res.P99)
+}
+
+func TestWriteBenchResults(t *testing.T) {
+	results := []benchResult{
+		{Name: "kraken", Pairs: []string{"BTC/USD"}, Runs: 10, P50: 50 * time.Millisecond, P95: 80 * time.Millisecond, P99: 90 * time.Millisecond},
+		{Name: benchAggregation, Pairs: []string{"BTC/USD"}, Runs: 10, P50: 1200 * time.Millisecond, P95: 1500 * time.Millisecond, P99: 1600 * time.Millisecond},
+		{Name: "bitstamp", Pairs: []string{"BTC/USD"}, Runs: 10, Errors: 1, P50: 100 * time.Millisecond, P95: 1400 * time.Millisecond, P99: 1500 * time.Millisecond},
+	}
+	sortBenchResults(results)
+
+	b := &bytes.Buffer{}
+	require.NoError(t, writeBenchResults(b, marshal.Plain, results))
+	assert.Equal(t, `NAME      PAIRS  RUNS  ERRORS    P50   P95   P99
+*             1    10       0   1.2s  1.5s  1.6s
+bitstamp      1    10       1  100ms  1.4s  1.5s
+kraken        1    10       0   50ms  80ms  90ms
+`, b.String())
+}
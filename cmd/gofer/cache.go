@@ -0,0 +1,376 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/prices"
+)
+
+var tableCacheColumns = []tableColumn{
+	{name: "STORE"},
+	{name: "PAIR"},
+	{name: "PRICE", right: true},
+	{name: "FETCHED"},
+	{name: "AGE", right: true},
+	{name: "HISTORY", right: true},
+	{name: "ERROR"},
+}
+
+var tableCacheFileColumns = []tableColumn{
+	{name: "STORE"},
+	{name: "LOCATION"},
+	{name: "ENTRIES", right: true},
+	{name: "SIZE", right: true},
+}
+
+// cacheEntry is a price of a pair kept in a persistent store of the agent
+// cache.
+type cacheEntry struct {
+	Store string  `json:"store"`
+	Pair  string  `json:"pair"`
+	Price float64 `json:"price"`
+
+	// FetchedAt is the time at which the price was fetched, and Age is the
+	// time since then, in seconds. They are not set for pairs of which the
+	// snapshot has only the history.
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	Age       *float64   `json:"age,omitempty"`
+
+	// History is the number of recent prices in the snapshot.
+	History int `json:"history,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// cacheFile describes a persistent store of the agent cache.
+type cacheFile struct {
+	Store    string `json:"store"`
+	Location string `json:"location"`
+	Entries  int    `json:"entries"`
+
+	// Size is the size of the file in bytes. It is not set for Redis.
+	Size *int64 `json:"size,omitempty"`
+}
+
+// persistentCache is the persistent store of prices of the agent cache,
+// which is a BoltDB file or Redis, and the snapshot file.
+type persistentCache struct {
+	store    prices.Store // Nil if prices are not persisted.
+	kind     string       // "bolt" or "redis".
+	location string       // Path of the BoltDB file or address of Redis.
+	snapshot string       // Path of the snapshot file, if any.
+}
+
+// persistentCache opens the persistent store of the agent cache. It fails
+// if neither the store nor the snapshot is configured.
+func (c *cliConfig) persistentCache() (*persistentCache, error) {
+	if c.Agent == nil || c.Agent.Cache == nil {
+		return nil, errors.New("the agent cache is not configured")
+	}
+	cache := c.Agent.Cache
+	if cache.Path == "" && cache.Redis == nil && cache.SnapshotPath == "" {
+		return nil, errors.New("the agent cache has no path, redis or snapshot_path option")
+	}
+	store, err := c.cacheStore()
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w, the file is used by another process, e.g. a running agent", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &persistentCache{store: store, snapshot: cache.SnapshotPath}
+	switch {
+	case cache.Path != "":
+		p.kind, p.location = "bolt", cache.Path
+	case cache.Redis != nil:
+		p.kind, p.location = "redis", cache.Redis.Addr
+	}
+	return p, nil
+}
+
+func (p *persistentCache) close() error {
+	if p.store == nil {
+		return nil
+	}
+	return p.store.Close()
+}
+
+// list returns the stores and their entries, sorted by store and pair.
+// The age of entries is calculated from now.
+func (p *persistentCache) list(now time.Time) ([]cacheFile, []cacheEntry, error) {
+	files := []cacheFile{}
+	entries := []cacheEntry{}
+	if p.store != nil {
+		stored, err := p.store.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		var storeEntries []cacheEntry
+		for pair, price := range stored {
+			e := cacheEntry{Store: p.kind, Pair: pair.String(), Price: price.Price.Price, Error: price.Price.Error}
+			e.setFetchedAt(price.FetchedAt, now)
+			storeEntries = append(storeEntries, e)
+		}
+		sortCacheEntries(storeEntries)
+		f := cacheFile{Store: p.kind, Location: p.location, Entries: len(storeEntries)}
+		if p.kind == "bolt" {
+			if f.Size, err = fileSize(p.location); err != nil {
+				return nil, nil, err
+			}
+		}
+		files = append(files, f)
+		entries = append(entries, storeEntries...)
+	}
+	if p.snapshot != "" {
+		pairs, err := prices.ReadSnapshot(p.snapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+		var snapshotEntries []cacheEntry
+		for _, sp := range pairs {
+			e := cacheEntry{Store: "snapshot", Pair: sp.Pair.String(), History: len(sp.History)}
+			if sp.Price != nil {
+				e.Price = sp.Price.Price.Price
+				e.Error = sp.Price.Price.Error
+				e.setFetchedAt(sp.Price.FetchedAt, now)
+			}
+			snapshotEntries = append(snapshotEntries, e)
+		}
+		sortCacheEntries(snapshotEntries)
+		f := cacheFile{Store: "snapshot", Location: p.snapshot, Entries: len(snapshotEntries)}
+		if f.Size, err = fileSize(p.snapshot); err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+		entries = append(entries, snapshotEntries...)
+	}
+	return files, entries, nil
+}
+
+// prune removes prices fetched before the given time from the store, and
+// prices and history older than it from the snapshot. Pairs of the
+// snapshot which have no price and history left are removed. It returns
+// the number of removed entries of every store.
+func (p *persistentCache) prune(before time.Time) (map[string]int, error) {
+	removed := make(map[string]int)
+	if p.store != nil {
+		stored, err := p.store.Load()
+		if err != nil {
+			return nil, err
+		}
+		var pairs []provider.Pair
+		for pair, price := range stored {
+			if price.FetchedAt.Before(before) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if err := p.store.Delete(pairs...); err != nil {
+			return nil, err
+		}
+		removed[p.kind] = len(pairs)
+	}
+	if p.snapshot != "" {
+		pairs, err := prices.ReadSnapshot(p.snapshot)
+		if err != nil {
+			return nil, err
+		}
+		var kept []prices.SnapshotPair
+		for _, sp := range pairs {
+			if sp.Price != nil && sp.Price.FetchedAt.Before(before) {
+				sp.Price = nil
+			}
+			var history []*provider.Price
+			for _, h := range sp.History {
+				if !h.Time.Before(before) {
+					history = append(history, h)
+				}
+			}
+			sp.History = history
+			if sp.Price == nil && len(sp.History) == 0 {
+				removed["snapshot"]++
+				continue
+			}
+			kept = append(kept, sp)
+		}
+		if removed["snapshot"] > 0 {
+			if err := prices.WriteSnapshot(p.snapshot, kept); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// clear removes all prices from the store, and removes the snapshot file.
+// It returns the number of removed entries of every store.
+func (p *persistentCache) clear() (map[string]int, error) {
+	removed := make(map[string]int)
+	if p.store != nil {
+		stored, err := p.store.Load()
+		if err != nil {
+			return nil, err
+		}
+		pairs := make([]provider.Pair, 0, len(stored))
+		for pair := range stored {
+			pairs = append(pairs, pair)
+		}
+		if err := p.store.Delete(pairs...); err != nil {
+			return nil, err
+		}
+		removed[p.kind] = len(pairs)
+	}
+	if p.snapshot != "" {
+		pairs, err := prices.ReadSnapshot(p.snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(p.snapshot); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		removed["snapshot"] = len(pairs)
+	}
+	return removed, nil
+}
+
+// writeRemoved writes the number of entries removed from every store, in
+// the order of the stores.
+func (p *persistentCache) writeRemoved(w io.Writer, removed map[string]int) error {
+	var stores [][2]string
+	if p.store != nil {
+		stores = append(stores, [2]string{p.kind, p.location})
+	}
+	if p.snapshot != "" {
+		stores = append(stores, [2]string{"snapshot", p.snapshot})
+	}
+	for _, s := range stores {
+		if _, err := fmt.Fprintf(w, "Removed %d entries from %s (%s)\n", removed[s[0]], s[1], s[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *cacheEntry) setFetchedAt(t, now time.Time) {
+	age := now.Sub(t).Seconds()
+	if age < 0 {
+		age = 0
+	}
+	e.FetchedAt = &t
+	e.Age = &age
+}
+
+func sortCacheEntries(entries []cacheEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Pair < entries[j].Pair
+	})
+}
+
+// fileSize returns the size of the file, or zero if it does not exist.
+func fileSize(path string) (*int64, error) {
+	var size int64
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		size = fi.Size()
+	}
+	return &size, nil
+}
+
+// formatSize formats the number of bytes using binary prefixes.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// writeCache writes the stores and their entries as a single JSON object
+// for the JSON and NDJSON formats, as a YAML document for the YAML format,
+// and as two tables otherwise.
+func writeCache(w io.Writer, format marshal.FormatType, files []cacheFile, entries []cacheEntry) error {
+	v := struct {
+		Files   []cacheFile  `json:"files"`
+		Entries []cacheEntry `json:"entries"`
+	}{files, entries}
+	if format == yamlFormat {
+		return writeYAML(w, v)
+	}
+	if format == marshal.JSON || format == marshal.NDJSON {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	err := writeList(w, format, entries, tableCacheColumns, func(e cacheEntry) ([]string, error) {
+		if e.FetchedAt == nil {
+			return []string{e.Store, e.Pair, "", "", "", historyCount(e.History), e.Error}, nil
+		}
+		return []string{
+			e.Store,
+			e.Pair,
+			thousands(e.Price),
+			e.FetchedAt.UTC().Format(time.RFC3339),
+			fmt.Sprintf("%ds", int64(*e.Age)),
+			historyCount(e.History),
+			e.Error,
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return err
+	}
+	return writeList(w, format, files, tableCacheFileColumns, func(f cacheFile) ([]string, error) {
+		size := ""
+		if f.Size != nil {
+			size = formatSize(*f.Size)
+		}
+		return []string{f.Store, f.Location, fmt.Sprint(f.Entries), size}, nil
+	})
+}
+
+func historyCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,140 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/prices"
+)
+
+func TestPersistentCache(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	now := time.Unix(1700000000, 0).UTC()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.db")
+	snapshot := filepath.Join(dir, "snapshot.json")
+	cachedPrice := func(pair provider.Pair, v float64, age time.Duration) *prices.CachedPrice {
+		return &prices.CachedPrice{
+			Price:     &provider.Price{Type: "median", Pair: pair, Price: v, Time: now.Add(-age)},
+			FetchedAt: now.Add(-age),
+		}
+	}
+	s, err := prices.NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Save(cachedPrice(btcusd, 20000, time.Minute)))
+	require.NoError(t, s.Save(cachedPrice(ethusd, 1500, 2*time.Hour)))
+	require.NoError(t, s.Close())
+	require.NoError(t, prices.WriteSnapshot(snapshot, []prices.SnapshotPair{
+		{
+			Pair:  btcusd,
+			Price: cachedPrice(btcusd, 20000, time.Minute),
+			History: []*provider.Price{
+				cachedPrice(btcusd, 19900, 2*time.Hour).Price,
+				cachedPrice(btcusd, 20000, time.Minute).Price,
+			},
+		},
+		{Pair: ethusd, History: []*provider.Price{cachedPrice(ethusd, 1500, 2*time.Hour).Price}},
+	}))
+
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval      = 30
+    path          = "`+path+`"
+    snapshot_path = "`+snapshot+`"
+  }
+}
+`)
+	p, err := cfg.persistentCache()
+	require.NoError(t, err)
+	defer p.close()
+
+	files, entries, err := p.list(now)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "bolt", files[0].Store)
+	assert.Equal(t, 2, files[0].Entries)
+	assert.Positive(t, *files[0].Size)
+	assert.Equal(t, "snapshot", files[1].Store)
+	assert.Equal(t, 2, files[1].Entries)
+
+	out := &bytes.Buffer{}
+	size := int64(32768)
+	files[0].Location, files[0].Size = "prices.db", &size
+	files[1].Location, files[1].Size = "snapshot.json", nil
+	require.NoError(t, writeCache(out, marshal.Plain, files, entries))
+	assert.Equal(t, ""+
+		"STORE     PAIR      PRICE  FETCHED                 AGE  HISTORY  ERROR\n"+
+		"bolt      BTC/USD  20,000  2023-11-14T22:12:20Z    60s\n"+
+		"bolt      ETH/USD   1,500  2023-11-14T20:13:20Z  7200s\n"+
+		"snapshot  BTC/USD  20,000  2023-11-14T22:12:20Z    60s        2\n"+
+		"snapshot  ETH/USD                                             1\n"+
+		"\n"+
+		"STORE     LOCATION       ENTRIES      SIZE\n"+
+		"bolt      prices.db            2  32.0 KiB\n"+
+		"snapshot  snapshot.json        2\n",
+		out.String(),
+	)
+
+	// Old prices and history are removed, and pairs of the snapshot without
+	// a price and history are removed.
+	removed, err := p.prune(now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"bolt": 1, "snapshot": 1}, removed)
+	_, entries, err = p.list(now)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "BTC/USD", entries[0].Pair)
+	assert.Equal(t, cacheEntry{Store: "snapshot", Pair: "BTC/USD", Price: 20000, History: 1}, withoutTimes(entries[1]))
+
+	removed, err = p.clear()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"bolt": 1, "snapshot": 1}, removed)
+	assert.NoFileExists(t, snapshot)
+	files, entries, err = p.list(now)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, int64(0), *files[1].Size)
+}
+
+func TestPersistentCache_NotConfigured(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval = 30
+  }
+}
+`)
+	_, err := cfg.persistentCache()
+	assert.Error(t, err)
+}
+
+func withoutTimes(e cacheEntry) cacheEntry {
+	e.FetchedAt = nil
+	e.Age = nil
+	return e
+}
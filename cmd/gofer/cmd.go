@@ -16,7 +16,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/logrus/flag"
 )
@@ -33,9 +38,28 @@ It is a tool that allows for easy data retrieval from various sources
 with aggregates that increase reliability in the DeFi environment.`,
 		SilenceErrors: true,
 		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.NoCache {
+				// Prices served by the agent may come from its cache.
+				opts.NoRPC = true
+			}
+			if opts.DryRun {
+				if err := opts.setupDryRun(cmd.Flags().Changed("config")); err != nil {
+					return err
+				}
+			}
+			if opts.Timeout > 0 {
+				cancelAfter(opts.Timeout, opts.cancel)
+			}
+			return nil
+		},
 	}
 
 	rootCmd.PersistentFlags().AddFlagSet(flag.NewLoggerFlagSet(&opts.LoggerFlag))
+	rootCmd.SetGlobalNormalizationFunc(normalizeFlagName)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return withCode(errCodeUsage, err)
+	})
 	rootCmd.PersistentFlags().StringSliceVarP(
 		&opts.ConfigFilePath,
 		"config",
@@ -49,12 +73,91 @@ with aggregates that increase reliability in the DeFi environment.`,
 		"o",
 		"output format",
 	)
+	rootCmd.PersistentFlags().StringVar(
+		&opts.Query,
+		"query",
+		"",
+		"filter the JSON output with a jq-like path, e.g. .price",
+	)
 	rootCmd.PersistentFlags().BoolVar(
 		&opts.NoRPC,
 		"norpc",
 		false,
 		"disable the use of RPC agent",
 	)
+	rootCmd.PersistentFlags().BoolVar(
+		&opts.NoCache,
+		"no-cache",
+		false,
+		"fetch prices from origins instead of cached prices of the agent, implies --norpc",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&opts.DryRun,
+		"dry-run",
+		false,
+		"use the bundled config and fixtures instead of the config file and origins, implies --norpc",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&opts.Timeout,
+		"timeout",
+		0,
+		"exit with an error if the command does not finish in time, 0 means no timeout",
+	)
 
 	return rootCmd
 }
+
+// flagAliases are alternative names of flags.
+var flagAliases = map[string]string{
+	"log-level":  "log.verbosity",
+	"log-format": "log.format",
+}
+
+// sharedFlagAliases are alternative names of flags which other commands use
+// for their own flags. They apply only to flag sets which have the flag.
+var sharedFlagAliases = map[string]string{
+	"interval": "follow.interval",
+	"repeat":   "count",
+}
+
+// normalizeFlagName replaces aliases of flags with the names of the flags.
+func normalizeFlagName(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if n, ok := flagAliases[name]; ok {
+		name = n
+	}
+	if n, ok := sharedFlagAliases[name]; ok && f.Lookup(n) != nil {
+		name = n
+	}
+	return pflag.NormalizedName(name)
+}
+
+// timeoutGracePeriod is the time a command is given to stop after its
+// context has been canceled.
+const timeoutGracePeriod = time.Second
+
+// cancelAfter cancels the root context of the command with a timeout error
+// if the command is still running after the duration.
+func cancelAfter(d time.Duration, cancel context.CancelCauseFunc) *time.Timer {
+	return time.AfterFunc(d, func() {
+		cancel(withCode(errCodeTimeout, fmt.Errorf("timed out after %s", d)))
+	})
+}
+
+// executeCommand executes the command with the root context. If the context
+// is canceled before the command finishes, the cause of the cancellation is
+// returned. Fetching prices from origins, and many other operations, cannot
+// be canceled, so the command is waited for only for timeoutGracePeriod.
+func executeCommand(ctx context.Context, cmd *cobra.Command) error {
+	done := make(chan error, 1)
+	go func() { done <- cmd.ExecuteContext(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	select {
+	case <-done:
+	case <-time.After(timeoutGracePeriod):
+	}
+	return context.Cause(ctx)
+}
@@ -17,48 +17,292 @@ package main
 
 import (
 	"context"
-	"gofer-cli/pkg/agent"
+	"errors"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/agent/grpc"
+	"gofer-cli/pkg/prices"
 )
 
 func NewAgentCmd(opts *options) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "agent",
 		Args:  cobra.NoArgs,
 		Short: "Start an RPC server",
 		Long:  `Start an RPC server.`,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(c *cobra.Command, args []string) error {
+			if c.Flags().Changed("format") {
+				// The format of responses is requested by clients with the
+				// Accept header.
+				return errors.New("the agent command does not support the format flag")
+			}
+			if opts.Timeout > 0 {
+				// The agent runs until it is stopped by a signal.
+				return errors.New("the agent command does not support the timeout flag")
+			}
 			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
 				return err
 			}
-			ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
-			services, err := opts.Config.ClientServices(ctx, opts.Logger(), true, marshal.JSON)
+			if opts.PIDFile != "" {
+				if err := writePIDFile(opts.PIDFile); err != nil {
+					return err
+				}
+				defer func() { _ = removePIDFile(opts.PIDFile) }()
+			}
+			// The agent is stopped gracefully by the interrupt and the
+			// SIGTERM signals, and reloaded by the SIGHUP signal.
+			ctx, _ := signal.NotifyContext(c.Context(), os.Interrupt, syscall.SIGTERM)
+			// Services are bound to their own context, so they can be
+			// stopped when they are replaced by a reload.
+			servicesCtx, servicesCancel := context.WithCancel(ctx)
+			defer servicesCancel()
+			services, err := opts.Config.ClientServices(servicesCtx, opts.Logger(), true, marshal.JSON)
+			if err != nil {
+				return err
+			}
+			if err = services.Start(servicesCtx); err != nil {
+				return err
+			}
+			priceProvider := agent.NewReloadableProvider(
+				services.PriceProvider,
+				services.PriceHook,
+				opts.Config.minSources(),
+			)
+			// Prices are served from the cache, if it is configured. The
+			// cache fetches prices from the reloadable provider, so it
+			// uses the price models of the current configuration.
+			var servedProvider provider.Provider = priceProvider
+			readinessChecks := make(map[string]agent.ReadinessCheck)
+			var collectors []prometheus.Collector
+			var history agent.PriceHistory
+			var updates <-chan provider.Pair
+			var nextRefresh agent.NextRefreshFunc
+			cache, err := opts.Config.priceCache(priceProvider, services.Logger)
+			if err != nil {
+				return err
+			}
+			if cache != nil {
+				if err = cache.Start(ctx); err != nil {
+					return err
+				}
+				defer func() { <-cache.Wait() }()
+				servedProvider = opts.Config.cachedProvider(cache)
+				readinessChecks["cache"] = cache.Ready
+				collectors = append(collectors, cache)
+				history = opts.Config.priceHistory(cache)
+				updates = updatedPairs(cache.Subscribe())
+				nextRefresh = cache.NextRefresh
+			}
+			var invalidate agent.InvalidateFunc
+			if opts.AdminInvalidate {
+				if cache == nil {
+					return errors.New("the --admin.invalidate flag requires the price cache to be configured")
+				}
+				invalidate = cache.Invalidate
+			}
+			r := &reloader{
+				ctx:      ctx,
+				opts:     opts,
+				provider: priceProvider,
+				cancel:   servicesCancel,
+			}
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			defer signal.Stop(hup)
+			go r.reloadOn(ctx, hup, services.Logger)
+			var reload agent.ReloadFunc
+			if opts.AdminReload {
+				reload = r.reload
+			}
+			var clientRules []agent.ClientRule
+			for _, r := range opts.TLSClientRules {
+				rule, err := agent.ParseClientRule(r)
+				if err != nil {
+					return err
+				}
+				clientRules = append(clientRules, rule)
+			}
+			ipFilter, err := opts.Config.ipFilter()
 			if err != nil {
 				return err
 			}
-			if err = services.Start(ctx); err != nil {
+			apiKeys, err := opts.Config.apiKeys()
+			if err != nil {
 				return err
 			}
 			cfg := agent.HTTPAgentConfig{
-				PriceProvider: services.PriceProvider,
-				PriceHook:     services.PriceHook,
-				Marshaller:    services.Marshaller,
-				Logger:        services.Logger,
-				Address:       opts.Config.Gofer.RPCListenAddr,
+				PriceProvider:    servedProvider,
+				PriceHook:        priceProvider,
+				Logger:           services.Logger,
+				Address:          opts.Config.Gofer.RPCListenAddr,
+				Addresses:        opts.ListenAddrs,
+				TLSCertFile:      opts.TLSCertFile,
+				TLSKeyFile:       opts.TLSKeyFile,
+				TLSClientCAFile:  opts.TLSClientCAFile,
+				TLSClientRules:   clientRules,
+				APIKeys:          apiKeys,
+				BasicAuth:        opts.Config.basicAuth(),
+				JWT:              opts.Config.jwt(),
+				RateLimit:        opts.Config.rateLimit(),
+				ConcurrencyLimit: opts.Config.concurrencyLimit(),
+				CORS:             opts.Config.cors(),
+				IPFilter:         ipFilter,
+				Webhooks:         opts.Config.webhooks(),
+				ShutdownTimeout:  opts.ShutdownTimeout,
+				AccessLog:        opts.AccessLog,
+				Reload:           reload,
+				Invalidate:       invalidate,
+				History:          history,
+				PriceUpdates:     updates,
+				NextRefresh:      nextRefresh,
+				ReadinessChecks:  readinessChecks,
+				Collectors:       collectors,
+			}
+			if err := opts.Config.applyServerConfig(&cfg); err != nil {
+				return err
 			}
 			httpAgent := agent.NewHTTPAgent(cfg)
 			err = httpAgent.Start(ctx)
 			if err != nil {
 				return err
 			}
+			if opts.GRPCListenAddr != "" {
+				grpcServer, err := grpc.NewServer(grpc.ServerConfig{
+					PriceProvider: servedProvider,
+					PriceHook:     priceProvider,
+					Logger:        services.Logger,
+					Address:       opts.GRPCListenAddr,
+					// In-flight calls are given the same time to finish
+					// as HTTP requests.
+					ShutdownTimeout: opts.ShutdownTimeout,
+					// The gRPC server is protected by the same access
+					// controls as the HTTP agent.
+					TLSConfig: httpAgent.TLSConfig(),
+					Authorize: httpAgent.Authorize,
+					// Streams share the price updates of the
+					// WebSocket and SSE endpoints.
+					PriceStream: httpAgent.SubscribePrices,
+				})
+				if err != nil {
+					return err
+				}
+				if err = grpcServer.Start(ctx); err != nil {
+					return err
+				}
+				defer func() { <-grpcServer.Wait() }()
+			}
+			if opts.DebugListenAddr != "" {
+				debugServer, err := agent.NewDebugServer(agent.DebugServerConfig{
+					Logger:  services.Logger,
+					Address: opts.DebugListenAddr,
+				})
+				if err != nil {
+					return err
+				}
+				if err = debugServer.Start(ctx); err != nil {
+					return err
+				}
+				defer func() { <-debugServer.Wait() }()
+			}
 			<-services.Wait()
 			return <-httpAgent.Wait()
 		},
 	}
+	cmd.Flags().StringArrayVar(
+		&opts.ListenAddrs,
+		"listen",
+		nil,
+		"listen address of the HTTP server, overrides rpc_listen_addr; may be repeated to listen on several addresses",
+	)
+	cmd.Flags().StringVar(
+		&opts.GRPCListenAddr,
+		"grpc",
+		"",
+		"gRPC listen address, the gRPC server is disabled if empty",
+	)
+	cmd.Flags().StringVar(
+		&opts.DebugListenAddr,
+		"debug.listen",
+		"",
+		"listen address of the pprof profiling endpoints, disabled if empty; must not be publicly reachable",
+	)
+	cmd.Flags().BoolVar(
+		&opts.AdminReload,
+		"admin.reload",
+		false,
+		"enable the POST /admin/reload endpoint, which reloads price models from the config files; requires authentication",
+	)
+	cmd.Flags().BoolVar(
+		&opts.AdminInvalidate,
+		"admin.invalidate",
+		false,
+		"enable the POST /admin/cache/invalidate endpoint, which refetches cached prices; requires authentication and the price cache",
+	)
+	cmd.Flags().StringVar(
+		&opts.PIDFile,
+		"pidfile",
+		"",
+		"write the PID of the agent to the file, which is removed when the agent stops",
+	)
+	cmd.Flags().BoolVar(
+		&opts.AccessLog,
+		"access-log",
+		false,
+		"log every HTTP request with the requested pairs and the client identity",
+	)
+	cmd.Flags().DurationVar(
+		&opts.ShutdownTimeout,
+		"shutdown-timeout",
+		10*time.Second,
+		"time given to in-flight requests and gRPC calls to finish when the agent is stopped",
+	)
+	cmd.Flags().StringVar(
+		&opts.TLSCertFile,
+		"tls.cert",
+		"",
+		"path to the TLS certificate, enables HTTPS if set",
+	)
+	cmd.Flags().StringVar(
+		&opts.TLSKeyFile,
+		"tls.key",
+		"",
+		"path to the TLS private key",
+	)
+	cmd.Flags().StringVar(
+		&opts.TLSClientCAFile,
+		"tls.client-ca",
+		"",
+		"path to the CA bundle used to verify client certificates, requires client certificates if set",
+	)
+	cmd.Flags().StringArrayVar(
+		&opts.TLSClientRules,
+		"tls.client-cn",
+		nil,
+		"allowed client certificate common name, optionally restricted to paths: CN or CN=/path1,/path2",
+	)
+	return cmd
+}
+
+// updatedPairs returns a channel of pairs whose prices were updated in the
+// cache. The channel is closed when the cache is stopped.
+func updatedPairs(sub *prices.Subscription) <-chan provider.Pair {
+	ch := make(chan provider.Pair)
+	go func() {
+		defer close(ch)
+		for ev := range sub.Events() {
+			ch <- ev.Pair
+		}
+	}()
+	return ch
 }
@@ -0,0 +1,111 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/query"
+)
+
+func NewBenchCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Measure latencies of price models and origins",
+		Long: `Measure latencies of price models and origins.
+
+Calculates prices of the given pairs, or of all pairs, repeatedly and reports
+the 50th, 95th and 99th percentiles of the latency of the full aggregation,
+and of every origin used to calculate the prices. Origins are sorted from the
+slowest to the fastest.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			if opts.BenchRuns <= 0 {
+				return errors.New("number of runs must be positive")
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			if len(pairs) == 0 {
+				if pairs, err = services.PriceProvider.Pairs(); err != nil {
+					return err
+				}
+			}
+			models, err := services.PriceProvider.Models(pairs...)
+			if err != nil {
+				return err
+			}
+			clients, err := opts.Config.Ethereum.ClientRegistry(ethereum.Dependencies{Logger: opts.Logger()})
+			if err != nil {
+				return err
+			}
+			infos := make(map[string]originInfo)
+			for _, i := range opts.Config.origins() {
+				infos[i.Name] = i
+			}
+			results := []benchResult{benchProvider(services.PriceProvider, pairs, opts.BenchRuns)}
+			wp := query.NewHTTPWorkerPool(probeWorkers)
+			for name, originPairs := range modelOrigins(models) {
+				info, ok := infos[name]
+				if !ok {
+					info = originInfo{Name: name, Builtin: true}
+				}
+				h, err := opts.Config.originHandler(info, wp, clients)
+				if err != nil {
+					return err
+				}
+				results = append(results, benchOrigin(name, h, originPairs, opts.BenchRuns))
+			}
+			sortBenchResults(results)
+			return writeBenchResults(os.Stdout, opts.Format.format, results)
+		},
+	}
+	cmd.Flags().IntVarP(
+		&opts.BenchRuns,
+		"runs",
+		"n",
+		10,
+		"how many times prices are calculated",
+	)
+	return cmd
+}
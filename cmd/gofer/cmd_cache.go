@@ -0,0 +1,150 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+)
+
+func NewCacheCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Args:  cobra.NoArgs,
+		Short: "Manage the persistent store of the agent cache",
+		Long: `Manage the persistent store of the agent cache.
+
+Inspects and removes prices kept by the agent cache in the BoltDB file given
+by the path option, in Redis, and in the snapshot file given by the
+snapshot_path option of the agent cache block.`,
+	}
+	cmd.AddCommand(
+		NewCacheLsCmd(opts),
+		NewCachePruneCmd(opts),
+		NewCacheClearCmd(opts),
+	)
+	return cmd
+}
+
+func NewCacheLsCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Args:    cobra.NoArgs,
+		Short:   "List prices in the persistent store of the agent cache",
+		Long: `List prices in the persistent store of the agent cache.
+
+Lists, for every stored price, the time at which it was fetched and its age,
+and, for the snapshot, the number of prices in the history, followed by the
+number of entries and the size on disk of every store.`,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			p, err := openPersistentCache(opts)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cErr := p.close(); err == nil {
+					err = cErr
+				}
+			}()
+			files, entries, err := p.list(time.Now())
+			if err != nil {
+				return err
+			}
+			return writeCache(os.Stdout, opts.Format.format, files, entries)
+		},
+	}
+}
+
+func NewCachePruneCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Args:  cobra.NoArgs,
+		Short: "Remove old prices from the persistent store of the agent cache",
+		Long: `Remove old prices from the persistent store of the agent cache.
+
+Removes prices fetched longer ago than the given duration, and prices of the
+snapshot history older than it. The agent should be stopped first, as it
+keeps the BoltDB file open and writes the snapshot when it shuts down.`,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			if opts.CacheOlderThan <= 0 {
+				return errors.New("older-than must be positive")
+			}
+			p, err := openPersistentCache(opts)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cErr := p.close(); err == nil {
+					err = cErr
+				}
+			}()
+			removed, err := p.prune(time.Now().Add(-opts.CacheOlderThan))
+			if err != nil {
+				return err
+			}
+			return p.writeRemoved(os.Stdout, removed)
+		},
+	}
+	cmd.Flags().DurationVar(
+		&opts.CacheOlderThan,
+		"older-than",
+		24*time.Hour,
+		"remove prices fetched longer ago than the duration",
+	)
+	return cmd
+}
+
+func NewCacheClearCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Args:  cobra.NoArgs,
+		Short: "Remove all prices from the persistent store of the agent cache",
+		Long: `Remove all prices from the persistent store of the agent cache.
+
+Removes all stored prices and the snapshot file. The agent should be stopped
+first, as it keeps the BoltDB file open and writes the snapshot when it
+shuts down.`,
+		RunE: func(_ *cobra.Command, _ []string) (err error) {
+			p, err := openPersistentCache(opts)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cErr := p.close(); err == nil {
+					err = cErr
+				}
+			}()
+			removed, err := p.clear()
+			if err != nil {
+				return err
+			}
+			return p.writeRemoved(os.Stdout, removed)
+		},
+	}
+}
+
+func openPersistentCache(opts *options) (*persistentCache, error) {
+	if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+		return nil, err
+	}
+	return opts.Config.persistentCache()
+}
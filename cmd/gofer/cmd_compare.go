@@ -0,0 +1,101 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func NewCompareCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Compare prices with prices of an external reference API",
+		Long: `Compare prices with prices of an external reference API.
+
+Calculates prices of the given pairs, or of all pairs, and compares them with
+prices fetched from the reference API configured in the reference block.
+Returns a non-zero status code if a price deviates from the reference price
+by more than the threshold, or if a price or a reference price could not be
+read, so it can be used as a sanity check before promoting configuration
+changes.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			if opts.CompareThreshold < 0 {
+				return errors.New("threshold must not be negative")
+			}
+			ref, err := opts.Config.reference(&http.Client{Timeout: referenceTimeout})
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := services.PriceProvider.Pairs()
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				if pairs, err = expandPairs(services.PriceProvider, args); err != nil {
+					return err
+				}
+			}
+			diffs, err := compareReference(ctx, services.PriceProvider, ref, pairs)
+			if err != nil {
+				return err
+			}
+			if err := writeReferenceDiffs(os.Stdout, opts.Format.format, diffs); err != nil {
+				return err
+			}
+			for _, d := range diffs {
+				if d.exceeds(opts.CompareThreshold) {
+					return errors.New("deviation threshold exceeded")
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Float64Var(
+		&opts.CompareThreshold,
+		"threshold",
+		1,
+		"maximum deviation of prices from reference prices, in percent",
+	)
+	return cmd
+}
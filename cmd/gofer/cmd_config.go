@@ -0,0 +1,107 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+)
+
+func NewConfigCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Args:  cobra.NoArgs,
+		Short: "Inspect the configuration",
+		Long:  `Inspect the configuration.`,
+	}
+	cmd.AddCommand(
+		NewConfigValidateCmd(opts),
+		NewConfigRenderCmd(opts),
+	)
+	return cmd
+}
+
+func NewConfigValidateCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Args:  cobra.NoArgs,
+		Short: "Validate the configuration without starting any services",
+		Long: `Validate the configuration without starting any services.
+
+Reports unknown blocks and attributes, references to undefined origins, pairs
+and Ethereum clients, duplicate price models and origins, and malformed
+addresses, with the file and line at which they are defined.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			var diags hcl.Diagnostics
+			err := config.LoadFiles(&opts.Config, opts.ConfigFilePath)
+			if err == nil {
+				diags = opts.Config.validate()
+			} else if !errors.As(err, &diags) {
+				return err
+			}
+			if err := writeDiagnostics(os.Stderr, diags); err != nil {
+				return err
+			}
+			if diags.HasErrors() {
+				return withCode(errCodeConfig, errors.New("configuration is invalid"))
+			}
+			fmt.Println("Configuration is valid")
+			return nil
+		},
+	}
+}
+
+func NewConfigRenderCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "render",
+		Args:  cobra.NoArgs,
+		Short: "Print the effective configuration",
+		Long: `Print the effective configuration.
+
+Prints the configuration as it is used by Gofer: all configuration files and
+included files merged, variables, environment variables and functions
+evaluated, and omitted optional attributes with their zero values. Secrets
+are redacted.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			_, err := os.Stdout.Write(opts.Config.render())
+			return err
+		},
+	}
+}
+
+// writeDiagnostics writes the diagnostics with snippets of the files in
+// which they occurred.
+func writeDiagnostics(w io.Writer, diags hcl.Diagnostics) error {
+	parser := hclparse.NewParser()
+	for _, d := range diags {
+		if d.Subject != nil {
+			// Parse errors are already reported by the diagnostics.
+			_, _ = parser.ParseHCLFile(d.Subject.Filename)
+		}
+	}
+	return hcl.NewDiagnosticTextWriter(w, parser.Files(), 120, false).WriteDiagnostics(diags)
+}
@@ -0,0 +1,71 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func NewConvertCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "convert AMOUNT FROM TO",
+		Args:  cobra.ExactArgs(3),
+		Short: "Convert an amount of an asset to another asset",
+		Long: `Convert an amount of an asset to another asset.
+
+Finds the shortest path of configured pairs between the assets, using pairs
+in both directions, so a cross rate is calculated if there is no pair for
+the assets. Shows the converted amount with the price, the time of the
+oldest price used and the path of the conversion.`,
+		Example: `  gofer convert 2.5 ETH USD`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			amount, from, to, err := parseConversionArgs(args)
+			if err != nil {
+				return err
+			}
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			c, err := convert(services.PriceProvider, amount, from, to)
+			if err != nil {
+				return err
+			}
+			return writeConversion(os.Stdout, opts.Format.format, c)
+		},
+	}
+}
@@ -0,0 +1,108 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/prices"
+)
+
+func NewDiffCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Compare prices with values of on-chain oracles",
+		Long: `Compare prices with values of on-chain oracles.
+
+Calculates prices of the given pairs, or of all pairs with a configured
+oracle, and compares them with the current values of the median contracts
+configured in the oracles block. Returns a non-zero status code if a price
+deviates from the value of its oracle by more than the threshold, or if
+a price or a value could not be read.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			if opts.DiffThreshold < 0 {
+				return errors.New("threshold must not be negative")
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			clients, err := opts.Config.Ethereum.ClientRegistry(ethereum.Dependencies{Logger: opts.Logger()})
+			if err != nil {
+				return err
+			}
+			oracles, err := opts.Config.oracles(clients)
+			if err != nil {
+				return err
+			}
+			available := make([]provider.Pair, 0, len(oracles))
+			for p := range oracles {
+				available = append(available, p)
+			}
+			pairs := available
+			if len(args) > 0 {
+				if pairs, err = prices.ExpandPairs(args, available); err != nil {
+					return err
+				}
+			}
+			diffs, err := diffOracles(ctx, services.PriceProvider, oracles, pairs)
+			if err != nil {
+				return err
+			}
+			if err := writeDiffs(os.Stdout, opts.Format.format, diffs); err != nil {
+				return err
+			}
+			for _, d := range diffs {
+				if d.exceeds(opts.DiffThreshold) {
+					return errors.New("deviation threshold exceeded")
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Float64Var(
+		&opts.DiffThreshold,
+		"threshold",
+		1,
+		"maximum deviation of prices from values of oracles, in percent",
+	)
+	return cmd
+}
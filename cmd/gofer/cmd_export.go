@@ -0,0 +1,149 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+
+	"gofer-cli/pkg/agent"
+)
+
+func NewExportCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Write prices of given PAIRs to files periodically",
+		Long: `Write prices of given PAIRs, or of all pairs, to a file periodically until
+interrupted.
+
+Prices are written in the format given with --format. The file is rotated
+when it exceeds the size given with --rotate.size, or the age given with
+--rotate.interval. Rotated files are named with the time of the rotation and
+can be compressed with gzip.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			switch {
+			case opts.ExportFile == "":
+				return errors.New("file must be given")
+			case opts.ExportInterval <= 0:
+				return errors.New("export interval must be positive")
+			case opts.RotateSize < 0 || opts.RotateInterval < 0:
+				return errors.New("rotation size and interval must not be negative")
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			file := newRotatingFile(opts.ExportFile, opts.RotateSize*1024*1024, opts.RotateInterval, opts.RotateCompress)
+			defer func() {
+				if cErr := file.Close(); err == nil {
+					err = cErr
+				}
+			}()
+			// The file is rotated before a round of prices is written. A new
+			// marshaller is used for every round, except for the CSV format,
+			// for which a new marshaller is used for every file, so the header
+			// is written once at the beginning of every file.
+			var m marshal.Marshaller
+			newMarshaller := func() (marshal.Marshaller, error) {
+				rotated, err := file.rotate()
+				if err != nil {
+					return nil, err
+				}
+				if m == nil || rotated || opts.Format.format != csvFormat {
+					m, err = opts.marshaller(opts.CSVColumns)
+				}
+				return m, err
+			}
+			interval := timeutil.NewTicker(opts.ExportInterval)
+			interval.Start(ctx)
+			f := &priceFollower{
+				provider:   services.PriceProvider,
+				hook:       services.PriceHook,
+				pairs:      pairs,
+				interval:   interval,
+				marshaller: newMarshaller,
+				out:        file,
+				errOut:     os.Stderr,
+			}
+			return f.run(ctx)
+		},
+	}
+	cmd.Flags().StringVar(
+		&opts.ExportFile,
+		"file",
+		"",
+		"path of the file to which prices are written",
+	)
+	cmd.Flags().DurationVar(
+		&opts.ExportInterval,
+		"interval",
+		time.Minute,
+		"how often prices are written",
+	)
+	cmd.Flags().Int64Var(
+		&opts.RotateSize,
+		"rotate.size",
+		100,
+		"size in megabytes after which the file is rotated, 0 disables rotation by size",
+	)
+	cmd.Flags().DurationVar(
+		&opts.RotateInterval,
+		"rotate.interval",
+		24*time.Hour,
+		"age after which the file is rotated, 0 disables rotation by age",
+	)
+	cmd.Flags().BoolVar(
+		&opts.RotateCompress,
+		"rotate.compress",
+		false,
+		"compress rotated files with gzip",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.CSVColumns,
+		"csv.columns",
+		agent.DefaultCSVColumns,
+		"columns written with --format csv: pair, type, base, quote, price, bid, ask, volume, timestamp, sources, error",
+	)
+	return cmd
+}
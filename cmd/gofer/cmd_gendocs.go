@@ -0,0 +1,68 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+func NewGenDocsCmd(opts *options) *cobra.Command {
+	var manDir, markdownDir string
+	cmd := &cobra.Command{
+		Use:    "gen-docs",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		Short:  "Generate man pages and markdown references of the commands",
+		Long: `Generate man pages and markdown references of the commands.
+
+The documentation is generated from the definitions of the commands and
+their flags, one file per command. It is meant to be run when packaging
+a release.`,
+		RunE: func(c *cobra.Command, _ []string) error {
+			if manDir == "" && markdownDir == "" {
+				return errors.New("at least one of --man and --markdown must be set")
+			}
+			root := c.Root()
+			root.DisableAutoGenTag = true
+			if manDir != "" {
+				if err := genDocsTree(root, manDir, manFilename, genMan(opts.Version)); err != nil {
+					return err
+				}
+			}
+			if markdownDir != "" {
+				if err := genDocsTree(root, markdownDir, markdownFilename, genMarkdown); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(
+		&manDir,
+		"man",
+		"",
+		"directory to write the man pages to",
+	)
+	cmd.Flags().StringVar(
+		&markdownDir,
+		"markdown",
+		"",
+		"directory to write the markdown references to",
+	)
+	return cmd
+}
@@ -0,0 +1,79 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func NewGraphCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Print price models of given PAIRs as a graph",
+		Long: `Print price models of given PAIRs, or of all pairs, as a graph.
+
+The graph is written in the DOT language, which can be rendered with Graphviz,
+or as a Mermaid flowchart, if the --mermaid flag is used.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			models, err := services.PriceProvider.Models(pairs...)
+			if err != nil {
+				return err
+			}
+			g := newModelGraph(models)
+			if opts.GraphMermaid {
+				return g.writeMermaid(os.Stdout)
+			}
+			return g.writeDOT(os.Stdout)
+		},
+	}
+	cmd.Flags().BoolVar(
+		&opts.GraphMermaid,
+		"mermaid",
+		false,
+		"write the graph as a Mermaid flowchart instead of DOT",
+	)
+	return cmd
+}
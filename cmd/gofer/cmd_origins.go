@@ -0,0 +1,113 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/query"
+)
+
+// probeWorkers is the number of workers which send HTTP requests to origins
+// when they are probed.
+const probeWorkers = 10
+
+func NewOriginsCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "origins",
+		Aliases: []string{"origin"},
+		Args:    cobra.NoArgs,
+		Short:   "List the origins used by the price models",
+		Long: `List the origins used by the price models.
+
+Lists the configured origins and the built-in origins referenced by the price
+models, the pairs for which each origin is used and the parameters of
+configured origins, such as endpoints and API keys. Secret parameters are
+redacted. Origins are listed as JSON with the json and ndjson formats, and as
+a table otherwise.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			return writeOrigins(os.Stdout, opts.Format.format, opts.Config.origins())
+		},
+	}
+	cmd.AddCommand(NewOriginsTestCmd(opts))
+	return cmd
+}
+
+func NewOriginsTestCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [ORIGIN...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Send a live request to origins used by the price models",
+		Long: `Send a live request to origins used by the price models.
+
+Fetches the price of a sample pair, the first pair for which an origin is used
+by the price models, from every origin, or only from the given origins, and
+reports whether the origin is reachable, how long the request took and
+whether the response could be parsed. Returns a non-zero status code if any
+origin failed.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			infos := opts.Config.origins()
+			if len(args) > 0 {
+				byName := make(map[string]originInfo, len(infos))
+				for _, i := range infos {
+					byName[i.Name] = i
+				}
+				infos = infos[:0]
+				for _, name := range args {
+					i, ok := byName[name]
+					if !ok {
+						return fmt.Errorf("unknown origin: %s", name)
+					}
+					infos = append(infos, i)
+				}
+			}
+			clients, err := opts.Config.Ethereum.ClientRegistry(ethereum.Dependencies{Logger: opts.Logger()})
+			if err != nil {
+				return err
+			}
+			probes := opts.Config.probeOrigins(infos, query.NewHTTPWorkerPool(probeWorkers), clients, opts.ProbeTimeout)
+			if err := writeProbes(os.Stdout, opts.Format.format, probes); err != nil {
+				return err
+			}
+			for _, p := range probes {
+				if !p.ok() {
+					return errors.New("origin test failed")
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(
+		&opts.ProbeTimeout,
+		"timeout",
+		10*time.Second,
+		"how long to wait for the response of an origin",
+	)
+	return cmd
+}
@@ -16,7 +16,9 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
 
@@ -24,24 +26,43 @@ import (
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/prices"
 )
 
 func NewPairsCmd(opts *options) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "pairs [PAIR...]",
 		Aliases: []string{"pair"},
 		Args:    cobra.MinimumNArgs(0),
 		Short:   "List all supported asset pairs",
 		Long:    `List all supported asset pairs.`,
-		RunE: func(_ *cobra.Command, args []string) (err error) {
+		RunE: func(c *cobra.Command, args []string) (err error) {
+			if args, err = opts.pairArgs(args); err != nil {
+				return err
+			}
 			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
 				return err
 			}
-			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
-			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, opts.Format.format)
+			if opts.Concurrency < 1 {
+				return errors.New("concurrency must be positive")
+			}
+			if opts.Format.format == promFormat {
+				// Models have no values to be written as metrics.
+				return fmt.Errorf("the pairs command does not support the %s format", opts.Format.String())
+			}
+			marshaller, err := opts.marshaller(nil)
 			if err != nil {
 				return err
 			}
+			ctx, ctxCancel := signal.NotifyContext(c.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				return err
+			}
+			// The marshaller of the services does not support all formats.
+			services.Marshaller = marshaller
 			if err = services.Start(ctx); err != nil {
 				return err
 			}
@@ -51,6 +72,9 @@ func NewPairsCmd(opts *options) *cobra.Command {
 					_ = services.Marshaller.Write(os.Stderr, err)
 				}
 				_ = services.Marshaller.Flush()
+				if err != nil {
+					writeJSONError(os.Stderr, err)
+				}
 				// Set err to nil because error was already handled by marshaller.
 				err = nil
 			}()
@@ -60,7 +84,10 @@ func NewPairsCmd(opts *options) *cobra.Command {
 					err = sErr
 				}
 			}()
-			pairs, err := provider.NewPairs(args...)
+			if opts.Concurrency > 1 {
+				services.PriceProvider = prices.NewConcurrentProvider(services.PriceProvider, opts.Concurrency)
+			}
+			pairs, err := expandPairs(services.PriceProvider, args)
 			if err != nil {
 				return err
 			}
@@ -68,6 +95,7 @@ func NewPairsCmd(opts *options) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			opts.Config.describeModels(models)
 			for _, p := range models {
 				if mErr := services.Marshaller.Write(os.Stdout, p); mErr != nil {
 					_ = services.Marshaller.Write(os.Stderr, mErr)
@@ -76,4 +104,87 @@ func NewPairsCmd(opts *options) *cobra.Command {
 			return
 		},
 	}
+	cmd.Flags().StringVar(
+		&opts.PairsFile,
+		"pairs-file",
+		"",
+		"read pairs from a file, one per line, in addition to the arguments, - for the standard input",
+	)
+	cmd.Flags().IntVar(
+		&opts.Concurrency,
+		"concurrency",
+		1,
+		"number of batches of pairs fetched at the same time",
+	)
+	return cmd
+}
+
+// expandPairs parses pairs given as command arguments. Patterns, such as
+// "*/USD", are expanded against the pairs supported by the price provider.
+func expandPairs(p provider.Provider, args []string) ([]provider.Pair, error) {
+	var available []provider.Pair
+	if prices.HasWildcards(args...) {
+		pairs, err := p.Pairs()
+		if err != nil {
+			return nil, err
+		}
+		available = pairs
+	}
+	return prices.ExpandPairs(args, available)
+}
+
+// pairArgs returns the pairs given as command arguments, followed by the
+// pairs read from the pairs file, if it is set. The "-" argument, and the
+// "-" pairs file, are replaced by the pairs read from the standard input.
+func (o *options) pairArgs(args []string) ([]string, error) {
+	return o.pairArgsFrom(os.Stdin, args)
+}
+
+// pairArgsFrom is pairArgs with the standard input read from stdin, which is
+// read at most once.
+func (o *options) pairArgsFrom(stdin io.Reader, args []string) ([]string, error) {
+	var stdinPairs []string
+	var stdinRead bool
+	readStdin := func() ([]string, error) {
+		if !stdinRead {
+			pairs, err := prices.ReadPairs(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read pairs from the standard input: %w", err)
+			}
+			stdinPairs, stdinRead = pairs, true
+		}
+		return stdinPairs, nil
+	}
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != "-" {
+			res = append(res, arg)
+			continue
+		}
+		pairs, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, pairs...)
+	}
+	switch o.PairsFile {
+	case "":
+		return res, nil
+	case "-":
+		pairs, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+		return append(res, pairs...), nil
+	}
+	f, err := os.Open(o.PairsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pairs, err := prices.ReadPairs(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pairs from %s: %w", o.PairsFile, err)
+	}
+	return append(res, pairs...), nil
 }
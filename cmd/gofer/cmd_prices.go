@@ -16,41 +16,94 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/prices"
 )
 
+// validateFollow validates the flags of the follow mode and enables it if
+// the follow interval or the count is set. It is called before services
+// are started, so invalid flags do not connect to the origins.
+func (o *options) validateFollow(flags *pflag.FlagSet) error {
+	if o.FollowCount < 0 {
+		return errors.New("count must not be negative")
+	}
+	if o.MinSources < 0 {
+		return errors.New("minimum number of sources must not be negative")
+	}
+	if flags.Changed("follow.interval") || o.FollowCount > 0 {
+		o.Follow = true
+	}
+	if !o.Follow {
+		return nil
+	}
+	if o.Strict {
+		return errors.New("the strict flag cannot be used with --follow")
+	}
+	if o.FollowInterval <= 0 {
+		return errors.New("follow interval must be positive")
+	}
+	return nil
+}
+
 func NewPricesCmd(opts *options) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "prices [PAIR...]",
 		Aliases: []string{"price"},
 		Args:    cobra.MinimumNArgs(0),
 		Short:   "Return prices for given PAIRs",
 		Long:    `Return prices for given PAIRs.`,
 		RunE: func(c *cobra.Command, args []string) (err error) {
+			if args, err = opts.pairArgs(args); err != nil {
+				return err
+			}
+			if err := opts.validateFollow(c.Flags()); err != nil {
+				return err
+			}
 			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
 				return err
 			}
-			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
-			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, opts.Format.format)
+			if opts.Concurrency < 1 {
+				return errors.New("concurrency must be positive")
+			}
+			marshaller, err := opts.marshaller(opts.CSVColumns)
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(c.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
 			if err != nil {
 				return err
 			}
+			// The marshaller of the services does not support all formats.
+			services.Marshaller = marshaller
 			if err = services.Start(ctx); err != nil {
 				return err
 			}
+			// Errors are written as JSON after the output of the marshaller.
+			var failures []error
 			defer func() {
 				if err != nil {
 					exitCode = 1
 					_ = services.Marshaller.Write(os.Stderr, err)
+					failures = append(failures, err)
 				}
 				_ = services.Marshaller.Flush()
+				for _, fErr := range failures {
+					writeJSONError(os.Stderr, fErr)
+				}
 				// Set err to nil because error was already handled by marshaller.
 				err = nil
 			}()
@@ -60,18 +113,68 @@ func NewPricesCmd(opts *options) *cobra.Command {
 					err = sErr
 				}
 			}()
-			pairs, err := provider.NewPairs(args...)
-			if err != nil {
-				return err
+			if opts.Concurrency > 1 {
+				services.PriceProvider = prices.NewConcurrentProvider(services.PriceProvider, opts.Concurrency)
 			}
-			prices, err := services.PriceProvider.Prices(pairs...)
+			pairs, err := expandPairs(services.PriceProvider, args)
 			if err != nil {
 				return err
 			}
+			if opts.Follow {
+				interval := timeutil.NewTicker(opts.FollowInterval)
+				interval.Start(ctx)
+				newMarshaller := func() (marshal.Marshaller, error) {
+					return opts.marshaller(opts.CSVColumns)
+				}
+				if opts.Format.format == csvFormat {
+					// The CSV marshaller can be flushed repeatedly, so the
+					// header is written only once.
+					newMarshaller = func() (marshal.Marshaller, error) {
+						return marshaller, nil
+					}
+				}
+				f := &priceFollower{
+					provider:   services.PriceProvider,
+					hook:       services.PriceHook,
+					pairs:      pairs,
+					interval:   interval,
+					marshaller: newMarshaller,
+					changes:    opts.FollowChanges,
+					count:      opts.FollowCount,
+					minSources: opts.MinSources,
+					out:        os.Stdout,
+					errOut:     os.Stderr,
+				}
+				return f.run(ctx)
+			}
+			var missing []provider.Pair
+			if opts.Strict {
+				if pairs, missing, err = splitMissingPairs(services.PriceProvider, pairs); err != nil {
+					return err
+				}
+			}
+			prices := make(map[provider.Pair]*provider.Price)
+			if len(pairs) > 0 || len(missing) == 0 {
+				if prices, err = services.PriceProvider.Prices(pairs...); err != nil {
+					return err
+				}
+			}
 			err = services.PriceHook.Check(prices)
 			if err != nil {
 				return err
 			}
+			// The strict mode fails because of not enough sources before
+			// prices are marked with errors by the minimum of sources.
+			var strictCode int
+			var strictErrs []error
+			if opts.Strict {
+				minSources := opts.StrictSources
+				if opts.MinSources > minSources {
+					minSources = opts.MinSources
+				}
+				strictCode, strictErrs = strictCheck(missing, prices, minSources)
+			}
+			checkMinSources(prices, opts.MinSources)
 			for _, p := range prices {
 				if mErr := services.Marshaller.Write(os.Stdout, p); mErr != nil {
 					_ = services.Marshaller.Write(os.Stderr, mErr)
@@ -84,7 +187,85 @@ func NewPricesCmd(opts *options) *cobra.Command {
 					break
 				}
 			}
+			if opts.Strict {
+				for _, sErr := range strictErrs {
+					_ = services.Marshaller.Write(os.Stderr, sErr)
+				}
+				failures = append(failures, strictErrs...)
+				if strictCode != 0 {
+					exitCode = strictCode
+				}
+			} else {
+				failures = append(failures, priceErrors(prices, opts.MinSources)...)
+			}
 			return
 		},
 	}
+	cmd.Flags().BoolVar(
+		&opts.Follow,
+		"follow",
+		false,
+		"keep printing prices every follow interval until interrupted",
+	)
+	cmd.Flags().DurationVar(
+		&opts.FollowInterval,
+		"follow.interval",
+		10*time.Second,
+		"how often prices are printed with --follow, implies --follow (alias --interval)",
+	)
+	cmd.Flags().IntVar(
+		&opts.FollowCount,
+		"count",
+		0,
+		"stop after printing prices count times, implies --follow (alias --repeat)",
+	)
+	cmd.Flags().BoolVar(
+		&opts.FollowChanges,
+		"follow.changes",
+		false,
+		"print only prices which changed since they were last printed with --follow",
+	)
+	cmd.Flags().StringVar(
+		&opts.PairsFile,
+		"pairs-file",
+		"",
+		"read pairs from a file, one per line, in addition to the arguments, - for the standard input",
+	)
+	cmd.Flags().IntVar(
+		&opts.Concurrency,
+		"concurrency",
+		1,
+		"number of batches of pairs fetched at the same time",
+	)
+	cmd.Flags().IntVar(
+		&opts.MinSources,
+		"min-sources",
+		0,
+		"set an error on prices calculated from fewer origin prices, with the number of origin prices used, 0 disables the check",
+	)
+	cmd.Flags().BoolVar(
+		&opts.Strict,
+		"strict",
+		false,
+		"exit with a non-zero status code if any pair is missing, has an error or has not enough sources",
+	)
+	cmd.Flags().IntVar(
+		&opts.StrictSources,
+		"strict.sources",
+		1,
+		"minimum number of origin prices from which a price is calculated in the strict mode",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.CSVColumns,
+		"csv.columns",
+		agent.DefaultCSVColumns,
+		"columns written with --format csv: pair, type, base, quote, price, bid, ask, volume, timestamp, sources, error",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.Fields,
+		"fields",
+		nil,
+		"fields of prices written with --format json, ndjson, csv or table: type, pair, base, quote, price, bid, ask, vol24h, ts, sources, error, params, prices",
+	)
+	return cmd
 }
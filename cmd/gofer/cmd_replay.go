@@ -0,0 +1,195 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+)
+
+func NewReplayCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Return prices for given PAIRs using recorded responses of origins",
+		Long: `Return prices for given PAIRs using recorded responses of origins.
+
+Prices are calculated by the local price models, but responses to HTTP
+requests, including requests to Ethereum RPC endpoints, are read from
+fixtures in the directory given with --fixtures instead of being sent.
+Fixtures are recorded with the record command. If no pairs are given, the
+pairs for which the fixtures were recorded are used.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fixturePrices(cmd.Context(), opts, args, false)
+		},
+	}
+	cmd.Flags().StringVar(
+		&opts.Fixtures,
+		"fixtures",
+		"",
+		"directory of recorded responses of origins",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.CSVColumns,
+		"csv.columns",
+		agent.DefaultCSVColumns,
+		"columns written with --format csv: pair, type, base, quote, price, bid, ask, volume, timestamp, sources, error",
+	)
+	return cmd
+}
+
+func NewRecordCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Return prices for given PAIRs and record responses of origins",
+		Long: `Return prices for given PAIRs and record responses of origins.
+
+Prices are calculated by the local price models, and responses to HTTP
+requests, including requests to Ethereum RPC endpoints, are written as
+fixtures to the directory given with --fixtures, together with a manifest
+describing the recording. The fixtures can be used by the replay command.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fixturePrices(cmd.Context(), opts, args, true)
+		},
+	}
+	cmd.Flags().StringVar(
+		&opts.Fixtures,
+		"fixtures",
+		"",
+		"directory to which responses of origins are recorded",
+	)
+	cmd.Flags().StringSliceVar(
+		&opts.CSVColumns,
+		"csv.columns",
+		agent.DefaultCSVColumns,
+		"columns written with --format csv: pair, type, base, quote, price, bid, ask, volume, timestamp, sources, error",
+	)
+	return cmd
+}
+
+// fixturePrices prints prices calculated by the local price models using
+// fixtures instead of sending HTTP requests or, if record is true, sending
+// requests and recording their responses as fixtures.
+func fixturePrices(ctx context.Context, opts *options, args []string, record bool) (err error) {
+	if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+		return err
+	}
+	if opts.Fixtures == "" {
+		return errors.New("fixtures directory must be given")
+	}
+	transport := &fixtureTransport{dir: opts.Fixtures}
+	if record {
+		if err := os.MkdirAll(opts.Fixtures, 0o755); err != nil {
+			return err
+		}
+		transport.record = http.DefaultTransport
+	} else if len(args) == 0 {
+		manifest, err := transport.readManifest()
+		if err != nil {
+			return err
+		}
+		if manifest != nil {
+			args = manifest.Pairs
+		}
+	}
+	// Origins and Ethereum clients send requests using the default
+	// transport, so it must be replaced before they are created.
+	http.DefaultTransport = transport
+	marshaller, err := opts.marshaller(opts.CSVColumns)
+	if err != nil {
+		return err
+	}
+	ctx, ctxCancel := signal.NotifyContext(ctx, os.Interrupt)
+	services, err := opts.Config.ClientServices(ctx, opts.Logger(), true, marshal.Plain)
+	if err != nil {
+		ctxCancel()
+		return err
+	}
+	// The marshaller of the services does not support all formats.
+	services.Marshaller = marshaller
+	if err = services.Start(ctx); err != nil {
+		ctxCancel()
+		return err
+	}
+	// Errors are written as JSON after the output of the marshaller.
+	var failures []error
+	defer func() {
+		if err != nil {
+			exitCode = 1
+			_ = services.Marshaller.Write(os.Stderr, err)
+			failures = append(failures, err)
+		}
+		_ = services.Marshaller.Flush()
+		for _, fErr := range failures {
+			writeJSONError(os.Stderr, fErr)
+		}
+		// Set err to nil because error was already handled by marshaller.
+		err = nil
+	}()
+	defer func() {
+		ctxCancel()
+		if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+			err = sErr
+		}
+	}()
+	pairs, err := expandPairs(services.PriceProvider, args)
+	if err != nil {
+		return err
+	}
+	prices, err := services.PriceProvider.Prices(pairs...)
+	if err != nil {
+		return err
+	}
+	if record {
+		if len(pairs) == 0 {
+			for pair := range prices {
+				pairs = append(pairs, pair)
+			}
+			sort.Slice(pairs, func(i, j int) bool {
+				return pairs[i].String() < pairs[j].String()
+			})
+		}
+		if err := transport.writeManifest(opts.Version, pairs); err != nil {
+			return err
+		}
+	}
+	for _, p := range prices {
+		if mErr := services.Marshaller.Write(os.Stdout, p); mErr != nil {
+			_ = services.Marshaller.Write(os.Stderr, mErr)
+		}
+	}
+	// If any pair has been returned with an error, then we should return a non-zero status code.
+	for _, p := range prices {
+		if p.Error != "" {
+			exitCode = 1
+			break
+		}
+	}
+	failures = append(failures, priceErrors(prices, 0)...)
+	return
+}
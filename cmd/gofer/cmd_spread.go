@@ -0,0 +1,71 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func NewSpreadCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "spread PAIR...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Show bid/ask spreads of the origins of PAIRs",
+		Long: `Show bid/ask spreads of the origins of PAIRs.
+
+Calculates prices of the given pairs and lists, for every origin price used,
+the bid, the ask, the spread in percent of their midpoint and the deviation
+of the origin price from the price of the pair. Origins are listed from the
+largest spread, so origins with degraded order books come first. Pairs may
+be given as patterns, as in the prices command.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			prices, err := services.PriceProvider.Prices(pairs...)
+			if err != nil {
+				return err
+			}
+			return writeSpreads(os.Stdout, opts.Format.format, spreads(prices))
+		},
+	}
+}
@@ -0,0 +1,188 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func NewTopCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Show a dashboard of prices",
+		Long: `Show a dashboard of prices.
+
+Shows the prices of the given pairs, or of all pairs, in the terminal, and
+refreshes them every interval. For every pair, the change since the previous
+refresh, the largest deviation of an origin price, the age of the price and
+the number of origin prices used are shown.
+
+Keys: q quits, s changes the sort column, r reverses the order, o shows the
+origin prices of every pair, and / sets a filter, such as "BTC" or "*/USD".`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			if opts.TopInterval <= 0 {
+				return errors.New("interval must be positive")
+			}
+			d, err := newTopDashboard(opts.TopInterval, opts.TopSort)
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			keys := make(chan byte)
+			if restore, err := enableKeys(int(os.Stdin.Fd())); err == nil {
+				defer func() { _ = restore() }()
+				go readKeys(os.Stdin, keys)
+			}
+			interval := timeutil.NewTicker(opts.TopInterval)
+			interval.Start(ctx)
+			t := &topRunner{
+				dashboard: d,
+				provider:  services.PriceProvider,
+				hook:      services.PriceHook,
+				pairs:     pairs,
+				interval:  interval,
+				keys:      keys,
+				out:       os.Stdout,
+				colors:    isTerminal(os.Stdout),
+			}
+			return t.run(ctx)
+		},
+	}
+	cmd.Flags().DurationVar(
+		&opts.TopInterval,
+		"interval",
+		5*time.Second,
+		"how often prices are refreshed",
+	)
+	cmd.Flags().StringVar(
+		&opts.TopSort,
+		"sort",
+		"pair",
+		"column by which pairs are sorted: pair, price, change, deviation, age, sources",
+	)
+	return cmd
+}
+
+// topRunner refreshes the dashboard on every tick of the interval, and
+// redraws it after every refresh and every key pressed.
+type topRunner struct {
+	dashboard *topDashboard
+	provider  provider.Provider
+	hook      provider.PriceHook
+	pairs     []provider.Pair
+	interval  *timeutil.Ticker
+	keys      <-chan byte
+	out       io.Writer
+	colors    bool
+}
+
+type topPrices struct {
+	prices map[provider.Pair]*provider.Price
+	err    error
+}
+
+// run shows the dashboard until the context is canceled or the user quits.
+// Prices are fetched in the background, so keys are handled while they are
+// fetched.
+func (t *topRunner) run(ctx context.Context) error {
+	_, _ = io.WriteString(t.out, "\033[?1049h\033[?25l") // Alternate screen, hidden cursor.
+	defer func() { _, _ = io.WriteString(t.out, "\033[?25h\033[?1049l") }()
+	results := make(chan topPrices, 1)
+	fetching := false
+	fetch := func() {
+		if fetching {
+			return
+		}
+		fetching = true
+		go func() {
+			ps, err := t.provider.Prices(t.pairs...)
+			if err == nil {
+				err = t.hook.Check(ps)
+			}
+			results <- topPrices{prices: ps, err: err}
+		}()
+	}
+	fetch()
+	if err := t.dashboard.render(t.out, time.Now(), t.colors); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.interval.TickCh():
+			fetch()
+			continue
+		case r := <-results:
+			fetching = false
+			t.dashboard.update(r.prices, r.err, time.Now())
+		case k := <-t.keys:
+			if t.dashboard.key(k) {
+				return nil
+			}
+		}
+		if err := t.dashboard.render(t.out, time.Now(), t.colors); err != nil {
+			return err
+		}
+	}
+}
+
+// readKeys sends the keys read from the reader to the channel, until the
+// reader fails.
+func readKeys(r io.Reader, keys chan<- byte) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := r.Read(buf); err != nil {
+			return
+		}
+		keys <- buf[0]
+	}
+}
@@ -0,0 +1,38 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewVersionCmd(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Args:  cobra.NoArgs,
+		Short: "Show the version and build information",
+		Long: `Show the version and build information.
+
+Shows the version, the commit and the date of the build, the Go version, the
+platform, the version of the configuration format, the supported output
+formats and the built-in origins. Include it in bug reports.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return writeVersion(os.Stdout, opts.Format.format, newVersionInfo(opts.Version))
+		},
+	}
+}
@@ -0,0 +1,154 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func NewWatchCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [PAIR...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Alert when prices of PAIRs move more than a threshold",
+		Long: `Alert when prices of PAIRs move more than a threshold.
+
+Fetches prices of the given pairs, or of all pairs, every interval, and
+writes an alert when a price differs from any price fetched within the
+window by at least the threshold, in percent. For every alert, the command
+given with --exec is run by the shell, with the alert as JSON on the
+standard input and in the GOFER_PAIR, GOFER_PRICE, GOFER_PREVIOUS_PRICE,
+GOFER_CHANGE and GOFER_TIME environment variables, and the alert is POSTed
+as JSON to the URL given with --webhook. A move is reported once; the next
+alert of the pair is measured from the price which triggered it.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if args, err = opts.pairArgs(args); err != nil {
+				return err
+			}
+			if err := config.LoadFiles(&opts.Config, opts.ConfigFilePath); err != nil {
+				return err
+			}
+			if opts.WatchThreshold <= 0 {
+				return errors.New("threshold must be positive")
+			}
+			if opts.WatchWindow <= 0 {
+				return errors.New("window must be positive")
+			}
+			if opts.WatchInterval <= 0 {
+				return errors.New("interval must be positive")
+			}
+			if opts.WatchWebhook != "" {
+				if u, err := url.Parse(opts.WatchWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return errors.New("webhook must be an absolute http or https URL")
+				}
+			}
+			var jsonFormat bool
+			switch opts.Format.format {
+			case marshal.JSON, marshal.NDJSON:
+				jsonFormat = true
+			case marshal.Plain:
+			default:
+				return fmt.Errorf("the watch command does not support the %s format", opts.Format.String())
+			}
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			services, err := opts.Config.ClientServices(ctx, opts.Logger(), opts.NoRPC, marshal.Plain)
+			if err != nil {
+				ctxCancel()
+				return err
+			}
+			if err = services.Start(ctx); err != nil {
+				ctxCancel()
+				return err
+			}
+			defer func() {
+				ctxCancel()
+				if sErr := <-services.Wait(); err == nil { // Ignore sErr if another error has already occurred.
+					err = sErr
+				}
+			}()
+			pairs, err := expandPairs(services.PriceProvider, args)
+			if err != nil {
+				return err
+			}
+			interval := timeutil.NewTicker(opts.WatchInterval)
+			interval.Start(ctx)
+			w := &priceWatcher{
+				watch:    &priceWatch{threshold: opts.WatchThreshold, window: opts.WatchWindow},
+				provider: services.PriceProvider,
+				hook:     services.PriceHook,
+				pairs:    pairs,
+				interval: interval,
+				command:  opts.WatchExec,
+				webhook:  opts.WatchWebhook,
+				client:   &http.Client{Timeout: 10 * time.Second},
+				json:     jsonFormat,
+				out:      os.Stdout,
+				errOut:   os.Stderr,
+				now:      time.Now,
+			}
+			return w.run(ctx)
+		},
+	}
+	cmd.Flags().Float64Var(
+		&opts.WatchThreshold,
+		"threshold",
+		1,
+		"minimum change of a price within the window, in percent, which triggers an alert",
+	)
+	cmd.Flags().DurationVar(
+		&opts.WatchWindow,
+		"window",
+		5*time.Minute,
+		"time within which price changes are measured",
+	)
+	cmd.Flags().DurationVar(
+		&opts.WatchInterval,
+		"interval",
+		10*time.Second,
+		"how often prices are fetched",
+	)
+	cmd.Flags().StringVar(
+		&opts.WatchExec,
+		"exec",
+		"",
+		"shell command run for every alert",
+	)
+	cmd.Flags().StringVar(
+		&opts.WatchWebhook,
+		"webhook",
+		"",
+		"URL to which every alert is POSTed as JSON",
+	)
+	cmd.Flags().StringVar(
+		&opts.PairsFile,
+		"pairs-file",
+		"",
+		"read pairs from a file, one per line, in addition to the arguments, - for the standard input",
+	)
+	return cmd
+}
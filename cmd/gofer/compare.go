@@ -0,0 +1,247 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+const (
+	// referenceTimeout is the timeout of a request to the reference API.
+	referenceTimeout = 10 * time.Second
+
+	// maxReferenceResponseSize is the maximum size of a response of the
+	// reference API.
+	maxReferenceResponseSize = 1 << 20
+)
+
+var tableReferenceDiffColumns = []tableColumn{
+	{name: "PAIR"},
+	{name: "PRICE", right: true},
+	{name: "REFERENCE", right: true},
+	{name: "DEVIATION", right: true},
+	{name: "ERROR"},
+}
+
+// referenceAPI fetches prices from an external price API.
+type referenceAPI struct {
+	client  *http.Client
+	url     string
+	query   jsonQuery
+	headers map[string]string
+	symbols map[string]string
+}
+
+// referenceDiff compares the price calculated by Gofer with the price
+// returned by the reference API.
+type referenceDiff struct {
+	Pair      string  `json:"pair"`
+	Price     float64 `json:"price"`
+	Reference float64 `json:"reference"`
+
+	// Deviation is the difference between the price and the reference
+	// price, in percent of the reference price.
+	Deviation float64 `json:"deviation"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// reference returns the configured reference API, which is queried using
+// the given HTTP client.
+func (c *cliConfig) reference(client *http.Client) (*referenceAPI, error) {
+	if c.Reference == nil {
+		return nil, errors.New("reference API is not configured")
+	}
+	if err := validateReferenceURL(c.Reference.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL of the reference API: %w", err)
+	}
+	q, err := parseQuery(c.Reference.Query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query of the reference API: %w", err)
+	}
+	return &referenceAPI{
+		client:  client,
+		url:     c.Reference.URL,
+		query:   q,
+		headers: c.Reference.Headers,
+		symbols: c.Reference.Symbols,
+	}, nil
+}
+
+// validateReferenceURL checks that the URL, with the placeholders
+// replaced, is an HTTP or HTTPS URL.
+func validateReferenceURL(s string) error {
+	u, err := url.Parse(strings.NewReplacer("{base}", "BASE", "{quote}", "QUOTE").Replace(s))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// symbol returns the symbol of the asset used by the API.
+func (r *referenceAPI) symbol(asset string) string {
+	if s, ok := r.symbols[asset]; ok {
+		return s
+	}
+	return asset
+}
+
+// Price fetches the price of the pair.
+func (r *referenceAPI) Price(ctx context.Context, pair provider.Pair) (float64, error) {
+	u := strings.NewReplacer(
+		"{base}", url.PathEscape(r.symbol(pair.Base)),
+		"{quote}", url.PathEscape(r.symbol(pair.Quote)),
+	).Replace(r.url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	var v any
+	dec := json.NewDecoder(io.LimitReader(res.Body, maxReferenceResponseSize))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return 0, fmt.Errorf("invalid response: %w", err)
+	}
+	results, err := r.query.eval(v)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) != 1 {
+		return 0, fmt.Errorf("query returned %d values, expected one price", len(results))
+	}
+	return referencePrice(results[0])
+}
+
+// referencePrice converts the value returned by the query to a price. The
+// price may be a JSON number or a string containing a number.
+func referencePrice(v any) (float64, error) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return 0, fmt.Errorf("query returned %s, expected a price", jsonType(v))
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid price: %q", s)
+	}
+	return f, nil
+}
+
+// compareReference compares prices of the pairs with prices of the
+// reference API. Results are sorted by pair.
+func compareReference(ctx context.Context, p provider.Provider, r *referenceAPI, pairs []provider.Pair) ([]referenceDiff, error) {
+	prices, err := p.Prices(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]provider.Pair{}, pairs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	diffs := make([]referenceDiff, len(sorted))
+	for i, pair := range sorted {
+		diffs[i] = diffReference(ctx, pair, prices[pair], r)
+	}
+	return diffs, nil
+}
+
+func diffReference(ctx context.Context, pair provider.Pair, price *provider.Price, r *referenceAPI) referenceDiff {
+	d := referenceDiff{Pair: pair.String()}
+	switch {
+	case price == nil:
+		d.Error = "no price"
+		return d
+	case price.Error != "":
+		d.Error = price.Error
+		return d
+	}
+	d.Price = price.Price
+	ref, err := r.Price(ctx, pair)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.Reference = ref
+	if d.Reference == 0 {
+		d.Error = "reference price is zero"
+		return d
+	}
+	d.Deviation = (d.Price - d.Reference) / d.Reference * 100
+	return d
+}
+
+// exceeds reports whether the comparison failed, or the absolute deviation
+// is greater than the threshold, in percent.
+func (d referenceDiff) exceeds(threshold float64) bool {
+	return d.Error != "" || math.Abs(d.Deviation) > threshold
+}
+
+// writeReferenceDiffs writes the comparisons as JSON, or NDJSON, if one of
+// these formats is used, and as a table otherwise.
+func writeReferenceDiffs(w io.Writer, format marshal.FormatType, diffs []referenceDiff) error {
+	return writeList(w, format, diffs, tableReferenceDiffColumns, func(d referenceDiff) ([]string, error) {
+		if d.Error != "" {
+			price := ""
+			if d.Price != 0 {
+				price = thousands(d.Price)
+			}
+			return []string{d.Pair, price, "", "", d.Error}, nil
+		}
+		return []string{
+			d.Pair,
+			thousands(d.Price),
+			thousands(d.Reference),
+			fmt.Sprintf("%+.4f%%", d.Deviation),
+			"",
+		}, nil
+	})
+}
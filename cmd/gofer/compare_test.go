@@ -0,0 +1,138 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestCompareReference(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/price/bitcoin/USD":
+			fmt.Fprint(w, `{"data": {"price": "20000"}}`)
+		case "/price/ETH/USD":
+			fmt.Fprint(w, `{"data": {"price": 2000.5}}`)
+		case "/price/MKR/USD":
+			fmt.Fprint(w, `{"data": {"price": null}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := loadTestConfig(t, testGoferBlock+`
+reference {
+  url     = "`+srv.URL+`/price/{base}/{quote}"
+  query   = ".data.price"
+  headers = { "X-API-Key" = "secret" }
+  symbols = { "BTC" = "bitcoin" }
+}
+`)
+	assert.Empty(t, cfg.validate())
+	ref, err := cfg.reference(srv.Client())
+	require.NoError(t, err)
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+	daiusd := provider.Pair{Base: "DAI", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", ethusd, btcusd, mkrusd, daiusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Price: 20200},
+		ethusd: {Pair: ethusd, Price: 2000.5},
+		mkrusd: {Pair: mkrusd, Price: 700},
+		daiusd: {Pair: daiusd, Price: 1},
+	}, nil).Once()
+
+	diffs, err := compareReference(context.Background(), p, ref, []provider.Pair{ethusd, btcusd, mkrusd, daiusd})
+	require.NoError(t, err)
+	assert.Equal(t, []referenceDiff{
+		{Pair: "BTC/USD", Price: 20200, Reference: 20000, Deviation: 1},
+		{Pair: "DAI/USD", Price: 1, Error: "unexpected status: 404 Not Found"},
+		{Pair: "ETH/USD", Price: 2000.5, Reference: 2000.5},
+		{Pair: "MKR/USD", Price: 700, Error: "query returned null, expected a price"},
+	}, diffs)
+
+	assert.False(t, diffs[0].exceeds(1))
+	assert.True(t, diffs[0].exceeds(0.5))
+	assert.True(t, diffs[1].exceeds(100))
+	p.AssertExpectations(t)
+}
+
+func TestWriteReferenceDiffs(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, writeReferenceDiffs(b, marshal.Plain, []referenceDiff{
+		{Pair: "BTC/USD", Price: 20200, Reference: 20000, Deviation: 1},
+		{Pair: "ETH/USD", Price: 2000, Error: "unexpected status: 404 Not Found"},
+	}))
+	assert.Equal(t, `PAIR      PRICE  REFERENCE  DEVIATION  ERROR
+BTC/USD  20,200     20,000   +1.0000%
+ETH/USD   2,000                        unexpected status: 404 Not Found
+`, b.String())
+}
+
+func TestConfig_Reference(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+reference {
+  url   = "ftp://example.com/{base}"
+  query = ".data["
+}
+`)
+	_, err := cfg.reference(http.DefaultClient)
+	assert.EqualError(t, err, `invalid URL of the reference API: unsupported scheme "ftp"`)
+
+	var summaries []string
+	for _, d := range cfg.validate() {
+		require.Equal(t, hcl.DiagError, d.Severity)
+		summaries = append(summaries, d.Summary)
+	}
+	assert.Equal(t, []string{"Invalid URL", "Invalid query"}, summaries)
+
+	cfg = loadTestConfig(t, testGoferBlock)
+	_, err = cfg.reference(http.DefaultClient)
+	assert.EqualError(t, err, "reference API is not configured")
+}
+
+func TestReferencePrice(t *testing.T) {
+	for _, v := range []any{"1.5", json.Number("1.5")} {
+		f, err := referencePrice(v)
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, f)
+	}
+	_, err := referencePrice("abc")
+	assert.EqualError(t, err, `invalid price: "abc"`)
+	_, err = referencePrice(true)
+	assert.EqualError(t, err, "query returned boolean, expected a price")
+}
@@ -0,0 +1,579 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/gofer"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/prices"
+)
+
+// cliConfig is the configuration of the Gofer CLI. It extends the Gofer
+// configuration with blocks that are used only by the CLI.
+type cliConfig struct {
+	gofer.Config
+
+	Agent *agentConfig `hcl:"agent,block,optional"`
+
+	Oracles *oraclesConfig `hcl:"oracles,block,optional"`
+
+	Reference *referenceConfig `hcl:"reference,block,optional"`
+}
+
+// oraclesConfig is the configuration of the on-chain median contracts to
+// which the "gofer diff" command compares prices.
+type oraclesConfig struct {
+	// EthereumClient is the name of the Ethereum client used to read the
+	// contracts.
+	EthereumClient string `hcl:"ethereum_client"`
+
+	// Contracts is a map of pairs to addresses of median contracts.
+	Contracts map[string]string `hcl:"contracts"`
+
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// referenceConfig is the configuration of the external price API to which
+// the "gofer compare" command compares prices.
+type referenceConfig struct {
+	// URL is the URL from which the price of a pair is fetched. The {base}
+	// and {quote} placeholders are replaced with the assets of the pair.
+	URL string `hcl:"url"`
+
+	// Query is a jq-like path of the price in the JSON response, as used
+	// by the --query flag.
+	Query string `hcl:"query"`
+
+	// Headers is a map of HTTP headers sent with the requests, e.g. API
+	// keys.
+	Headers map[string]string `hcl:"headers,optional"`
+
+	// Symbols is a map of assets to symbols used by the API in place of
+	// the assets. Assets without a symbol are used as they are.
+	Symbols map[string]string `hcl:"symbols,optional"`
+
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// agentConfig is the configuration of the "gofer agent" command.
+type agentConfig struct {
+	// APIKeys is a map of labels to API keys. If not empty, clients must
+	// send one of the keys in the X-API-Key header. Labels are used to
+	// attribute requests in logs.
+	APIKeys map[string]string `hcl:"api_keys,optional"`
+
+	// BasicAuth is a map of user names to bcrypt password hashes. If not
+	// empty, clients must authenticate with HTTP Basic auth.
+	BasicAuth map[string]string `hcl:"basic_auth,optional"`
+
+	// JWT enables JWT bearer-token authentication.
+	JWT *jwtConfig `hcl:"jwt,block,optional"`
+
+	// RateLimit enables per-client rate limiting.
+	RateLimit *rateLimitConfig `hcl:"rate_limit,block,optional"`
+
+	// Concurrency limits the number of requests handled at the same time.
+	Concurrency *concurrencyConfig `hcl:"concurrency,block,optional"`
+
+	// CORS enables Cross-Origin Resource Sharing.
+	CORS *corsConfig `hcl:"cors,block,optional"`
+
+	// IPFilter restricts access to the agent to the configured networks.
+	IPFilter *ipFilterConfig `hcl:"ip_filter,block,optional"`
+
+	// Webhooks enables webhook subscriptions.
+	Webhooks *webhooksConfig `hcl:"webhooks,block,optional"`
+
+	// Cache enables serving prices from a periodically refreshed cache
+	// instead of fetching them on every request.
+	Cache *cacheConfig `hcl:"cache,block,optional"`
+
+	// Server configures HTTP server timeouts and limits.
+	Server *serverConfig `hcl:"server,block,optional"`
+}
+
+// serverConfig is the configuration of HTTP server timeouts and limits.
+// Zero values use the agent defaults.
+type serverConfig struct {
+	ReadTimeout    int   `hcl:"read_timeout,optional"`  // In seconds.
+	WriteTimeout   int   `hcl:"write_timeout,optional"` // In seconds.
+	IdleTimeout    int   `hcl:"idle_timeout,optional"`  // In seconds.
+	MaxHeaderBytes int   `hcl:"max_header_bytes,optional"`
+	MaxBodySize    int64 `hcl:"max_body_size,optional"`
+
+	// H2C enables HTTP/2 over cleartext connections.
+	H2C                       bool `hcl:"h2c,optional"`
+	HTTP2MaxConcurrentStreams int  `hcl:"http2_max_concurrent_streams,optional"`
+
+	// SocketMode is the octal permission mode of the Unix domain socket
+	// file, e.g. "0660".
+	SocketMode string `hcl:"socket_mode,optional"`
+}
+
+// concurrencyConfig is the configuration of the concurrency limit.
+type concurrencyConfig struct {
+	MaxInFlight  int `hcl:"max_in_flight"`
+	MaxQueued    int `hcl:"max_queued,optional"`
+	QueueTimeout int `hcl:"queue_timeout,optional"` // In seconds.
+}
+
+// corsConfig is the configuration of Cross-Origin Resource Sharing.
+type corsConfig struct {
+	AllowedOrigins []string `hcl:"allowed_origins"`
+	AllowedMethods []string `hcl:"allowed_methods,optional"`
+	AllowedHeaders []string `hcl:"allowed_headers,optional"`
+	MaxAge         int      `hcl:"max_age,optional"` // In seconds.
+}
+
+// ipFilterConfig is the configuration of IP address filtering. Networks
+// are given in CIDR notation or as single IP addresses.
+type ipFilterConfig struct {
+	Allow          []string `hcl:"allow,optional"`
+	Deny           []string `hcl:"deny,optional"`
+	TrustedProxies []string `hcl:"trusted_proxies,optional"`
+}
+
+// webhooksConfig is the configuration of webhook subscriptions.
+type webhooksConfig struct {
+	Secret           string `hcl:"secret"`
+	MaxRetries       int    `hcl:"max_retries,optional"`
+	RetryBackoff     int    `hcl:"retry_backoff,optional"` // In seconds.
+	Timeout          int    `hcl:"timeout,optional"`       // In seconds.
+	MaxSubscriptions int    `hcl:"max_subscriptions,optional"`
+	AllowPrivateURLs bool   `hcl:"allow_private_urls,optional"`
+}
+
+// cacheConfig is the configuration of the price cache. If Pairs is empty,
+// all pairs with a price model are cached.
+type cacheConfig struct {
+	Interval int      `hcl:"interval"`         // In seconds.
+	TTL      int      `hcl:"ttl,optional"`     // In seconds.
+	MaxAge   int      `hcl:"max_age,optional"` // In seconds.
+	Pairs    []string `hcl:"pairs,optional"`
+
+	// CacheOnly makes the agent serve prices only from the cache, without
+	// fetching prices which are not cached.
+	CacheOnly bool `hcl:"cache_only,optional"`
+
+	// WarmUpTimeout, if positive, makes the agent fetch all cached prices
+	// on startup and report readiness only after they are fetched or the
+	// timeout elapses. In seconds.
+	WarmUpTimeout int `hcl:"warm_up_timeout,optional"`
+
+	// Spread makes the agent update cached prices evenly over the interval
+	// instead of all at once.
+	Spread bool `hcl:"spread,optional"`
+
+	// Jitter, if positive, delays the update of every cached price by
+	// a random time up to the jitter. In seconds.
+	Jitter int `hcl:"jitter,optional"`
+
+	// Workers is the maximum number of cached prices updated at the same
+	// time. Defaults to 1.
+	Workers int `hcl:"workers,optional"`
+
+	// PairTimeout, if positive, is the maximum time to update a single
+	// cached price. In seconds.
+	PairTimeout int `hcl:"pair_timeout,optional"`
+
+	// HistorySize is the number of recent prices kept in memory for every
+	// cached pair and served by the /history endpoint. If zero, no history
+	// is kept.
+	HistorySize int `hcl:"history_size,optional"`
+
+	// SnapshotPath is the path of the file to which cached prices and their
+	// history are written on shutdown and from which they are loaded on
+	// startup. If empty, no snapshot is written.
+	SnapshotPath string `hcl:"snapshot_path,optional"`
+
+	// DeviationThreshold, if positive, is the percentage by which a cached
+	// price must move to be logged and sent to streaming clients.
+	DeviationThreshold float64 `hcl:"deviation_threshold,optional"`
+
+	// Freshness, if positive, is the maximum age of prices reported by the
+	// origins, after which they are not served. PairFreshness overrides it
+	// for individual pairs. In seconds.
+	Freshness     int            `hcl:"freshness,optional"`
+	PairFreshness map[string]int `hcl:"pair_freshness,optional"`
+
+	// MaxOnDemandPairs, if positive, lets the cache also refresh pairs
+	// which are not listed in Pairs once they are requested, evicting the
+	// least recently requested ones above the limit.
+	MaxOnDemandPairs int `hcl:"max_on_demand_pairs,optional"`
+
+	// Path is the path of the BoltDB file in which cached prices are
+	// persisted. If empty, prices are kept only in memory.
+	Path string `hcl:"path,optional"`
+
+	// Redis enables sharing cached prices between agents through Redis.
+	Redis *redisConfig `hcl:"redis,block,optional"`
+}
+
+// redisConfig is the configuration of the Redis price store.
+type redisConfig struct {
+	Addr      string `hcl:"addr"`
+	Password  string `hcl:"password,optional"`
+	DB        int    `hcl:"db,optional"`
+	KeyPrefix string `hcl:"key_prefix,optional"`
+	TTL       int    `hcl:"ttl,optional"`      // In seconds.
+	LockTTL   int    `hcl:"lock_ttl,optional"` // In seconds.
+}
+
+// rateLimitConfig is the configuration of per-client rate limiting.
+type rateLimitConfig struct {
+	RequestsPerSecond float64 `hcl:"requests_per_second"`
+	Burst             int     `hcl:"burst,optional"`
+}
+
+// jwtConfig is the configuration of JWT bearer-token authentication.
+type jwtConfig struct {
+	HMACSecret string `hcl:"hmac_secret,optional"`
+	JWKSURL    string `hcl:"jwks_url,optional"`
+	Issuer     string `hcl:"issuer,optional"`
+	Audience   string `hcl:"audience,optional"`
+}
+
+// apiKeysEnv is the environment variable from which API keys of the agent
+// are read, in addition to the keys of the agent block, as comma-separated
+// label:key pairs.
+const apiKeysEnv = "GOFER_API_KEYS"
+
+// apiKeys returns the API keys defined in the agent block and in the
+// GOFER_API_KEYS environment variable, or nil if there are none. A key in
+// the environment variable replaces a key with the same label in the
+// config.
+func (c *cliConfig) apiKeys() (map[string]string, error) {
+	envKeys, err := parseAPIKeys(os.Getenv(apiKeysEnv))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", apiKeysEnv, err)
+	}
+	var keys map[string]string
+	if c.Agent != nil {
+		keys = c.Agent.APIKeys
+	}
+	if len(envKeys) == 0 {
+		return keys, nil
+	}
+	merged := make(map[string]string, len(keys)+len(envKeys))
+	for label, key := range keys {
+		merged[label] = key
+	}
+	for label, key := range envKeys {
+		merged[label] = key
+	}
+	return merged, nil
+}
+
+// parseAPIKeys parses API keys given as comma-separated label:key pairs.
+// Keys are not included in errors, so they are not leaked to logs.
+func parseAPIKeys(s string) (map[string]string, error) {
+	var keys map[string]string
+	for i, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, key, ok := strings.Cut(entry, ":")
+		label, key = strings.TrimSpace(label), strings.TrimSpace(key)
+		if !ok || label == "" || key == "" {
+			return nil, fmt.Errorf("entry %d must be in the label:key format", i+1)
+		}
+		if _, ok := keys[label]; ok {
+			return nil, fmt.Errorf("duplicate label %q", label)
+		}
+		if keys == nil {
+			keys = make(map[string]string)
+		}
+		keys[label] = key
+	}
+	return keys, nil
+}
+
+// basicAuth returns the configured Basic auth users, or nil if the agent
+// block is not present.
+func (c *cliConfig) basicAuth() map[string]string {
+	if c.Agent == nil {
+		return nil
+	}
+	return c.Agent.BasicAuth
+}
+
+// jwt returns the JWT authentication configuration, or nil if it is not
+// configured.
+func (c *cliConfig) jwt() *agent.JWTConfig {
+	if c.Agent == nil || c.Agent.JWT == nil {
+		return nil
+	}
+	return &agent.JWTConfig{
+		HMACSecret: []byte(c.Agent.JWT.HMACSecret),
+		JWKSURL:    c.Agent.JWT.JWKSURL,
+		Issuer:     c.Agent.JWT.Issuer,
+		Audience:   c.Agent.JWT.Audience,
+	}
+}
+
+// rateLimit returns the rate limiting configuration, or nil if it is not
+// configured.
+func (c *cliConfig) rateLimit() *agent.RateLimitConfig {
+	if c.Agent == nil || c.Agent.RateLimit == nil {
+		return nil
+	}
+	return &agent.RateLimitConfig{
+		RequestsPerSecond: c.Agent.RateLimit.RequestsPerSecond,
+		Burst:             c.Agent.RateLimit.Burst,
+	}
+}
+
+// concurrencyLimit returns the concurrency limit configuration, or nil if
+// it is not configured.
+func (c *cliConfig) concurrencyLimit() *agent.ConcurrencyLimitConfig {
+	if c.Agent == nil || c.Agent.Concurrency == nil {
+		return nil
+	}
+	return &agent.ConcurrencyLimitConfig{
+		MaxInFlight:  c.Agent.Concurrency.MaxInFlight,
+		MaxQueued:    c.Agent.Concurrency.MaxQueued,
+		QueueTimeout: time.Duration(c.Agent.Concurrency.QueueTimeout) * time.Second,
+	}
+}
+
+// cors returns the CORS configuration, or nil if it is not configured.
+func (c *cliConfig) cors() *agent.CORSConfig {
+	if c.Agent == nil || c.Agent.CORS == nil {
+		return nil
+	}
+	return &agent.CORSConfig{
+		AllowedOrigins: c.Agent.CORS.AllowedOrigins,
+		AllowedMethods: c.Agent.CORS.AllowedMethods,
+		AllowedHeaders: c.Agent.CORS.AllowedHeaders,
+		MaxAge:         time.Duration(c.Agent.CORS.MaxAge) * time.Second,
+	}
+}
+
+// webhooks returns the webhook configuration, or nil if it is not
+// configured.
+func (c *cliConfig) webhooks() *agent.WebhookConfig {
+	if c.Agent == nil || c.Agent.Webhooks == nil {
+		return nil
+	}
+	return &agent.WebhookConfig{
+		Secret:           []byte(c.Agent.Webhooks.Secret),
+		MaxRetries:       c.Agent.Webhooks.MaxRetries,
+		RetryBackoff:     time.Duration(c.Agent.Webhooks.RetryBackoff) * time.Second,
+		Timeout:          time.Duration(c.Agent.Webhooks.Timeout) * time.Second,
+		MaxSubscriptions: c.Agent.Webhooks.MaxSubscriptions,
+		AllowPrivateURLs: c.Agent.Webhooks.AllowPrivateURLs,
+	}
+}
+
+// ipFilter returns the IP filter configuration, or nil if it is not
+// configured.
+func (c *cliConfig) ipFilter() (*agent.IPFilterConfig, error) {
+	if c.Agent == nil || c.Agent.IPFilter == nil {
+		return nil, nil
+	}
+	allow, err := parsePrefixes(c.Agent.IPFilter.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip_filter.allow: %w", err)
+	}
+	deny, err := parsePrefixes(c.Agent.IPFilter.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip_filter.deny: %w", err)
+	}
+	proxies, err := parsePrefixes(c.Agent.IPFilter.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip_filter.trusted_proxies: %w", err)
+	}
+	return &agent.IPFilterConfig{
+		Allow:          allow,
+		Deny:           deny,
+		TrustedProxies: proxies,
+	}, nil
+}
+
+// parsePrefixes parses networks in CIDR notation. Single IP addresses are
+// treated as networks containing only that address.
+func parsePrefixes(s []string) ([]netip.Prefix, error) {
+	var res []netip.Prefix
+	for _, v := range s {
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return res, nil
+}
+
+// priceCache returns the price cache which fetches prices from the given
+// provider, or nil if the cache is not configured.
+func (c *cliConfig) priceCache(p provider.Provider, logger log.Logger) (*prices.Cache, error) {
+	if c.Agent == nil || c.Agent.Cache == nil {
+		return nil, nil
+	}
+	if c.Agent.Cache.Interval <= 0 {
+		return nil, errors.New("cache interval must be positive")
+	}
+	pairs := c.Agent.Cache.Pairs
+	if len(pairs) == 0 {
+		all, err := p.Pairs()
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range all {
+			pairs = append(pairs, pair.String())
+		}
+	}
+	pairFreshness := make(map[string]time.Duration, len(c.Agent.Cache.PairFreshness))
+	for pair, d := range c.Agent.Cache.PairFreshness {
+		pairFreshness[pair] = time.Duration(d) * time.Second
+	}
+	cfg := prices.Config{
+		Pairs:              pairs,
+		PriceProvider:      p,
+		Interval:           timeutil.NewTicker(time.Duration(c.Agent.Cache.Interval) * time.Second),
+		TTL:                time.Duration(c.Agent.Cache.TTL) * time.Second,
+		MaxAge:             time.Duration(c.Agent.Cache.MaxAge) * time.Second,
+		WarmUpTimeout:      time.Duration(c.Agent.Cache.WarmUpTimeout) * time.Second,
+		Spread:             c.Agent.Cache.Spread,
+		Jitter:             time.Duration(c.Agent.Cache.Jitter) * time.Second,
+		HistorySize:        c.Agent.Cache.HistorySize,
+		SnapshotPath:       c.Agent.Cache.SnapshotPath,
+		DeviationThreshold: c.Agent.Cache.DeviationThreshold,
+		Freshness:          time.Duration(c.Agent.Cache.Freshness) * time.Second,
+		PairFreshness:      pairFreshness,
+		MaxOnDemandPairs:   c.Agent.Cache.MaxOnDemandPairs,
+		Workers:            c.Agent.Cache.Workers,
+		PairTimeout:        time.Duration(c.Agent.Cache.PairTimeout) * time.Second,
+		Logger:             logger,
+	}
+	store, err := c.cacheStore()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return prices.New(cfg)
+	}
+	cfg.Store = store
+	cache, err := prices.New(cfg)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+// cachedProvider returns the price provider which serves prices from the
+// cache, and, unless the cache_only option is set, fetches prices which are
+// not cached.
+func (c *cliConfig) cachedProvider(cache *prices.Cache) *prices.CachedProvider {
+	if c.Agent.Cache.CacheOnly {
+		return prices.NewCacheOnlyProvider(cache)
+	}
+	return prices.NewCachedProvider(cache)
+}
+
+// priceHistory returns the history of cached prices, or nil if no history
+// is kept.
+func (c *cliConfig) priceHistory(cache *prices.Cache) agent.PriceHistory {
+	if c.Agent.Cache.HistorySize == 0 {
+		return nil
+	}
+	return cache
+}
+
+// cacheStore returns the store of cached prices, or nil if prices are kept
+// only in memory.
+func (c *cliConfig) cacheStore() (prices.Store, error) {
+	cache := c.Agent.Cache
+	switch {
+	case cache.Path != "" && cache.Redis != nil:
+		return nil, errors.New("cache path and redis cannot be used together")
+	case cache.Path != "":
+		return prices.NewBoltStore(cache.Path)
+	case cache.Redis != nil:
+		return prices.NewRedisStore(prices.RedisStoreConfig{
+			Addr:      cache.Redis.Addr,
+			Password:  cache.Redis.Password,
+			DB:        cache.Redis.DB,
+			KeyPrefix: cache.Redis.KeyPrefix,
+			TTL:       time.Duration(cache.Redis.TTL) * time.Second,
+			LockTTL:   time.Duration(cache.Redis.LockTTL) * time.Second,
+		})
+	}
+	return nil, nil
+}
+
+// minSources returns the minimum number of sources of every median price
+// model, by pair.
+func (c *cliConfig) minSources() map[provider.Pair]int {
+	res := make(map[provider.Pair]int)
+	for _, m := range c.Gofer.PriceModels {
+		if m.Median != nil {
+			res[m.Pair] = m.Median.MinSources
+		}
+	}
+	return res
+}
+
+// applyServerConfig sets the HTTP server timeouts, limits and socket mode
+// in the agent configuration, if they are configured.
+func (c *cliConfig) applyServerConfig(cfg *agent.HTTPAgentConfig) error {
+	if c.Agent == nil || c.Agent.Server == nil {
+		return nil
+	}
+	if c.Agent.Server.SocketMode != "" {
+		mode, err := strconv.ParseUint(c.Agent.Server.SocketMode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid socket mode: %w", err)
+		}
+		cfg.SocketMode = fs.FileMode(mode)
+	}
+	cfg.ReadTimeout = time.Duration(c.Agent.Server.ReadTimeout) * time.Second
+	cfg.WriteTimeout = time.Duration(c.Agent.Server.WriteTimeout) * time.Second
+	cfg.IdleTimeout = time.Duration(c.Agent.Server.IdleTimeout) * time.Second
+	cfg.MaxHeaderBytes = c.Agent.Server.MaxHeaderBytes
+	cfg.MaxRequestBodySize = c.Agent.Server.MaxBodySize
+	cfg.H2C = c.Agent.Server.H2C
+	if c.Agent.Server.HTTP2MaxConcurrentStreams < 0 {
+		return errors.New("http2_max_concurrent_streams must not be negative")
+	}
+	cfg.HTTP2MaxConcurrentStreams = uint32(c.Agent.Server.HTTP2MaxConcurrentStreams)
+	return nil
+}
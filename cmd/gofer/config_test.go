@@ -0,0 +1,443 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/prices"
+)
+
+func loadTestConfig(t *testing.T, hcl string) cliConfig {
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(hcl), 0o600))
+	var cfg cliConfig
+	require.NoError(t, config.LoadFiles(&cfg, []string{path}))
+	return cfg
+}
+
+const testGoferBlock = `
+gofer {
+  rpc_listen_addr = "127.0.0.1:9101"
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "origin" { origin = "bitstamp" }
+    min_sources = 1
+  }
+}
+`
+
+func TestConfig_Agent(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  api_keys = {
+    relayer = "secret"
+  }
+  jwt {
+    hmac_secret = "hmac"
+    issuer      = "auth"
+  }
+}
+`)
+	assert.Equal(t, "127.0.0.1:9101", cfg.Gofer.RPCListenAddr)
+	apiKeys, err := cfg.apiKeys()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"relayer": "secret"}, apiKeys)
+	require.NotNil(t, cfg.jwt())
+	assert.Equal(t, []byte("hmac"), cfg.jwt().HMACSecret)
+	assert.Equal(t, "auth", cfg.jwt().Issuer)
+}
+
+func TestConfig_AgentServer(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  server {
+    read_timeout  = 5
+    write_timeout = 15
+    max_body_size = 4096
+    socket_mode   = "0600"
+    h2c           = true
+
+    http2_max_concurrent_streams = 100
+  }
+}
+`)
+	var agentCfg agent.HTTPAgentConfig
+	require.NoError(t, cfg.applyServerConfig(&agentCfg))
+	assert.Equal(t, 5*time.Second, agentCfg.ReadTimeout)
+	assert.Equal(t, 15*time.Second, agentCfg.WriteTimeout)
+	assert.Equal(t, time.Duration(0), agentCfg.IdleTimeout)
+	assert.Equal(t, int64(4096), agentCfg.MaxRequestBodySize)
+	assert.Equal(t, fs.FileMode(0o600), agentCfg.SocketMode)
+	assert.True(t, agentCfg.H2C)
+	assert.Equal(t, uint32(100), agentCfg.HTTP2MaxConcurrentStreams)
+}
+
+func TestConfig_MinSources(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock)
+	assert.Equal(t, map[provider.Pair]int{{Base: "BTC", Quote: "USD"}: 1}, cfg.minSources())
+}
+
+func TestConfig_AgentConcurrency(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  concurrency {
+    max_in_flight = 10
+    max_queued    = 100
+  }
+}
+`)
+	require.NotNil(t, cfg.concurrencyLimit())
+	assert.Equal(t, 10, cfg.concurrencyLimit().MaxInFlight)
+	assert.Equal(t, 100, cfg.concurrencyLimit().MaxQueued)
+	assert.Equal(t, time.Duration(0), cfg.concurrencyLimit().QueueTimeout)
+}
+
+func TestConfig_AgentBasicAuth(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  basic_auth = {
+    relayer = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+  }
+}
+`)
+	assert.Equal(t, map[string]string{
+		"relayer": "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+	}, cfg.basicAuth())
+}
+
+func TestConfig_AgentIPFilter(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  ip_filter {
+    allow           = ["10.0.0.0/8", "192.168.1.10"]
+    deny            = ["10.0.1.1/24"]
+    trusted_proxies = ["::1"]
+  }
+}
+`)
+	f, err := cfg.ipFilter()
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+	}, f.Allow)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24")}, f.Deny)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("::1/128")}, f.TrustedProxies)
+
+	cfg = loadTestConfig(t, testGoferBlock+`
+agent {
+  ip_filter {
+    allow = ["10.0.0.0/33"]
+  }
+}
+`)
+	_, err = cfg.ipFilter()
+	assert.Error(t, err)
+}
+
+func TestConfig_AgentWebhooks(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  webhooks {
+    secret        = "secret"
+    max_retries   = 5
+    retry_backoff = 2
+
+    allow_private_urls = true
+  }
+}
+`)
+	require.NotNil(t, cfg.webhooks())
+	assert.Equal(t, []byte("secret"), cfg.webhooks().Secret)
+	assert.Equal(t, 5, cfg.webhooks().MaxRetries)
+	assert.Equal(t, 2*time.Second, cfg.webhooks().RetryBackoff)
+	assert.Equal(t, time.Duration(0), cfg.webhooks().Timeout)
+	assert.True(t, cfg.webhooks().AllowPrivateURLs)
+}
+
+func TestConfig_AgentCache(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{btcusd}, nil)
+
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval        = 30
+    max_age         = 300
+    warm_up_timeout = 10
+  }
+}
+`)
+	cache, err := cfg.priceCache(p, null.New())
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	assert.Error(t, cache.Ready())
+	_, err = cache.Price(btcusd)
+	assert.ErrorIs(t, err, prices.ErrNoPrice)
+	_, err = cache.Price(provider.Pair{Base: "ETH", Quote: "USD"})
+	assert.ErrorIs(t, err, prices.ErrUnknownPair)
+
+	cfg = loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval = 30
+    ttl      = 60
+    max_age  = 30
+  }
+}
+`)
+	_, err = cfg.priceCache(p, null.New())
+	assert.Error(t, err)
+}
+
+func TestConfig_AgentCacheOnly(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Pair: btcusd, Price: 42}, nil)
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval   = 30
+    pairs      = ["BTC/USD"]
+    cache_only = true
+  }
+}
+`)
+	cache, err := cfg.priceCache(p, null.New())
+	require.NoError(t, err)
+
+	// Prices which are not cached are not fetched.
+	_, err = cfg.cachedProvider(cache).Price(btcusd)
+	assert.ErrorIs(t, err, prices.ErrNoPrice)
+	p.AssertNotCalled(t, "Price", btcusd)
+
+	cfg.Agent.Cache.CacheOnly = false
+	price, err := cfg.cachedProvider(cache).Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, price.Price)
+}
+
+func TestConfig_AgentCacheOnly_NoCache(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{btcusd}, nil).Maybe()
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval   = 30
+    pairs      = ["BTC/USD"]
+    cache_only = true
+  }
+}
+`)
+	cache, err := cfg.priceCache(p, null.New())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sock := filepath.Join(t.TempDir(), "gofer.sock")
+	s := agent.NewHTTPAgent(agent.HTTPAgentConfig{
+		PriceProvider: cfg.cachedProvider(cache),
+		PriceHook:     nopHook{},
+		Logger:        null.New(),
+		Address:       "unix://" + sock,
+	})
+	require.NoError(t, s.Start(ctx))
+	defer func() { cancel(); <-s.Wait() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	for _, path := range []string{"/v1/price?pair=BTC/USD", "/v1/prices?pairs=BTC/USD"} {
+		req, err := http.NewRequest(http.MethodGet, "http://gofer"+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Cache-Control", "no-cache")
+		req.Header.Set("Pragma", "no-cache")
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+	}
+
+	// In the cache-only mode, prices are never fetched on demand, even if
+	// the client asks for a response which is not served from a cache.
+	p.AssertNotCalled(t, "Price", btcusd)
+	p.AssertNotCalled(t, "Prices", btcusd)
+}
+
+func TestConfig_AgentCacheHistory(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval     = 30
+    pairs        = ["BTC/USD"]
+    history_size = 100
+  }
+}
+`)
+	cache, err := cfg.priceCache(&mocks.Provider{}, null.New())
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.priceHistory(cache))
+
+	cfg.Agent.Cache.HistorySize = 0
+	assert.Nil(t, cfg.priceHistory(cache))
+}
+
+func TestConfig_AgentCacheStore(t *testing.T) {
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{{Base: "BTC", Quote: "USD"}}, nil)
+	path := filepath.Join(t.TempDir(), "prices.db")
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval = 30
+    path     = "`+path+`"
+  }
+}
+`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cache, err := cfg.priceCache(p, null.New())
+	require.NoError(t, err)
+	require.NoError(t, cache.Start(ctx))
+	assert.FileExists(t, path)
+
+	// The store is closed when the cache is stopped, so it can be opened
+	// again.
+	cancel()
+	<-cache.Wait()
+	s, err := prices.NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+}
+
+func TestConfig_AgentCacheRedis(t *testing.T) {
+	m := miniredis.RunT(t)
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval = 30
+    pairs    = ["BTC/USD"]
+    redis {
+      addr       = "`+m.Addr()+`"
+      key_prefix = "replicas:"
+    }
+  }
+}
+`)
+	store, err := cfg.cacheStore()
+	require.NoError(t, err)
+	defer store.Close()
+	assert.IsType(t, &prices.RedisStore{}, store)
+
+	cfg.Agent.Cache.Path = filepath.Join(t.TempDir(), "prices.db")
+	_, err = cfg.cacheStore()
+	assert.Error(t, err)
+}
+
+func TestConfig_APIKeysEnv(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  api_keys = {
+    relayer = "secret"
+    dashboard = "old"
+  }
+}
+`)
+	t.Setenv(apiKeysEnv, "dashboard:new, monitor:key:with:colons,")
+	apiKeys, err := cfg.apiKeys()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"relayer": "secret", "dashboard": "new", "monitor": "key:with:colons"}, apiKeys)
+	// The keys of the config are not modified.
+	assert.Equal(t, "old", cfg.Agent.APIKeys["dashboard"])
+
+	// Keys may be given only in the environment variable.
+	noAgent := loadTestConfig(t, testGoferBlock)
+	apiKeys, err = noAgent.apiKeys()
+	require.NoError(t, err)
+	assert.Len(t, apiKeys, 2)
+
+	for _, v := range []string{"relayer", "relayer:", ":secret", "a:1,a:2"} {
+		t.Setenv(apiKeysEnv, v)
+		_, err = cfg.apiKeys()
+		assert.Error(t, err, v)
+		assert.NotContains(t, err.Error(), "secret", v)
+	}
+}
+
+func TestConfig_NoAgent(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock)
+	apiKeys, err := cfg.apiKeys()
+	require.NoError(t, err)
+	assert.Nil(t, apiKeys)
+	assert.Nil(t, cfg.basicAuth())
+	assert.Nil(t, cfg.jwt())
+	assert.Nil(t, cfg.concurrencyLimit())
+	assert.Nil(t, cfg.webhooks())
+	cache, err := cfg.priceCache(&mocks.Provider{}, null.New())
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+}
+
+func TestConfig_AgentCacheFreshness(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	old := time.Now().Add(-10 * time.Minute)
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Pair: btcusd, Price: 42, Time: old}, nil)
+	p.On("Price", ethusd).Return(&provider.Price{Pair: ethusd, Price: 21, Time: old}, nil)
+	cfg := loadTestConfig(t, testGoferBlock+`
+agent {
+  cache {
+    interval       = 30
+    pairs          = ["BTC/USD", "ETH/USD"]
+    freshness      = 300
+    pair_freshness = {
+      "ETH/USD" = 3600
+    }
+  }
+}
+`)
+	cache, err := cfg.priceCache(p, null.New())
+	require.NoError(t, err)
+
+	price, err := cfg.cachedProvider(cache).Price(btcusd)
+	require.NoError(t, err)
+	assert.NotEmpty(t, price.Error)
+	price, err = cfg.cachedProvider(cache).Price(ethusd)
+	require.NoError(t, err)
+	assert.Empty(t, price.Error)
+}
@@ -0,0 +1,211 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+var tableConversionColumns = []tableColumn{
+	{name: "AMOUNT", right: true},
+	{name: "FROM"},
+	{name: "RESULT", right: true},
+	{name: "TO"},
+	{name: "PRICE", right: true},
+	{name: "TIMESTAMP"},
+	{name: "PATH"},
+}
+
+// conversionStep is a pair used to convert between two assets. If the pair
+// is inverted, its quote asset is converted to its base asset.
+type conversionStep struct {
+	pair     provider.Pair
+	inverted bool
+}
+
+// conversion is an amount of an asset converted to another asset.
+type conversion struct {
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Result float64 `json:"result"`
+
+	// Price is the price of the asset converted from, in the asset
+	// converted to.
+	Price float64 `json:"price"`
+
+	// Time is the time of the oldest price used in the conversion.
+	Time time.Time `json:"timestamp"`
+
+	// Path lists the assets through which the amount is converted,
+	// including the assets converted from and to.
+	Path []string `json:"path"`
+
+	// Pairs lists the pairs whose prices are used in the conversion.
+	Pairs []string `json:"pairs"`
+}
+
+// conversionPath finds the shortest path of pairs to convert from one asset
+// to another. Pairs can be used in both directions, so a price of a cross
+// rate is calculated if there is no pair for the assets.
+func conversionPath(pairs []provider.Pair, from, to string) ([]conversionStep, error) {
+	sorted := append([]provider.Pair{}, pairs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	edges := make(map[string][]conversionStep)
+	for _, p := range sorted {
+		edges[p.Base] = append(edges[p.Base], conversionStep{pair: p})
+		edges[p.Quote] = append(edges[p.Quote], conversionStep{pair: p, inverted: true})
+	}
+	// Search the graph of assets breadth-first. The step through which an
+	// asset is reached is kept to rebuild the path.
+	prev := map[string]conversionStep{}
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 && !visited[to] {
+		asset := queue[0]
+		queue = queue[1:]
+		for _, s := range edges[asset] {
+			next := s.to()
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			prev[next] = s
+			queue = append(queue, next)
+		}
+	}
+	if !visited[to] {
+		return nil, fmt.Errorf("unable to convert %s to %s: no pairs connect the assets", from, to)
+	}
+	var path []conversionStep
+	for asset := to; asset != from; asset = prev[asset].from() {
+		path = append([]conversionStep{prev[asset]}, path...)
+	}
+	return path, nil
+}
+
+func (s conversionStep) from() string {
+	if s.inverted {
+		return s.pair.Quote
+	}
+	return s.pair.Base
+}
+
+func (s conversionStep) to() string {
+	if s.inverted {
+		return s.pair.Base
+	}
+	return s.pair.Quote
+}
+
+// convert converts the amount using the prices of the pairs in the path.
+func convert(p provider.Provider, amount float64, from, to string) (conversion, error) {
+	c := conversion{Amount: amount, From: from, To: to, Price: 1, Path: []string{from}, Pairs: []string{}}
+	pairs, err := p.Pairs()
+	if err != nil {
+		return c, err
+	}
+	path, err := conversionPath(pairs, from, to)
+	if err != nil {
+		return c, err
+	}
+	used := make([]provider.Pair, len(path))
+	for i, s := range path {
+		used[i] = s.pair
+	}
+	prices := map[provider.Pair]*provider.Price{}
+	if len(used) > 0 {
+		if prices, err = p.Prices(used...); err != nil {
+			return c, err
+		}
+	}
+	for _, s := range path {
+		price := prices[s.pair]
+		switch {
+		case price == nil:
+			return c, fmt.Errorf("no price for %s", s.pair)
+		case price.Error != "":
+			return c, fmt.Errorf("unable to get price for %s: %s", s.pair, price.Error)
+		case price.Price <= 0:
+			return c, fmt.Errorf("invalid price for %s: %g", s.pair, price.Price)
+		}
+		if s.inverted {
+			c.Price /= price.Price
+		} else {
+			c.Price *= price.Price
+		}
+		if c.Time.IsZero() || price.Time.Before(c.Time) {
+			c.Time = price.Time
+		}
+		c.Path = append(c.Path, s.to())
+		c.Pairs = append(c.Pairs, s.pair.String())
+	}
+	c.Result = amount * c.Price
+	return c, nil
+}
+
+// parseConversionArgs parses the amount and the assets of the convert
+// command.
+func parseConversionArgs(args []string) (amount float64, from, to string, err error) {
+	amount, err = strconv.ParseFloat(strings.ReplaceAll(args[0], ",", ""), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, "", "", fmt.Errorf("invalid amount: %s", args[0])
+	}
+	from, to = strings.ToUpper(args[1]), strings.ToUpper(args[2])
+	if from == "" || to == "" || strings.Contains(from, "/") || strings.Contains(to, "/") {
+		return 0, "", "", errors.New("assets must be given as symbols, e.g. ETH USD")
+	}
+	return amount, from, to, nil
+}
+
+// writeConversion writes the conversion as a JSON object with the json
+// and ndjson formats, as a YAML document with the yaml format, and as
+// a table otherwise.
+func writeConversion(w io.Writer, format marshal.FormatType, c conversion) error {
+	switch format {
+	case marshal.JSON:
+		format = marshal.NDJSON
+	case yamlFormat:
+		return writeYAML(w, c)
+	}
+	return writeList(w, format, []conversion{c}, tableConversionColumns, func(c conversion) ([]string, error) {
+		ts := ""
+		if !c.Time.IsZero() {
+			ts = c.Time.In(time.UTC).Format(time.RFC3339)
+		}
+		return []string{
+			thousands(c.Amount),
+			c.From,
+			thousands(c.Result),
+			c.To,
+			thousands(c.Price),
+			ts,
+			strings.Join(c.Path, " -> "),
+		}, nil
+	})
+}
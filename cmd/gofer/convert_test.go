@@ -0,0 +1,143 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestConversionPath(t *testing.T) {
+	ethbtc := provider.Pair{Base: "ETH", Quote: "BTC"}
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkreur := provider.Pair{Base: "MKR", Quote: "EUR"}
+	pairs := []provider.Pair{ethbtc, btcusd, mkreur}
+
+	tests := []struct {
+		pairs    []provider.Pair
+		from, to string
+		want     []conversionStep
+		wantErr  string
+	}{
+		{pairs: pairs, from: "BTC", to: "USD", want: []conversionStep{{pair: btcusd}}},
+		{pairs: pairs, from: "USD", to: "BTC", want: []conversionStep{{pair: btcusd, inverted: true}}},
+		{pairs: pairs, from: "ETH", to: "USD", want: []conversionStep{{pair: ethbtc}, {pair: btcusd}}},
+		{pairs: pairs, from: "USD", to: "ETH", want: []conversionStep{{pair: btcusd, inverted: true}, {pair: ethbtc, inverted: true}}},
+		// The shortest path is used.
+		{pairs: append(pairs, ethusd), from: "ETH", to: "USD", want: []conversionStep{{pair: ethusd}}},
+		{pairs: pairs, from: "ETH", to: "ETH", want: nil},
+		{pairs: pairs, from: "ETH", to: "EUR", wantErr: "unable to convert ETH to EUR: no pairs connect the assets"},
+		{pairs: pairs, from: "DAI", to: "USD", wantErr: "unable to convert DAI to USD: no pairs connect the assets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"/"+tt.to, func(t *testing.T) {
+			path, err := conversionPath(tt.pairs, tt.from, tt.to)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, path)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	ethbtc := provider.Pair{Base: "ETH", Quote: "BTC"}
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	older := time.Unix(1600000000, 0)
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{ethbtc, btcusd}, nil)
+	p.On("Prices", ethbtc, btcusd).Return(map[provider.Pair]*provider.Price{
+		ethbtc: {Pair: ethbtc, Price: 0.05, Time: older},
+		btcusd: {Pair: btcusd, Price: 20000, Time: older.Add(time.Minute)},
+	}, nil).Once()
+
+	c, err := convert(p, 2.5, "ETH", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, conversion{
+		Amount: 2.5,
+		From:   "ETH",
+		To:     "USD",
+		Result: 2500,
+		Price:  1000,
+		Time:   older,
+		Path:   []string{"ETH", "BTC", "USD"},
+		Pairs:  []string{"ETH/BTC", "BTC/USD"},
+	}, c)
+
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Error: "not enough sources"},
+	}, nil).Once()
+	_, err = convert(p, 1, "USD", "BTC")
+	assert.EqualError(t, err, "unable to get price for BTC/USD: not enough sources")
+	p.AssertExpectations(t)
+}
+
+func TestParseConversionArgs(t *testing.T) {
+	amount, from, to, err := parseConversionArgs([]string{"1,000.5", "eth", "usd"})
+	require.NoError(t, err)
+	assert.Equal(t, 1000.5, amount)
+	assert.Equal(t, "ETH", from)
+	assert.Equal(t, "USD", to)
+
+	_, _, _, err = parseConversionArgs([]string{"2.5abc", "ETH", "USD"})
+	assert.EqualError(t, err, "invalid amount: 2.5abc")
+	_, _, _, err = parseConversionArgs([]string{"NaN", "ETH", "USD"})
+	assert.EqualError(t, err, "invalid amount: NaN")
+	_, _, _, err = parseConversionArgs([]string{"1", "ETH/USD", "BTC"})
+	assert.Error(t, err)
+}
+
+func TestWriteConversion(t *testing.T) {
+	c := conversion{
+		Amount: 2.5,
+		From:   "ETH",
+		To:     "USD",
+		Result: 2500,
+		Price:  1000,
+		Time:   time.Unix(1600000000, 0).UTC(),
+		Path:   []string{"ETH", "BTC", "USD"},
+		Pairs:  []string{"ETH/BTC", "BTC/USD"},
+	}
+	b := &bytes.Buffer{}
+	require.NoError(t, writeConversion(b, marshal.Plain, c))
+	assert.Equal(t, `AMOUNT  FROM  RESULT  TO   PRICE  TIMESTAMP             PATH
+   2.5  ETH    2,500  USD  1,000  2020-09-13T12:26:40Z  ETH -> BTC -> USD
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, writeConversion(b, marshal.JSON, c))
+	assert.JSONEq(t, `{
+		"amount": 2.5,
+		"from": "ETH",
+		"to": "USD",
+		"result": 2500,
+		"price": 1000,
+		"timestamp": "2020-09-13T12:26:40Z",
+		"path": ["ETH", "BTC", "USD"],
+		"pairs": ["ETH/BTC", "BTC/USD"]
+	}`, b.String())
+}
@@ -0,0 +1,173 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum/geth"
+	medianGeth "github.com/chronicleprotocol/oracle-suite/pkg/price/median/geth"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+// oracleDecimals is the number of decimals of values of median contracts.
+const oracleDecimals = 18
+
+var tableDiffColumns = []tableColumn{
+	{name: "PAIR"},
+	{name: "PRICE", right: true},
+	{name: "ORACLE", right: true},
+	{name: "DEVIATION", right: true},
+	{name: "ORACLE AGE"},
+	{name: "ERROR"},
+}
+
+// oracle is an on-chain median contract.
+type oracle interface {
+	// Val returns the current value of the oracle.
+	Val(ctx context.Context) (*big.Int, error)
+
+	// Age returns the time of the last update of the oracle.
+	Age(ctx context.Context) (time.Time, error)
+}
+
+// oracleDiff compares the price calculated by Gofer with the value of
+// an on-chain oracle.
+type oracleDiff struct {
+	Pair   string    `json:"pair"`
+	Price  float64   `json:"price"`
+	Oracle float64   `json:"oracle"`
+	Age    time.Time `json:"oracle_age"`
+
+	// Deviation is the difference between the price and the value of the
+	// oracle, in percent of the value of the oracle.
+	Deviation float64 `json:"deviation"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// oracles returns the configured median contracts, which are read using the
+// given Ethereum clients.
+func (c *cliConfig) oracles(clients ethereum.ClientRegistry) (map[provider.Pair]oracle, error) {
+	if c.Oracles == nil {
+		return nil, errors.New("oracles are not configured")
+	}
+	client, ok := clients[c.Oracles.EthereumClient]
+	if !ok {
+		return nil, fmt.Errorf("ethereum client %s not found", c.Oracles.EthereumClient)
+	}
+	res := make(map[provider.Pair]oracle, len(c.Oracles.Contracts))
+	for s, addr := range c.Oracles.Contracts {
+		pair, err := provider.NewPair(s)
+		if err != nil {
+			return nil, err
+		}
+		address, err := types.AddressFromHex(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contract address of the oracle %s: %w", s, err)
+		}
+		res[pair] = medianGeth.NewMedian(geth.NewClient(client), address) //nolint:staticcheck
+	}
+	return res, nil
+}
+
+// diffOracles compares prices of the pairs with values of their oracles.
+// Results are sorted by pair.
+func diffOracles(ctx context.Context, p provider.Provider, oracles map[provider.Pair]oracle, pairs []provider.Pair) ([]oracleDiff, error) {
+	prices, err := p.Prices(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]provider.Pair{}, pairs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	diffs := make([]oracleDiff, len(sorted))
+	for i, pair := range sorted {
+		diffs[i] = diffOracle(ctx, pair, prices[pair], oracles[pair])
+	}
+	return diffs, nil
+}
+
+func diffOracle(ctx context.Context, pair provider.Pair, price *provider.Price, o oracle) oracleDiff {
+	d := oracleDiff{Pair: pair.String()}
+	switch {
+	case price == nil:
+		d.Error = "no price"
+		return d
+	case price.Error != "":
+		d.Error = price.Error
+		return d
+	case o == nil:
+		d.Error = "no oracle"
+		return d
+	}
+	d.Price = price.Price
+	val, err := o.Val(ctx)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.Oracle, _ = new(big.Float).Quo(
+		new(big.Float).SetInt(val),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(oracleDecimals), nil)),
+	).Float64()
+	if d.Age, err = o.Age(ctx); err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	if d.Oracle == 0 {
+		d.Error = "oracle value is zero"
+		return d
+	}
+	d.Deviation = (d.Price - d.Oracle) / d.Oracle * 100
+	return d
+}
+
+// exceeds reports whether the comparison failed, or the absolute deviation
+// is greater than the threshold, in percent.
+func (d oracleDiff) exceeds(threshold float64) bool {
+	return d.Error != "" || math.Abs(d.Deviation) > threshold
+}
+
+// writeDiffs writes the comparisons as JSON, or NDJSON, if one of these
+// formats is used, and as a table otherwise.
+func writeDiffs(w io.Writer, format marshal.FormatType, diffs []oracleDiff) error {
+	return writeList(w, format, diffs, tableDiffColumns, func(d oracleDiff) ([]string, error) {
+		if d.Error != "" {
+			return []string{d.Pair, "", "", "", "", d.Error}, nil
+		}
+		return []string{
+			d.Pair,
+			thousands(d.Price),
+			thousands(d.Oracle),
+			fmt.Sprintf("%+.4f%%", d.Deviation),
+			d.Age.In(time.UTC).Format(time.RFC3339),
+			"",
+		}, nil
+	})
+}
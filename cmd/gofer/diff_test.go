@@ -0,0 +1,121 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+type testOracle struct {
+	val *big.Int
+	age time.Time
+	err error
+}
+
+func (o testOracle) Val(context.Context) (*big.Int, error) {
+	return o.val, o.err
+}
+
+func (o testOracle) Age(context.Context) (time.Time, error) {
+	return o.age, o.err
+}
+
+// wad returns the value with 18 decimals.
+func wad(v int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(v), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+}
+
+func TestDiffOracles(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+	age := time.Unix(1600000000, 0)
+	p := &mocks.Provider{}
+	p.On("Prices", ethusd, btcusd, mkrusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Price: 20200},
+		ethusd: {Pair: ethusd, Price: 1990},
+		mkrusd: {Pair: mkrusd, Error: "not enough sources"},
+	}, nil).Once()
+	oracles := map[provider.Pair]oracle{
+		btcusd: testOracle{val: wad(20000), age: age},
+		ethusd: testOracle{err: errors.New("rpc error")},
+		mkrusd: testOracle{val: wad(700), age: age},
+	}
+
+	diffs, err := diffOracles(context.Background(), p, oracles, []provider.Pair{ethusd, btcusd, mkrusd})
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+	assert.Equal(t, oracleDiff{Pair: "BTC/USD", Price: 20200, Oracle: 20000, Age: age, Deviation: 1}, diffs[0])
+	assert.Equal(t, "ETH/USD", diffs[1].Pair)
+	assert.Equal(t, "rpc error", diffs[1].Error)
+	assert.Equal(t, "MKR/USD", diffs[2].Pair)
+	assert.Equal(t, "not enough sources", diffs[2].Error)
+
+	assert.False(t, diffs[0].exceeds(1))
+	assert.True(t, diffs[0].exceeds(0.5))
+	assert.True(t, diffs[1].exceeds(100))
+	p.AssertExpectations(t)
+}
+
+func TestWriteDiffs(t *testing.T) {
+	diffs := []oracleDiff{
+		{Pair: "BTC/USD", Price: 20200, Oracle: 20000, Age: time.Unix(1600000000, 0), Deviation: 1},
+		{Pair: "ETH/USD", Error: "rpc error"},
+	}
+	b := &bytes.Buffer{}
+	require.NoError(t, writeDiffs(b, marshal.Plain, diffs))
+	assert.Equal(t, `PAIR      PRICE  ORACLE  DEVIATION  ORACLE AGE            ERROR
+BTC/USD  20,200  20,000   +1.0000%  2020-09-13T12:26:40Z
+ETH/USD                                                   rpc error
+`, b.String())
+}
+
+func TestConfig_Oracles(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+`
+oracles {
+  ethereum_client = "mainnet"
+  contracts = {
+    "BTC/USD" = "0xe0F30cb149fAADC7247E953746Be9BbBB6B5751f"
+  }
+}
+`)
+	_, err := cfg.oracles(nil)
+	assert.EqualError(t, err, "ethereum client mainnet not found")
+
+	var summaries []string
+	for _, d := range cfg.validate() {
+		require.Equal(t, hcl.DiagError, d.Severity)
+		summaries = append(summaries, d.Summary)
+	}
+	assert.Equal(t, []string{"Unknown Ethereum client"}, summaries)
+
+	cfg = loadTestConfig(t, testGoferBlock)
+	_, err = cfg.oracles(nil)
+	assert.EqualError(t, err, "oracles are not configured")
+}
@@ -0,0 +1,72 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// dryRunFiles contains the config and the fixtures used with the --dry-run
+// flag.
+//
+//go:embed dryrun
+var dryRunFiles embed.FS
+
+// setupDryRun makes commands read responses to HTTP requests from the
+// bundled fixtures, and load the bundled config unless the config file is
+// given explicitly. Prices are never fetched from an agent.
+//
+// The config is written to a temporary directory, because config files are
+// loaded from the file system. It is removed by removeDryRunConfig.
+func (o *options) setupDryRun(config bool) error {
+	fixtures, err := fs.Sub(dryRunFiles, "dryrun/fixtures")
+	if err != nil {
+		return err
+	}
+	// Origins, Ethereum clients and the reference API send requests using
+	// the default transport.
+	http.DefaultTransport = &fixtureTransport{fsys: fixtures}
+	o.NoRPC = true
+	if config {
+		return nil
+	}
+	b, err := dryRunFiles.ReadFile("dryrun/config.hcl")
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "gofer-dry-run-")
+	if err != nil {
+		return err
+	}
+	o.dryRunDir = dir
+	path := filepath.Join(dir, "config.hcl")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return err
+	}
+	o.ConfigFilePath = []string{path}
+	return nil
+}
+
+// removeDryRunConfig removes the config written by setupDryRun, if any.
+func (o *options) removeDryRunConfig() {
+	if o.dryRunDir != "" {
+		_ = os.RemoveAll(o.dryRunDir)
+	}
+}
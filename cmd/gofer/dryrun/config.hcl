@@ -0,0 +1,66 @@
+# Config used with the --dry-run flag. Responses of the origins, and of the
+# reference API, are read from the fixtures in the fixtures directory, so
+# prices are always the same and no requests are sent.
+gofer {
+  rpc_listen_addr = "127.0.0.1:9101"
+
+  origin "coinbasepro" {
+    type = "coinbasepro"
+  }
+
+  origin "gemini" {
+    type = "gemini"
+  }
+
+  origin "bittrex" {
+    type = "bittrex"
+  }
+
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "origin" { origin = "coinbasepro" }
+    source "BTC/USD" "origin" { origin = "gemini" }
+    source "BTC/USD" "origin" { origin = "bittrex" }
+    min_sources = 2
+  }
+
+  price_model "ETH/USD" "median" {
+    source "ETH/USD" "origin" { origin = "coinbasepro" }
+    source "ETH/USD" "origin" { origin = "gemini" }
+    source "ETH/USD" "origin" { origin = "bittrex" }
+    min_sources = 2
+  }
+
+  # Bittrex fails to return the price, so the price is calculated from two
+  # origin prices.
+  price_model "MKR/USD" "median" {
+    source "MKR/USD" "origin" { origin = "coinbasepro" }
+    source "MKR/USD" "origin" { origin = "gemini" }
+    source "MKR/USD" "origin" { origin = "bittrex" }
+    min_sources = 2
+  }
+
+  price_model "ETH/BTC" "indirect" {
+    source "ETH/USD" "origin" { origin = "." }
+    source "BTC/USD" "origin" { origin = "." }
+  }
+}
+
+ethereum {
+  client "default" {
+    rpc_urls = ["https://rpc.example.com"]
+    chain_id = 1
+  }
+}
+
+oracles {
+  ethereum_client = "default"
+  contracts = {
+    "BTC/USD" = "0xe0F30cb149fAADC7247E953746Be9BbBB6B5751f"
+    "ETH/USD" = "0x64DE91F5A373Cd4c28de3600cB34C7C6cE410C85"
+  }
+}
+
+reference {
+  url   = "https://reference.example.com/v1/prices/{base}/{quote}"
+  query = ".price"
+}
@@ -0,0 +1,83 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+)
+
+func TestDryRun(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	opts := &options{ConfigFilePath: []string{"./config.hcl"}}
+	require.NoError(t, opts.setupDryRun(false))
+	assert.True(t, opts.NoRPC)
+	require.Len(t, opts.ConfigFilePath, 1)
+	require.NoError(t, config.LoadFiles(&opts.Config, opts.ConfigFilePath))
+	assert.Empty(t, opts.Config.validate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	services, err := opts.Config.ClientServices(ctx, null.New(), true, marshal.Plain)
+	require.NoError(t, err)
+	require.NoError(t, services.Start(ctx))
+	defer func() {
+		cancel()
+		<-services.Wait()
+	}()
+
+	// Every pair of the bundled config has a price, and the bittrex origin
+	// fails for MKR/USD.
+	prices, err := services.PriceProvider.Prices()
+	require.NoError(t, err)
+	want := map[string]float64{"BTC/USD": 20000, "ETH/USD": 1500, "MKR/USD": 700, "ETH/BTC": 0.075}
+	require.Len(t, prices, len(want))
+	for pair, price := range prices {
+		assert.Empty(t, price.Error, pair)
+		assert.InDelta(t, want[pair.String()], price.Price, 1e-9, pair)
+	}
+	assert.Equal(t, 2, agent.PriceSources(prices[provider.Pair{Base: "MKR", Quote: "USD"}]))
+
+	// The config is removed when the command finishes.
+	opts.removeDryRunConfig()
+	_, err = os.Stat(opts.ConfigFilePath[0])
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDryRun_Config(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	// The config file given explicitly is used.
+	opts := &options{ConfigFilePath: []string{"./gofer.hcl"}}
+	require.NoError(t, opts.setupDryRun(true))
+	assert.Equal(t, []string{"./gofer.hcl"}, opts.ConfigFilePath)
+	assert.IsType(t, &fixtureTransport{}, http.DefaultTransport)
+	opts.removeDryRunConfig()
+}
@@ -0,0 +1,137 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+
+	"gofer-cli/pkg/agent"
+)
+
+// Codes of the errors written to the standard error output as JSON, which
+// wrappers may use to tell classes of errors apart.
+const (
+	errCodeError            = "error"
+	errCodeUsage            = "usage"
+	errCodeConfig           = "config"
+	errCodeTimeout          = "timeout"
+	errCodePairNotFound     = "pair_not_found"
+	errCodePrice            = "price"
+	errCodeNotEnoughSources = "not_enough_sources"
+)
+
+// codedError is an error with the code, and optionally the pair and the
+// origin, reported in the JSON error output.
+type codedError struct {
+	code   string
+	pair   string
+	origin string
+	err    error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns the error with the code.
+func withCode(code string, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// pairError returns the error of the pair with the code. The origin is the
+// first origin which returned an error for the price, if any.
+func pairError(code string, p *provider.Price, pair provider.Pair, err error) error {
+	e := &codedError{code: code, pair: pair.String(), err: err}
+	if p != nil {
+		for _, o := range originPrices(p) {
+			if o.Error != "" {
+				e.origin = o.Parameters["origin"]
+				break
+			}
+		}
+	}
+	return e
+}
+
+// jsonError is an error written to the standard error output as JSON.
+type jsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Pair    string `json:"pair,omitempty"`
+	Origin  string `json:"origin,omitempty"`
+}
+
+// newJSONError returns the JSON representation of the error. Errors without
+// a code are classified by their type.
+func newJSONError(err error) jsonError {
+	res := jsonError{Code: errCodeError, Message: err.Error()}
+	var (
+		coded    *codedError
+		notFound graph.ErrPairNotFound
+		diags    hcl.Diagnostics
+	)
+	switch {
+	case errors.As(err, &coded):
+		res.Code, res.Pair, res.Origin = coded.code, coded.pair, coded.origin
+	case errors.As(err, &notFound):
+		res.Code, res.Pair = errCodePairNotFound, notFound.Pair.String()
+	case errors.As(err, &diags):
+		res.Code = errCodeConfig
+	}
+	return res
+}
+
+// writeJSONError writes the error as a single line of JSON, so wrappers can
+// tell classes of errors apart without parsing messages.
+func writeJSONError(w io.Writer, err error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(newJSONError(err))
+}
+
+// priceErrors returns the errors of the prices which have been returned
+// with an error, sorted by pair. Errors set by checkMinSources fail because
+// of not enough sources.
+func priceErrors(prices map[provider.Pair]*provider.Price, minSources int) []error {
+	var errs []error
+	for pair, p := range prices {
+		if p.Error == "" {
+			continue
+		}
+		code := errCodePrice
+		if n := agent.PriceSources(p); n < minSources && p.Error == minSourcesError(n, minSources) {
+			code = errCodeNotEnoughSources
+		}
+		errs = append(errs, pairError(code, p, pair, fmt.Errorf("%s: %s", pair, p.Error)))
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].(*codedError).pair < errs[j].(*codedError).pair
+	})
+	return errs
+}
@@ -0,0 +1,90 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+func TestNewJSONError(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := &provider.Price{Pair: btcusd, Error: "not enough sources", Prices: []*provider.Price{
+		{Type: "origin", Parameters: map[string]string{"origin": "binance"}},
+		{Type: "origin", Parameters: map[string]string{"origin": "kraken"}, Error: "timeout"},
+	}}
+	tests := []struct {
+		err  error
+		want jsonError
+	}{
+		{
+			err:  errors.New("failed"),
+			want: jsonError{Code: errCodeError, Message: "failed"},
+		},
+		{
+			err:  withCode(errCodeUsage, errors.New("unknown flag: --foo")),
+			want: jsonError{Code: errCodeUsage, Message: "unknown flag: --foo"},
+		},
+		{
+			err:  fmt.Errorf("wrapped: %w", pairError(errCodePrice, price, btcusd, errors.New("BTC/USD: failed"))),
+			want: jsonError{Code: errCodePrice, Message: "wrapped: BTC/USD: failed", Pair: "BTC/USD", Origin: "kraken"},
+		},
+		{
+			err:  graph.ErrPairNotFound{Pair: btcusd},
+			want: jsonError{Code: errCodePairNotFound, Message: "unable to find the BTC/USD pair", Pair: "BTC/USD"},
+		},
+		{
+			err:  hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Unsupported block type"}},
+			want: jsonError{Code: errCodeConfig, Message: "<nil>: Unsupported block type; "},
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, newJSONError(tt.err))
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSONError(&buf, withCode(errCodeTimeout, errors.New("timed out after 1s")))
+	assert.Equal(t, `{"code":"timeout","message":"timed out after 1s"}`+"\n", buf.String())
+}
+
+func TestPriceErrors(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+	errs := priceErrors(map[provider.Pair]*provider.Price{
+		mkrusd: {Pair: mkrusd, Error: "0 sources, at least 2 required", Prices: []*provider.Price{{Error: "failed"}}},
+		btcusd: {Pair: btcusd},
+		ethusd: {Pair: ethusd, Error: "failed"},
+	}, 2)
+	var got []jsonError
+	for _, err := range errs {
+		got = append(got, newJSONError(err))
+	}
+	assert.Equal(t, []jsonError{
+		{Code: errCodePrice, Message: "ETH/USD: failed", Pair: "ETH/USD"},
+		{Code: errCodeNotEnoughSources, Message: "MKR/USD: 0 sources, at least 2 required", Pair: "MKR/USD"},
+	}, got)
+}
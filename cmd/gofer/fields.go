@@ -0,0 +1,205 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+)
+
+// priceFields are the fields of prices which may be selected with the
+// fields flag, mapped to their CSV columns. The names are the keys of the
+// JSON output, with pair and sources, which are added to the JSON output
+// when selected. The params and prices fields are written only as JSON.
+var priceFields = map[string]string{
+	"type":    "type",
+	"pair":    "pair",
+	"base":    "base",
+	"quote":   "quote",
+	"price":   "price",
+	"bid":     "bid",
+	"ask":     "ask",
+	"vol24h":  "volume",
+	"ts":      "timestamp",
+	"sources": "sources",
+	"error":   "error",
+	"params":  "",
+	"prices":  "",
+}
+
+func validateFields(fields []string) error {
+	for _, f := range fields {
+		if _, ok := priceFields[f]; !ok {
+			return fmt.Errorf("unsupported field: %s", f)
+		}
+	}
+	return nil
+}
+
+// csvFieldColumns returns the CSV columns of the fields.
+func csvFieldColumns(fields []string) ([]string, error) {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = priceFields[f]
+		if columns[i] == "" {
+			return nil, fmt.Errorf("the %s field is not supported by the csv format", f)
+		}
+	}
+	return columns, nil
+}
+
+type fieldsItem struct {
+	writer io.Writer
+	value  json.RawMessage
+}
+
+// fieldsMarshaller implements the marshal.Marshaller interface. It writes
+// prices as JSON objects with only the selected fields, in the order of the
+// fields. Models and errors are written as by the JSON marshaller.
+//
+// Unlike the marshallers of the marshal package, it forgets items once they
+// are flushed, so it can be flushed repeatedly.
+type fieldsMarshaller struct {
+	ndjson bool
+	fields []string
+	items  []fieldsItem
+}
+
+func newFieldsMarshaller(ndjson bool, fields []string) *fieldsMarshaller {
+	return &fieldsMarshaller{ndjson: ndjson, fields: fields}
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *fieldsMarshaller) Write(writer io.Writer, item interface{}) error {
+	var value json.RawMessage
+	var err error
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		value, err = m.price(typedItem)
+	case *provider.Model:
+		value, err = json.Marshal(typedItem.Pair.String())
+	case error:
+		value, err = json.Marshal(struct {
+			Error string `json:"error"`
+		}{Error: typedItem.Error()})
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	if err != nil {
+		return err
+	}
+	m.items = append(m.items, fieldsItem{writer: writer, value: value})
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *fieldsMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	if m.ndjson {
+		for _, i := range items {
+			if _, err := i.writer.Write(append(i.value, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var writers []io.Writer
+	values := make(map[io.Writer][]json.RawMessage)
+	for _, i := range items {
+		if _, ok := values[i.writer]; !ok {
+			writers = append(writers, i.writer)
+		}
+		values[i.writer] = append(values[i.writer], i.value)
+	}
+	for _, w := range writers {
+		b, err := json.Marshal(values[w])
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// price returns the JSON object of the price with the selected fields. The
+// fields are taken from the output of the JSON marshaller, so they are
+// encoded the same way.
+func (m *fieldsMarshaller) price(p *provider.Price) (json.RawMessage, error) {
+	jm, err := marshal.NewMarshal(marshal.NDJSON)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := jm.Write(buf, p); err != nil {
+		return nil, err
+	}
+	if err := jm.Flush(); err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &object); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range m.fields {
+		value, ok := object[f]
+		switch f {
+		case "pair":
+			value, err = json.Marshal(p.Pair.String())
+		case "sources":
+			value, err = json.Marshal(agent.PriceSources(p))
+		case "params":
+			if !ok {
+				value, err = json.Marshal(map[string]string{})
+			}
+		case "prices":
+			if !ok {
+				value, err = json.Marshal([]any{})
+			}
+		case "error":
+			if !ok {
+				value, err = json.Marshal("")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := json.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return json.RawMessage(b.String()), nil
+}
@@ -0,0 +1,105 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func TestFieldsMarshaller(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := &provider.Price{
+		Type:      "median",
+		Pair:      btcusd,
+		Price:     45242.13,
+		Volume24h: 12.5,
+		Time:      time.Unix(1700000000, 0),
+		Prices: []*provider.Price{
+			{Type: "origin", Pair: btcusd, Price: 45227.05},
+			{Type: "origin", Pair: btcusd, Error: "timeout"},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	m := newFieldsMarshaller(true, []string{"price", "ts", "vol24h", "pair", "sources", "error"})
+	require.NoError(t, m.Write(out, price))
+	require.NoError(t, m.Write(errOut, errors.New("failed")))
+	require.NoError(t, m.Flush())
+	require.NoError(t, m.Write(out, &provider.Price{Pair: btcusd, Price: 1, Time: time.Unix(1700000000, 0), Error: "stale"}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, ""+
+		`{"price":45242.13,"ts":"2023-11-14T22:13:20Z","vol24h":12.5,"pair":"BTC/USD","sources":1,"error":""}`+"\n"+
+		`{"price":1,"ts":"2023-11-14T22:13:20Z","vol24h":0,"pair":"BTC/USD","sources":0,"error":"stale"}`+"\n",
+		out.String(),
+	)
+	assert.Equal(t, `{"error":"failed"}`+"\n", errOut.String())
+
+	// With the JSON format, the items written to a writer are an array.
+	out.Reset()
+	m = newFieldsMarshaller(false, []string{"base", "params"})
+	require.NoError(t, m.Write(out, price))
+	require.NoError(t, m.Write(out, &provider.Price{Pair: btcusd, Parameters: map[string]string{"origin": "kraken"}}))
+	require.NoError(t, m.Write(out, &provider.Model{Pair: btcusd}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, `[{"base":"BTC","params":{}},{"base":"BTC","params":{"origin":"kraken"}},"BTC/USD"]`+"\n", out.String())
+}
+
+func TestOptionsFieldsMarshaller(t *testing.T) {
+	price := &provider.Price{
+		Pair:  provider.Pair{Base: "BTC", Quote: "USD"},
+		Price: 45242.13,
+		Time:  time.Unix(1700000000, 0),
+	}
+	tests := []struct {
+		format marshal.FormatType
+		fields []string
+		want   string
+		err    bool
+	}{
+		{format: marshal.NDJSON, fields: []string{"pair", "price"}, want: `{"pair":"BTC/USD","price":45242.13}` + "\n"},
+		{format: csvFormat, fields: []string{"pair", "price", "ts", "vol24h"}, want: "pair,price,timestamp,volume\nBTC/USD,45242.13,2023-11-14T22:13:20Z,0\n"},
+		{format: tableFormat, fields: []string{"base", "quote", "price"}, want: "BASE  QUOTE      PRICE\nBTC   USD    45,242.13\n"},
+		{format: csvFormat, fields: []string{"prices"}, err: true},
+		{format: tableFormat, fields: []string{"params"}, err: true},
+		{format: marshal.Plain, fields: []string{"price"}, err: true},
+		{format: marshal.NDJSON, fields: []string{"foo"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(formatMap[tt.format], func(t *testing.T) {
+			opts := &options{Format: formatTypeValue{format: tt.format}, Fields: tt.fields}
+			m, err := opts.marshaller(nil)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			out := &bytes.Buffer{}
+			require.NoError(t, m.Write(out, price))
+			require.NoError(t, m.Flush())
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,265 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// fixtureManifestName is the name of the file which describes a directory
+// of fixtures.
+const fixtureManifestName = "manifest.json"
+
+// fixture is a recorded response to an HTTP request.
+type fixture struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body"`
+	RecordedAt  time.Time         `json:"recorded_at"`
+	LatencyMS   int64             `json:"latency_ms"`
+}
+
+// fixtureManifest describes a directory of fixtures recorded by the
+// "gofer record" command.
+type fixtureManifest struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	Version    string    `json:"version"`
+	Pairs      []string  `json:"pairs"`
+	Fixtures   []string  `json:"fixtures"`
+}
+
+// fixtureTransport is an http.RoundTripper which responds to requests with
+// fixtures read from a directory, or from fsys if it is not nil. If record
+// is not nil, requests are sent using it instead, and responses are written
+// to the directory as fixtures.
+//
+// Requests are matched by the method, the URL and the body. The id of
+// JSON-RPC requests is ignored, and replaced in responses, because it
+// differs between runs.
+type fixtureTransport struct {
+	dir    string
+	fsys   fs.FS
+	record http.RoundTripper
+
+	mu       sync.Mutex
+	recorded map[string]bool // Names of recorded fixtures.
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	key, id := rpcRequestKey(body)
+	name := fixtureName(req.Method, req.URL, key)
+	if t.record != nil {
+		return t.roundTripRecord(req, body, filepath.Join(t.dir, name))
+	}
+	b, err := fs.ReadFile(t.fixtures(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no fixture for %s %s", req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+	}
+	resBody := []byte(f.Body)
+	if id != nil {
+		resBody = replaceRPCID(resBody, id)
+	}
+	header := make(http.Header, len(f.Headers))
+	for k, v := range f.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(resBody)),
+		ContentLength: int64(len(resBody)),
+		Request:       req,
+	}, nil
+}
+
+// fixtures returns the file system from which fixtures are read.
+func (t *fixtureTransport) fixtures() fs.FS {
+	if t.fsys != nil {
+		return t.fsys
+	}
+	return os.DirFS(t.dir)
+}
+
+func (t *fixtureTransport) roundTripRecord(req *http.Request, body []byte, path string) (*http.Response, error) {
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	start := time.Now()
+	res, err := t.record.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	headers := make(map[string]string, len(res.Header))
+	for k := range res.Header {
+		headers[k] = res.Header.Get(k)
+	}
+	b, err := json.MarshalIndent(fixture{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(body),
+		Status:      res.StatusCode,
+		Headers:     headers,
+		Body:        string(resBody),
+		RecordedAt:  start.UTC(),
+		LatencyMS:   time.Since(start).Milliseconds(),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.recorded == nil {
+		t.recorded = make(map[string]bool)
+	}
+	t.recorded[filepath.Base(path)] = true
+	return res, nil
+}
+
+// recordedFixtures returns the sorted names of recorded fixtures.
+func (t *fixtureTransport) recordedFixtures() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := make([]string, 0, len(t.recorded))
+	for name := range t.recorded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeManifest writes the manifest of the recorded fixtures.
+func (t *fixtureTransport) writeManifest(version string, pairs []provider.Pair) error {
+	m := fixtureManifest{
+		RecordedAt: time.Now().UTC(),
+		Version:    version,
+		Pairs:      make([]string, len(pairs)),
+		Fixtures:   t.recordedFixtures(),
+	}
+	for i, p := range pairs {
+		m.Pairs[i] = p.String()
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.dir, fixtureManifestName), b, 0o644)
+}
+
+// readManifest reads the manifest of fixtures. It returns nil if the
+// directory has no manifest.
+func (t *fixtureTransport) readManifest() (*fixtureManifest, error) {
+	b, err := fs.ReadFile(t.fixtures(), fixtureManifestName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m fixtureManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// fixtureName returns the name of the fixture file for the request. It
+// starts with the host, so fixtures of an origin can be easily found.
+func fixtureName(method string, u *url.URL, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + "\n" + u.String() + "\n"))
+	h.Write(body)
+	return strings.ReplaceAll(u.Host, ":", "_") + "-" + hex.EncodeToString(h.Sum(nil))[:16] + ".json"
+}
+
+// rpcRequestKey returns the body of a JSON-RPC request without the id, and
+// the id. Other bodies are returned as they are, with a nil id.
+func rpcRequestKey(body []byte) ([]byte, json.RawMessage) {
+	var req map[string]json.RawMessage
+	if json.Unmarshal(body, &req) != nil || req["jsonrpc"] == nil {
+		return body, nil
+	}
+	id := req["id"]
+	delete(req, "id")
+	key, err := json.Marshal(req)
+	if err != nil {
+		return body, nil
+	}
+	return key, id
+}
+
+// replaceRPCID replaces the id of a JSON-RPC response.
+func replaceRPCID(body []byte, id json.RawMessage) []byte {
+	var res map[string]json.RawMessage
+	if json.Unmarshal(body, &res) != nil {
+		return body
+	}
+	res["id"] = id
+	b, err := json.Marshal(res)
+	if err != nil {
+		return body
+	}
+	return b
+}
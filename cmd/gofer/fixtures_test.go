@@ -0,0 +1,133 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestFixtureTransport(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.Method {
+		case http.MethodPost:
+			b, _ := io.ReadAll(r.Body)
+			assert.Contains(t, string(b), `"method":"eth_blockNumber"`)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"last":"100"}`))
+		}
+	}))
+	dir := t.TempDir()
+
+	get := func(c *http.Client) (string, error) {
+		res, err := c.Get(srv.URL + "/ticker/btcusd")
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		return string(b), err
+	}
+	post := func(c *http.Client, id string) (string, error) {
+		body := `{"jsonrpc":"2.0","id":` + id + `,"method":"eth_blockNumber","params":[]}`
+		res, err := c.Post(srv.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		return string(b), err
+	}
+
+	// Record responses.
+	recorder := &fixtureTransport{dir: dir, record: http.DefaultTransport}
+	c := &http.Client{Transport: recorder}
+	body, err := get(c)
+	require.NoError(t, err)
+	assert.Equal(t, `{"last":"100"}`, body)
+	body, err = post(c, "1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`, body)
+	assert.Equal(t, 2, calls)
+	fixtures := recorder.recordedFixtures()
+	assert.Len(t, fixtures, 2)
+	require.NoError(t, recorder.writeManifest("0.1.0", []provider.Pair{{Base: "BTC", Quote: "USD"}}))
+	srv.Close()
+
+	b, err := os.ReadFile(filepath.Join(dir, fixtures[0]))
+	require.NoError(t, err)
+	var f fixture
+	require.NoError(t, json.Unmarshal(b, &f))
+	assert.True(t, strings.HasPrefix(f.URL, srv.URL))
+	assert.Equal(t, http.StatusOK, f.Status)
+	assert.WithinDuration(t, time.Now(), f.RecordedAt, time.Minute)
+
+	// Replay responses.
+	replayer := &fixtureTransport{dir: dir}
+	manifest, err := replayer.readManifest()
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	assert.Equal(t, "0.1.0", manifest.Version)
+	assert.Equal(t, []string{"BTC/USD"}, manifest.Pairs)
+	assert.Equal(t, fixtures, manifest.Fixtures)
+
+	c = &http.Client{Transport: replayer}
+	res, err := c.Get(srv.URL + "/ticker/btcusd")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	_ = res.Body.Close()
+	body, err = get(c)
+	require.NoError(t, err)
+	assert.Equal(t, `{"last":"100"}`, body)
+	body, err = post(c, "7")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":7,"result":"0x10"}`, body)
+	assert.Equal(t, 2, calls)
+
+	_, err = c.Get(srv.URL + "/ticker/ethusd")
+	assert.ErrorContains(t, err, "no fixture for GET "+srv.URL+"/ticker/ethusd")
+}
+
+func TestFixtureTransport_NoManifest(t *testing.T) {
+	m, err := (&fixtureTransport{dir: t.TempDir()}).readManifest()
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestFixtureName(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.kraken.com:443/0/public/Ticker", nil)
+	require.NoError(t, err)
+	name := fixtureName(req.Method, req.URL, nil)
+	assert.Regexp(t, `^api\.kraken\.com_443-[0-9a-f]{16}\.json$`, name)
+	assert.NotEqual(t, name, fixtureName(http.MethodPost, req.URL, nil))
+	assert.NotEqual(t, name, fixtureName(req.Method, req.URL, []byte("body")))
+}
@@ -0,0 +1,122 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+// priceFollower prints prices of pairs on every tick of the interval until
+// its context is canceled.
+type priceFollower struct {
+	provider provider.Provider
+	hook     provider.PriceHook
+	pairs    []provider.Pair
+	interval *timeutil.Ticker
+
+	// marshaller returns the marshaller used to print a round of prices.
+	marshaller func() (marshal.Marshaller, error)
+
+	// changes, if true, prints only prices which differ from the prices
+	// printed last.
+	changes bool
+
+	// count, if positive, is the number of times prices are printed before
+	// the follower stops.
+	count int
+
+	// minSources, if positive, is the minimum number of origin prices from
+	// which a price is calculated; other prices are printed with an error.
+	minSources int
+
+	out    io.Writer
+	errOut io.Writer
+
+	last map[provider.Pair]*provider.Price
+}
+
+// run prints the prices once, and then on every tick of the interval,
+// which must be started, until the context is canceled or the prices are
+// printed count times. Errors are printed to errOut and do not stop the
+// follower.
+func (f *priceFollower) run(ctx context.Context) error {
+	for n := 1; ; n++ {
+		if err := f.print(); err != nil {
+			return err
+		}
+		if f.count > 0 && n >= f.count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-f.interval.TickCh():
+		}
+	}
+}
+
+// print fetches and prints the prices, sorted by pair. The marshaller is
+// flushed at the end, so every round of prices is written as soon as it is
+// fetched.
+func (f *priceFollower) print() error {
+	m, err := f.marshaller()
+	if err != nil {
+		return err
+	}
+	prices, err := f.provider.Prices(f.pairs...)
+	if err == nil {
+		err = f.hook.Check(prices)
+	}
+	if err != nil {
+		_ = m.Write(f.errOut, err)
+		return m.Flush()
+	}
+	checkMinSources(prices, f.minSources)
+	pairs := make([]provider.Pair, 0, len(prices))
+	for pair := range prices {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+	for _, pair := range pairs {
+		p := prices[pair]
+		if f.changes && !f.changed(pair, p) {
+			continue
+		}
+		if mErr := m.Write(f.out, p); mErr != nil {
+			_ = m.Write(f.errOut, mErr)
+		}
+	}
+	return m.Flush()
+}
+
+// changed reports whether the price of the pair differs from the price
+// printed last, and remembers it.
+func (f *priceFollower) changed(pair provider.Pair, p *provider.Price) bool {
+	if f.last == nil {
+		f.last = make(map[provider.Pair]*provider.Price)
+	}
+	last, ok := f.last[pair]
+	f.last[pair] = p
+	return !ok || last.Price != p.Price || last.Error != p.Error
+}
@@ -0,0 +1,156 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+
+	"gofer-cli/pkg/agent"
+)
+
+func TestPriceFollower(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	ts := time.Unix(1700000000, 0)
+	price := func(pair provider.Pair, v float64) *provider.Price {
+		return &provider.Price{Type: "median", Pair: pair, Price: v, Time: ts}
+	}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: price(btcusd, 42),
+		ethusd: price(ethusd, 21),
+	}, nil).Once()
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: price(btcusd, 42),
+		ethusd: price(ethusd, 22),
+	}, nil).Once()
+	p.On("Prices", btcusd, ethusd).Return((map[provider.Pair]*provider.Price)(nil), errors.New("failed")).Once()
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	ticker := timeutil.NewTicker(0)
+	ticker.Start(ctx)
+	f := &priceFollower{
+		provider: p,
+		hook:     nopHook{},
+		pairs:    []provider.Pair{btcusd, ethusd},
+		interval: ticker,
+		marshaller: func() (marshal.Marshaller, error) {
+			return marshal.NewMarshal(marshal.NDJSON)
+		},
+		changes: true,
+		out:     out,
+		errOut:  errOut,
+	}
+	done := make(chan error)
+	go func() { done <- f.run(ctx) }()
+
+	// Prices are printed once immediately, and then on every tick. A tick
+	// is received only after the previous prices are printed.
+	ticker.Tick()
+	ticker.Tick()
+	cancel()
+	require.NoError(t, <-done)
+
+	// Only the changed price is printed again, and errors do not stop
+	// the follower.
+	assert.Equal(t, ""+
+		`{"type":"median","base":"BTC","quote":"USD","price":42,"bid":0,"ask":0,"vol24h":0,"ts":"2023-11-14T22:13:20Z"}`+"\n"+
+		`{"type":"median","base":"ETH","quote":"USD","price":21,"bid":0,"ask":0,"vol24h":0,"ts":"2023-11-14T22:13:20Z"}`+"\n"+
+		`{"type":"median","base":"ETH","quote":"USD","price":22,"bid":0,"ask":0,"vol24h":0,"ts":"2023-11-14T22:13:20Z"}`+"\n",
+		out.String(),
+	)
+	assert.Equal(t, `{"error":"failed"}`+"\n", errOut.String())
+}
+
+func TestPriceFollower_Count(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Type: "median", Pair: btcusd, Price: 42},
+	}, nil).Twice()
+
+	m, err := agent.NewCSVMarshaller([]string{"pair", "price"})
+	require.NoError(t, err)
+	out := &bytes.Buffer{}
+	ticker := timeutil.NewTicker(0)
+	ticker.Start(ctx)
+	f := &priceFollower{
+		provider: p,
+		hook:     nopHook{},
+		pairs:    []provider.Pair{btcusd},
+		interval: ticker,
+		marshaller: func() (marshal.Marshaller, error) {
+			return m, nil
+		},
+		count:  2,
+		out:    out,
+		errOut: out,
+	}
+	done := make(chan error)
+	go func() { done <- f.run(ctx) }()
+
+	// The follower stops after printing the prices twice, without waiting
+	// for another tick.
+	ticker.Tick()
+	require.NoError(t, <-done)
+	p.AssertExpectations(t)
+	assert.Equal(t, "pair,price\nBTC/USD,42\nBTC/USD,42\n", out.String())
+}
+
+func TestPriceFollower_MinSources(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Type: "median", Pair: btcusd, Price: 42, Prices: []*provider.Price{{}}},
+	}, nil).Once()
+
+	m, err := agent.NewCSVMarshaller([]string{"pair", "sources", "error"})
+	require.NoError(t, err)
+	out := &bytes.Buffer{}
+	f := &priceFollower{
+		provider: p,
+		hook:     nopHook{},
+		pairs:    []provider.Pair{btcusd},
+		marshaller: func() (marshal.Marshaller, error) {
+			return m, nil
+		},
+		count:      1,
+		minSources: 2,
+		out:        out,
+		errOut:     out,
+	}
+	require.NoError(t, f.run(context.Background()))
+	assert.Equal(t, "pair,sources,error\nBTC/USD,1,\"1 sources, at least 2 required\"\n", out.String())
+}
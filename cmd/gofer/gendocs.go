@@ -0,0 +1,211 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// docCommands returns the command and all its subcommands that are shown
+// in the help, ordered by their paths.
+func docCommands(cmd *cobra.Command) []*cobra.Command {
+	cmds := []*cobra.Command{cmd}
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		cmds = append(cmds, docCommands(c)...)
+	}
+	sort.SliceStable(cmds[1:], func(i, j int) bool {
+		return cmds[i+1].CommandPath() < cmds[j+1].CommandPath()
+	})
+	return cmds
+}
+
+// docSeeAlso returns the parent and the subcommands of the command, which
+// are referenced in the "see also" section of its documentation.
+func docSeeAlso(cmd *cobra.Command) []*cobra.Command {
+	var cmds []*cobra.Command
+	if cmd.HasParent() {
+		cmds = append(cmds, cmd.Parent())
+	}
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		cmds = append(cmds, c)
+	}
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return cmds[i].CommandPath() < cmds[j].CommandPath()
+	})
+	return cmds
+}
+
+// markdownFilename returns the name of the markdown file documenting the
+// command, e.g. "gofer_origins_test.md".
+func markdownFilename(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "_") + ".md"
+}
+
+// manFilename returns the name of the man page documenting the command,
+// e.g. "gofer-origins-test.1".
+func manFilename(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-") + ".1"
+}
+
+// genDocsTree writes the documentation of the command and its subcommands
+// to dir, one file per command.
+func genDocsTree(
+	cmd *cobra.Command,
+	dir string,
+	filename func(*cobra.Command) string,
+	gen func(io.Writer, *cobra.Command) error,
+) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, c := range docCommands(cmd) {
+		b := &bytes.Buffer{}
+		if err := gen(b, c); err != nil {
+			return fmt.Errorf("unable to generate documentation for %s: %w", c.CommandPath(), err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filename(c)), b.Bytes(), 0o644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+	return nil
+}
+
+// genMarkdown writes the markdown reference of the command. The layout is
+// the same as the one of the cobra documentation generator.
+func genMarkdown(w io.Writer, cmd *cobra.Command) error {
+	cmd.InitDefaultHelpFlag()
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "## %s\n\n%s\n\n", cmd.CommandPath(), cmd.Short)
+	if cmd.Long != "" {
+		fmt.Fprintf(b, "### Synopsis\n\n%s\n\n", strings.TrimSpace(cmd.Long))
+	}
+	if cmd.Runnable() {
+		fmt.Fprintf(b, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(b, "### Examples\n\n```\n%s\n```\n\n", cmd.Example)
+	}
+	if fs := cmd.NonInheritedFlags(); fs.HasAvailableFlags() {
+		fmt.Fprintf(b, "### Options\n\n```\n%s```\n\n", fs.FlagUsages())
+	}
+	if fs := cmd.InheritedFlags(); fs.HasAvailableFlags() {
+		fmt.Fprintf(b, "### Options inherited from parent commands\n\n```\n%s```\n\n", fs.FlagUsages())
+	}
+	if see := docSeeAlso(cmd); len(see) > 0 {
+		fmt.Fprintf(b, "### SEE ALSO\n\n")
+		for _, c := range see {
+			fmt.Fprintf(b, "* [%s](%s)\t - %s\n", c.CommandPath(), markdownFilename(c), c.Short)
+		}
+		fmt.Fprintln(b)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// genMan returns a function that writes the man page of a command, with
+// the version shown in the footer of the page.
+func genMan(version string) func(io.Writer, *cobra.Command) error {
+	return func(w io.Writer, cmd *cobra.Command) error {
+		cmd.InitDefaultHelpFlag()
+		name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+		b := &bytes.Buffer{}
+		fmt.Fprintf(b, ".nh\n.TH %q \"1\" \"\" %q \"Gofer Manual\"\n\n",
+			strings.ToUpper(name), strings.TrimSpace("gofer "+version))
+		fmt.Fprintf(b, ".SH NAME\n%s \\- %s\n\n", roffEscape(name), roffEscape(cmd.Short))
+		fmt.Fprintf(b, ".SH SYNOPSIS\n\\fB%s\\fP\n\n", roffEscape(cmd.UseLine()))
+		if cmd.Long != "" {
+			fmt.Fprintf(b, ".SH DESCRIPTION\n%s\n\n", roffText(cmd.Long))
+		}
+		if fs := cmd.NonInheritedFlags(); fs.HasAvailableFlags() {
+			fmt.Fprintf(b, ".SH OPTIONS\n%s\n", roffFlags(fs))
+		}
+		if fs := cmd.InheritedFlags(); fs.HasAvailableFlags() {
+			fmt.Fprintf(b, ".SH OPTIONS INHERITED FROM PARENT COMMANDS\n%s\n", roffFlags(fs))
+		}
+		if cmd.Example != "" {
+			fmt.Fprintf(b, ".SH EXAMPLE\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n\n", roffEscape(cmd.Example))
+		}
+		if see := docSeeAlso(cmd); len(see) > 0 {
+			var refs []string
+			for _, c := range see {
+				refs = append(refs, fmt.Sprintf("\\fB%s(1)\\fP", strings.ReplaceAll(c.CommandPath(), " ", "-")))
+			}
+			fmt.Fprintf(b, ".SH SEE ALSO\n%s\n", strings.Join(refs, ", "))
+		}
+		_, err := w.Write(b.Bytes())
+		return err
+	}
+}
+
+// roffFlags formats the flags as roff paragraphs, one per flag.
+func roffFlags(fs *pflag.FlagSet) string {
+	b := &strings.Builder{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		b.WriteString(".PP\n")
+		if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fP, ", f.Shorthand)
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fP", roffEscape(f.Name))
+		varname, usage := pflag.UnquoteUsage(f)
+		if varname != "" && f.NoOptDefVal == "" {
+			fmt.Fprintf(b, "=%s", roffEscape(varname))
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "[]" && f.DefValue != "0" && f.DefValue != "0s" {
+			fmt.Fprintf(b, " (default %s)", roffEscape(f.DefValue))
+		}
+		fmt.Fprintf(b, "\n.RS\n%s\n.RE\n\n", roffEscape(usage))
+	})
+	return b.String()
+}
+
+// roffText formats the text as roff paragraphs separated by blank lines.
+func roffText(s string) string {
+	var paras []string
+	for _, p := range strings.Split(strings.TrimSpace(s), "\n\n") {
+		paras = append(paras, ".PP\n"+roffEscape(strings.TrimSpace(p)))
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// roffEscape escapes the text so that it is not interpreted by roff.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,78 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenDocsTree(t *testing.T) {
+	opts := &options{Version: "0.11.0"}
+	rootCmd := NewRootCommand(opts)
+	rootCmd.AddCommand(
+		NewOriginsCmd(opts),
+		NewVersionCmd(opts),
+		NewGenDocsCmd(opts),
+	)
+
+	dir := t.TempDir()
+	require.NoError(t, genDocsTree(rootCmd, filepath.Join(dir, "md"), markdownFilename, genMarkdown))
+	require.NoError(t, genDocsTree(rootCmd, filepath.Join(dir, "man"), manFilename, genMan(opts.Version)))
+
+	// Hidden commands, such as gen-docs, are not documented.
+	var md, man []string
+	for _, c := range docCommands(rootCmd) {
+		md = append(md, markdownFilename(c))
+		man = append(man, manFilename(c))
+	}
+	assert.Equal(t, []string{"gofer.md", "gofer_origins.md", "gofer_origins_test.md", "gofer_version.md"}, md)
+	assert.Equal(t, []string{"gofer.1", "gofer-origins.1", "gofer-origins-test.1", "gofer-version.1"}, man)
+	entries, err := os.ReadDir(filepath.Join(dir, "man"))
+	require.NoError(t, err)
+	assert.Len(t, entries, len(man))
+
+	b, err := os.ReadFile(filepath.Join(dir, "md", "gofer_origins.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "## gofer origins\n")
+	assert.Contains(t, string(b), "```\ngofer origins [flags]\n```")
+	assert.Contains(t, string(b), "--config strings")
+	assert.Contains(t, string(b), "* [gofer origins test](gofer_origins_test.md)")
+
+	b, err = os.ReadFile(filepath.Join(dir, "man", "gofer-origins.1"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `.TH "GOFER-ORIGINS" "1" "" "gofer 0.11.0" "Gofer Manual"`)
+	assert.Contains(t, string(b), `\fB\-c\fP, \fB\-\-config\fP=strings (default [./config.hcl])`)
+	assert.Contains(t, string(b), `\fBgofer(1)\fP, \fBgofer-origins-test(1)\fP`)
+}
+
+func TestRoffEscape(t *testing.T) {
+	assert.Equal(t, `a \e b`, roffEscape(`a \ b`))
+	assert.Equal(t, "\\&.TH\n\\&'x\nok", roffEscape(".TH\n'x\nok"))
+	assert.Equal(t, ".PP\nfirst\n\n.PP\nsecond", roffText("\nfirst\n\nsecond\n"))
+}
+
+func TestGenDocsCmd_RequiresOutput(t *testing.T) {
+	opts := &options{}
+	rootCmd := NewRootCommand(opts)
+	rootCmd.AddCommand(NewGenDocsCmd(opts))
+	rootCmd.SetArgs([]string{"gen-docs"})
+	assert.Error(t, rootCmd.Execute())
+}
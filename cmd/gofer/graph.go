@@ -0,0 +1,151 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+type graphNode struct {
+	typ    string
+	name   string // Name of the origin, for origin nodes.
+	pair   provider.Pair
+	origin bool
+}
+
+// modelGraph is a graph of price models. Models which are identical,
+// including all their sources, are represented by a single node, so models
+// used by other models, and origins used by many models, are drawn once.
+type modelGraph struct {
+	nodes []graphNode
+	edges [][2]int
+	ids   map[string]int
+}
+
+func newModelGraph(models map[provider.Pair]*provider.Model) *modelGraph {
+	g := &modelGraph{ids: make(map[string]int)}
+	pairs := make([]provider.Pair, 0, len(models))
+	for p := range models {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+	for _, p := range pairs {
+		g.add(models[p])
+	}
+	return g
+}
+
+// add adds the model and its sources to the graph and returns the id of
+// the model node.
+func (g *modelGraph) add(m *provider.Model) int {
+	key := modelKey(m)
+	if id, ok := g.ids[key]; ok {
+		return id
+	}
+	id := len(g.nodes)
+	g.ids[key] = id
+	g.nodes = append(g.nodes, graphNode{
+		typ:    m.Type,
+		name:   m.Parameters["origin"],
+		pair:   m.Pair,
+		origin: m.Type == "origin",
+	})
+	for _, s := range m.Models {
+		// The edge is added before the sources of the source, so edges are
+		// written in the same order as nodes.
+		e := len(g.edges)
+		g.edges = append(g.edges, [2]int{id, -1})
+		child := g.add(s)
+		g.edges[e][1] = child
+	}
+	return id
+}
+
+// modelKey returns a string which identifies the model with all its sources.
+func modelKey(m *provider.Model) string {
+	var b strings.Builder
+	b.WriteString(m.Type + "(" + m.Pair.String())
+	params := make([]string, 0, len(m.Parameters))
+	for k, v := range m.Parameters {
+		params = append(params, k+"="+v)
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		b.WriteString("," + p)
+	}
+	for _, s := range m.Models {
+		b.WriteString("," + modelKey(s))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// label returns lines of the label of the node. Origin nodes are labeled
+// with the name of the origin, other nodes with the type of the model.
+func (n graphNode) label() []string {
+	if n.origin {
+		return []string{n.name, n.pair.String()}
+	}
+	return []string{n.typ, n.pair.String()}
+}
+
+// writeDOT writes the graph in the DOT language used by Graphviz.
+func (g *modelGraph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph gofer {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for id, n := range g.nodes {
+		shape := "ellipse"
+		if n.origin {
+			shape = "box"
+		}
+		label := strings.ReplaceAll(strings.Join(n.label(), "\n"), `"`, `\"`)
+		label = strings.ReplaceAll(label, "\n", `\n`)
+		fmt.Fprintf(&b, "  n%d [label=\"%s\", shape=%s];\n", id, label, shape)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  n%d -> n%d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart.
+func (g *modelGraph) writeMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for id, n := range g.nodes {
+		label := strings.ReplaceAll(strings.Join(n.label(), "<br>"), `"`, "#quot;")
+		if n.origin {
+			fmt.Fprintf(&b, "  n%d([\"%s\"])\n", id, label)
+		} else {
+			fmt.Fprintf(&b, "  n%d[\"%s\"]\n", id, label)
+		}
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  n%d --> n%d\n", e[0], e[1])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,100 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func testGraphModels() map[provider.Pair]*provider.Model {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethbtc := provider.Pair{Base: "ETH", Quote: "BTC"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	btcusdModel := &provider.Model{Type: "median", Pair: btcusd, Models: []*provider.Model{
+		{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "bitstamp"}},
+		{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "kraken"}},
+	}}
+	return map[provider.Pair]*provider.Model{
+		btcusd: btcusdModel,
+		ethusd: {Type: "median", Pair: ethusd, Models: []*provider.Model{
+			{Type: "origin", Pair: ethusd, Parameters: map[string]string{"origin": "kraken"}},
+			{Type: "indirect", Pair: ethusd, Models: []*provider.Model{
+				{Type: "origin", Pair: ethbtc, Parameters: map[string]string{"origin": "kraken"}},
+				btcusdModel,
+			}},
+		}},
+	}
+}
+
+func TestModelGraph_DOT(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, newModelGraph(testGraphModels()).writeDOT(b))
+	assert.Equal(t, `digraph gofer {
+  rankdir=LR;
+  n0 [label="median\nBTC/USD", shape=ellipse];
+  n1 [label="bitstamp\nBTC/USD", shape=box];
+  n2 [label="kraken\nBTC/USD", shape=box];
+  n3 [label="median\nETH/USD", shape=ellipse];
+  n4 [label="kraken\nETH/USD", shape=box];
+  n5 [label="indirect\nETH/USD", shape=ellipse];
+  n6 [label="kraken\nETH/BTC", shape=box];
+  n0 -> n1;
+  n0 -> n2;
+  n3 -> n4;
+  n3 -> n5;
+  n5 -> n6;
+  n5 -> n0;
+}
+`, b.String())
+}
+
+func TestModelGraph_Mermaid(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, newModelGraph(testGraphModels()).writeMermaid(b))
+	assert.Equal(t, `flowchart LR
+  n0["median<br>BTC/USD"]
+  n1(["bitstamp<br>BTC/USD"])
+  n2(["kraken<br>BTC/USD"])
+  n3["median<br>ETH/USD"]
+  n4(["kraken<br>ETH/USD"])
+  n5["indirect<br>ETH/USD"]
+  n6(["kraken<br>ETH/BTC"])
+  n0 --> n1
+  n0 --> n2
+  n3 --> n4
+  n3 --> n5
+  n5 --> n6
+  n5 --> n0
+`, b.String())
+}
+
+func TestModelKey(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	a := &provider.Model{Type: "indirect", Pair: btcusd, Models: []*provider.Model{
+		{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "bitstamp"}},
+	}}
+	b := &provider.Model{Type: "indirect", Pair: btcusd, Models: []*provider.Model{
+		{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "kraken"}},
+	}}
+	assert.NotEqual(t, modelKey(a), modelKey(b))
+	assert.Equal(t, modelKey(a), modelKey(&provider.Model{Type: "indirect", Pair: btcusd, Models: a.Models}))
+}
@@ -16,6 +16,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -26,6 +28,10 @@ import (
 // exitCode to be returned by the application.
 var exitCode = 0
 
+// exitCodeTimeout is returned if the command timed out, as by the timeout
+// utility.
+const exitCodeTimeout = 124
+
 func main() {
 	opts := options{
 		Format:  formatTypeValue{format: marshal.NDJSON},
@@ -37,12 +43,38 @@ func main() {
 		NewPairsCmd(&opts),
 		NewPricesCmd(&opts),
 		NewAgentCmd(&opts),
+		NewConfigCmd(&opts),
+		NewCacheCmd(&opts),
+		NewOriginsCmd(&opts),
+		NewBenchCmd(&opts),
+		NewGraphCmd(&opts),
+		NewDiffCmd(&opts),
+		NewCompareCmd(&opts),
+		NewExportCmd(&opts),
+		NewReplayCmd(&opts),
+		NewRecordCmd(&opts),
+		NewTopCmd(&opts),
+		NewConvertCmd(&opts),
+		NewSpreadCmd(&opts),
+		NewWatchCmd(&opts),
+		NewVersionCmd(&opts),
+		NewGenDocsCmd(&opts),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	opts.cancel = cancel
+	err := executeCommand(ctx, rootCmd)
+	cancel(nil)
+	opts.removeDryRunConfig()
+	if err != nil {
 		fmt.Printf("Error: %s\n", err)
-		if exitCode == 0 {
-			os.Exit(1)
+		writeJSONError(os.Stderr, err)
+		var coded *codedError
+		switch {
+		case errors.As(err, &coded) && coded.code == errCodeTimeout:
+			exitCode = exitCodeTimeout
+		case exitCode == 0:
+			exitCode = 1
 		}
 	}
 	os.Exit(exitCode)
@@ -0,0 +1,128 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// Freshness requirements of origin prices. Origin nodes of price models
+// are always created with these TTLs by the price provider.
+const (
+	originPriceTTL    = time.Minute     // Older prices are fetched again.
+	originPriceMaxTTL = 5 * time.Minute // Older prices are expired.
+)
+
+// describeModels adds the settings of the config, which are not a part of
+// the models returned by the price provider, to the parameters of the
+// models, so the models can be audited without reading the config:
+//
+//   - min_sources of median models,
+//   - origin_type, params.* and the ttl and max_ttl of origin prices, of
+//     origin models,
+//   - post_price.* of the post price hook of the pair, of the root models.
+//
+// Secret parameters of origins are redacted.
+func (c *cliConfig) describeModels(models map[provider.Pair]*provider.Model) {
+	// Price models take precedence over the sources of other models.
+	minSources := c.minSources()
+	for _, m := range c.Gofer.PriceModels {
+		collectMinSources(reflect.ValueOf(m.Sources), minSources)
+	}
+	originTypes := make(map[string]string)
+	originParams := make(map[string]map[string]any)
+	for _, o := range c.Gofer.Origins {
+		originTypes[o.Origin] = o.Type
+		originParams[o.Origin] = o.Params
+	}
+	var describe func(m *provider.Model)
+	describe = func(m *provider.Model) {
+		if m.Parameters == nil {
+			m.Parameters = make(map[string]string)
+		}
+		switch m.Type {
+		case "median":
+			if n, ok := minSources[m.Pair]; ok {
+				m.Parameters["min_sources"] = fmt.Sprint(n)
+			}
+		case "origin":
+			if typ, ok := originTypes[m.Parameters["origin"]]; ok {
+				m.Parameters["origin_type"] = typ
+			}
+			for k, v := range originParams[m.Parameters["origin"]] {
+				m.Parameters["params."+k] = paramValue(k, v)
+			}
+			m.Parameters["ttl"] = originPriceTTL.String()
+			m.Parameters["max_ttl"] = originPriceMaxTTL.String()
+		}
+		for _, s := range m.Models {
+			describe(s)
+		}
+	}
+	hooks := make(map[provider.Pair]map[string]any)
+	for _, h := range c.Gofer.Hooks {
+		hooks[h.Pair] = h.PostPriceHook
+	}
+	for pair, m := range models {
+		describe(m)
+		for k, v := range hooks[pair] {
+			m.Parameters["post_price."+k] = paramValue(k, v)
+		}
+	}
+}
+
+// collectMinSources adds the minimum number of sources of the median
+// sources, and of their sources, to res, unless res already has one for the
+// pair. The type of the sources is not exported by the config package, so
+// they are read with reflection.
+func collectMinSources(sources reflect.Value, res map[provider.Pair]int) {
+	for i := 0; i < sources.Len(); i++ {
+		s := sources.Index(i)
+		pair := s.FieldByName("Pair").Interface().(provider.Pair)
+		if median := s.FieldByName("Median"); !median.IsNil() {
+			if _, ok := res[pair]; !ok {
+				res[pair] = int(median.Elem().FieldByName("MinSources").Int())
+			}
+		}
+		collectMinSources(s.FieldByName("Sources"), res)
+	}
+}
+
+// paramValue returns the value of the parameter as a string. Strings are
+// returned as they are, other values as JSON. Values of secret parameters,
+// and passwords in URLs, are redacted.
+func paramValue(name string, v any) string {
+	if secretAttributes[name] {
+		return redacted
+	}
+	if s, ok := v.(string); ok {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -16,29 +16,106 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/chronicleprotocol/oracle-suite/pkg/config/gofer"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/logrus/flag"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/agent/grpc"
 )
 
 // These are the command options that can be set by CLI flags.
 type options struct {
 	flag.LoggerFlag
-	ConfigFilePath []string
-	Format         formatTypeValue
-	Config         gofer.Config
-	NoRPC          bool
-	Version        string
+	ConfigFilePath   []string
+	Format           formatTypeValue
+	Query            string
+	Config           cliConfig
+	NoRPC            bool
+	NoCache          bool
+	DryRun           bool
+	Timeout          time.Duration
+	Version          string
+	ListenAddrs      []string
+	GRPCListenAddr   string
+	DebugListenAddr  string
+	ShutdownTimeout  time.Duration
+	AccessLog        bool
+	AdminReload      bool
+	AdminInvalidate  bool
+	PIDFile          string
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSClientCAFile  string
+	TLSClientRules   []string
+	Follow           bool
+	FollowInterval   time.Duration
+	FollowChanges    bool
+	FollowCount      int
+	CSVColumns       []string
+	Fields           []string
+	PairsFile        string
+	Concurrency      int
+	Strict           bool
+	StrictSources    int
+	MinSources       int
+	ProbeTimeout     time.Duration
+	BenchRuns        int
+	GraphMermaid     bool
+	DiffThreshold    float64
+	CompareThreshold float64
+	ExportFile       string
+	ExportInterval   time.Duration
+	RotateSize       int64
+	RotateInterval   time.Duration
+	RotateCompress   bool
+	Fixtures         string
+	TopInterval      time.Duration
+	TopSort          string
+	WatchThreshold   float64
+	WatchWindow      time.Duration
+	WatchInterval    time.Duration
+	WatchExec        string
+	WatchWebhook     string
+	CacheOlderThan   time.Duration
+
+	// dryRunDir is the temporary directory of the config used with
+	// --dry-run.
+	dryRunDir string
+
+	// cancel cancels the root context of the command. The cause is
+	// returned as the error of the command.
+	cancel context.CancelCauseFunc
 }
 
+// Format types of the CSV, table, YAML, MessagePack, protobuf and Prometheus
+// outputs. They are not supported by the marshal package, and are written by
+// the tableMarshaller, the yamlMarshaller and the CSV, MessagePack, protobuf
+// and Prometheus marshallers of the agent instead.
+const (
+	csvFormat      marshal.FormatType = -1
+	tableFormat    marshal.FormatType = -2
+	yamlFormat     marshal.FormatType = -3
+	msgpackFormat  marshal.FormatType = -4
+	protobufFormat marshal.FormatType = -5
+	promFormat     marshal.FormatType = -6
+)
+
 var formatMap = map[marshal.FormatType]string{
 	marshal.Plain:  "plain",
 	marshal.Trace:  "trace",
 	marshal.JSON:   "json",
 	marshal.NDJSON: "ndjson",
+	csvFormat:      "csv",
+	tableFormat:    "table",
+	yamlFormat:     "yaml",
+	msgpackFormat:  "msgpack",
+	protobufFormat: "protobuf",
+	promFormat:     "prom",
 }
 
 // formatTypeValue is a wrapper for the FormatType to allow implement
@@ -70,5 +147,68 @@ func (v *formatTypeValue) Set(s string) error {
 }
 
 func (v *formatTypeValue) Type() string {
-	return "plain|trace|json|ndjson"
+	return "plain|trace|json|ndjson|csv|table|yaml|msgpack|protobuf|prom"
+}
+
+// marshaller returns a new marshaller of the format. The columns are used
+// only by the CSV format.
+func (v *formatTypeValue) marshaller(columns []string) (marshal.Marshaller, error) {
+	switch v.format {
+	case csvFormat:
+		return agent.NewCSVMarshaller(columns)
+	case tableFormat:
+		return newTableMarshaller(nil)
+	case yamlFormat:
+		return newYAMLMarshaller(nil), nil
+	case msgpackFormat:
+		return agent.NewMsgpackMarshaller(), nil
+	case protobufFormat:
+		return grpc.NewProtobufMarshaller(), nil
+	case promFormat:
+		return agent.NewPrometheusMarshaller(), nil
+	}
+	return marshal.NewMarshal(v.format)
+}
+
+// fieldsMarshaller returns a new marshaller of the output format which
+// writes only the selected fields of prices. If no fields are selected, the
+// columns are used by the CSV format.
+func (o *options) fieldsMarshaller(columns []string) (marshal.Marshaller, error) {
+	if len(o.Fields) == 0 {
+		return o.Format.marshaller(columns)
+	}
+	if err := validateFields(o.Fields); err != nil {
+		return nil, err
+	}
+	switch o.Format.format {
+	case marshal.JSON, marshal.NDJSON:
+		return newFieldsMarshaller(o.Format.format == marshal.NDJSON, o.Fields), nil
+	case csvFormat:
+		columns, err := csvFieldColumns(o.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return agent.NewCSVMarshaller(columns)
+	case tableFormat:
+		return newTableMarshaller(o.Fields)
+	case yamlFormat:
+		return newYAMLMarshaller(o.Fields), nil
+	}
+	return nil, fmt.Errorf("the fields flag is not supported by the %s format", o.Format.String())
+}
+
+// marshaller returns a new marshaller of the output format. If the query is
+// set, the marshaller writes the query results instead.
+func (o *options) marshaller(columns []string) (marshal.Marshaller, error) {
+	m, err := o.fieldsMarshaller(columns)
+	if err != nil {
+		return nil, err
+	}
+	if o.Query == "" {
+		return m, nil
+	}
+	if o.Format.format != marshal.JSON && o.Format.format != marshal.NDJSON {
+		return nil, fmt.Errorf("the query flag requires the json or ndjson format")
+	}
+	return newQueryMarshaller(m, o.Query)
 }
@@ -16,9 +16,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
 )
 
 func TestFormatTypeValue(t *testing.T) {
@@ -33,3 +46,229 @@ func TestFormatTypeValue(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatTypeValue_Marshaller(t *testing.T) {
+	for ct, st := range formatMap {
+		t.Run(st, func(t *testing.T) {
+			m, err := (&formatTypeValue{format: ct}).marshaller(nil)
+			require.NoError(t, err)
+			assert.NotNil(t, m)
+		})
+	}
+}
+
+func TestNDJSONFormat(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	prices := []*provider.Price{
+		{
+			Type:  "aggregator",
+			Pair:  btcusd,
+			Price: 45242.13,
+			Prices: []*provider.Price{
+				{Type: "origin", Pair: btcusd, Price: 45242.13, Parameters: map[string]string{"origin": "kraken"}},
+				{Type: "origin", Pair: btcusd, Error: "invalid response:\n{\n  \"error\": 1\n}"},
+			},
+		},
+		{Type: "aggregator", Pair: ethusd, Error: "not enough sources\n"},
+	}
+
+	// Every price, with the prices used to calculate it, is written as
+	// a single compact JSON object in a separate line, even if its errors
+	// contain new lines.
+	for _, fields := range [][]string{nil, {"pair", "prices", "error"}} {
+		opts := &options{Format: formatTypeValue{format: marshal.NDJSON}, Fields: fields}
+		m, err := opts.marshaller(nil)
+		require.NoError(t, err)
+		out := &bytes.Buffer{}
+		for _, p := range prices {
+			require.NoError(t, m.Write(out, p))
+		}
+		require.NoError(t, m.Flush())
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		require.Len(t, lines, len(prices))
+		for _, l := range lines {
+			var v map[string]any
+			require.NoError(t, json.Unmarshal([]byte(l), &v))
+			b := &bytes.Buffer{}
+			require.NoError(t, json.Compact(b, []byte(l)))
+			assert.Equal(t, b.String(), l)
+		}
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	opts := &options{}
+	rootCmd := NewRootCommand(opts)
+	rootCmd.AddCommand(
+		NewPairsCmd(opts),
+		NewPricesCmd(opts),
+		NewAgentCmd(opts),
+		NewConfigCmd(opts),
+		NewCacheCmd(opts),
+		NewOriginsCmd(opts),
+		NewBenchCmd(opts),
+		NewGraphCmd(opts),
+		NewDiffCmd(opts),
+		NewCompareCmd(opts),
+		NewExportCmd(opts),
+		NewReplayCmd(opts),
+		NewRecordCmd(opts),
+		NewTopCmd(opts),
+		NewConvertCmd(opts),
+		NewSpreadCmd(opts),
+		NewWatchCmd(opts),
+		NewVersionCmd(opts),
+		NewGenDocsCmd(opts),
+	)
+
+	// Merging the persistent flags panics if a flag of a command has the
+	// same name or shorthand as a persistent flag.
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		assert.NotPanics(t, func() { c.InheritedFlags() }, c.CommandPath())
+		for _, sc := range c.Commands() {
+			visit(sc)
+		}
+	}
+	visit(rootCmd)
+}
+
+func TestCancelAfter(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancelAfter(10*time.Millisecond, cancel)
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, errCodeTimeout, newJSONError(context.Cause(ctx)).Code)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// Stopped timers do not cancel the context.
+	ctx, cancel = context.WithCancelCause(context.Background())
+	cancelAfter(10*time.Millisecond, cancel).Stop()
+	select {
+	case <-ctx.Done():
+		t.Fatal("canceled")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestExecuteCommand(t *testing.T) {
+	failed := errors.New("failed")
+	cmd := &cobra.Command{RunE: func(*cobra.Command, []string) error { return failed }}
+	cmd.SetArgs(nil)
+	assert.Equal(t, failed, executeCommand(context.Background(), cmd))
+
+	// The command is stopped with the context, and the cause of the
+	// cancellation is returned instead of the error of the command.
+	var stopped bool
+	cmd = &cobra.Command{RunE: func(cmd *cobra.Command, _ []string) error {
+		<-cmd.Context().Done()
+		stopped = true
+		return cmd.Context().Err()
+	}}
+	cmd.SetArgs(nil)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancelAfter(10*time.Millisecond, cancel)
+	err := executeCommand(ctx, cmd)
+	assert.Equal(t, errCodeTimeout, newJSONError(err).Code)
+	assert.True(t, stopped)
+}
+
+func TestLogFlagAliases(t *testing.T) {
+	opts := &options{}
+	rootCmd := NewRootCommand(opts)
+	rootCmd.AddCommand(&cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }})
+	rootCmd.SetArgs([]string{"test", "--log-level", "debug", "--log-format", "json"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Equal(t, "debug", opts.Verbosity().String())
+	assert.Equal(t, "json", rootCmd.PersistentFlags().Lookup("log.format").Value.String())
+}
+
+func TestPricesFlagAliases(t *testing.T) {
+	opts := &options{}
+	rootCmd := NewRootCommand(opts)
+	rootCmd.AddCommand(NewPricesCmd(opts), NewExportCmd(opts))
+
+	pricesCmd, _, err := rootCmd.Find([]string{"prices"})
+	require.NoError(t, err)
+	require.NoError(t, pricesCmd.ParseFlags([]string{"--interval", "30s", "--repeat", "10"}))
+	assert.Equal(t, 30*time.Second, opts.FollowInterval)
+	assert.Equal(t, 10, opts.FollowCount)
+	assert.True(t, pricesCmd.Flags().Changed("follow.interval"))
+
+	// Other commands keep their own flags with the same names.
+	exportCmd, _, err := rootCmd.Find([]string{"export"})
+	require.NoError(t, err)
+	require.NoError(t, exportCmd.ParseFlags([]string{"--interval", "5s"}))
+	assert.Equal(t, 5*time.Second, opts.ExportInterval)
+	assert.Equal(t, 30*time.Second, opts.FollowInterval)
+}
+
+func TestPricesFollowValidation(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{args: []string{"--count", "-1"}, err: "count must not be negative"},
+		{args: []string{"--interval", "0s"}, err: "follow interval must be positive"},
+		{args: []string{"--repeat", "3", "--strict"}, err: "the strict flag cannot be used with --follow"},
+		{args: []string{"--follow", "--min-sources", "-1"}, err: "minimum number of sources must not be negative"},
+	} {
+		opts := &options{}
+		rootCmd := NewRootCommand(opts)
+		rootCmd.AddCommand(NewPricesCmd(opts))
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+		// The flags are validated before the config is loaded and the
+		// services are started.
+		rootCmd.SetArgs(append([]string{"prices", "BTC/USD", "--config", "/nonexistent.hcl"}, tt.args...))
+		assert.EqualError(t, rootCmd.Execute(), tt.err, tt.args)
+	}
+}
+
+func TestNoCacheImpliesNoRPC(t *testing.T) {
+	for _, tt := range []struct {
+		args  []string
+		noRPC bool
+	}{
+		{args: []string{"test"}, noRPC: false},
+		{args: []string{"test", "--norpc"}, noRPC: true},
+		{args: []string{"test", "--no-cache"}, noRPC: true},
+	} {
+		opts := &options{}
+		rootCmd := NewRootCommand(opts)
+		rootCmd.AddCommand(&cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }})
+		rootCmd.SetArgs(tt.args)
+		require.NoError(t, rootCmd.Execute())
+		assert.Equal(t, tt.noRPC, opts.NoRPC, tt.args)
+	}
+}
+
+func TestPairArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pairs.txt")
+	require.NoError(t, os.WriteFile(file, []byte("ETH/USD\n"), 0o600))
+	for _, tt := range []struct {
+		args      []string
+		pairsFile string
+		want      []string
+	}{
+		{args: []string{"BTC/USD"}, want: []string{"BTC/USD"}},
+		{args: []string{"-"}, want: []string{"DAI/USD", "*/EUR"}},
+		{args: []string{"BTC/USD", "-", "ETH/BTC"}, want: []string{"BTC/USD", "DAI/USD", "*/EUR", "ETH/BTC"}},
+		{args: []string{"BTC/USD"}, pairsFile: "-", want: []string{"BTC/USD", "DAI/USD", "*/EUR"}},
+		{args: []string{"-"}, pairsFile: file, want: []string{"DAI/USD", "*/EUR", "ETH/USD"}},
+		// The standard input is read only once.
+		{args: []string{"-", "-"}, pairsFile: "-", want: []string{"DAI/USD", "*/EUR", "DAI/USD", "*/EUR", "DAI/USD", "*/EUR"}},
+	} {
+		opts := &options{PairsFile: tt.pairsFile}
+		got, err := opts.pairArgsFrom(strings.NewReader("DAI/USD\n# Comment\n*/EUR\n"), tt.args)
+		require.NoError(t, err, tt.args)
+		assert.Equal(t, tt.want, got, tt.args)
+	}
+
+	_, err := (&options{}).pairArgsFrom(strings.NewReader("BTCUSD\n"), []string{"-"})
+	assert.ErrorContains(t, err, "unable to read pairs from the standard input: line 1")
+}
@@ -0,0 +1,214 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+)
+
+// originInfo describes an origin and the pairs for which it is used by the
+// price models.
+type originInfo struct {
+	Name string `json:"name"`
+
+	// Type is the type of the origin. Built-in origins, which are not
+	// configured with an origin block, have the type of their name.
+	Type string `json:"type"`
+
+	Pairs  []string       `json:"pairs"`
+	Params map[string]any `json:"params,omitempty"`
+
+	// Builtin is true for origins which are not configured.
+	Builtin bool `json:"builtin"`
+}
+
+// origins returns the configured origins and the built-in origins used by
+// the price models, sorted by name. Secret parameters are redacted.
+func (c *cliConfig) origins() []originInfo {
+	pairs := make(map[string]map[provider.Pair]bool)
+	queue := append(c.Gofer.PriceModels[:0:0], c.Gofer.PriceModels...)
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		queue = append(queue, s.Sources...)
+		if s.Origin == nil || s.Origin.Origin == "." {
+			continue
+		}
+		if pairs[s.Origin.Origin] == nil {
+			pairs[s.Origin.Origin] = make(map[provider.Pair]bool)
+		}
+		pairs[s.Origin.Origin][s.Pair] = true
+	}
+	infos := make(map[string]*originInfo)
+	for _, o := range c.Gofer.Origins {
+		infos[o.Origin] = &originInfo{Name: o.Origin, Type: o.Type, Params: redactParams(o.Params)}
+	}
+	builtin := origins.DefaultOriginSet(nil).Handlers()
+	for name := range pairs {
+		if _, ok := infos[name]; ok {
+			continue
+		}
+		typ := ""
+		if _, ok := builtin[name]; ok {
+			typ = name
+		}
+		infos[name] = &originInfo{Name: name, Type: typ, Builtin: true}
+	}
+	res := make([]originInfo, 0, len(infos))
+	for name, info := range infos {
+		info.Pairs = []string{}
+		for pair := range pairs[name] {
+			info.Pairs = append(info.Pairs, pair.String())
+		}
+		sort.Strings(info.Pairs)
+		res = append(res, *info)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// redactParams returns a copy of the origin parameters with values of
+// secret parameters redacted.
+func redactParams(params map[string]any) map[string]any {
+	if len(params) == 0 {
+		return nil
+	}
+	res := make(map[string]any, len(params))
+	for k, v := range params {
+		switch {
+		case secretAttributes[k]:
+			res[k] = redacted
+		case isMap(v):
+			res[k] = redactParams(v.(map[string]any))
+		default:
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func isMap(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+var tableOriginColumns = []tableColumn{{name: "NAME"}, {name: "TYPE"}, {name: "PAIRS"}, {name: "PARAMS"}}
+
+// writeOrigins writes the origins as JSON, or NDJSON, if one of these
+// formats is used, and as a table otherwise.
+func writeOrigins(w io.Writer, format marshal.FormatType, infos []originInfo) error {
+	return writeList(w, format, infos, tableOriginColumns, func(i originInfo) ([]string, error) {
+		typ := i.Type
+		if typ == "" {
+			typ = "unknown"
+		}
+		params, err := formatParams(i.Params)
+		if err != nil {
+			return nil, err
+		}
+		return []string{i.Name, typ, strings.Join(i.Pairs, ","), params}, nil
+	})
+}
+
+// writeList writes the items as a JSON array, as JSON objects in separate
+// lines for the NDJSON format, as a YAML list for the YAML format, or as
+// a table with the given columns for other formats.
+func writeList[T any](w io.Writer, format marshal.FormatType, items []T, columns []tableColumn, row func(T) ([]string, error)) error {
+	switch format {
+	case marshal.JSON:
+		b, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case marshal.NDJSON:
+		for _, i := range items {
+			b, err := json.Marshal(i)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yamlFormat:
+		if items == nil {
+			items = []T{}
+		}
+		return writeYAML(w, items)
+	}
+	rows := [][]string{make([]string, len(columns))}
+	for c, col := range columns {
+		rows[0][c] = col.name
+	}
+	for _, i := range items {
+		r, err := row(i)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, r)
+	}
+	widths := make([]int, len(columns))
+	for _, r := range rows {
+		for c, s := range r {
+			if n := utf8.RuneCountInString(s); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+	for _, r := range rows {
+		if _, err := io.WriteString(w, strings.TrimRight(tableLine(columns, r, widths), " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatParams formats the parameters as space separated key=value pairs,
+// sorted by key. Values which are not strings are formatted as JSON.
+func formatParams(params map[string]any) (string, error) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for n, k := range keys {
+		v, ok := params[k].(string)
+		if !ok {
+			b, err := json.Marshal(params[k])
+			if err != nil {
+				return "", err
+			}
+			v = string(b)
+		}
+		parts[n] = k + "=" + v
+	}
+	return strings.Join(parts, " "), nil
+}
@@ -0,0 +1,107 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+const testOriginsConfig = `
+gofer {
+  origin "binance_us" {
+    type   = "binance"
+    params = {
+      url = "https://www.binance.us"
+    }
+  }
+  origin "openexchangerates" {
+    type   = "openexchangerates"
+    params = {
+      api_key = "secret"
+    }
+  }
+
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "origin" { origin = "bitstamp" }
+    source "BTC/USD" "origin" { origin = "binance_us" }
+    source "BTC/USD" "indirect" {
+      source "BTC/ETH" "origin" { origin = "bitstamp" }
+      source "ETH/USD" "origin" { origin = "." }
+    }
+    min_sources = 1
+  }
+  price_model "ETH/USD" "origin" { origin = "foo" }
+}
+`
+
+func TestConfig_Origins(t *testing.T) {
+	cfg := loadTestConfig(t, testOriginsConfig)
+	assert.Equal(t, []originInfo{
+		{
+			Name:   "binance_us",
+			Type:   "binance",
+			Pairs:  []string{"BTC/USD"},
+			Params: map[string]any{"url": "https://www.binance.us"},
+		},
+		{
+			Name:    "bitstamp",
+			Type:    "bitstamp",
+			Pairs:   []string{"BTC/ETH", "BTC/USD"},
+			Builtin: true,
+		},
+		{
+			Name:    "foo",
+			Pairs:   []string{"ETH/USD"},
+			Builtin: true,
+		},
+		{
+			Name:   "openexchangerates",
+			Type:   "openexchangerates",
+			Pairs:  []string{},
+			Params: map[string]any{"api_key": redacted},
+		},
+	}, cfg.origins())
+}
+
+func TestWriteOrigins(t *testing.T) {
+	cfg := loadTestConfig(t, testOriginsConfig)
+	infos := cfg.origins()
+
+	b := &bytes.Buffer{}
+	require.NoError(t, writeOrigins(b, marshal.Plain, infos))
+	assert.Equal(t, `NAME               TYPE               PAIRS            PARAMS
+binance_us         binance            BTC/USD          url=https://www.binance.us
+bitstamp           bitstamp           BTC/ETH,BTC/USD
+foo                unknown            ETH/USD
+openexchangerates  openexchangerates                   api_key=REDACTED
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, writeOrigins(b, marshal.NDJSON, infos[:1]))
+	assert.JSONEq(t, `{
+  "name": "binance_us",
+  "type": "binance",
+  "pairs": ["BTC/USD"],
+  "params": {"url": "https://www.binance.us"},
+  "builtin": false
+}`, b.String())
+}
@@ -0,0 +1,50 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofer.pid")
+	pid := strconv.Itoa(os.Getpid()) + "\n"
+
+	require.NoError(t, writePIDFile(path))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, pid, string(b))
+
+	// A file of a running process must not be overwritten.
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0o644))
+	assert.Error(t, writePIDFile(path))
+
+	// A file of another process is not removed.
+	require.NoError(t, removePIDFile(path))
+	assert.FileExists(t, path)
+
+	// A file left by a process which is not running is overwritten.
+	require.NoError(t, os.WriteFile(path, []byte("0\n"), 0o644))
+	require.NoError(t, writePIDFile(path))
+	require.NoError(t, removePIDFile(path))
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,204 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/priceprovider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/query"
+)
+
+var errProbeTimeout = errors.New("timeout")
+
+var tableProbeColumns = []tableColumn{
+	{name: "ORIGIN"},
+	{name: "PAIR"},
+	{name: "REACHABLE"},
+	{name: "PARSED"},
+	{name: "LATENCY", right: true},
+	{name: "ERROR"},
+}
+
+// originProbe is the result of a live request to an origin.
+type originProbe struct {
+	Origin string `json:"origin"`
+	Pair   string `json:"pair"`
+
+	// Reachable is true if all HTTP requests to the origin succeeded. For
+	// origins which do not use HTTP, such as on-chain origins, it is true if
+	// the price was fetched.
+	Reachable bool `json:"reachable"`
+
+	// Parsed is true if a price was parsed from the response.
+	Parsed bool `json:"parsed"`
+
+	Price     float64       `json:"price,omitempty"`
+	Latency   time.Duration `json:"-"`
+	LatencyMS int64         `json:"latency_ms"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ok reports whether the origin is reachable and its response was parsed.
+func (p originProbe) ok() bool {
+	return p.Reachable && p.Parsed
+}
+
+// probeWorkerPool wraps a query.WorkerPool and records errors of HTTP
+// requests, to tell unreachable origins apart from unexpected responses.
+type probeWorkerPool struct {
+	wp query.WorkerPool
+
+	mu       sync.Mutex
+	requests int
+	err      error
+}
+
+// Query implements the query.WorkerPool interface.
+func (p *probeWorkerPool) Query(req *query.HTTPRequest) *query.HTTPResponse {
+	res := p.wp.Query(req)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests++
+	if res.Error != nil && p.err == nil {
+		p.err = res.Error
+	}
+	return res
+}
+
+func (p *probeWorkerPool) result() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests, p.err
+}
+
+// originHandler returns the handler of the origin, which sends HTTP requests
+// using the given worker pool.
+func (c *cliConfig) originHandler(info originInfo, wp query.WorkerPool, clients ethereum.ClientRegistry) (origins.Handler, error) {
+	if !info.Builtin {
+		for _, o := range c.Gofer.Origins {
+			if o.Origin == info.Name {
+				return priceprovider.NewHandler(o.Type, wp, clients, o.Params)
+			}
+		}
+	}
+	if h, ok := origins.DefaultOriginSet(wp).Handlers()[info.Name]; ok {
+		return h, nil
+	}
+	return nil, fmt.Errorf("%w: %s", origins.ErrUnknownOrigin, info.Name)
+}
+
+// probeOrigin fetches the price of the pair from the origin. If the handler
+// does not return within the timeout, the probe fails and the late result is
+// discarded.
+func probeOrigin(name string, h origins.Handler, wp *probeWorkerPool, pair provider.Pair, timeout time.Duration) originProbe {
+	probe := originProbe{Origin: name, Pair: pair.String()}
+	resCh := make(chan []origins.FetchResult, 1)
+	start := time.Now()
+	go func() {
+		resCh <- h.Fetch([]origins.Pair{{Base: pair.Base, Quote: pair.Quote}})
+	}()
+	var err error
+	select {
+	case res := <-resCh:
+		switch {
+		case len(res) == 0:
+			err = origins.ErrMissingResponseForPair
+		case res[0].Error != nil:
+			err = res[0].Error
+		default:
+			probe.Price = res[0].Price.Price
+		}
+	case <-time.After(timeout):
+		err = errProbeTimeout
+	}
+	probe.Latency = time.Since(start)
+	probe.LatencyMS = probe.Latency.Milliseconds()
+	requests, qErr := wp.result()
+	switch {
+	case qErr != nil:
+		err = qErr
+	case requests > 0:
+		probe.Reachable = true
+	default:
+		probe.Reachable = err == nil
+	}
+	probe.Parsed = err == nil
+	if err != nil {
+		probe.Error = err.Error()
+	}
+	return probe
+}
+
+// probeOrigins probes the origins in parallel, using the first pair for
+// which an origin is used by the price models. Origins which are not used
+// by any price model are skipped. Results are returned in the order of the
+// origins.
+func (c *cliConfig) probeOrigins(infos []originInfo, wp query.WorkerPool, clients ethereum.ClientRegistry, timeout time.Duration) []originProbe {
+	var used []originInfo
+	for _, info := range infos {
+		if len(info.Pairs) > 0 {
+			used = append(used, info)
+		}
+	}
+	probes := make([]originProbe, len(used))
+	var wg sync.WaitGroup
+	for n, info := range used {
+		probes[n] = originProbe{Origin: info.Name, Pair: info.Pairs[0]}
+		pair, err := provider.NewPair(info.Pairs[0])
+		if err != nil {
+			probes[n].Error = err.Error()
+			continue
+		}
+		pwp := &probeWorkerPool{wp: wp}
+		h, err := c.originHandler(info, pwp, clients)
+		if err != nil {
+			probes[n].Error = err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func(n int, name string) {
+			defer wg.Done()
+			probes[n] = probeOrigin(name, h, pwp, pair, timeout)
+		}(n, info.Name)
+	}
+	wg.Wait()
+	return probes
+}
+
+// writeProbes writes the results of probes as JSON, or NDJSON, if one of
+// these formats is used, and as a table otherwise.
+func writeProbes(w io.Writer, format marshal.FormatType, probes []originProbe) error {
+	return writeList(w, format, probes, tableProbeColumns, func(p originProbe) ([]string, error) {
+		return []string{
+			p.Origin,
+			p.Pair,
+			strconv.FormatBool(p.Reachable),
+			strconv.FormatBool(p.Parsed),
+			p.Latency.Round(time.Millisecond).String(),
+			p.Error,
+		}, nil
+	})
+}
@@ -0,0 +1,159 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/query"
+)
+
+type handlerFunc func(pairs []origins.Pair) []origins.FetchResult
+
+func (f handlerFunc) Fetch(pairs []origins.Pair) []origins.FetchResult {
+	return f(pairs)
+}
+
+func TestConfig_ProbeOrigins(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok/api/v2/ticker/btcusd":
+			_, _ = w.Write([]byte(`{"last":"100","bid":"99","ask":"101","volume":"5","timestamp":"1600000000"}`))
+		default:
+			_, _ = w.Write([]byte(`<html></html>`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := loadTestConfig(t, fmt.Sprintf(`
+gofer {
+  origin "ok" {
+    type   = "bitstamp"
+    params = { url = "%[1]s/ok" }
+  }
+  origin "broken" {
+    type   = "bitstamp"
+    params = { url = "%[1]s/broken" }
+  }
+  origin "unused" {
+    type   = "bitstamp"
+  }
+
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "origin" { origin = "ok" }
+    source "BTC/USD" "origin" { origin = "broken" }
+    source "BTC/USD" "origin" { origin = "foo" }
+    min_sources = 1
+  }
+}
+`, srv.URL))
+	probes := cfg.probeOrigins(cfg.origins(), query.NewHTTPWorkerPool(1), nil, time.Second)
+	require.Len(t, probes, 3)
+
+	assert.Equal(t, "broken", probes[0].Origin)
+	assert.Equal(t, "BTC/USD", probes[0].Pair)
+	assert.True(t, probes[0].Reachable)
+	assert.False(t, probes[0].Parsed)
+	assert.Contains(t, probes[0].Error, "failed to parse bitstamp response")
+
+	assert.Equal(t, "foo", probes[1].Origin)
+	assert.False(t, probes[1].ok())
+	assert.Contains(t, probes[1].Error, "unknown origin")
+
+	assert.Equal(t, "ok", probes[2].Origin)
+	assert.True(t, probes[2].ok())
+	assert.Equal(t, 100.0, probes[2].Price)
+	assert.Empty(t, probes[2].Error)
+}
+
+func TestProbeOrigin(t *testing.T) {
+	pair := provider.Pair{Base: "BTC", Quote: "USD"}
+
+	// The request failed.
+	wp := &probeWorkerPool{wp: workerPoolFunc(func(*query.HTTPRequest) *query.HTTPResponse {
+		return &query.HTTPResponse{Error: fmt.Errorf("connection refused")}
+	})}
+	h := handlerFunc(func(pairs []origins.Pair) []origins.FetchResult {
+		res := wp.Query(&query.HTTPRequest{})
+		return []origins.FetchResult{{Error: res.Error}}
+	})
+	p := probeOrigin("foo", h, wp, pair, time.Second)
+	assert.False(t, p.Reachable)
+	assert.False(t, p.Parsed)
+	assert.Equal(t, "connection refused", p.Error)
+
+	// The origin does not send HTTP requests.
+	wp = &probeWorkerPool{wp: workerPoolFunc(nil)}
+	h = func(pairs []origins.Pair) []origins.FetchResult {
+		return []origins.FetchResult{{Price: origins.Price{Pair: pairs[0], Price: 1}}}
+	}
+	p = probeOrigin("foo", h, wp, pair, time.Second)
+	assert.True(t, p.ok())
+	assert.Equal(t, 1.0, p.Price)
+
+	// The origin does not respond in time.
+	done := make(chan struct{})
+	defer close(done)
+	h = func(pairs []origins.Pair) []origins.FetchResult {
+		<-done
+		return nil
+	}
+	p = probeOrigin("foo", h, wp, pair, 10*time.Millisecond)
+	assert.False(t, p.Reachable)
+	assert.Equal(t, "timeout", p.Error)
+	assert.GreaterOrEqual(t, p.Latency, 10*time.Millisecond)
+}
+
+type workerPoolFunc func(req *query.HTTPRequest) *query.HTTPResponse
+
+func (f workerPoolFunc) Query(req *query.HTTPRequest) *query.HTTPResponse {
+	return f(req)
+}
+
+func TestWriteProbes(t *testing.T) {
+	probes := []originProbe{
+		{Origin: "bitstamp", Pair: "BTC/USD", Reachable: true, Parsed: true, Price: 100, Latency: 120 * time.Millisecond, LatencyMS: 120},
+		{Origin: "kraken", Pair: "BTC/USD", Error: "timeout", Latency: 10 * time.Second, LatencyMS: 10000},
+	}
+	b := &bytes.Buffer{}
+	require.NoError(t, writeProbes(b, marshal.Plain, probes))
+	assert.Equal(t, `ORIGIN    PAIR     REACHABLE  PARSED  LATENCY  ERROR
+bitstamp  BTC/USD  true       true      120ms
+kraken    BTC/USD  false      false       10s  timeout
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, writeProbes(b, marshal.NDJSON, probes[:1]))
+	assert.JSONEq(t, `{
+  "origin": "bitstamp",
+  "pair": "BTC/USD",
+  "reachable": true,
+  "parsed": true,
+  "price": 100,
+  "latency_ms": 120
+}`, b.String())
+}
@@ -0,0 +1,258 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+type queryStepKind int
+
+const (
+	queryField queryStepKind = iota
+	queryIndex
+	queryIterate
+)
+
+type queryStep struct {
+	kind  queryStepKind
+	field string
+	index int
+}
+
+// jsonQuery is a path in JSON values, written in a subset of the jq syntax:
+// "." is the value itself, ".name" or ."name" is a field of an object,
+// "[n]" is an element of an array, negative indexes count from the end, and
+// "[]" iterates over elements of an array or values of an object. For
+// example, ".prices[].base" returns the base of every price used to
+// calculate a price.
+type jsonQuery []queryStep
+
+func parseQuery(s string) (jsonQuery, error) {
+	if !strings.HasPrefix(s, ".") {
+		return nil, fmt.Errorf("invalid query %q: must start with a dot", s)
+	}
+	var q jsonQuery
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '.' && i+1 < len(s) && s[i+1] == '"':
+			end := strings.IndexByte(s[i+2:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query %q: unterminated string", s)
+			}
+			q = append(q, queryStep{kind: queryField, field: s[i+2 : i+2+end]})
+			i += end + 3
+		case s[i] == '.':
+			end := i + 1
+			for end < len(s) && isQueryIdent(s[end]) {
+				end++
+			}
+			if end > i+1 {
+				q = append(q, queryStep{kind: queryField, field: s[i+1 : end]})
+			} else if end < len(s) && s[end] != '[' {
+				return nil, fmt.Errorf("invalid query %q: unexpected %q", s, s[end])
+			}
+			i = end
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query %q: unterminated bracket", s)
+			}
+			inner := s[i+1 : i+end]
+			switch {
+			case inner == "":
+				q = append(q, queryStep{kind: queryIterate})
+			case strings.HasPrefix(inner, `"`) && strings.HasSuffix(inner, `"`) && len(inner) >= 2:
+				q = append(q, queryStep{kind: queryField, field: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid query %q: invalid index %q", s, inner)
+				}
+				q = append(q, queryStep{kind: queryIndex, index: n})
+			}
+			i += end + 1
+		default:
+			return nil, fmt.Errorf("invalid query %q: unexpected %q", s, s[i])
+		}
+	}
+	return q, nil
+}
+
+func isQueryIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// eval returns the values at the path. Fields of null and missing fields
+// are null, like in jq.
+func (q jsonQuery) eval(v any) ([]any, error) {
+	values := []any{v}
+	for _, step := range q {
+		var next []any
+		for _, v := range values {
+			switch step.kind {
+			case queryField:
+				switch t := v.(type) {
+				case nil:
+					next = append(next, nil)
+				case map[string]any:
+					next = append(next, t[step.field])
+				default:
+					return nil, fmt.Errorf("cannot get field %q of %s", step.field, jsonType(v))
+				}
+			case queryIndex:
+				switch t := v.(type) {
+				case nil:
+					next = append(next, nil)
+				case []any:
+					n := step.index
+					if n < 0 {
+						n += len(t)
+					}
+					if n < 0 || n >= len(t) {
+						next = append(next, nil)
+					} else {
+						next = append(next, t[n])
+					}
+				default:
+					return nil, fmt.Errorf("cannot get element %d of %s", step.index, jsonType(v))
+				}
+			case queryIterate:
+				switch t := v.(type) {
+				case []any:
+					next = append(next, t...)
+				case map[string]any:
+					keys := make([]string, 0, len(t))
+					for k := range t {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, t[k])
+					}
+				default:
+					return nil, fmt.Errorf("cannot iterate over %s", jsonType(v))
+				}
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// queryMarshaller is a marshal.Marshaller which applies a query to the JSON
+// values written by another marshaller, which must write JSON. Every result
+// is written in a separate line, strings without quotes. Errors are written
+// as they are.
+type queryMarshaller struct {
+	marshaller marshal.Marshaller
+	query      jsonQuery
+	writers    []io.Writer
+	buffers    map[io.Writer]*bytes.Buffer
+}
+
+func newQueryMarshaller(m marshal.Marshaller, query string) (*queryMarshaller, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return &queryMarshaller{marshaller: m, query: q, buffers: make(map[io.Writer]*bytes.Buffer)}, nil
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *queryMarshaller) Write(writer io.Writer, item interface{}) error {
+	if _, ok := item.(error); ok {
+		return m.marshaller.Write(writer, item)
+	}
+	buf, ok := m.buffers[writer]
+	if !ok {
+		buf = &bytes.Buffer{}
+		m.buffers[writer] = buf
+		m.writers = append(m.writers, writer)
+	}
+	return m.marshaller.Write(buf, item)
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *queryMarshaller) Flush() error {
+	if err := m.marshaller.Flush(); err != nil {
+		return err
+	}
+	writers, buffers := m.writers, m.buffers
+	m.writers, m.buffers = nil, make(map[io.Writer]*bytes.Buffer)
+	for _, w := range writers {
+		dec := json.NewDecoder(buffers[w])
+		dec.UseNumber()
+		for {
+			var v any
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			results, err := m.query.eval(v)
+			if err != nil {
+				return err
+			}
+			for _, r := range results {
+				if err := writeQueryResult(w, r); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func writeQueryResult(w io.Writer, v any) error {
+	if s, ok := v.(string); ok {
+		_, err := io.WriteString(w, s+"\n")
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
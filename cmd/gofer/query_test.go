@@ -0,0 +1,158 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  jsonQuery
+		err   bool
+	}{
+		{query: ".", want: nil},
+		{query: ".price", want: jsonQuery{{kind: queryField, field: "price"}}},
+		{query: `."volume24h"`, want: jsonQuery{{kind: queryField, field: "volume24h"}}},
+		{query: `.["a b"]`, want: jsonQuery{{kind: queryField, field: "a b"}}},
+		{
+			query: ".prices[].base",
+			want: jsonQuery{
+				{kind: queryField, field: "prices"},
+				{kind: queryIterate},
+				{kind: queryField, field: "base"},
+			},
+		},
+		{
+			query: ".prices[-1]",
+			want:  jsonQuery{{kind: queryField, field: "prices"}, {kind: queryIndex, index: -1}},
+		},
+		{query: "price", err: true},
+		{query: ".prices[", err: true},
+		{query: ".prices[x]", err: true},
+		{query: `."price`, err: true},
+		{query: ".price-1", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseQuery(tt.query)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q)
+		})
+	}
+}
+
+func TestQueryMarshaller(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := &provider.Price{
+		Type:  "median",
+		Pair:  btcusd,
+		Price: 45242.13,
+		Time:  time.Unix(1700000000, 0),
+		Prices: []*provider.Price{
+			{Type: "origin", Pair: btcusd, Price: 45227.05},
+			{Type: "origin", Pair: provider.Pair{Base: "BTC", Quote: "USDT"}, Price: 45242.13},
+		},
+	}
+
+	tests := []struct {
+		format marshal.FormatType
+		query  string
+		want   string
+		err    bool
+	}{
+		{format: marshal.NDJSON, query: ".price", want: "45242.13\n"},
+		{format: marshal.JSON, query: ".[].price", want: "45242.13\n"},
+		{format: marshal.NDJSON, query: ".prices[].quote", want: "USD\nUSDT\n"},
+		{format: marshal.NDJSON, query: ".prices[-1].price", want: "45242.13\n"},
+		{format: marshal.NDJSON, query: ".prices[5]", want: "null\n"},
+		{format: marshal.NDJSON, query: ".missing.price", want: "null\n"},
+		{format: marshal.NDJSON, query: ".price[0]", err: true},
+		{format: marshal.NDJSON, query: ".price[]", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			base, err := marshal.NewMarshal(tt.format)
+			require.NoError(t, err)
+			m, err := newQueryMarshaller(base, tt.query)
+			require.NoError(t, err)
+
+			out := &bytes.Buffer{}
+			require.NoError(t, m.Write(out, price))
+			err = m.Flush()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestQueryMarshaller_Errors(t *testing.T) {
+	base, err := marshal.NewMarshal(marshal.NDJSON)
+	require.NoError(t, err)
+	m, err := newQueryMarshaller(base, ".price")
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	require.NoError(t, m.Write(errOut, errors.New("failed")))
+	require.NoError(t, m.Flush())
+
+	// Flushed values are not written again.
+	require.NoError(t, m.Write(out, &provider.Price{Price: 1}))
+	require.NoError(t, m.Flush())
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, "1\n", out.String())
+	assert.Contains(t, errOut.String(), "failed")
+}
+
+func TestOptionsMarshaller(t *testing.T) {
+	opts := &options{Format: formatTypeValue{format: marshal.NDJSON}, Query: ".price"}
+	m, err := opts.marshaller(nil)
+	require.NoError(t, err)
+	assert.IsType(t, &queryMarshaller{}, m)
+
+	opts.Format.format = csvFormat
+	_, err = opts.marshaller(nil)
+	assert.Error(t, err)
+
+	opts.Query = ""
+	m, err = opts.marshaller(nil)
+	require.NoError(t, err)
+	csv, err := agent.NewCSVMarshaller(nil)
+	require.NoError(t, err)
+	assert.IsType(t, csv, m)
+}
@@ -0,0 +1,86 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent"
+)
+
+// reloader rebuilds the price models and origins from the config files
+// and swaps them into a running agent.
+type reloader struct {
+	mu sync.Mutex
+
+	// ctx is the context of the agent. Services created during a reload
+	// are bound to it, not to the context of the reload request.
+	ctx      context.Context
+	opts     *options
+	provider *agent.ReloadableProvider
+	// cancel stops the services of the currently used configuration.
+	cancel context.CancelFunc
+}
+
+// reload implements the agent.ReloadFunc function. Only the price models,
+// origins and hooks are reloaded; changes to the agent block require a
+// restart.
+func (r *reloader) reload(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var cfg cliConfig
+	if err := config.LoadFiles(&cfg, r.opts.ConfigFilePath); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	services, err := cfg.ClientServices(ctx, r.opts.Logger(), true, marshal.JSON)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if err := services.Start(ctx); err != nil {
+		cancel()
+		return err
+	}
+	r.provider.Swap(services.PriceProvider, services.PriceHook, cfg.minSources())
+	r.cancel()
+	r.cancel = cancel
+	return nil
+}
+
+// reloadOn reloads the configuration every time a signal is received,
+// until the context is canceled. Failed reloads are logged, and the current
+// configuration is kept.
+func (r *reloader) reloadOn(ctx context.Context, signals <-chan os.Signal, logger log.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := r.reload(ctx); err != nil {
+				logger.WithError(err).Error("Unable to reload configuration")
+				continue
+			}
+			logger.Info("Configuration reloaded")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+
+	"gofer-cli/pkg/agent"
+)
+
+type nopHook struct{}
+
+func (nopHook) Check(map[provider.Pair]*provider.Price) error { return nil }
+
+func TestReloader_Reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	rp := agent.NewReloadableProvider(&mocks.Provider{}, nopHook{}, nil)
+	r := &reloader{
+		ctx:      ctx,
+		opts:     &options{ConfigFilePath: []string{path}},
+		provider: rp,
+		cancel:   func() {},
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+gofer {
+  price_model "ETH/USD" "median" {
+    source "ETH/USD" "origin" { origin = "bitstamp" }
+    min_sources = 2
+  }
+}
+`), 0o600))
+	require.NoError(t, r.reload(context.Background()))
+	pairs, err := rp.Pairs()
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{{Base: "ETH", Quote: "USD"}}, pairs)
+	assert.Equal(t, map[provider.Pair]int{{Base: "ETH", Quote: "USD"}: 2}, rp.MinSources())
+
+	// An invalid config must not replace the current one.
+	require.NoError(t, os.WriteFile(path, []byte(`gofer {`), 0o600))
+	assert.Error(t, r.reload(context.Background()))
+	pairs, err = rp.Pairs()
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{{Base: "ETH", Quote: "USD"}}, pairs)
+}
+
+func TestReloader_ReloadOn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	rp := agent.NewReloadableProvider(&mocks.Provider{}, nopHook{}, nil)
+	r := &reloader{
+		ctx:      ctx,
+		opts:     &options{ConfigFilePath: []string{path}},
+		provider: rp,
+		cancel:   func() {},
+	}
+	require.NoError(t, os.WriteFile(path, []byte(`
+gofer {
+  price_model "BTC/USD" "origin" { origin = "bitstamp" }
+}
+`), 0o600))
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		r.reloadOn(ctx, signals, null.New())
+		close(done)
+	}()
+	// Signals are received one by one, so the first reload is finished
+	// when the second signal is received.
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	pairs, err := rp.Pairs()
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{{Base: "BTC", Quote: "USD"}}, pairs)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,214 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+)
+
+// secretAttributes are names of attributes, and keys of maps, whose values
+// are redacted when the configuration is rendered. Keys of redacted maps
+// are kept.
+var secretAttributes = map[string]bool{
+	"api_key":     true,
+	"api_keys":    true,
+	"basic_auth":  true,
+	"headers":     true,
+	"hmac_secret": true,
+	"password":    true,
+	"secret":      true,
+}
+
+const redacted = "REDACTED"
+
+// render returns the loaded configuration as HCL, with included files
+// merged, variables and functions evaluated, and all attributes of the
+// configured blocks, including the omitted ones, which have zero values.
+// Secrets, and passwords in URLs, are redacted.
+func (c *cliConfig) render() []byte {
+	f := hclwrite.NewEmptyFile()
+	renderBody(f.Body(), reflect.ValueOf(c).Elem())
+	return f.Bytes()
+}
+
+// renderBody renders fields of the struct with hcl tags as attributes and
+// blocks. Untagged fields, such as embedded structs or the type specific
+// configuration of price model sources, are rendered in the same body.
+func renderBody(body *hclwrite.Body, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		tag, ok := sf.Tag.Lookup("hcl")
+		if !ok {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				renderBody(body, fv)
+			}
+			continue
+		}
+		name, kind := hclTag(tag)
+		switch kind {
+		case "attr":
+			body.SetAttributeValue(name, attributeValue(name, fv))
+		case "block":
+			renderBlocks(body, name, fv)
+		}
+	}
+}
+
+// renderBlocks renders a block, a pointer to a block or a slice of blocks.
+func renderBlocks(body *hclwrite.Body, name string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			renderBlocks(body, name, v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			renderBlocks(body, name, v.Index(i))
+		}
+	case reflect.Struct:
+		var labels []string
+		for i := 0; i < v.NumField(); i++ {
+			if _, kind := hclTag(v.Type().Field(i).Tag.Get("hcl")); kind == "label" {
+				labels = append(labels, ctyValue(v.Field(i)).AsString())
+			}
+		}
+		if len(body.Attributes()) > 0 || len(body.Blocks()) > 0 {
+			body.AppendNewline()
+		}
+		renderBody(body.AppendNewBlock(name, labels).Body(), v)
+	}
+}
+
+// hclTag returns the name and the kind of the field with the hcl tag: an
+// "attr", a "block", a "label", or another kind, such as "remain".
+func hclTag(tag string) (name, kind string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, o := range strings.Split(opts, ",") {
+		switch o {
+		case "", "optional":
+		default:
+			return name, o
+		}
+	}
+	return name, "attr"
+}
+
+func attributeValue(name string, v reflect.Value) cty.Value {
+	if !secretAttributes[name] {
+		return ctyValue(v)
+	}
+	if v.Kind() == reflect.Map {
+		vals := make(map[string]cty.Value, v.Len())
+		for _, k := range v.MapKeys() {
+			vals[fmt.Sprint(k.Interface())] = cty.StringVal(redacted)
+		}
+		if len(vals) == 0 {
+			return cty.EmptyObjectVal
+		}
+		return cty.ObjectVal(vals)
+	}
+	if v.IsZero() {
+		return ctyValue(v)
+	}
+	return cty.StringVal(redacted)
+}
+
+// ctyValue converts the value of an attribute to a cty value. Values with
+// a String method, such as pairs, addresses and URLs, are rendered as
+// strings.
+func ctyValue(v reflect.Value) cty.Value {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(*config.URL); ok {
+			return cty.StringVal((*url.URL)(u).Redacted())
+		}
+	}
+	if s, ok := stringer(v); ok {
+		return cty.StringVal(s.String())
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return cty.NullVal(cty.DynamicPseudoType)
+		}
+		return ctyValue(v.Elem())
+	case reflect.String:
+		return cty.StringVal(v.String())
+	case reflect.Bool:
+		return cty.BoolVal(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cty.NumberIntVal(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cty.NumberUIntVal(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cty.NumberFloatVal(v.Float())
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return cty.EmptyTupleVal
+		}
+		vals := make([]cty.Value, v.Len())
+		for i := range vals {
+			vals[i] = ctyValue(v.Index(i))
+		}
+		return cty.TupleVal(vals)
+	case reflect.Map:
+		if v.Len() == 0 {
+			return cty.EmptyObjectVal
+		}
+		vals := make(map[string]cty.Value, v.Len())
+		for _, k := range v.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			vals[key] = attributeValue(key, v.MapIndex(k))
+		}
+		return cty.ObjectVal(vals)
+	}
+	return cty.StringVal(fmt.Sprint(v.Interface()))
+}
+
+// stringer returns the value as a fmt.Stringer, if it or a pointer to it
+// implements the interface.
+func stringer(v reflect.Value) (fmt.Stringer, bool) {
+	if !v.CanInterface() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return nil, false
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok && v.Kind() != reflect.Pointer {
+		return s, true
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
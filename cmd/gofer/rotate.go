@@ -0,0 +1,156 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// rotatedTimeFormat is the format of the time of the rotation which is
+// added to names of rotated files.
+const rotatedTimeFormat = "20060102T150405.000Z"
+
+// rotatingFile is an io.Writer which writes to a file. The file is rotated,
+// that is, renamed with the time of the rotation added to its name, when it
+// exceeds the maximum size or age. Files are rotated only by the rotate
+// method, so a file is never split in the middle of a write.
+type rotatingFile struct {
+	path     string
+	maxSize  int64         // In bytes. If zero, files are not rotated by size.
+	maxAge   time.Duration // If zero, files are not rotated by age.
+	compress bool          // If true, rotated files are compressed with gzip.
+	now      func() time.Time
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, compress bool) *rotatingFile {
+	return &rotatingFile{
+		path:     path,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		compress: compress,
+		now:      time.Now,
+	}
+}
+
+// Write implements the io.Writer interface.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.file == nil {
+		if _, err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate rotates the file if it exceeds the maximum size or age, and opens
+// a new one. It reports whether a new file was opened. A file which exists
+// when the first file is opened is rotated, if it is not empty.
+func (f *rotatingFile) rotate() (bool, error) {
+	if f.file != nil {
+		bySize := f.maxSize > 0 && f.size >= f.maxSize
+		byAge := f.maxAge > 0 && f.now().Sub(f.opened) >= f.maxAge
+		if !bySize && !byAge {
+			return false, nil
+		}
+		if err := f.file.Close(); err != nil {
+			return false, err
+		}
+		f.file = nil
+		if err := f.archive(); err != nil {
+			return false, err
+		}
+	} else if fi, err := os.Stat(f.path); err == nil && fi.Size() > 0 {
+		if err := f.archive(); err != nil {
+			return false, err
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return false, err
+	}
+	f.file = file
+	f.size = 0
+	f.opened = f.now()
+	return true, nil
+}
+
+// Close closes the current file.
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// archive renames the file with the current time added to its name, before
+// the extension, and compresses it if compression is enabled.
+func (f *rotatingFile) archive() error {
+	ext := filepath.Ext(f.path)
+	name := strings.TrimSuffix(f.path, ext) + "-" + f.now().UTC().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(f.path, name); err != nil {
+		return err
+	}
+	if !f.compress {
+		return nil
+	}
+	return gzipFile(name)
+}
+
+// gzipFile compresses the file into a file with the ".gz" extension added to
+// its name, and removes it.
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	// The file must be closed before it is removed on some systems.
+	_ = src.Close()
+	return os.Remove(name)
+}
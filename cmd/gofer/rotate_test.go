@@ -0,0 +1,119 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRotatingFile_Size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.ndjson")
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	f := newRotatingFile(path, 10, 0, false)
+	f.now = func() time.Time { return now }
+
+	rotated, err := f.rotate()
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	_, err = f.Write([]byte("0123456789"))
+	require.NoError(t, err)
+
+	// The file is rotated only by the rotate method.
+	_, err = f.Write([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789a", readFile(t, path))
+
+	now = now.Add(time.Second)
+	rotated, err = f.rotate()
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	_, err = f.Write([]byte("b"))
+	require.NoError(t, err)
+
+	rotated, err = f.rotate()
+	require.NoError(t, err)
+	assert.False(t, rotated)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "b", readFile(t, path))
+	assert.Equal(t, "0123456789a", readFile(t, filepath.Join(dir, "prices-20230510T120001.000Z.ndjson")))
+}
+
+func TestRotatingFile_Age(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.csv")
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	f := newRotatingFile(path, 0, time.Hour, true)
+	f.now = func() time.Time { return now }
+
+	_, err := f.Write([]byte("a"))
+	require.NoError(t, err)
+
+	now = now.Add(59 * time.Minute)
+	rotated, err := f.rotate()
+	require.NoError(t, err)
+	assert.False(t, rotated)
+
+	now = now.Add(time.Minute)
+	rotated, err = f.rotate()
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	require.NoError(t, f.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	rotatedPath := filepath.Join(dir, "prices-20230510T130000.000Z.csv.gz")
+	assert.ElementsMatch(t, []string{path, rotatedPath}, files)
+
+	gz, err := os.Open(rotatedPath)
+	require.NoError(t, err)
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	b, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(b))
+}
+
+func TestRotatingFile_Existing(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+	f := newRotatingFile(path, 0, 0, false)
+	f.now = func() time.Time { return time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC) }
+
+	_, err := f.Write([]byte("new"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "new", readFile(t, path))
+	assert.Equal(t, "old", readFile(t, filepath.Join(dir, "prices-20230510T120000.000Z")))
+}
@@ -0,0 +1,132 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+var tableSpreadColumns = []tableColumn{
+	{name: "PAIR"},
+	{name: "ORIGIN"},
+	{name: "ORIGIN PAIR"},
+	{name: "PRICE", right: true},
+	{name: "BID", right: true},
+	{name: "ASK", right: true},
+	{name: "SPREAD", right: true},
+	{name: "DEVIATION", right: true},
+	{name: "ERROR"},
+}
+
+// originSpread describes the order book of an origin used to calculate
+// the price of a pair.
+type originSpread struct {
+	Pair       string  `json:"pair"`
+	Origin     string  `json:"origin"`
+	OriginPair string  `json:"origin_pair"`
+	Price      float64 `json:"price"`
+	Bid        float64 `json:"bid"`
+	Ask        float64 `json:"ask"`
+
+	// Spread is the difference between the ask and the bid, in percent of
+	// their midpoint. It is not set if the origin does not return the bid
+	// or the ask.
+	Spread *float64 `json:"spread,omitempty"`
+
+	// Deviation is the difference between the origin price and the price
+	// of the pair, in percent of the price of the pair. It is set only for
+	// origin prices of the same pair.
+	Deviation *float64 `json:"deviation,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// spreads returns the spreads of the origins used to calculate the prices,
+// ordered by the pair, and from the largest spread within a pair.
+func spreads(prices map[provider.Pair]*provider.Price) []originSpread {
+	var res []originSpread
+	for _, p := range prices {
+		for _, o := range originPrices(p) {
+			s := originSpread{
+				Pair:       p.Pair.String(),
+				Origin:     o.Parameters["origin"],
+				OriginPair: o.Pair.String(),
+				Price:      o.Price,
+				Bid:        o.Bid,
+				Ask:        o.Ask,
+				Error:      o.Error,
+			}
+			if o.Error == "" && o.Bid > 0 && o.Ask > 0 {
+				spread := (o.Ask - o.Bid) / ((o.Ask + o.Bid) / 2) * 100
+				s.Spread = &spread
+			}
+			if o.Error == "" && p.Error == "" && o.Pair == p.Pair && p.Price != 0 {
+				dev := (o.Price - p.Price) / p.Price * 100
+				s.Deviation = &dev
+			}
+			res = append(res, s)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Pair != res[j].Pair {
+			return res[i].Pair < res[j].Pair
+		}
+		si, sj := res[i].Spread, res[j].Spread
+		if (si == nil) != (sj == nil) {
+			return si != nil
+		}
+		if si != nil && *si != *sj {
+			return *si > *sj
+		}
+		if res[i].Origin != res[j].Origin {
+			return res[i].Origin < res[j].Origin
+		}
+		return res[i].OriginPair < res[j].OriginPair
+	})
+	return res
+}
+
+// writeSpreads writes the spreads as JSON, or NDJSON, if one of these
+// formats is used, and as a table otherwise.
+func writeSpreads(w io.Writer, format marshal.FormatType, spreads []originSpread) error {
+	percent := func(f *float64, format string) string {
+		if f == nil {
+			return ""
+		}
+		return fmt.Sprintf(format, *f)
+	}
+	return writeList(w, format, spreads, tableSpreadColumns, func(s originSpread) ([]string, error) {
+		if s.Error != "" {
+			return []string{s.Pair, s.Origin, s.OriginPair, "", "", "", "", "", s.Error}, nil
+		}
+		return []string{
+			s.Pair,
+			s.Origin,
+			s.OriginPair,
+			thousands(s.Price),
+			thousands(s.Bid),
+			thousands(s.Ask),
+			percent(s.Spread, "%.4f%%"),
+			percent(s.Deviation, "%+.4f%%"),
+			"",
+		}, nil
+	})
+}
@@ -0,0 +1,106 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func TestSpreads(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	btcusdt := provider.Pair{Base: "BTC", Quote: "USDT"}
+	usdtusd := provider.Pair{Base: "USDT", Quote: "USD"}
+	origin := func(name string, pair provider.Pair, price, bid, ask float64) *provider.Price {
+		return &provider.Price{
+			Type:       "origin",
+			Parameters: map[string]string{"origin": name},
+			Pair:       pair,
+			Price:      price,
+			Bid:        bid,
+			Ask:        ask,
+		}
+	}
+	prices := map[provider.Pair]*provider.Price{
+		btcusd: {
+			Type:  "median",
+			Pair:  btcusd,
+			Price: 20000,
+			Prices: []*provider.Price{
+				origin("kraken", btcusd, 20000, 19990, 20010),
+				origin("bitstamp", btcusd, 20200, 20000, 20400),
+				origin("gemini", btcusd, 19900, 0, 0),
+				{Type: "origin", Parameters: map[string]string{"origin": "binance"}, Pair: btcusd, Error: "timeout"},
+				{
+					Type: "indirect",
+					Pair: btcusd,
+					Prices: []*provider.Price{
+						origin("binance", btcusdt, 20000, 19999, 20001),
+						origin("kraken", usdtusd, 1, 0.9999, 1.0001),
+					},
+				},
+			},
+		},
+	}
+
+	res := spreads(prices)
+	var got []string
+	for _, s := range res {
+		got = append(got, s.Origin+" "+s.OriginPair)
+	}
+	assert.Equal(t, []string{
+		"bitstamp BTC/USD",
+		"kraken BTC/USD",
+		"kraken USDT/USD",
+		"binance BTC/USDT",
+		"binance BTC/USD",
+		"gemini BTC/USD",
+	}, got)
+
+	require.NotNil(t, res[0].Spread)
+	assert.InDelta(t, 1.9802, *res[0].Spread, 0.0001)
+	require.NotNil(t, res[0].Deviation)
+	assert.InDelta(t, 1, *res[0].Deviation, 0.0001)
+
+	// Origin prices of other pairs have no deviation.
+	assert.NotNil(t, res[2].Spread)
+	assert.Nil(t, res[2].Deviation)
+
+	// Origins without a bid and an ask, or with an error, have no spread.
+	assert.Equal(t, "timeout", res[4].Error)
+	assert.Nil(t, res[4].Spread)
+	assert.Nil(t, res[5].Spread)
+	assert.NotNil(t, res[5].Deviation)
+}
+
+func TestWriteSpreads(t *testing.T) {
+	spread, dev := 0.1, -0.5
+	b := &bytes.Buffer{}
+	require.NoError(t, writeSpreads(b, marshal.Plain, []originSpread{
+		{Pair: "BTC/USD", Origin: "kraken", OriginPair: "BTC/USD", Price: 19900, Bid: 19890, Ask: 19910, Spread: &spread, Deviation: &dev},
+		{Pair: "BTC/USD", Origin: "binance", OriginPair: "BTC/USD", Error: "timeout"},
+	}))
+	assert.Equal(t, `PAIR     ORIGIN   ORIGIN PAIR   PRICE     BID     ASK   SPREAD  DEVIATION  ERROR
+BTC/USD  kraken   BTC/USD      19,900  19,890  19,910  0.1000%   -0.5000%
+BTC/USD  binance  BTC/USD                                                  timeout
+`, b.String())
+}
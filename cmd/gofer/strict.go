@@ -0,0 +1,113 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+
+	"gofer-cli/pkg/agent"
+)
+
+// Exit codes of the prices command in the strict mode. If prices fail for
+// several reasons, the lowest exit code is used.
+const (
+	exitMissingPair      = 2
+	exitPriceError       = 3
+	exitNotEnoughSources = 4
+)
+
+// splitMissingPairs returns the pairs which have price models, and the
+// pairs which have not. The provider fails to return any prices if one of
+// the pairs is missing, so missing pairs must be found first.
+func splitMissingPairs(p provider.Provider, pairs []provider.Pair) (found, missing []provider.Pair, err error) {
+	if len(pairs) == 0 {
+		return nil, nil, nil
+	}
+	available, err := p.Pairs()
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[provider.Pair]bool, len(available))
+	for _, pair := range available {
+		known[pair] = true
+	}
+	for _, pair := range pairs {
+		if known[pair] {
+			found = append(found, pair)
+		} else {
+			missing = append(missing, pair)
+		}
+	}
+	return found, missing, nil
+}
+
+// strictCheck returns the exit code of the prices command in the strict
+// mode, and the reasons why pairs failed, sorted by pair. The exit code is 0
+// if every requested pair has a price without errors, calculated from at
+// least minSources origin prices.
+func strictCheck(missing []provider.Pair, prices map[provider.Pair]*provider.Price, minSources int) (int, []error) {
+	code := 0
+	var errs []error
+	fail := func(c int, err error) {
+		if code == 0 || c < code {
+			code = c
+		}
+		errs = append(errs, err)
+	}
+	for _, pair := range missing {
+		fail(exitMissingPair, pairError(errCodePairNotFound, nil, pair, fmt.Errorf("%s: price model not found", pair)))
+	}
+	pairs := make([]provider.Pair, 0, len(prices))
+	for pair := range prices {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].String() < pairs[j].String() })
+	for _, pair := range pairs {
+		p := prices[pair]
+		switch n := agent.PriceSources(p); {
+		case p.Error != "":
+			fail(exitPriceError, pairError(errCodePrice, p, pair, fmt.Errorf("%s: %s", pair, p.Error)))
+		case n < minSources:
+			err := fmt.Errorf("%s: %d sources, at least %d required", pair, n, minSources)
+			fail(exitNotEnoughSources, pairError(errCodeNotEnoughSources, p, pair, err))
+		}
+	}
+	return code, errs
+}
+
+// checkMinSources sets the error of the prices which have no error but are
+// calculated from fewer than minSources origin prices. The error tells how
+// many origin prices were used. Prices are not checked if minSources is not
+// positive.
+func checkMinSources(prices map[provider.Pair]*provider.Price, minSources int) {
+	if minSources <= 0 {
+		return
+	}
+	for _, p := range prices {
+		if n := agent.PriceSources(p); p.Error == "" && n < minSources {
+			p.Error = minSourcesError(n, minSources)
+		}
+	}
+}
+
+// minSourcesError returns the error of a price calculated from n origin
+// prices, fewer than minSources.
+func minSourcesError(n, minSources int) string {
+	return fmt.Sprintf("%d sources, at least %d required", n, minSources)
+}
@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestSplitMissingPairs(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{btcusd, ethusd}, nil)
+
+	found, missing, err := splitMissingPairs(p, []provider.Pair{mkrusd, ethusd, btcusd})
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{ethusd, btcusd}, found)
+	assert.Equal(t, []provider.Pair{mkrusd}, missing)
+
+	// If no pairs are requested, prices for all pairs are returned, so no
+	// pairs are missing.
+	found, missing, err = splitMissingPairs(p, nil)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	assert.Empty(t, missing)
+}
+
+func TestStrictCheck(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+	reliable := &provider.Price{Pair: btcusd, Prices: []*provider.Price{{}, {}, {}}}
+	failed := &provider.Price{Pair: ethusd, Error: "not enough sources"}
+	few := &provider.Price{Pair: ethusd, Prices: []*provider.Price{{}, {Error: "timeout"}}}
+
+	tests := []struct {
+		name    string
+		missing []provider.Pair
+		prices  map[provider.Pair]*provider.Price
+		code    int
+		errs    []string
+	}{
+		{
+			name:   "ok",
+			prices: map[provider.Pair]*provider.Price{btcusd: reliable},
+		},
+		{
+			name:   "sources",
+			prices: map[provider.Pair]*provider.Price{btcusd: reliable, ethusd: few},
+			code:   exitNotEnoughSources,
+			errs:   []string{"ETH/USD: 1 sources, at least 2 required"},
+		},
+		{
+			name:   "error",
+			prices: map[provider.Pair]*provider.Price{ethusd: failed, btcusd: reliable},
+			code:   exitPriceError,
+			errs:   []string{"ETH/USD: not enough sources"},
+		},
+		{
+			name:    "missing",
+			missing: []provider.Pair{mkrusd},
+			prices:  map[provider.Pair]*provider.Price{ethusd: failed},
+			code:    exitMissingPair,
+			errs:    []string{"MKR/USD: price model not found", "ETH/USD: not enough sources"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, errs := strictCheck(tt.missing, tt.prices, 2)
+			assert.Equal(t, tt.code, code)
+			var msgs []string
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			assert.Equal(t, tt.errs, msgs)
+		})
+	}
+}
+
+func TestCheckMinSources(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+	prices := map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Prices: []*provider.Price{{}, {}, {Error: "timeout"}}},
+		ethusd: {Pair: ethusd, Prices: []*provider.Price{{}, {Error: "timeout"}}},
+		mkrusd: {Pair: mkrusd, Error: "failed"},
+	}
+
+	checkMinSources(prices, 0)
+	assert.Empty(t, prices[btcusd].Error)
+	assert.Empty(t, prices[ethusd].Error)
+
+	checkMinSources(prices, 2)
+	assert.Empty(t, prices[btcusd].Error)
+	assert.Equal(t, "1 sources, at least 2 required", prices[ethusd].Error)
+	// Errors of prices are not replaced.
+	assert.Equal(t, "failed", prices[mkrusd].Error)
+}
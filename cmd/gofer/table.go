@@ -0,0 +1,279 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+
+	"gofer-cli/pkg/agent"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+type tableColumn struct {
+	name  string
+	right bool // Numbers are aligned to the right.
+}
+
+// tablePriceColumn is a column of a table of prices.
+type tablePriceColumn struct {
+	tableColumn
+	cell func(p *provider.Price) string
+}
+
+// tablePriceColumns are the columns which may be written for prices, by
+// the names of the fields. The error field is written as the status.
+var tablePriceColumns = map[string]tablePriceColumn{
+	"type":    {tableColumn{name: "TYPE"}, func(p *provider.Price) string { return p.Type }},
+	"pair":    {tableColumn{name: "PAIR"}, func(p *provider.Price) string { return p.Pair.String() }},
+	"base":    {tableColumn{name: "BASE"}, func(p *provider.Price) string { return p.Pair.Base }},
+	"quote":   {tableColumn{name: "QUOTE"}, func(p *provider.Price) string { return p.Pair.Quote }},
+	"price":   {tableColumn{name: "PRICE", right: true}, func(p *provider.Price) string { return thousands(p.Price) }},
+	"bid":     {tableColumn{name: "BID", right: true}, func(p *provider.Price) string { return thousands(p.Bid) }},
+	"ask":     {tableColumn{name: "ASK", right: true}, func(p *provider.Price) string { return thousands(p.Ask) }},
+	"vol24h":  {tableColumn{name: "VOLUME", right: true}, func(p *provider.Price) string { return thousands(p.Volume24h) }},
+	"ts":      {tableColumn{name: "TIMESTAMP"}, func(p *provider.Price) string { return p.Time.In(time.UTC).Format(time.RFC3339) }},
+	"sources": {tableColumn{name: "SOURCES", right: true}, func(p *provider.Price) string { return strconv.Itoa(agent.PriceSources(p)) }},
+	"error": {tableColumn{name: "STATUS"}, func(p *provider.Price) string {
+		status, _ := tablePriceStatus(p)
+		return status
+	}},
+}
+
+// defaultTableFields are the fields written by default.
+var defaultTableFields = []string{"pair", "price", "bid", "ask", "vol24h", "ts", "sources", "error"}
+
+var tableModelColumns = []tableColumn{{name: "PAIR"}, {name: "TYPE"}}
+
+type tableRow struct {
+	cells []string
+	color string
+}
+
+// tableItem is a row of a table, or a plain text line if it has no
+// columns.
+type tableItem struct {
+	writer  io.Writer
+	columns []tableColumn
+	row     tableRow
+	text    string
+}
+
+// tableMarshaller implements the marshal.Marshaller interface. It writes
+// prices, or models, as a table with aligned columns and numbers with
+// thousands separators. Rows of prices with errors are red, rows of stale
+// prices are yellow and the other rows are green, if colors are enabled for
+// the writer. Errors are written as plain text after the tables.
+type tableMarshaller struct {
+	colors  func(w io.Writer) bool
+	columns []tablePriceColumn
+	items   []tableItem
+}
+
+func newTableMarshaller(fields []string) (*tableMarshaller, error) {
+	if len(fields) == 0 {
+		fields = defaultTableFields
+	}
+	columns := make([]tablePriceColumn, len(fields))
+	for i, f := range fields {
+		c, ok := tablePriceColumns[f]
+		if !ok {
+			return nil, fmt.Errorf("the %s field is not supported by the table format", f)
+		}
+		columns[i] = c
+	}
+	return &tableMarshaller{colors: isTerminal, columns: columns}, nil
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *tableMarshaller) Write(writer io.Writer, item interface{}) error {
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		m.items = append(m.items, m.priceItem(writer, typedItem))
+	case *provider.Model:
+		m.items = append(m.items, tableItem{writer: writer, columns: tableModelColumns, row: tableRow{
+			cells: []string{typedItem.Pair.String(), typedItem.Type},
+		}})
+	case error:
+		m.items = append(m.items, tableItem{writer: writer, text: fmt.Sprintf("Error: %s", typedItem.Error())})
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface. Rows written to the
+// same writer are written as a single table.
+func (m *tableMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	var writers []io.Writer
+	tables := make(map[io.Writer][]tableItem)
+	for _, i := range items {
+		if _, ok := tables[i.writer]; !ok {
+			writers = append(writers, i.writer)
+		}
+		tables[i.writer] = append(tables[i.writer], i)
+	}
+	for _, w := range writers {
+		if err := m.writeTable(w, tables[w]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *tableMarshaller) writeTable(w io.Writer, items []tableItem) error {
+	colors := m.colors(w)
+	var columns []tableColumn
+	var rows []tableRow
+	var texts []string
+	for _, i := range items {
+		if i.columns == nil {
+			texts = append(texts, i.text)
+			continue
+		}
+		columns = i.columns
+		rows = append(rows, i.row)
+	}
+	if columns != nil {
+		header := tableRow{cells: make([]string, len(columns))}
+		for c, col := range columns {
+			header.cells[c] = col.name
+		}
+		widths := make([]int, len(columns))
+		for _, r := range append([]tableRow{header}, rows...) {
+			for c, s := range r.cells {
+				if n := utf8.RuneCountInString(s); n > widths[c] {
+					widths[c] = n
+				}
+			}
+		}
+		if _, err := io.WriteString(w, tableLine(columns, header.cells, widths)+"\n"); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			line := tableLine(columns, r.cells, widths)
+			if colors && r.color != "" {
+				line = r.color + line + colorReset
+			}
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, t := range texts {
+		if colors {
+			t = colorRed + t + colorReset
+		}
+		if _, err := io.WriteString(w, t+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableLine pads the cells to the widths of the columns. The last column is
+// not padded, unless it is aligned to the right.
+func tableLine(columns []tableColumn, cells []string, widths []int) string {
+	var b strings.Builder
+	for c, s := range cells {
+		if c > 0 {
+			b.WriteString("  ")
+		}
+		pad := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(s))
+		switch {
+		case columns[c].right:
+			b.WriteString(pad + s)
+		case c == len(cells)-1:
+			b.WriteString(s)
+		default:
+			b.WriteString(s + pad)
+		}
+	}
+	return b.String()
+}
+
+func (m *tableMarshaller) priceItem(writer io.Writer, p *provider.Price) tableItem {
+	columns := make([]tableColumn, len(m.columns))
+	cells := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		columns[i] = c.tableColumn
+		cells[i] = c.cell(p)
+	}
+	_, color := tablePriceStatus(p)
+	return tableItem{writer: writer, columns: columns, row: tableRow{cells: cells, color: color}}
+}
+
+// tablePriceStatus returns the status of the price and the color of its
+// row.
+func tablePriceStatus(p *provider.Price) (string, string) {
+	switch {
+	case p.Error != "":
+		return strings.TrimSpace(p.Error), colorRed
+	case p.Parameters["stale"] == "true":
+		return "stale", colorYellow
+	}
+	return "ok", colorGreen
+}
+
+// thousands formats the number with commas separating thousands.
+func thousands(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+	return sign + b.String()
+}
+
+// isTerminal reports whether the writer is a terminal, and colors are not
+// disabled with the NO_COLOR environment variable.
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,88 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestTableMarshaller(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	daiusd := provider.Pair{Base: "DAI", Quote: "USD"}
+
+	out := &bytes.Buffer{}
+	m, err := newTableMarshaller(nil)
+	require.NoError(t, err)
+	m.colors = func(io.Writer) bool { return true }
+	require.NoError(t, m.Write(out, &provider.Price{
+		Pair:   btcusd,
+		Price:  45242.13,
+		Bid:    45236.308,
+		Ask:    45239.98,
+		Time:   ts,
+		Prices: []*provider.Price{{Price: 45242.13}, {Price: 45227.05}},
+	}))
+	require.NoError(t, m.Write(out, &provider.Price{
+		Pair:       ethusd,
+		Price:      3501.5,
+		Volume24h:  1234567.25,
+		Time:       ts,
+		Parameters: map[string]string{"stale": "true"},
+	}))
+	require.NoError(t, m.Write(out, &provider.Price{Pair: daiusd, Time: ts, Error: "no sources"}))
+	require.NoError(t, m.Write(out, errors.New("failed")))
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, ""+
+		"PAIR         PRICE         BID        ASK        VOLUME  TIMESTAMP             SOURCES  STATUS\n"+
+		"\033[32mBTC/USD  45,242.13  45,236.308  45,239.98             0  2023-11-14T22:13:20Z        2  ok\033[0m\n"+
+		"\033[33mETH/USD    3,501.5           0          0  1,234,567.25  2023-11-14T22:13:20Z        1  stale\033[0m\n"+
+		"\033[31mDAI/USD          0           0          0             0  2023-11-14T22:13:20Z        0  no sources\033[0m\n"+
+		"\033[31mError: failed\033[0m\n",
+		out.String(),
+	)
+
+	// Colors are disabled for writers other than terminals.
+	out.Reset()
+	m, err = newTableMarshaller(nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Write(out, &provider.Model{Type: "median", Pair: btcusd}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "PAIR     TYPE\nBTC/USD  median\n", out.String())
+}
+
+func TestThousands(t *testing.T) {
+	for f, s := range map[float64]string{
+		0:            "0",
+		999:          "999",
+		1000:         "1,000",
+		-1234567.891: "-1,234,567.891",
+		0.000123:     "0.000123",
+	} {
+		assert.Equal(t, s, thousands(f))
+	}
+}
@@ -0,0 +1,345 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+
+	"gofer-cli/pkg/agent"
+	"gofer-cli/pkg/prices"
+)
+
+// topSorts are the columns by which the pairs of the dashboard may be
+// sorted, in the order in which they are cycled through.
+var topSorts = []string{"pair", "price", "change", "deviation", "age", "sources"}
+
+var topColumns = []tableColumn{
+	{name: "PAIR"},
+	{name: "PRICE", right: true},
+	{name: "CHANGE", right: true},
+	{name: "DEVIATION", right: true},
+	{name: "AGE", right: true},
+	{name: "SOURCES", right: true},
+	{name: "STATUS"},
+}
+
+// topOrigin is a price of an origin used to calculate the price of a pair.
+type topOrigin struct {
+	price *provider.Price
+	// deviation is the difference between the origin price and the price
+	// of the pair, in percents, or NaN if the origin price is of another
+	// pair or has an error.
+	deviation float64
+}
+
+// topRow is a pair shown by the dashboard.
+type topRow struct {
+	price *provider.Price
+	// change is the difference between the price and the price fetched
+	// before, in percents, or NaN if there is no previous price.
+	change float64
+	// deviation is the largest absolute deviation of the origin prices, or
+	// NaN if there are none.
+	deviation float64
+	origins   []topOrigin
+}
+
+// topDashboard is the state of the dashboard of the top command. Prices
+// are set by update, and keys pressed by the user are handled by key.
+type topDashboard struct {
+	interval time.Duration
+	sortBy   string
+	reverse  bool
+	origins  bool // Show the origin prices of every pair.
+	filter   string
+	editing  bool // The filter is being typed.
+	input    string
+
+	rows     []topRow
+	previous map[provider.Pair]float64
+	err      error
+	updated  time.Time
+}
+
+func newTopDashboard(interval time.Duration, sortBy string) (*topDashboard, error) {
+	if !topSortable(sortBy) {
+		return nil, fmt.Errorf("unsupported sort column: %s", sortBy)
+	}
+	return &topDashboard{interval: interval, sortBy: sortBy, previous: make(map[provider.Pair]float64)}, nil
+}
+
+func topSortable(column string) bool {
+	for _, s := range topSorts {
+		if s == column {
+			return true
+		}
+	}
+	return false
+}
+
+// update replaces the prices shown by the dashboard. If err is not nil,
+// the previous prices are kept and the error is shown.
+func (d *topDashboard) update(ps map[provider.Pair]*provider.Price, err error, now time.Time) {
+	d.err = err
+	d.updated = now
+	if err != nil {
+		return
+	}
+	d.rows = d.rows[:0]
+	for pair, p := range ps {
+		row := topRow{price: p, change: math.NaN(), deviation: math.NaN()}
+		if prev, ok := d.previous[pair]; ok && prev != 0 && p.Error == "" {
+			row.change = (p.Price - prev) / prev * 100
+		}
+		if p.Error == "" {
+			d.previous[pair] = p.Price
+		}
+		for _, o := range originPrices(p) {
+			dev := math.NaN()
+			if o.Pair == p.Pair && o.Error == "" && p.Error == "" && p.Price != 0 {
+				dev = (o.Price - p.Price) / p.Price * 100
+				if math.IsNaN(row.deviation) || math.Abs(dev) > row.deviation {
+					row.deviation = math.Abs(dev)
+				}
+			}
+			row.origins = append(row.origins, topOrigin{price: o, deviation: dev})
+		}
+		d.rows = append(d.rows, row)
+	}
+}
+
+// originPrices returns the origin prices from which the price was
+// calculated.
+func originPrices(p *provider.Price) []*provider.Price {
+	if p.Type == "origin" {
+		return []*provider.Price{p}
+	}
+	var res []*provider.Price
+	for _, c := range p.Prices {
+		res = append(res, originPrices(c)...)
+	}
+	return res
+}
+
+// key handles a key pressed by the user, and reports whether the dashboard
+// should be closed.
+func (d *topDashboard) key(k byte) bool {
+	if d.editing {
+		switch k {
+		case '\r', '\n':
+			d.filter, d.editing = d.input, false
+		case 0x1b: // Escape.
+			d.editing = false
+		case 0x7f, '\b':
+			if d.input != "" {
+				d.input = d.input[:len(d.input)-1]
+			}
+		default:
+			if k >= 0x20 && k < 0x7f {
+				d.input += string(k)
+			}
+		}
+		return false
+	}
+	switch k {
+	case 'q':
+		return true
+	case 's':
+		for i, s := range topSorts {
+			if s == d.sortBy {
+				d.sortBy = topSorts[(i+1)%len(topSorts)]
+				break
+			}
+		}
+	case 'r':
+		d.reverse = !d.reverse
+	case 'o':
+		d.origins = !d.origins
+	case '/':
+		d.editing, d.input = true, d.filter
+	}
+	return false
+}
+
+// visible returns the rows which match the filter, sorted.
+func (d *topDashboard) visible(now time.Time) []topRow {
+	var rows []topRow
+	for _, r := range d.rows {
+		if topMatch(d.filter, r.price.Pair) {
+			rows = append(rows, r)
+		}
+	}
+	less := func(a, b topRow) bool { return a.price.Pair.String() < b.price.Pair.String() }
+	key := func(r topRow) float64 {
+		switch d.sortBy {
+		case "price":
+			return r.price.Price
+		case "change":
+			return math.Abs(r.change)
+		case "deviation":
+			return r.deviation
+		case "age":
+			return float64(now.Sub(r.price.Time))
+		case "sources":
+			return float64(agent.PriceSources(r.price))
+		}
+		return 0
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if d.reverse {
+			i, j = j, i
+		}
+		ki, kj := key(rows[i]), key(rows[j])
+		switch {
+		case d.sortBy == "pair" || ki == kj || math.IsNaN(ki) && math.IsNaN(kj):
+			return less(rows[i], rows[j])
+		case math.IsNaN(ki):
+			return false
+		case math.IsNaN(kj):
+			return true
+		}
+		// Numbers are sorted from the largest, so the most interesting
+		// pairs are at the top.
+		return ki > kj
+	})
+	return rows
+}
+
+// topMatch reports whether the pair matches the filter: a pair pattern,
+// such as "*/USD", or a part of the pair.
+func topMatch(filter string, pair provider.Pair) bool {
+	filter = strings.ToUpper(filter)
+	if prices.HasWildcards(filter) {
+		ok, _ := path.Match(filter, pair.String())
+		return ok
+	}
+	return strings.Contains(pair.String(), filter)
+}
+
+// render writes the dashboard to the writer, which should be a terminal.
+// The screen is cleared first.
+func (d *topDashboard) render(w io.Writer, now time.Time, colors bool) error {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "gofer top - %s, every %s, sorted by %s", now.In(time.UTC).Format(time.RFC3339), d.interval, d.sortBy)
+	if d.reverse {
+		b.WriteString(" reversed")
+	}
+	if d.filter != "" {
+		fmt.Fprintf(&b, ", filter: %s", d.filter)
+	}
+	b.WriteString("\n\n")
+
+	header := make([]string, len(topColumns))
+	for i, c := range topColumns {
+		header[i] = c.name
+	}
+	rows := []tableRow{{cells: header}}
+	for _, r := range d.visible(now) {
+		status, color := tablePriceStatus(r.price)
+		rows = append(rows, tableRow{cells: []string{
+			r.price.Pair.String(),
+			thousands(r.price.Price),
+			topPercent(r.change, true),
+			topPercent(r.deviation, false),
+			topAge(now, r.price.Time),
+			fmt.Sprintf("%d/%d", agent.PriceSources(r.price), len(r.origins)),
+			status,
+		}, color: color})
+		if !d.origins {
+			continue
+		}
+		for _, o := range r.origins {
+			status, color := tablePriceStatus(o.price)
+			name := "  " + o.price.Parameters["origin"]
+			if o.price.Pair != r.price.Pair {
+				name += " " + o.price.Pair.String()
+			}
+			rows = append(rows, tableRow{cells: []string{
+				name,
+				thousands(o.price.Price),
+				"",
+				topPercent(o.deviation, true),
+				topAge(now, o.price.Time),
+				"",
+				status,
+			}, color: color})
+		}
+	}
+	widths := make([]int, len(topColumns))
+	for _, r := range rows {
+		for c, s := range r.cells {
+			if n := len([]rune(s)); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+	for _, r := range rows {
+		line := strings.TrimRight(tableLine(topColumns, r.cells, widths), " ")
+		if colors && r.color != "" {
+			line = r.color + line + colorReset
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	if d.err != nil {
+		line := fmt.Sprintf("Error: %s", d.err)
+		if colors {
+			line = colorRed + line + colorReset
+		}
+		b.WriteString(line + "\n")
+	}
+	if d.editing {
+		fmt.Fprintf(&b, "filter: %s", d.input)
+	} else {
+		b.WriteString("q quit, s sort, r reverse, o origins, / filter")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// topPercent formats a percentage, or returns "-" for NaN.
+func topPercent(f float64, sign bool) string {
+	if math.IsNaN(f) {
+		return "-"
+	}
+	if sign {
+		return fmt.Sprintf("%+.4f%%", f)
+	}
+	return fmt.Sprintf("%.4f%%", f)
+}
+
+// topAge formats the time elapsed since the time, rounded to seconds.
+func topAge(now, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	age := now.Sub(t)
+	if age < 0 {
+		age = 0
+	}
+	return strconv.FormatInt(int64(age/time.Second), 10) + "s"
+}
@@ -0,0 +1,148 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestTopDashboard(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	btcusdt := provider.Pair{Base: "BTC", Quote: "USDT"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	origin := func(name string, pair provider.Pair, price float64, err string) *provider.Price {
+		return &provider.Price{
+			Type:       "origin",
+			Pair:       pair,
+			Price:      price,
+			Time:       now.Add(-5 * time.Second),
+			Parameters: map[string]string{"origin": name},
+			Error:      err,
+		}
+	}
+	prices := func(btc, eth float64) map[provider.Pair]*provider.Price {
+		return map[provider.Pair]*provider.Price{
+			btcusd: {
+				Type:  "aggregator",
+				Pair:  btcusd,
+				Price: btc,
+				Time:  now.Add(-5 * time.Second),
+				Prices: []*provider.Price{
+					origin("bitstamp", btcusd, btc*1.01, ""),
+					origin("kraken", btcusd, btc, ""),
+					origin("gemini", btcusd, 0, "timeout"),
+					{Type: "aggregator", Pair: btcusdt, Prices: []*provider.Price{origin("binance", btcusdt, btc, "")}},
+				},
+			},
+			ethusd: {
+				Type:   "aggregator",
+				Pair:   ethusd,
+				Price:  eth,
+				Time:   now.Add(-time.Minute),
+				Prices: []*provider.Price{origin("kraken", ethusd, eth, "")},
+			},
+		}
+	}
+
+	d, err := newTopDashboard(5*time.Second, "pair")
+	require.NoError(t, err)
+	d.update(prices(40000, 2000), nil, now)
+	d.update(prices(40000, 2200), nil, now)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, d.render(out, now, false))
+	assert.Equal(t, ""+
+		"\033[H\033[2Jgofer top - 2023-11-14T22:13:20Z, every 5s, sorted by pair\n"+
+		"\n"+
+		"PAIR      PRICE     CHANGE  DEVIATION  AGE  SOURCES  STATUS\n"+
+		"BTC/USD  40,000   +0.0000%    1.0000%   5s      3/4  ok\n"+
+		"ETH/USD   2,200  +10.0000%    0.0000%  60s      1/1  ok\n"+
+		"\n"+
+		"q quit, s sort, r reverse, o origins, / filter",
+		out.String(),
+	)
+
+	// Sort by the change, show origins and filter pairs.
+	for _, k := range []byte("sso/btc\r") {
+		assert.False(t, d.key(k))
+	}
+	out.Reset()
+	require.NoError(t, d.render(out, now, false))
+	assert.Equal(t, ""+
+		"\033[H\033[2Jgofer top - 2023-11-14T22:13:20Z, every 5s, sorted by change, filter: btc\n"+
+		"\n"+
+		"PAIR                 PRICE    CHANGE  DEVIATION  AGE  SOURCES  STATUS\n"+
+		"BTC/USD             40,000  +0.0000%    1.0000%   5s      3/4  ok\n"+
+		"  bitstamp          40,400             +1.0000%   5s           ok\n"+
+		"  kraken            40,000             +0.0000%   5s           ok\n"+
+		"  gemini                 0                    -   5s           timeout\n"+
+		"  binance BTC/USDT  40,000                    -   5s           ok\n"+
+		"\n"+
+		"q quit, s sort, r reverse, o origins, / filter",
+		out.String(),
+	)
+
+	// Errors keep the previous prices.
+	d.update(nil, errors.New("failed"), now)
+	d.key('/')
+	d.key(0x7f)
+	d.key('*')
+	out.Reset()
+	require.NoError(t, d.render(out, now, false))
+	assert.Contains(t, out.String(), "BTC/USD")
+	assert.True(t, strings.HasSuffix(out.String(), "\nError: failed\nfilter: bt*"))
+
+	// Keys are typed into the filter until it is set or canceled.
+	assert.False(t, d.key('q'))
+	assert.False(t, d.key(0x1b))
+	assert.Equal(t, "btc", d.filter)
+	assert.True(t, d.key('q'))
+}
+
+func TestTopDashboard_Sort(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	d, err := newTopDashboard(time.Second, "price")
+	require.NoError(t, err)
+	d.update(map[provider.Pair]*provider.Price{
+		{Base: "A", Quote: "USD"}: {Pair: provider.Pair{Base: "A", Quote: "USD"}, Price: 1},
+		{Base: "B", Quote: "USD"}: {Pair: provider.Pair{Base: "B", Quote: "USD"}, Price: 3},
+		{Base: "C", Quote: "USD"}: {Pair: provider.Pair{Base: "C", Quote: "USD"}, Price: 2},
+	}, nil, now)
+	pairs := func() (res []string) {
+		for _, r := range d.visible(now) {
+			res = append(res, r.price.Pair.Base)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"B", "C", "A"}, pairs())
+	d.key('r')
+	assert.Equal(t, []string{"A", "C", "B"}, pairs())
+	d.filter = "[AB]/*"
+	assert.Equal(t, []string{"A", "B"}, pairs())
+
+	_, err = newTopDashboard(time.Second, "foo")
+	assert.Error(t, err)
+}
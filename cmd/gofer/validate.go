@@ -0,0 +1,297 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	"github.com/chronicleprotocol/oracle-suite/pkg/config/priceprovider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+)
+
+// configSchemaVersion is the version of the configuration format. It is
+// increased when the configuration changes in an incompatible way.
+const configSchemaVersion = 1
+
+// configSchema describes the top-level blocks of the configuration. Other
+// blocks and attributes are ignored when the configuration is loaded.
+var configSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "gofer"},
+		{Type: "ethereum"},
+		{Type: "logger"},
+		{Type: "agent"},
+		{Type: "oracles"},
+		{Type: "reference"},
+	},
+}
+
+var addressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// validate checks the loaded configuration for mistakes which are not
+// reported when it is loaded, or only when the services are started.
+func (c *cliConfig) validate() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if c.Remain != nil {
+		_, d := c.Remain.Content(configSchema)
+		diags = append(diags, d...)
+	}
+	diags = append(diags, c.validateListenAddrs()...)
+	diags = append(diags, c.validateOrigins()...)
+	diags = append(diags, c.validatePriceModels()...)
+	diags = append(diags, c.validateOracles()...)
+	diags = append(diags, c.validateReference()...)
+	if _, err := c.ipFilter(); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid IP filter",
+			Detail:   err.Error(),
+		})
+	}
+	return diags
+}
+
+func (c *cliConfig) validateListenAddrs() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, name := range []string{"rpc_listen_addr", "rpc_agent_addr"} {
+		attr, ok := c.Gofer.Content.Attributes[name]
+		if !ok {
+			continue
+		}
+		addr := c.Gofer.RPCListenAddr
+		if name == "rpc_agent_addr" {
+			addr = c.Gofer.RPCAgentAddr
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid address",
+				Detail:   fmt.Sprintf("The %s attribute must be a host and a port: %v.", name, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// validateOracles checks that the oracles refer to an existing Ethereum
+// client, and that their pairs and contract addresses are valid.
+func (c *cliConfig) validateOracles() hcl.Diagnostics {
+	if c.Oracles == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	clients := make(map[string]bool)
+	if c.Ethereum != nil {
+		for _, client := range c.Ethereum.Clients {
+			clients[client.Name] = true
+		}
+	}
+	if attr, ok := c.Oracles.Content.Attributes["ethereum_client"]; ok && !clients[c.Oracles.EthereumClient] {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown Ethereum client",
+			Detail:   fmt.Sprintf("The oracles refer to the Ethereum client %q, which is not defined.", c.Oracles.EthereumClient),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	attr, ok := c.Oracles.Content.Attributes["contracts"]
+	if !ok {
+		return diags
+	}
+	for pair, addr := range c.Oracles.Contracts {
+		if _, err := provider.NewPair(pair); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid pair",
+				Detail:   fmt.Sprintf("The oracle pair %q is not valid: %v.", pair, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+		if !addressRegexp.MatchString(addr) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid contract address",
+				Detail:   fmt.Sprintf("The contract address %q of the oracle %s is not a valid address.", addr, pair),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// validateReference checks that the URL of the reference API is valid and
+// that its query can be parsed.
+func (c *cliConfig) validateReference() hcl.Diagnostics {
+	if c.Reference == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	if attr, ok := c.Reference.Content.Attributes["url"]; ok {
+		if err := validateReferenceURL(c.Reference.URL); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid URL",
+				Detail:   fmt.Sprintf("The URL of the reference API is not valid: %v.", err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+	if attr, ok := c.Reference.Content.Attributes["query"]; ok {
+		if _, err := parseQuery(c.Reference.Query); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid query",
+				Detail:   fmt.Sprintf("The query of the reference API is not valid: %v.", err),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// validateOrigins checks that origin names are unique, their types are
+// supported, and that they refer to existing Ethereum clients and valid
+// contract addresses.
+func (c *cliConfig) validateOrigins() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	clients := make(map[string]bool)
+	if c.Ethereum != nil {
+		for _, client := range c.Ethereum.Clients {
+			clients[client.Name] = true
+		}
+	}
+	seen := make(map[string]hcl.Range)
+	for _, o := range c.Gofer.Origins {
+		if r, ok := seen[o.Origin]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate origin",
+				Detail:   fmt.Sprintf("The origin %q is already defined at %s.", o.Origin, r),
+				Subject:  o.Range.Ptr(),
+			})
+		}
+		seen[o.Origin] = o.Range
+		_, err := priceprovider.NewHandler(o.Type, nil, ethereum.ClientRegistry{}, o.Params)
+		if errors.Is(err, origins.ErrUnknownOrigin) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown origin type",
+				Detail:   fmt.Sprintf("The origin %q has an unsupported type %q.", o.Origin, o.Type),
+				Subject:  o.Range.Ptr(),
+			})
+		}
+		if name, ok := o.Params["ethereum_client"].(string); ok && !clients[name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown Ethereum client",
+				Detail:   fmt.Sprintf("The origin %q refers to the Ethereum client %q, which is not defined.", o.Origin, name),
+				Subject:  o.Range.Ptr(),
+			})
+		}
+		contracts, _ := o.Params["contracts"].(map[string]any)
+		for key, v := range contracts {
+			if addr, _ := v.(string); !addressRegexp.MatchString(addr) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid contract address",
+					Detail:   fmt.Sprintf("The contract address %q of %s in the origin %q is not a valid address.", v, key, o.Origin),
+					Subject:  o.Range.Ptr(),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+// validatePriceModels checks that every pair has a single price model, and
+// that all sources refer to known origins or pairs.
+func (c *cliConfig) validatePriceModels() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	known := make(map[string]bool)
+	for name := range origins.DefaultOriginSet(nil).Handlers() {
+		known[name] = true
+	}
+	for _, o := range c.Gofer.Origins {
+		known[o.Origin] = true
+	}
+	models := make(map[string]hcl.Range)
+	for _, m := range c.Gofer.PriceModels {
+		if r, ok := models[m.Pair.String()]; ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate price model",
+				Detail:   fmt.Sprintf("The price model of %s is already defined at %s.", m.Pair, r),
+				Subject:  m.Range.Ptr(),
+			})
+			continue
+		}
+		models[m.Pair.String()] = m.Range
+	}
+	// Sources are walked breadth-first, because their type is not exported.
+	queue := append(c.Gofer.PriceModels[:0:0], c.Gofer.PriceModels...)
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		switch s.Type {
+		case "origin":
+			switch {
+			case s.Origin == nil:
+			case s.Origin.Origin == ".":
+				if _, ok := models[s.Pair.String()]; !ok {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Unknown pair",
+						Detail:   fmt.Sprintf("The source refers to the price model of %s, which is not defined.", s.Pair),
+						Subject:  s.Range.Ptr(),
+					})
+				}
+			case !known[s.Origin.Origin]:
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unknown origin",
+					Detail:   fmt.Sprintf("The source of %s refers to the origin %q, which is not defined.", s.Pair, s.Origin.Origin),
+					Subject:  s.Range.Ptr(),
+				})
+			}
+		case "median", "indirect":
+			if len(s.Sources) == 0 {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "No sources",
+					Detail:   fmt.Sprintf("The %s source of %s must have at least one source.", s.Type, s.Pair),
+					Subject:  s.Range.Ptr(),
+				})
+			}
+			queue = append(queue, s.Sources...)
+		default:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown source type",
+				Detail:   fmt.Sprintf("The source of %s has an unsupported type %q.", s.Pair, s.Type),
+				Subject:  s.Range.Ptr(),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,96 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock)
+	assert.Empty(t, cfg.validate())
+
+	cfg = loadTestConfig(t, `
+gofer {
+  rpc_listen_addr = "localhost"
+
+  origin "uniswap" {
+    type   = "uniswapV3"
+    params = {
+      contracts = {
+        "ETH/USD" = "0x123"
+      }
+    }
+  }
+  origin "uniswap" {
+    type = "uniswap"
+  }
+  origin "curve" {
+    type   = "curve"
+    params = {
+      ethereum_client = "mainnet"
+    }
+  }
+  origin "foo" {
+    type = "foo"
+  }
+
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "origin" { origin = "bitstamp" }
+    source "BTC/USD" "origin" { origin = "bitstmap" }
+    min_sources = 1
+  }
+  price_model "BTC/USD" "median" {
+    source "BTC/USD" "indirect" {
+      source "BTC/ETH" "origin" { origin = "." }
+    }
+    min_sources = 1
+  }
+}
+
+unknown {}
+`)
+	var summaries []string
+	for _, d := range cfg.validate() {
+		require.Equal(t, hcl.DiagError, d.Severity)
+		require.NotNil(t, d.Subject, d.Summary)
+		summaries = append(summaries, d.Summary)
+	}
+	assert.ElementsMatch(t, []string{
+		"Unsupported block type",
+		"Invalid address",
+		"Invalid contract address",
+		"Duplicate origin",
+		"Unknown Ethereum client",
+		"Unknown origin type",
+		"Duplicate price model",
+		"Unknown origin",
+		"Unknown pair",
+	}, summaries)
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	cfg := loadTestConfig(t, testGoferBlock+"\nunknown {}\n")
+	out := &bytes.Buffer{}
+	require.NoError(t, writeDiagnostics(out, cfg.validate()))
+	assert.Contains(t, out.String(), "config.hcl line 10")
+	assert.Contains(t, out.String(), "unknown {}")
+}
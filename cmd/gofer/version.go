@@ -0,0 +1,140 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/origins"
+)
+
+// Build metadata, which may be set when building, e.g.:
+//
+//	go build -ldflags "-X main.buildCommit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// If they are not set, they are read from the version control information
+// embedded by the Go toolchain.
+var (
+	buildCommit string
+	buildDate   string
+)
+
+// versionInfo describes the build of gofer and what it supports.
+type versionInfo struct {
+	Version       string   `json:"version"`
+	Commit        string   `json:"commit"`
+	BuildDate     string   `json:"build_date"`
+	GoVersion     string   `json:"go_version"`
+	Platform      string   `json:"platform"`
+	ConfigVersion int      `json:"config_schema_version"`
+	Formats       []string `json:"formats"`
+	Origins       []string `json:"origins"`
+}
+
+func newVersionInfo(version string) versionInfo {
+	v := versionInfo{
+		Version:       version,
+		Commit:        buildCommit,
+		BuildDate:     buildDate,
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		ConfigVersion: configSchemaVersion,
+		Formats:       strings.Split((&formatTypeValue{}).Type(), "|"),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		commit, date := vcsInfo(bi.Settings)
+		if v.Commit == "" {
+			v.Commit = commit
+		}
+		if v.BuildDate == "" {
+			v.BuildDate = date
+		}
+	}
+	for name := range origins.DefaultOriginSet(nil).Handlers() {
+		v.Origins = append(v.Origins, name)
+	}
+	sort.Strings(v.Origins)
+	return v
+}
+
+// vcsInfo returns the commit and its time from the build settings. The
+// commit is suffixed with "-dirty" if there were uncommitted changes.
+func vcsInfo(settings []debug.BuildSetting) (commit, date string) {
+	dirty := false
+	for _, s := range settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.time":
+			date = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if commit != "" && dirty {
+		commit += "-dirty"
+	}
+	return commit, date
+}
+
+// writeVersion writes the version information as a JSON object with the
+// json and ndjson formats, as a YAML document with the yaml format, and as
+// text with the other formats.
+func writeVersion(w io.Writer, format marshal.FormatType, v versionInfo) error {
+	if format == yamlFormat {
+		return writeYAML(w, v)
+	}
+	if format == marshal.JSON || format == marshal.NDJSON {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	_, err := fmt.Fprintf(w, ""+
+		"gofer %s\n"+
+		"commit:         %s\n"+
+		"build date:     %s\n"+
+		"go version:     %s\n"+
+		"platform:       %s\n"+
+		"config schema:  %d\n"+
+		"formats:        %s\n"+
+		"origins:        %s\n",
+		orUnknown(v.Version),
+		orUnknown(v.Commit),
+		orUnknown(v.BuildDate),
+		v.GoVersion,
+		v.Platform,
+		v.ConfigVersion,
+		strings.Join(v.Formats, ", "),
+		strings.Join(v.Origins, ", "),
+	)
+	return err
+}
@@ -0,0 +1,85 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+func TestVCSInfo(t *testing.T) {
+	commit, date := vcsInfo([]debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: "5b023da"},
+		{Key: "vcs.time", Value: "2023-05-10T12:00:00Z"},
+		{Key: "vcs.modified", Value: "true"},
+	})
+	assert.Equal(t, "5b023da-dirty", commit)
+	assert.Equal(t, "2023-05-10T12:00:00Z", date)
+
+	commit, date = vcsInfo(nil)
+	assert.Empty(t, commit)
+	assert.Empty(t, date)
+}
+
+func TestWriteVersion(t *testing.T) {
+	v := versionInfo{
+		Version:       "0.11.0",
+		GoVersion:     "go1.20.4",
+		Platform:      "linux/amd64",
+		ConfigVersion: 1,
+		Formats:       []string{"plain", "json"},
+		Origins:       []string{"binance", "kraken"},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeVersion(out, marshal.Plain, v))
+	assert.Equal(t, ""+
+		"gofer 0.11.0\n"+
+		"commit:         unknown\n"+
+		"build date:     unknown\n"+
+		"go version:     go1.20.4\n"+
+		"platform:       linux/amd64\n"+
+		"config schema:  1\n"+
+		"formats:        plain, json\n"+
+		"origins:        binance, kraken\n",
+		out.String(),
+	)
+
+	out.Reset()
+	require.NoError(t, writeVersion(out, marshal.NDJSON, v))
+	assert.JSONEq(t, `{
+		"version": "0.11.0",
+		"commit": "",
+		"build_date": "",
+		"go_version": "go1.20.4",
+		"platform": "linux/amd64",
+		"config_schema_version": 1,
+		"formats": ["plain", "json"],
+		"origins": ["binance", "kraken"]
+	}`, out.String())
+
+	// Built-in origins and formats are listed.
+	v = newVersionInfo("0.11.0")
+	assert.Contains(t, v.Origins, "kraken")
+	assert.Contains(t, v.Formats, "table")
+}
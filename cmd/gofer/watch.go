@@ -0,0 +1,255 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+// priceAlert is a change of the price of a pair, within the window of the
+// watch command, larger than the threshold.
+type priceAlert struct {
+	Pair          string  `json:"pair"`
+	Price         float64 `json:"price"`
+	PreviousPrice float64 `json:"previous_price"`
+	// Change is the difference between the price and the previous price,
+	// in percent of the previous price.
+	Change       float64   `json:"change"`
+	PreviousTime time.Time `json:"previous_time"`
+	Time         time.Time `json:"time"`
+}
+
+// watchPoint is a price of a pair fetched by the watch command.
+type watchPoint struct {
+	price float64
+	time  time.Time
+}
+
+// priceWatch keeps the prices of pairs fetched within the window, and
+// reports changes larger than the threshold.
+type priceWatch struct {
+	threshold float64 // In percent.
+	window    time.Duration
+
+	history map[provider.Pair][]watchPoint
+}
+
+// update adds the prices fetched at the given time, and returns alerts,
+// sorted by pair, for the pairs whose price differs from any price fetched
+// within the window by at least the threshold. The change from the most
+// distant price is reported. After an alert, the history of the pair is
+// cleared, so a single move is reported once. Prices with errors are
+// ignored.
+func (w *priceWatch) update(prices map[provider.Pair]*provider.Price, now time.Time) []priceAlert {
+	if w.history == nil {
+		w.history = make(map[provider.Pair][]watchPoint)
+	}
+	var alerts []priceAlert
+	for pair, p := range prices {
+		if p == nil || p.Error != "" {
+			continue
+		}
+		var points []watchPoint
+		for _, pt := range w.history[pair] {
+			if now.Sub(pt.time) <= w.window {
+				points = append(points, pt)
+			}
+		}
+		var alert *priceAlert
+		for _, pt := range points {
+			if pt.price == 0 {
+				continue
+			}
+			change := (p.Price - pt.price) / pt.price * 100
+			if math.Abs(change) < w.threshold || (alert != nil && math.Abs(change) <= math.Abs(alert.Change)) {
+				continue
+			}
+			alert = &priceAlert{
+				Pair:          pair.String(),
+				Price:         p.Price,
+				PreviousPrice: pt.price,
+				Change:        change,
+				PreviousTime:  pt.time,
+				Time:          now,
+			}
+		}
+		if alert != nil {
+			alerts = append(alerts, *alert)
+			points = nil
+		}
+		w.history[pair] = append(points, watchPoint{price: p.Price, time: now})
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Pair < alerts[j].Pair
+	})
+	return alerts
+}
+
+// priceWatcher fetches prices of pairs on every tick of the interval, and
+// writes, runs the command and sends the webhook for every alert.
+type priceWatcher struct {
+	watch    *priceWatch
+	provider provider.Provider
+	hook     provider.PriceHook
+	pairs    []provider.Pair
+	interval *timeutil.Ticker
+
+	// command, if not empty, is run by the shell for every alert.
+	command string
+
+	// webhook, if not empty, is the URL to which alerts are POSTed.
+	webhook string
+	client  *http.Client
+
+	// json, if true, writes alerts and errors as JSON objects, one per
+	// line.
+	json bool
+
+	out    io.Writer
+	errOut io.Writer
+
+	now func() time.Time
+}
+
+// run checks the prices once, and then on every tick of the interval,
+// which must be started, until the context is canceled. Errors are written
+// to errOut and do not stop the watcher.
+func (w *priceWatcher) run(ctx context.Context) error {
+	for {
+		w.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.interval.TickCh():
+		}
+	}
+}
+
+// check fetches the prices and handles the alerts. Alerts are handled one
+// after another, so a slow command delays the next check.
+func (w *priceWatcher) check(ctx context.Context) {
+	prices, err := w.provider.Prices(w.pairs...)
+	if err == nil {
+		err = w.hook.Check(prices)
+	}
+	if err != nil {
+		w.writeError(err)
+		return
+	}
+	for _, a := range w.watch.update(prices, w.now()) {
+		if err := w.writeAlert(a); err != nil {
+			w.writeError(err)
+		}
+		if w.command != "" {
+			if err := w.runCommand(ctx, a); err != nil {
+				w.writeError(fmt.Errorf("command failed for %s: %w", a.Pair, err))
+			}
+		}
+		if w.webhook != "" {
+			if err := w.sendWebhook(ctx, a); err != nil {
+				w.writeError(fmt.Errorf("unable to send the webhook for %s: %w", a.Pair, err))
+			}
+		}
+	}
+}
+
+func (w *priceWatcher) writeAlert(a priceAlert) error {
+	if w.json {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", b)
+		return err
+	}
+	_, err := fmt.Fprintf(
+		w.out,
+		"%s %f -> %f (%+.2f%% in %s)\n",
+		a.Pair,
+		a.PreviousPrice,
+		a.Price,
+		a.Change,
+		a.Time.Sub(a.PreviousTime).Round(time.Second),
+	)
+	return err
+}
+
+func (w *priceWatcher) writeError(err error) {
+	if w.json {
+		b, _ := json.Marshal(map[string]string{"error": err.Error()})
+		_, _ = fmt.Fprintf(w.errOut, "%s\n", b)
+		return
+	}
+	_, _ = fmt.Fprintf(w.errOut, "Error: %s\n", err)
+}
+
+// runCommand runs the command with the alert as JSON on the standard input
+// and in the GOFER_* environment variables.
+func (w *priceWatcher) runCommand(ctx context.Context, a priceAlert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", w.command)
+	cmd.Env = append(
+		os.Environ(),
+		"GOFER_PAIR="+a.Pair,
+		"GOFER_PRICE="+strconv.FormatFloat(a.Price, 'f', -1, 64),
+		"GOFER_PREVIOUS_PRICE="+strconv.FormatFloat(a.PreviousPrice, 'f', -1, 64),
+		"GOFER_CHANGE="+strconv.FormatFloat(a.Change, 'f', 2, 64),
+		"GOFER_TIME="+a.Time.UTC().Format(time.RFC3339),
+	)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = w.out
+	cmd.Stderr = w.errOut
+	return cmd.Run()
+}
+
+// sendWebhook POSTs the alert as JSON to the webhook URL.
+func (w *priceWatcher) sendWebhook(ctx context.Context, a priceAlert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhook, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
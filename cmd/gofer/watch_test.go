@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestPriceWatch(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	ts := time.Unix(1700000000, 0)
+	prices := func(btc, eth float64) map[provider.Pair]*provider.Price {
+		return map[provider.Pair]*provider.Price{
+			btcusd: {Pair: btcusd, Price: btc},
+			ethusd: {Pair: ethusd, Price: eth},
+		}
+	}
+	w := &priceWatch{threshold: 5, window: 5 * time.Minute}
+
+	assert.Empty(t, w.update(prices(100, 100), ts))
+	assert.Empty(t, w.update(prices(103, 97), ts.Add(time.Minute)))
+
+	// The change from the most distant price within the window is reported.
+	assert.Equal(t, []priceAlert{
+		{Pair: "BTC/USD", Price: 106, PreviousPrice: 100, Change: 6, PreviousTime: ts, Time: ts.Add(2 * time.Minute)},
+		{Pair: "ETH/USD", Price: 94, PreviousPrice: 100, Change: -6, PreviousTime: ts, Time: ts.Add(2 * time.Minute)},
+	}, w.update(prices(106, 94), ts.Add(2*time.Minute)))
+
+	// A move is reported once.
+	assert.Empty(t, w.update(prices(107, 94), ts.Add(3*time.Minute)))
+
+	// Prices outside the window and prices with errors are ignored.
+	w.update(map[provider.Pair]*provider.Price{btcusd: {Pair: btcusd, Price: 200, Error: "failed"}}, ts.Add(4*time.Minute))
+	assert.Empty(t, w.update(prices(112, 94), ts.Add(9*time.Minute)))
+	assert.Len(t, w.update(prices(118, 94), ts.Add(10*time.Minute)), 1)
+}
+
+func TestPriceWatcher(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ts := time.Unix(1700000000, 0)
+	price := func(v float64) map[provider.Pair]*provider.Price {
+		return map[provider.Pair]*provider.Price{btcusd: {Pair: btcusd, Price: v}}
+	}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(price(100), nil).Once()
+	p.On("Prices", btcusd).Return((map[provider.Pair]*provider.Price)(nil), errors.New("failed")).Once()
+	p.On("Prices", btcusd).Return(price(110), nil).Once()
+
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	env := filepath.Join(t.TempDir(), "env")
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	now := ts
+	w := &priceWatcher{
+		watch:    &priceWatch{threshold: 5, window: 5 * time.Minute},
+		provider: p,
+		hook:     nopHook{},
+		pairs:    []provider.Pair{btcusd},
+		command:  `echo "$GOFER_PAIR $GOFER_PREVIOUS_PRICE $GOFER_PRICE $GOFER_CHANGE $GOFER_TIME" > ` + env,
+		webhook:  srv.URL,
+		client:   srv.Client(),
+		out:      out,
+		errOut:   errOut,
+		now:      func() time.Time { return now },
+	}
+	ctx := context.Background()
+	w.check(ctx)
+	w.check(ctx)
+	now = ts.Add(time.Minute)
+	w.check(ctx)
+
+	// Errors are written and do not stop the watcher.
+	assert.Equal(t, "BTC/USD 100.000000 -> 110.000000 (+10.00% in 1m0s)\n", out.String())
+	assert.Equal(t, "Error: failed\n", errOut.String())
+
+	b, err := os.ReadFile(env)
+	require.NoError(t, err)
+	assert.Equal(t, "BTC/USD 100 110 10.00 2023-11-14T22:14:20Z\n", string(b))
+
+	var alert priceAlert
+	require.NoError(t, json.Unmarshal(body, &alert))
+	assert.Equal(t, "BTC/USD", alert.Pair)
+	assert.Equal(t, 10.0, alert.Change)
+}
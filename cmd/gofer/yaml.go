@@ -0,0 +1,184 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+type yamlItem struct {
+	writer io.Writer
+	pair   string // Empty for errors.
+	node   *yaml.Node
+}
+
+// yamlMarshaller implements the marshal.Marshaller interface. On every
+// flush, it writes a YAML document with the list of items to every writer.
+// Items are encoded as by the NDJSON marshaller, or as by the
+// fieldsMarshaller if fields are selected, and converted to YAML with the
+// same keys in the same order. Prices and models are sorted by pair, and
+// followed by errors, so the output of repeated runs can be diffed.
+//
+// Unlike the marshallers of the marshal package, it forgets items once they
+// are flushed, so it can be flushed repeatedly. Documents written to the
+// same writer are separated with "---".
+type yamlMarshaller struct {
+	fields  []string
+	written map[io.Writer]bool
+	items   []yamlItem
+}
+
+func newYAMLMarshaller(fields []string) *yamlMarshaller {
+	return &yamlMarshaller{fields: fields, written: make(map[io.Writer]bool)}
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *yamlMarshaller) Write(writer io.Writer, item interface{}) error {
+	var pair string
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		pair = typedItem.Pair.String()
+	case *provider.Model:
+		pair = typedItem.Pair.String()
+	case error:
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	b, err := m.json(item)
+	if err != nil {
+		return err
+	}
+	node, err := jsonToYAML(b)
+	if err != nil {
+		return err
+	}
+	m.items = append(m.items, yamlItem{writer: writer, pair: pair, node: node})
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *yamlMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	sort.SliceStable(items, func(i, j int) bool {
+		if (items[i].pair == "") != (items[j].pair == "") {
+			return items[i].pair != ""
+		}
+		return items[i].pair < items[j].pair
+	})
+	var writers []io.Writer
+	lists := make(map[io.Writer]*yaml.Node)
+	for _, i := range items {
+		list, ok := lists[i.writer]
+		if !ok {
+			list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			lists[i.writer] = list
+			writers = append(writers, i.writer)
+		}
+		list.Content = append(list.Content, i.node)
+	}
+	for _, w := range writers {
+		if m.written[w] {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		m.written[w] = true
+		if err := encodeYAML(w, lists[w]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// json returns the item encoded as a single line of JSON.
+func (m *yamlMarshaller) json(item interface{}) ([]byte, error) {
+	var jm marshal.Marshaller
+	if len(m.fields) > 0 {
+		jm = newFieldsMarshaller(true, m.fields)
+	} else {
+		var err error
+		if jm, err = marshal.NewMarshal(marshal.NDJSON); err != nil {
+			return nil, err
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := jm.Write(buf, item); err != nil {
+		return nil, err
+	}
+	if err := jm.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYAML writes the value, encoded as by the encoding/json package, as
+// a YAML document.
+func writeYAML(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	node, err := jsonToYAML(b)
+	if err != nil {
+		return err
+	}
+	return encodeYAML(w, node)
+}
+
+// jsonToYAML converts the JSON document to a YAML node with the same keys
+// in the same order.
+func jsonToYAML(b []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, errors.New("empty JSON document")
+	}
+	node := doc.Content[0]
+	resetYAMLStyle(node)
+	return node, nil
+}
+
+func encodeYAML(w io.Writer, node *yaml.Node) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetYAMLStyle removes the flow style and the quotes of the JSON syntax
+// from the node, so it is encoded in the block style, and strings are
+// quoted only where needed.
+func resetYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetYAMLStyle(c)
+	}
+}
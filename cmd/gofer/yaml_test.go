@@ -0,0 +1,103 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestYAMLMarshaller(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	eth := &provider.Price{
+		Type:       "median",
+		Pair:       ethusd,
+		Price:      2000,
+		Time:       ts,
+		Parameters: map[string]string{"minimumSuccessfulSources": "1"},
+		Prices: []*provider.Price{
+			{Type: "origin", Pair: ethusd, Price: 2000, Time: ts, Parameters: map[string]string{"origin": "kraken"}},
+		},
+	}
+	btc := &provider.Price{Type: "median", Pair: btcusd, Price: 42000.5, Time: ts, Error: "not enough sources"}
+
+	out := &bytes.Buffer{}
+	m := newYAMLMarshaller(nil)
+	require.NoError(t, m.Write(out, eth))
+	require.NoError(t, m.Write(out, errors.New("failed")))
+	require.NoError(t, m.Write(out, btc))
+	require.NoError(t, m.Flush())
+
+	// Items are flushed, so the marshaller can be flushed again.
+	require.NoError(t, m.Write(out, &provider.Model{Pair: btcusd}))
+	require.NoError(t, m.Flush())
+
+	// Keys are in the order of the JSON output, prices are sorted by pair,
+	// and strings are quoted only where needed.
+	assert.Equal(t, ""+
+		"- type: median\n"+
+		"  base: BTC\n"+
+		"  quote: USD\n"+
+		"  price: 42000.5\n"+
+		"  bid: 0\n"+
+		"  ask: 0\n"+
+		"  vol24h: 0\n"+
+		"  ts: \"2023-11-14T22:13:20Z\"\n"+
+		"  error: not enough sources\n"+
+		"- type: median\n"+
+		"  base: ETH\n"+
+		"  quote: USD\n"+
+		"  price: 2000\n"+
+		"  bid: 0\n"+
+		"  ask: 0\n"+
+		"  vol24h: 0\n"+
+		"  ts: \"2023-11-14T22:13:20Z\"\n"+
+		"  params:\n"+
+		"    minimumSuccessfulSources: \"1\"\n"+
+		"  prices:\n"+
+		"    - type: origin\n"+
+		"      base: ETH\n"+
+		"      quote: USD\n"+
+		"      price: 2000\n"+
+		"      bid: 0\n"+
+		"      ask: 0\n"+
+		"      vol24h: 0\n"+
+		"      ts: \"2023-11-14T22:13:20Z\"\n"+
+		"      params:\n"+
+		"        origin: kraken\n"+
+		"- error: failed\n"+
+		"---\n"+
+		"- BTC/USD\n",
+		out.String(),
+	)
+}
+
+func TestYAMLMarshaller_Fields(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := newYAMLMarshaller([]string{"pair", "price", "sources"})
+	require.NoError(t, m.Write(out, &provider.Price{Pair: provider.Pair{Base: "ETH", Quote: "USD"}, Price: 2000}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "- pair: ETH/USD\n  price: 2000\n  sources: 1\n", out.String())
+}
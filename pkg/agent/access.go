@@ -0,0 +1,163 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// AccessError is returned by Authorize when a request is rejected by the
+// access controls of the agent.
+type AccessError struct {
+	// Status is the HTTP status code the agent would respond with.
+	Status  int
+	Message string
+}
+
+// Error implements the error interface.
+func (e *AccessError) Error() string {
+	return e.Message
+}
+
+// HTTPStatus returns the HTTP status code of the rejected request.
+func (e *AccessError) HTTPStatus() int {
+	return e.Status
+}
+
+// accessControl returns a middleware which applies the rate limit, the
+// authentication methods and the client certificate rules configured for
+// the agent.
+func (s *HTTPAgent) accessControl() (func(http.Handler) http.Handler, error) {
+	var layers []func(http.Handler) http.Handler
+	if s.rateLimiter != nil {
+		if s.rateLimiter.limit <= 0 {
+			return nil, errors.New("rate limit must be greater than zero")
+		}
+		// The rate limiter is applied after authentication, so
+		// authenticated clients are limited by their API key.
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return s.limitRate(s.rateLimiter, h)
+		})
+	}
+	if len(s.apiKeys) > 0 {
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return s.authenticateAPIKey(s.apiKeys, h)
+		})
+	}
+	if len(s.basicAuth) > 0 {
+		if s.jwt != nil {
+			return nil, errors.New("basic auth cannot be used together with JWT authentication")
+		}
+		if err := verifyBasicAuthUsers(s.basicAuth); err != nil {
+			return nil, err
+		}
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return s.authenticateBasic(s.basicAuth, h)
+		})
+	}
+	if s.jwt != nil {
+		auth, err := newJWTAuthenticator(*s.jwt)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return s.authenticateJWT(auth, h)
+		})
+	}
+	if len(s.tlsClientRules) > 0 {
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return authorizeClients(s.tlsClientRules, h)
+		})
+	}
+	if s.rateLimiter != nil && len(layers) > 1 {
+		// If any authentication method is configured, failed attempts
+		// are limited by the IP address of the client before
+		// credentials are checked, so they cannot be guessed at an
+		// unlimited rate.
+		layers = append(layers, func(h http.Handler) http.Handler {
+			return s.limitAuthFailures(s.rateLimiter, h)
+		})
+	}
+	return func(h http.Handler) http.Handler {
+		for _, l := range layers {
+			h = l(h)
+		}
+		return h
+	}, nil
+}
+
+// grantAccess is the handler reached by requests allowed by the access
+// controls.
+var grantAccess = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+// accessRecorder records the response of the access controls.
+type accessRecorder struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func (r *accessRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *accessRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Authorize applies the access controls of the agent, the IP filter, the
+// rate limit, the authentication methods and the client certificate rules,
+// to a request of another server, so that prices served by it are protected
+// in the same way as the agent endpoints. The request must have the path of
+// the equivalent agent endpoint, the remote address of the client and, if
+// the client connected with TLS, its connection state. Credentials are read
+// from the request headers. The agent must be started.
+//
+// If the request is rejected, an *AccessError is returned.
+func (s *HTTPAgent) Authorize(r *http.Request) error {
+	if s.access == nil {
+		return errors.New("agent is not started")
+	}
+	rec := &accessRecorder{header: make(http.Header)}
+	s.access.ServeHTTP(rec, r)
+	if rec.status == http.StatusNoContent {
+		return nil
+	}
+	return &AccessError{Status: rec.status, Message: strings.TrimSpace(rec.body.String())}
+}
+
+// TLSConfig returns the TLS configuration of the agent, including the
+// client CA bundle, or nil if TLS is disabled. The agent must be started.
+func (s *HTTPAgent) TLSConfig() *tls.Config {
+	if s.certs == nil {
+		return nil
+	}
+	return s.server.TLSConfig.Clone()
+}
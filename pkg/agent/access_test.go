@@ -0,0 +1,69 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_Authorize(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.apiKeys = map[string]string{"test": "secret"}
+	s.ipFilter = &IPFilterConfig{Deny: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	assert.Error(t, s.Authorize(httptest.NewRequest(http.MethodGet, "/prices", nil)))
+	require.NoError(t, s.initServer())
+
+	tests := []struct {
+		remoteAddr string
+		key        string
+		want       int
+	}{
+		{remoteAddr: "172.16.0.1:1234", key: "secret"},
+		{remoteAddr: "172.16.0.1:1234", want: http.StatusUnauthorized},
+		{remoteAddr: "172.16.0.1:1234", key: "invalid", want: http.StatusUnauthorized},
+		{remoteAddr: "10.0.0.1:1234", key: "secret", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/prices", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.key != "" {
+			req.Header.Set(APIKeyHeader, tt.key)
+		}
+		err := s.Authorize(req)
+		if tt.want == 0 {
+			assert.NoError(t, err, "%s with %q", tt.remoteAddr, tt.key)
+			continue
+		}
+		var accessErr *AccessError
+		require.ErrorAs(t, err, &accessErr, "%s with %q", tt.remoteAddr, tt.key)
+		assert.Equal(t, tt.want, accessErr.HTTPStatus(), "%s with %q", tt.remoteAddr, tt.key)
+	}
+}
+
+func TestHTTPAgent_Authorize_NoAccessControls(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	require.NoError(t, s.initServer())
+	assert.NoError(t, s.Authorize(httptest.NewRequest(http.MethodGet, "/prices", nil)))
+	assert.Nil(t, s.TLSConfig())
+}
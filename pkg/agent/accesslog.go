@@ -0,0 +1,91 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+type accessLogCtxKey struct{}
+
+// accessLogEntry collects request details that are known only to the
+// inner handlers, such as the authenticated client and requested pairs.
+type accessLogEntry struct {
+	apiKey  string
+	user    string
+	subject string
+	pairs   []provider.Pair
+}
+
+// accessLogFromContext returns the access log entry of the request, or nil
+// if access logging is disabled.
+func accessLogFromContext(ctx context.Context) *accessLogEntry {
+	e, _ := ctx.Value(accessLogCtxKey{}).(*accessLogEntry)
+	return e
+}
+
+// logRequestedPairs records the pairs requested by the client in the
+// access log.
+func logRequestedPairs(ctx context.Context, pairs ...provider.Pair) {
+	if e := accessLogFromContext(ctx); e != nil {
+		e.pairs = append(e.pairs, pairs...)
+	}
+}
+
+// logAccess wraps the handler to log every request with its method, path,
+// requested pairs, status, latency and the identity of the client.
+func (s *HTTPAgent) logAccess(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogCtxKey{}, entry)))
+
+		fields := log.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     rec.status,
+			"latency":    time.Since(start).String(),
+			"remoteAddr": r.RemoteAddr,
+		}
+		if len(entry.pairs) > 0 {
+			pairs := make([]string, 0, len(entry.pairs))
+			for _, p := range entry.pairs {
+				pairs = append(pairs, p.String())
+			}
+			fields["pairs"] = strings.Join(pairs, ",")
+		}
+		if entry.apiKey != "" {
+			fields["apiKey"] = entry.apiKey
+		}
+		if entry.user != "" {
+			fields["user"] = entry.user
+		}
+		if entry.subject != "" {
+			fields["subject"] = entry.subject
+		}
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			fields["clientCN"] = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		s.requestLog(r).WithFields(fields).Info("HTTP request")
+	})
+}
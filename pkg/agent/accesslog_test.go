@@ -0,0 +1,85 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/callback"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_AccessLog(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: testPrice(btcusd, 42),
+		ethusd: testPrice(ethusd, 4),
+	}, nil)
+
+	var mu sync.Mutex
+	var entries []log.Fields
+	s := newTestAgent(t, p)
+	s.log = callback.New(log.Debug, func(_ log.Level, fields log.Fields, msg string) {
+		if msg != "HTTP request" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, fields)
+	})
+	s.accessLog = true
+	s.apiKeys = map[string]string{"relayer": "secret"}
+	require.NoError(t, s.initServer())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/prices?pairs=BTC/USD,ETH/USD", nil)
+	req.Header.Set(APIKeyHeader, "secret")
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/prices?pairs=BTC/USD", nil)
+	req.Header.Set(APIKeyHeader, "invalid")
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, http.MethodGet, entries[0]["method"])
+	assert.Equal(t, "/v1/prices", entries[0]["path"])
+	assert.Equal(t, http.StatusOK, entries[0]["status"])
+	assert.Equal(t, "BTC/USD,ETH/USD", entries[0]["pairs"])
+	assert.Equal(t, "relayer", entries[0]["apiKey"])
+	assert.Contains(t, entries[0], "latency")
+
+	assert.Equal(t, http.StatusUnauthorized, entries[1]["status"])
+	assert.NotContains(t, entries[1], "apiKey")
+	assert.NotContains(t, entries[1], "pairs")
+}
+
+func TestHTTPAgent_AccessLogDisabled(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.log = callback.New(log.Debug, func(_ log.Level, _ log.Fields, msg string) {
+		assert.NotEqual(t, "HTTP request", msg)
+	})
+	require.NoError(t, s.initServer())
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+}
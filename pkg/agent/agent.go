@@ -17,46 +17,209 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/graphql-go/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
-	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
 )
 
 // HTTPAgentConfig is the configuration for Lair.
 type HTTPAgentConfig struct {
 	PriceProvider provider.Provider
 	PriceHook     provider.PriceHook
-	Marshaller    marshal.Marshaller
 	Logger        log.Logger
-	// Address is used for the rpc.Listener function.
+	// Address is the listen address of the HTTP server. Addresses with the
+	// unix:// prefix, e.g. unix:///var/run/gofer.sock, are paths of Unix
+	// domain sockets.
 	Address string
+	// Addresses are the listen addresses of the HTTP server, which serves
+	// the same endpoints on every address, e.g. on a TCP address and on a
+	// Unix domain socket. If empty, Address is used.
+	Addresses []string
+	// SocketMode is the permission mode of the Unix domain socket file. If
+	// zero, defaultSocketMode is used.
+	SocketMode fs.FileMode
+	// StreamInterval describes how often prices for streaming clients are
+	// refreshed. If zero, defaultStreamInterval is used.
+	StreamInterval time.Duration
+	// PriceUpdates, if set, receives pairs whose prices were updated, e.g.
+	// by a price cache. Prices of these pairs are refreshed for streaming
+	// clients immediately, in addition to the StreamInterval refreshes.
+	PriceUpdates <-chan provider.Pair
+	// TLSCertFile and TLSKeyFile are paths to the certificate and the private
+	// key used to serve HTTPS. If empty, plain HTTP is used. Certificates are
+	// reloaded when the files change or when the process receives SIGHUP.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile is a path to a CA bundle used to verify client
+	// certificates. If set, clients must present a valid certificate.
+	TLSClientCAFile string
+	// TLSClientRules restricts access to clients with the given certificate
+	// common names. If empty, all clients with a valid certificate are
+	// allowed.
+	TLSClientRules []ClientRule
+	// APIKeys is a map of labels to API keys. If not empty, requests must
+	// contain one of the keys in the X-API-Key header.
+	APIKeys map[string]string
+	// BasicAuth is a map of user names to bcrypt password hashes. If not
+	// empty, requests must contain valid HTTP Basic auth credentials.
+	// Basic auth cannot be used together with JWT authentication.
+	BasicAuth map[string]string
+	// JWT, if set, enables JWT bearer-token authentication.
+	JWT *JWTConfig
+	// RateLimit, if set, enables per-client rate limiting.
+	RateLimit *RateLimitConfig
+	// ConcurrencyLimit, if set, limits the number of requests handled at
+	// the same time.
+	ConcurrencyLimit *ConcurrencyLimitConfig
+	// CORS, if set, enables Cross-Origin Resource Sharing for the configured
+	// origins.
+	CORS *CORSConfig
+	// IPFilter, if set, restricts access to the configured networks.
+	IPFilter *IPFilterConfig
+	// ShutdownTimeout is the time given to in-flight requests to finish
+	// when the agent is stopped. If zero, defaultShutdownTimeout is used.
+	ShutdownTimeout time.Duration
+	// ReadTimeout, WriteTimeout and IdleTimeout are passed to the
+	// http.Server. If zero, defaultReadTimeout, defaultWriteTimeout and
+	// defaultIdleTimeout are used. The write timeout does not apply to the
+	// streaming endpoints.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// MaxHeaderBytes is the maximum size of request headers. If zero,
+	// http.DefaultMaxHeaderBytes is used.
+	MaxHeaderBytes int
+	// MaxRequestBodySize is the maximum size of a request body, in bytes.
+	// If zero, defaultMaxRequestBodySize is used.
+	MaxRequestBodySize int64
+	// H2C enables HTTP/2 over cleartext connections, both with prior
+	// knowledge and with the HTTP/1.1 Upgrade header. HTTP/2 is always
+	// enabled over TLS, so H2C cannot be used together with TLS.
+	H2C bool
+	// HTTP2MaxConcurrentStreams is the maximum number of concurrent
+	// streams per HTTP/2 connection. If zero, the golang.org/x/net/http2
+	// default is used.
+	HTTP2MaxConcurrentStreams uint32
+	// AccessLog enables logging of every HTTP request, with the requested
+	// pairs and the identity of the client.
+	AccessLog bool
+	// MinSources is the minimum number of sources required to calculate
+	// the price of each pair. It is reported by the /pairs endpoint. If the
+	// price provider or the price hook is a ReloadableProvider, its numbers
+	// are used instead.
+	MinSources map[provider.Pair]int
+	// History, if set, enables the /history endpoint, which serves recent
+	// prices kept in memory.
+	History PriceHistory
+	// Webhooks, if set, enables the /webhooks endpoints, which allow
+	// clients to register callback URLs notified about price changes.
+	Webhooks *WebhookConfig
+	// Reload, if set, enables the POST /admin/reload endpoint, which calls
+	// it to reload the configuration. The endpoint requires one of the
+	// authentication methods to be configured.
+	Reload ReloadFunc
+	// Invalidate, if set, enables the POST /admin/cache/invalidate
+	// endpoint, which calls it to refetch cached prices. Like the reload
+	// endpoint, it requires one of the authentication methods to be
+	// configured.
+	Invalidate InvalidateFunc
+	// NextRefresh, if set, is used to compute the Cache-Control max-age of
+	// price responses, which may be cached until the first of the prices
+	// is refreshed. If nil, price responses have a zero max-age and must
+	// be revalidated with their ETag.
+	NextRefresh NextRefreshFunc
+	// ReadinessChecks are additional checks reported by the /readyz
+	// endpoint, by name. The agent always checks that it is started and
+	// that the price provider is reachable.
+	ReadinessChecks map[string]ReadinessCheck
+	// Collectors are additional Prometheus collectors exported by the
+	// /metrics endpoint.
+	Collectors []prometheus.Collector
 }
 
+const (
+	defaultStreamInterval  = 10 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
+	defaultReadTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 30 * time.Second
+	defaultIdleTimeout     = 120 * time.Second
+
+	defaultMaxRequestBodySize = 1 << 20
+)
+
 // HTTPAgent returns the services that are configured from the Config struct.
 type HTTPAgent struct {
 	ctx    context.Context
 	waitCh chan error
 
-	address       string
-	server        *http.Server
-	priceProvider provider.Provider
-	priceHook     provider.PriceHook
-	marshaller    marshal.Marshaller
-	log           log.Logger
+	addresses       []string
+	socketMode      fs.FileMode
+	tlsCertFile     string
+	tlsKeyFile      string
+	tlsClientCAFile string
+	tlsClientRules  []ClientRule
+	apiKeys         map[string]string
+	basicAuth       map[string]string
+	jwt             *JWTConfig
+	rateLimiter     *rateLimiter
+	concurrency     *concurrencyLimiter
+	cors            *CORSConfig
+	ipFilter        *IPFilterConfig
+	// access applies the access controls of the agent to requests of other
+	// servers, see Authorize.
+	access          http.Handler
+	shutdownTimeout time.Duration
+	maxBodySize     int64
+	h2c             bool
+	http2           *http2.Server
+	inFlight        atomic.Int64
+	certs           *certReloader
+	server          *http.Server
+	mux             *http.ServeMux
+	priceProvider   provider.Provider
+	priceHook       provider.PriceHook
+	stream          *priceStream
+	graphqlSchema   graphql.Schema
+	openAPIDoc      []byte
+	metrics         *metrics
+	nextRefresh     NextRefreshFunc
+	accessLog       bool
+	minSources      func() map[provider.Pair]int
+	reload          ReloadFunc
+	invalidate      InvalidateFunc
+	webhooks        *webhookManager
+	history         PriceHistory
+	reloadMu        sync.Mutex
+	readinessChecks map[string]ReadinessCheck
+	log             log.Logger
 }
 
 type pricesRequest struct {
 	Pairs []provider.Pair
+	Depth *int
 }
 
 type priceRequest struct {
-	Pair provider.Pair
+	Pair  provider.Pair
+	Depth *int
 }
 
 type jsonPrice struct {
@@ -94,15 +257,92 @@ func jsonPriceFromGoferPrice(t *provider.Price) jsonPrice {
 }
 
 func NewHTTPAgent(cfg HTTPAgentConfig) *HTTPAgent {
-	return &HTTPAgent{
-		waitCh:        make(chan error),
-		address:       cfg.Address,
-		priceProvider: cfg.PriceProvider,
-		priceHook:     cfg.PriceHook,
-		marshaller:    cfg.Marshaller,
-		log:           cfg.Logger,
-		server:        &http.Server{Addr: cfg.Address},
+	if cfg.StreamInterval == 0 {
+		cfg.StreamInterval = defaultStreamInterval
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = defaultReadTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+	if cfg.SocketMode == 0 {
+		cfg.SocketMode = defaultSocketMode
+	}
+	if cfg.MaxRequestBodySize == 0 {
+		cfg.MaxRequestBodySize = defaultMaxRequestBodySize
+	}
+	m := newMetrics(cfg.Collectors...)
+	p := &instrumentedProvider{Provider: cfg.PriceProvider, metrics: m}
+	addresses := cfg.Addresses
+	if len(addresses) == 0 {
+		addresses = []string{cfg.Address}
+	}
+	a := &HTTPAgent{
+		waitCh:          make(chan error),
+		addresses:       addresses,
+		socketMode:      cfg.SocketMode,
+		tlsCertFile:     cfg.TLSCertFile,
+		tlsKeyFile:      cfg.TLSKeyFile,
+		tlsClientCAFile: cfg.TLSClientCAFile,
+		tlsClientRules:  cfg.TLSClientRules,
+		apiKeys:         cfg.APIKeys,
+		basicAuth:       cfg.BasicAuth,
+		jwt:             cfg.JWT,
+		cors:            cfg.CORS,
+		ipFilter:        cfg.IPFilter,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		maxBodySize:     cfg.MaxRequestBodySize,
+		h2c:             cfg.H2C,
+		http2:           &http2.Server{MaxConcurrentStreams: cfg.HTTP2MaxConcurrentStreams},
+		nextRefresh:     cfg.NextRefresh,
+		accessLog:       cfg.AccessLog,
+		minSources:      func() map[provider.Pair]int { return cfg.MinSources },
+		reload:          cfg.Reload,
+		invalidate:      cfg.Invalidate,
+		history:         cfg.History,
+		priceProvider:   p,
+		priceHook:       cfg.PriceHook,
+		stream:          newPriceStream(p, cfg.PriceHook, cfg.StreamInterval, cfg.PriceUpdates, cfg.Logger),
+		metrics:         m,
+		log:             cfg.Logger,
+		server: &http.Server{
+			Addr:           cfg.Address,
+			ReadTimeout:    cfg.ReadTimeout,
+			WriteTimeout:   cfg.WriteTimeout,
+			IdleTimeout:    cfg.IdleTimeout,
+			MaxHeaderBytes: cfg.MaxHeaderBytes,
+		},
+		mux: http.NewServeMux(),
+	}
+	if r, ok := cfg.PriceProvider.(*ReloadableProvider); ok {
+		a.minSources = r.MinSources
+	} else if r, ok := cfg.PriceHook.(*ReloadableProvider); ok {
+		a.minSources = r.MinSources
+	}
+	if cfg.Webhooks != nil {
+		a.webhooks = newWebhookManager(*cfg.Webhooks, a.stream, cfg.Logger)
 	}
+	if cfg.RateLimit != nil {
+		a.rateLimiter = newRateLimiter(*cfg.RateLimit)
+	}
+	if cfg.ConcurrencyLimit != nil {
+		a.concurrency = newConcurrencyLimiter(*cfg.ConcurrencyLimit)
+	}
+	a.readinessChecks = map[string]ReadinessCheck{
+		"agent":    a.checkStarted,
+		"provider": a.checkProvider,
+	}
+	for name, check := range cfg.ReadinessChecks {
+		a.readinessChecks[name] = check
+	}
+	return a
 }
 
 // Start implements the supervisor.Service interface.
@@ -121,79 +361,305 @@ func (s *HTTPAgent) Start(ctx context.Context) error {
 		return err
 	}
 
-	go func() {
-		s.log.Debug("Starting HTTP server")
-		err := s.server.ListenAndServe()
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			s.log.WithError(err).Error("HTTP server crashed")
+	lns := make([]net.Listener, 0, len(s.addresses))
+	for _, address := range s.addresses {
+		ln, err := listen(address, s.socketMode)
+		if err != nil {
+			for _, ln := range lns {
+				_ = ln.Close()
+			}
+			return fmt.Errorf("unable to listen on %s: %w", address, err)
 		}
-	}()
+		lns = append(lns, ln)
+	}
+
+	for _, ln := range lns {
+		go s.serve(ln)
+	}
+	go s.stream.run(ctx)
+	if s.certs != nil {
+		go s.certs.watch(ctx)
+	}
+	if s.rateLimiter != nil {
+		go s.rateLimiter.run(ctx)
+	}
 	go s.contextCancelHandler()
 	return nil
 }
 
+// serve serves HTTP requests accepted by the listener until the server is
+// shut down.
+func (s *HTTPAgent) serve(ln net.Listener) {
+	var err error
+	s.log.Debugf("Starting HTTP server on %s", ln.Addr())
+	if s.certs != nil {
+		err = s.server.ServeTLS(ln, "", "")
+	} else {
+		err = s.server.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.log.WithError(err).Error("HTTP server crashed")
+	}
+}
+
 // Wait implements the supervisor.Service interface.
 func (s *HTTPAgent) Wait() <-chan error {
 	return s.waitCh
 }
 
 func (s *HTTPAgent) initServer() error {
-	s.log.Infof("initializing HTTP server on %s", s.address)
+	s.log.Infof("initializing HTTP server on %s", strings.Join(s.addresses, ", "))
+
+	schema, err := s.newGraphQLSchema()
+	if err != nil {
+		return err
+	}
+	s.graphqlSchema = schema
+
+	doc, err := s.openAPISpec()
+	if err != nil {
+		return err
+	}
+	s.openAPIDoc = doc
 
-	http.HandleFunc("/", s.handlePrices)
-	http.HandleFunc("/price", s.handlePrice)
-	http.HandleFunc("/prices", s.handlePrices)
+	if s.tlsCertFile != "" || s.tlsKeyFile != "" {
+		if s.tlsCertFile == "" || s.tlsKeyFile == "" {
+			return errors.New("both TLS certificate and key files must be provided")
+		}
+		certs, err := newCertReloader(s.tlsCertFile, s.tlsKeyFile, s.log)
+		if err != nil {
+			return err
+		}
+		s.certs = certs
+		s.server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+		if s.tlsClientCAFile != "" {
+			pool, err := loadCertPool(s.tlsClientCAFile)
+			if err != nil {
+				return err
+			}
+			s.server.TLSConfig.ClientCAs = pool
+			s.server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	if (s.tlsClientCAFile != "" || len(s.tlsClientRules) > 0) && s.certs == nil {
+		return errors.New("client certificate authentication requires TLS to be enabled")
+	}
+	if len(s.tlsClientRules) > 0 && s.tlsClientCAFile == "" {
+		return errors.New("client rules require a client CA bundle")
+	}
+	if s.webhooks != nil && len(s.webhooks.cfg.Secret) == 0 {
+		return errors.New("webhooks require a signing secret")
+	}
+	if s.reload != nil || s.invalidate != nil {
+		if err := s.checkAdminAuth(); err != nil {
+			return err
+		}
+	}
+	if s.h2c && s.certs != nil {
+		return errors.New("h2c cannot be used together with TLS")
+	}
+	// The HTTP/2 server is configured explicitly, so that its settings and
+	// graceful shutdown also apply to h2c connections.
+	if err := http2.ConfigureServer(s.server, s.http2); err != nil {
+		return err
+	}
+
+	var handler http.Handler = limitBody(s.maxBodySize, compress(s.mux))
+	if s.concurrency != nil {
+		if cap(s.concurrency.slots) <= 0 {
+			return errors.New("concurrency limit must be greater than zero")
+		}
+		// The concurrency limit is applied after authentication and rate
+		// limiting, so rejected requests do not occupy a slot.
+		handler = s.limitConcurrency(s.concurrency, handler)
+	}
+	accessControl, err := s.accessControl()
+	if err != nil {
+		return err
+	}
+	handler = accessControl(handler)
+	if s.cors != nil {
+		// Preflight requests do not carry credentials, so CORS must be
+		// handled before authentication.
+		handler = cors(*s.cors, handler)
+	}
+	if s.ipFilter != nil {
+		handler = s.filterIP(*s.ipFilter, handler)
+	}
+	s.access = accessControl(grantAccess)
+	if s.ipFilter != nil {
+		s.access = s.filterIP(*s.ipFilter, s.access)
+	}
+	if s.accessLog {
+		handler = s.logAccess(handler)
+	}
+	handler = assignRequestID(handler)
+	handler = s.trackInFlight(handler)
+	if s.h2c {
+		handler = h2c.NewHandler(handler, s.http2)
+	}
+	s.server.Handler = handler
+
+	s.handleAPI("/", s.handlePrices)
+	s.handleAPI("/price", s.handlePrice)
+	s.handleAPI("/prices", s.handlePrices)
+	s.handleAPI("/prices/wait", s.handleWaitPrice)
+	s.handleAPI("/models", s.handleModels)
+	s.handleAPI("/pairs", s.handlePairs)
+	s.handleAPI("/ws", s.handleWebSocket)
+	s.handleAPI("/stream", s.handleStream)
+	s.handleAPI("/graphql", s.handleGraphQL)
+	s.handleAPI("/rpc", s.handleRPC)
+	s.handleAPI("/openapi.json", s.handleOpenAPI)
+	s.handleAPI("/gofer.proto", s.handleProtoSchema)
+	if s.history != nil {
+		s.handleAPI("/history", s.handleHistory)
+	}
+	if s.webhooks != nil {
+		s.handleAPI("/webhooks", s.handleWebhooks)
+		s.handleAPI("/webhooks/", s.handleWebhook)
+	}
+	s.mux.Handle("/metrics", s.metrics.handler())
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	if s.reload != nil {
+		s.handle("/admin/reload", s.handleReload)
+	}
+	if s.invalidate != nil {
+		s.handle("/admin/cache/invalidate", s.handleInvalidate)
+	}
 
 	return nil
 }
 
+// handle registers the handler for the given pattern and instruments it
+// with metrics.
+func (s *HTTPAgent) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.metrics.instrument(pattern, h))
+}
+
+// trackInFlight wraps the handler to count requests that are being served.
+func (s *HTTPAgent) trackInFlight(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// contextCancelHandler gracefully shuts down the server when the context is
+// canceled. In-flight requests are given the shutdown timeout to finish,
+// after which remaining connections are closed.
 func (s *HTTPAgent) contextCancelHandler() {
 	defer func() { close(s.waitCh) }()
-	defer s.log.Debug("Stopped")
 	<-s.ctx.Done()
-	s.waitCh <- s.server.Close()
+	if s.webhooks != nil {
+		s.webhooks.close()
+	}
+	s.log.Debugf("Shutting down, waiting up to %s for in-flight requests", s.shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Report requests that did not finish before forcibly closing
+		// their connections.
+		unfinished := s.inFlight.Load()
+		err = s.server.Close()
+		s.log.
+			WithField("unfinishedRequests", unfinished).
+			Warn("Stopped before all in-flight requests finished")
+	} else {
+		s.log.WithField("unfinishedRequests", s.inFlight.Load()).Debug("Stopped")
+	}
+	s.waitCh <- err
 }
 
 func (s *HTTPAgent) handlePrice(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get("Content-Type") != "application/json" {
-		msg := "Content-Type header is not application/json"
-		http.Error(w, msg, http.StatusUnsupportedMediaType)
+	format, ok := negotiateFormat(r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "none of the accepted media types is supported", http.StatusNotAcceptable)
 		return
 	}
-
-	var p priceRequest
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var (
+		p     priceRequest
+		depth int
+	)
+	switch r.Method {
+	case http.MethodGet:
+		if q := r.URL.Query().Get("pair"); q != "" {
+			pair, err := provider.NewPair(q)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			p.Pair = pair
+		}
+		d, err := depthFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		depth = d
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != "application/json" {
+			msg := "Content-Type header is not application/json"
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), bodyErrorStatus(err))
+			return
+		}
+		d, err := depthFromBody(p.Depth)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		depth = d
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	if p.Pair.Empty() {
 		_, _ = io.WriteString(w, "{}")
 		return
 	}
+	logRequestedPairs(r.Context(), p.Pair)
 
 	prices, err := s.priceProvider.Prices(p.Pair)
 	if err != nil {
-		s.log.Errorf("failed to get prices: %v", err)
+		s.requestLog(r).Errorf("failed to get prices: %v", err)
 		_, _ = io.WriteString(w, `{"error":"failed to get prices"}`)
 		return
 	}
 	err = s.priceHook.Check(prices)
 	if err != nil {
-		s.log.Errorf("failed to check prices: %v", err)
+		s.requestLog(r).Errorf("failed to check prices: %v", err)
 		_, _ = io.WriteString(w, `{"error":"failed to check prices"}`)
 		return
 	}
 	price, ok := prices[p.Pair]
 	if !ok {
-		s.log.Infof("Invalid price response for %s: %v", p.Pair.String(), prices)
+		s.requestLog(r).Infof("Invalid price response for %s: %v", p.Pair.String(), prices)
 		_, _ = io.WriteString(w, "{}")
 		return
 	}
+	if s.checkNotModified(w, r, depth, price) {
+		return
+	}
+	price = truncatePrice(price, depth)
 
+	if format != nil && format.contentType != "application/json" {
+		s.writePrices(w, r, format, price)
+		return
+	}
 	b, err := json.Marshal(jsonPriceFromGoferPrice(price))
 	if err != nil {
-		s.log.Infof("Failed to get price for %s: %v", p.Pair.String(), err)
+		s.requestLog(r).Infof("Failed to get price for %s: %v", p.Pair.String(), err)
 		_, _ = io.WriteString(w, "{}")
 		return
 	}
@@ -201,46 +667,130 @@ func (s *HTTPAgent) handlePrice(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *HTTPAgent) handlePrices(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get("Content-Type") != "application/json" {
-		msg := "Content-Type header is not application/json"
-		http.Error(w, msg, http.StatusUnsupportedMediaType)
+	format, ok := negotiateFormat(r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "none of the accepted media types is supported", http.StatusNotAcceptable)
 		return
 	}
+	var (
+		p     pricesRequest
+		depth int
+	)
+	switch r.Method {
+	case http.MethodGet:
+		pairs, err := pairsFromQuery(r.URL.Query()["pairs"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.Pairs = pairs
+		d, err := depthFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		depth = d
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != "application/json" {
+			msg := "Content-Type header is not application/json"
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), bodyErrorStatus(err))
+			return
+		}
+		d, err := depthFromBody(p.Depth)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		depth = d
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(p.Pairs) == 0 {
+		s.writePrices(w, r, format)
+		return
+	}
+	logRequestedPairs(r.Context(), p.Pairs...)
 
-	var p pricesRequest
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	prices, errs := s.pairPrices(r, p.Pairs...)
+	if len(prices) == 0 && len(errs) > 0 {
+		s.writePricesError(w, errs)
 		return
 	}
-	if p.Pairs == nil || len(p.Pairs) == 0 {
-		_, _ = io.WriteString(w, "{}")
+
+	list := make([]*provider.Price, 0, len(prices))
+	for _, p := range prices {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Pair.String() < list[j].Pair.String()
+	})
+	for i, p := range list {
+		list[i] = truncatePrice(p, depth)
+	}
+	if len(errs) > 0 {
+		s.writePartialPrices(w, r, format, list, errs)
 		return
 	}
+	if s.checkNotModified(w, r, depth, list...) {
+		return
+	}
+	s.writePrices(w, r, format, list...)
+}
 
-	prices, err := s.priceProvider.Prices(p.Pairs...)
-	if err != nil {
-		s.log.Errorf("failed to get prices: %v", err)
-		_, _ = io.WriteString(w, `{"error":"failed to get prices"}`)
+// writePrices writes the prices in the given format. If format is nil, the
+// prices are written as a JSON array.
+func (s *HTTPAgent) writePrices(w http.ResponseWriter, r *http.Request, format *responseFormat, prices ...*provider.Price) {
+	if format == nil {
+		f := responseFormats["application/json"]
+		format = &f
+	}
+	if len(prices) == 0 && format.contentType == "application/json" {
+		// The JSON marshaller writes nothing for no items.
+		w.Header().Set("Content-Type", format.contentType)
+		_, _ = io.WriteString(w, "[]")
 		return
 	}
-	err = s.priceHook.Check(prices)
+	m, err := format.newMarshaller()
 	if err != nil {
-		s.log.Errorf("failed to check prices: %v", err)
-		_, _ = io.WriteString(w, `{"error":"failed to check prices"}`)
+		s.requestLog(r).Errorf("failed to create marshaller: %v", err)
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
 		return
 	}
-
+	w.Header().Set("Content-Type", format.contentType)
 	for _, p := range prices {
-		if mErr := s.marshaller.Write(w, p); mErr != nil {
-			_ = s.marshaller.Write(w, mErr)
+		if mErr := m.Write(w, p); mErr != nil {
+			_ = m.Write(w, mErr)
 		}
 	}
-	err = s.marshaller.Flush()
+	err = m.Flush()
 	if err != nil {
-		s.log.Errorf("failed to marshal response: %v", err)
+		s.requestLog(r).Errorf("failed to marshal response: %v", err)
 		_, _ = io.WriteString(w, `{"error":"failed to marshal json"}`)
 		return
 	}
-	//_, _ = io.WriteString(w, string(b))
+}
+
+// pairsFromQuery parses pairs from the "pairs" query parameter. Pairs may be
+// given as a comma-separated list, as repeated parameters, or both.
+func pairsFromQuery(values []string) ([]provider.Pair, error) {
+	var pairs []provider.Pair
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			pair, err := provider.NewPair(s)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
 }
@@ -0,0 +1,173 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+type nopHook struct{}
+
+func (nopHook) Check(map[provider.Pair]*provider.Price) error { return nil }
+
+func newTestAgent(t *testing.T, p provider.Provider) *HTTPAgent {
+	allowPairs(p)
+	return NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider: p,
+		PriceHook:     nopHook{},
+		Logger:        null.New(),
+	})
+}
+
+// allowPairs allows the Pairs method of a mocked provider to be called, as
+// it is by the metrics of every price fetch. It returns no pairs, unless
+// the test set up the method before.
+func allowPairs(p provider.Provider) {
+	if m, ok := p.(*mocks.Provider); ok {
+		m.On("Pairs").Return([]provider.Pair(nil), nil).Maybe()
+	}
+}
+
+func testPrice(pair provider.Pair, price float64) *provider.Price {
+	return &provider.Price{
+		Type:  "median",
+		Pair:  pair,
+		Price: price,
+		Time:  time.Unix(1700000000, 0),
+	}
+}
+
+func TestHTTPAgent_GetPrice(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?pair=btc/usd", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res jsonPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "BTC", res.Base)
+	assert.Equal(t, "USD", res.Quote)
+	assert.Equal(t, 42.0, res.Price)
+}
+
+func TestHTTPAgent_GetPrice_InvalidPair(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestAgent(t, &mocks.Provider{}).handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?pair=BTCUSD", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHTTPAgent_GetPrices(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: testPrice(btcusd, 42),
+		ethusd: testPrice(ethusd, 21),
+	}, nil)
+
+	s := newTestAgent(t, p)
+
+	// Every response must be a single JSON array, also when the agent has
+	// already served other requests.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD,ETH/USD", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var res []jsonPrice
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res, 2)
+		assert.Equal(t, "BTC", res[0].Base)
+		assert.Equal(t, 42.0, res[0].Price)
+		assert.Equal(t, "ETH", res[1].Base)
+		assert.Equal(t, 21.0, res[1].Price)
+	}
+	p.AssertExpectations(t)
+}
+
+func TestHTTPAgent_PostPrices(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/prices", strings.NewReader(`{"pairs":["BTC/USD"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrices(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res []jsonPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res, 1)
+	assert.Equal(t, "BTC", res[0].Base)
+}
+
+func TestHTTPAgent_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestAgent(t, &mocks.Provider{}).handlePrices(rec, httptest.NewRequest(http.MethodDelete, "/prices", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHTTPAgent_OwnServeMux(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+
+	// Initializing two agents must not register routes twice on a shared mux.
+	for i := 0; i < 2; i++ {
+		s := newTestAgent(t, p)
+		require.NoError(t, s.initServer())
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPairsFromQuery(t *testing.T) {
+	pairs, err := pairsFromQuery([]string{"BTC/USD, eth/usd", "", "DAI/USD"})
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{
+		{Base: "BTC", Quote: "USD"},
+		{Base: "ETH", Quote: "USD"},
+		{Base: "DAI", Quote: "USD"},
+	}, pairs)
+
+	_, err = pairsFromQuery([]string{"BTCUSD"})
+	assert.Error(t, err)
+}
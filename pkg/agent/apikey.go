@@ -0,0 +1,85 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// APIKeyHeader is the header used to send API keys to the agent.
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyLabelCtxKey struct{}
+
+// apiKeyLabel returns the label of the API key used to authenticate the
+// request, or an empty string if the request was not authenticated with
+// an API key.
+func apiKeyLabel(ctx context.Context) string {
+	label, _ := ctx.Value(apiKeyLabelCtxKey{}).(string)
+	return label
+}
+
+// authenticateAPIKey wraps the handler to only allow requests with one of
+// the given API keys. Keys are given as a map of labels to keys. Health
+// check endpoints do not require a key.
+func (s *HTTPAgent) authenticateAPIKey(keys map[string]string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			authFailed(r)
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+		label, ok := matchAPIKey(keys, key)
+		if !ok {
+			s.requestLog(r).
+				WithField("remoteAddr", r.RemoteAddr).
+				WithField("path", r.URL.Path).
+				Warn("Invalid API key")
+			authFailed(r)
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+		s.requestLog(r).
+			WithField("apiKey", label).
+			WithField("method", r.Method).
+			WithField("path", r.URL.Path).
+			WithField("remoteAddr", r.RemoteAddr).
+			Debug("Authenticated request")
+		if e := accessLogFromContext(r.Context()); e != nil {
+			e.apiKey = label
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyLabelCtxKey{}, label)))
+	})
+}
+
+// matchAPIKey returns the label of the given key. All keys are compared to
+// avoid leaking information through timing.
+func matchAPIKey(keys map[string]string, key string) (string, bool) {
+	var match string
+	for label, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			match = label
+		}
+	}
+	return match, match != ""
+}
@@ -0,0 +1,50 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_AuthenticateAPIKey(t *testing.T) {
+	var label string
+	a := newTestAgent(t, &mocks.Provider{})
+	h := a.authenticateAPIKey(map[string]string{"relayer": "secret"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		label = apiKeyLabel(r.Context())
+	}))
+
+	request := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("/prices", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("/prices", "invalid"))
+	assert.Equal(t, http.StatusOK, request("/healthz", ""))
+	assert.Equal(t, http.StatusOK, request("/prices", "secret"))
+	assert.Equal(t, "relayer", label)
+}
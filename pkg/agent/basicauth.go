@@ -0,0 +1,150 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicAuthCacheTTL describes how long verified credentials are remembered.
+const basicAuthCacheTTL = time.Minute
+
+type basicAuthUserCtxKey struct{}
+
+// basicAuthUser returns the name of the user authenticated with HTTP Basic
+// auth, or an empty string if the request was not authenticated with Basic
+// auth.
+func basicAuthUser(ctx context.Context) string {
+	user, _ := ctx.Value(basicAuthUserCtxKey{}).(string)
+	return user
+}
+
+// basicAuthDummyHash is compared against passwords of unknown users, so
+// that the response time does not reveal which users exist.
+var basicAuthDummyHash, _ = bcrypt.GenerateFromPassword([]byte("gofer"), bcrypt.DefaultCost)
+
+// verifyBasicAuthUsers verifies that all users have valid bcrypt hashes.
+func verifyBasicAuthUsers(users map[string]string) error {
+	for user, hash := range users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash for user %q: %w", user, err)
+		}
+	}
+	return nil
+}
+
+// basicAuthCache remembers recently verified credentials, so bcrypt, which
+// is slow by design, does not run on every request of a client. Only valid
+// credentials are added, so there is at most one entry per user.
+type basicAuthCache struct {
+	mu sync.Mutex
+
+	ttl     time.Duration
+	entries map[string]basicAuthEntry
+}
+
+type basicAuthEntry struct {
+	sum     [sha256.Size]byte
+	expires time.Time
+}
+
+func newBasicAuthCache(ttl time.Duration) *basicAuthCache {
+	return &basicAuthCache{
+		ttl:     ttl,
+		entries: make(map[string]basicAuthEntry),
+	}
+}
+
+// verified returns true if the password of the user was verified less than
+// the TTL ago.
+func (c *basicAuthCache) verified(user, password string, now time.Time) bool {
+	c.mu.Lock()
+	e, ok := c.entries[user]
+	c.mu.Unlock()
+	if !ok || !now.Before(e.expires) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(sum[:], e.sum[:]) == 1
+}
+
+// add remembers the verified password of the user.
+func (c *basicAuthCache) add(user, password string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[user] = basicAuthEntry{
+		sum:     sha256.Sum256([]byte(password)),
+		expires: now.Add(c.ttl),
+	}
+}
+
+// authenticateBasic wraps the handler to only allow requests with valid
+// HTTP Basic auth credentials. Users are given as a map of user names to
+// bcrypt password hashes. Health check endpoints do not require
+// credentials. Valid credentials are cached for a short time.
+func (s *HTTPAgent) authenticateBasic(users map[string]string, h http.Handler) http.Handler {
+	cache := newBasicAuthCache(basicAuthCacheTTL)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gofer", charset="UTF-8"`)
+			authFailed(r)
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+		hash, known := users[user]
+		now := time.Now()
+		if !known || !cache.verified(user, password, now) {
+			if !known {
+				hash = string(basicAuthDummyHash)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !known {
+				s.requestLog(r).
+					WithField("user", user).
+					WithField("remoteAddr", r.RemoteAddr).
+					WithField("path", r.URL.Path).
+					Warn("Invalid Basic auth credentials")
+				w.Header().Set("WWW-Authenticate", `Basic realm="gofer", charset="UTF-8"`)
+				authFailed(r)
+				http.Error(w, "invalid credentials", http.StatusUnauthorized)
+				return
+			}
+			cache.add(user, password, now)
+		}
+		s.requestLog(r).
+			WithField("user", user).
+			WithField("method", r.Method).
+			WithField("path", r.URL.Path).
+			WithField("remoteAddr", r.RemoteAddr).
+			Debug("Authenticated request")
+		if e := accessLogFromContext(r.Context()); e != nil {
+			e.user = user
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), basicAuthUserCtxKey{}, user)))
+	})
+}
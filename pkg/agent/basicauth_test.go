@@ -0,0 +1,118 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_AuthenticateBasic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	var user string
+	a := newTestAgent(t, &mocks.Provider{})
+	h := a.authenticateBasic(map[string]string{"relayer": string(hash)}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = basicAuthUser(r.Context())
+	}))
+
+	request := func(path, user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("/prices", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Basic")
+	assert.Equal(t, http.StatusUnauthorized, request("/prices", "relayer", "invalid").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("/prices", "unknown", "secret").Code)
+	assert.Equal(t, http.StatusOK, request("/healthz", "", "").Code)
+	assert.Equal(t, http.StatusOK, request("/prices", "relayer", "secret").Code)
+	assert.Equal(t, "relayer", user)
+}
+
+func TestHTTPAgent_BasicAuthConfig(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.basicAuth = map[string]string{"relayer": "plaintext"}
+	assert.Error(t, s.initServer())
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	s = newTestAgent(t, &mocks.Provider{})
+	s.basicAuth = map[string]string{"relayer": string(hash)}
+	s.jwt = &JWTConfig{HMACSecret: []byte("secret")}
+	assert.Error(t, s.initServer())
+}
+
+func TestBasicAuthCache(t *testing.T) {
+	now := time.Now()
+	c := newBasicAuthCache(time.Minute)
+	assert.False(t, c.verified("relayer", "secret", now))
+
+	c.add("relayer", "secret", now)
+	assert.True(t, c.verified("relayer", "secret", now.Add(30*time.Second)))
+	assert.False(t, c.verified("relayer", "invalid", now))
+	assert.False(t, c.verified("unknown", "secret", now))
+	assert.False(t, c.verified("relayer", "secret", now.Add(time.Minute)))
+}
+
+func TestHTTPAgent_AuthenticateBasic_RateLimit(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	s := newTestAgent(t, &mocks.Provider{})
+	s.basicAuth = map[string]string{"relayer": string(hash)}
+	s.rateLimiter = newRateLimiter(RateLimitConfig{RequestsPerSecond: 0.1, Burst: 2})
+	require.NoError(t, s.initServer())
+
+	request := func(addr, user, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/prices", nil)
+		req.RemoteAddr = addr
+		req.SetBasicAuth(user, password)
+		var accessErr *AccessError
+		if errors.As(s.Authorize(req), &accessErr) {
+			return accessErr.HTTPStatus()
+		}
+		return http.StatusOK
+	}
+
+	// Failed attempts are limited by the IP address, so passwords cannot
+	// be guessed at an unlimited rate.
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1000", "relayer", "guess1"))
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1000", "unknown", "guess2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1000", "relayer", "guess3"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1000", "relayer", "secret"))
+
+	// Authenticated clients are limited by their user.
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1000", "relayer", "secret"))
+	assert.Equal(t, http.StatusOK, request("10.0.0.3:1000", "relayer", "secret"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.4:1000", "relayer", "secret"))
+}
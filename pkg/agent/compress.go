@@ -0,0 +1,176 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressMinSize is the minimum size of a response body to be compressed.
+// Smaller responses are sent as is, because compression would not reduce
+// their size significantly.
+const compressMinSize = 1024
+
+// compress wraps the handler to compress responses with gzip or deflate,
+// depending on the Accept-Encoding header of the request. Streaming
+// endpoints are not compressed.
+func compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		path := trimAPIVersion(r.URL.Path)
+		if enc == "" || r.Method == http.MethodHead || path == "/ws" || path == "/stream" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: enc, status: http.StatusOK}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the preferred supported encoding from the
+// Accept-Encoding header, or an empty string if none is acceptable.
+func negotiateEncoding(header string) string {
+	var best string
+	var bestQ float64
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "deflate" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		// Prefer gzip if both encodings have the same quality.
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressResponseWriter buffers the beginning of the response to decide
+// whether it is large enough to be compressed.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	wroteHeader bool
+	buf         []byte
+	enc         io.WriteCloser
+	done        bool
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.done {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= compressMinSize {
+		if err := w.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher interface.
+func (w *compressResponseWriter) Flush() {
+	if w.enc == nil && !w.done {
+		if len(w.buf) > 0 {
+			_ = w.startCompression()
+		} else {
+			w.writeUncompressed()
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressResponseWriter) startCompression() error {
+	hdr := w.Header()
+	if hdr.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		w.writeUncompressed()
+		return nil
+	}
+	hdr.Set("Content-Encoding", w.encoding)
+	hdr.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	switch w.encoding {
+	case "gzip":
+		w.enc = gzip.NewWriter(w.ResponseWriter)
+	case "deflate":
+		fw, err := flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		w.enc = fw
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.enc.Write(buf)
+	return err
+}
+
+// writeUncompressed writes the buffered response without compression.
+// Further writes are passed to the underlying writer directly.
+func (w *compressResponseWriter) writeUncompressed() {
+	w.done = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *compressResponseWriter) close() {
+	switch {
+	case w.enc != nil:
+		_ = w.enc.Close()
+	case !w.done:
+		w.writeUncompressed()
+	}
+}
@@ -0,0 +1,117 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultQueueTimeout is the maximum time a request waits in the queue if
+// ConcurrencyLimitConfig.QueueTimeout is zero.
+const defaultQueueTimeout = 5 * time.Second
+
+// ConcurrencyLimitConfig is the configuration of the limit of requests that
+// are handled at the same time.
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight is the maximum number of requests handled at the same
+	// time.
+	MaxInFlight int
+	// MaxQueued is the maximum number of requests waiting for one of the
+	// in-flight requests to finish. Requests exceeding the queue are
+	// rejected immediately.
+	MaxQueued int
+	// QueueTimeout is the maximum time a request waits in the queue. If
+	// zero, defaultQueueTimeout is used.
+	QueueTimeout time.Duration
+}
+
+// concurrencyLimiter limits the number of requests handled at the same
+// time, with a bounded queue of waiting requests.
+type concurrencyLimiter struct {
+	slots     chan struct{}
+	queued    atomic.Int64
+	maxQueued int64
+	timeout   time.Duration
+}
+
+func newConcurrencyLimiter(cfg ConcurrencyLimitConfig) *concurrencyLimiter {
+	if cfg.MaxInFlight < 0 {
+		cfg.MaxInFlight = 0
+	}
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = defaultQueueTimeout
+	}
+	return &concurrencyLimiter{
+		slots:     make(chan struct{}, cfg.MaxInFlight),
+		maxQueued: int64(cfg.MaxQueued),
+		timeout:   cfg.QueueTimeout,
+	}
+}
+
+// acquire waits for a free slot. It returns false if the queue is full, the
+// queue timeout elapses or the request is canceled.
+func (l *concurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.queued.Add(1) > l.maxQueued {
+		l.queued.Add(-1)
+		return false
+	}
+	defer l.queued.Add(-1)
+	t := time.NewTimer(l.timeout)
+	defer t.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
+
+// limitConcurrency wraps the handler to limit the number of requests handled
+// at the same time. Requests that cannot be handled are rejected with 503
+// Service Unavailable. Health checks, long-lived streaming requests and
+// long-polling requests are not limited.
+func (s *HTTPAgent) limitConcurrency(l *concurrencyLimiter, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch trimAPIVersion(r.URL.Path) {
+		case "/healthz", "/readyz", "/ws", "/stream", "/prices/wait":
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !l.acquire(r) {
+			s.requestLog(r).
+				WithField("path", r.URL.Path).
+				Debug("Concurrency limit exceeded")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+		h.ServeHTTP(w, r)
+	})
+}
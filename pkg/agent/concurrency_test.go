@@ -0,0 +1,93 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_LimitConcurrency(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	l := newConcurrencyLimiter(ConcurrencyLimitConfig{
+		MaxInFlight:  1,
+		MaxQueued:    1,
+		QueueTimeout: time.Second,
+	})
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	h := s.limitConcurrency(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	codes := make(chan int, 2)
+	var wg sync.WaitGroup
+	serve := func() {
+		defer wg.Done()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/prices", nil))
+		codes <- rec.Code
+	}
+
+	// The first request is handled, the second one is queued.
+	wg.Add(2)
+	go serve()
+	<-started
+	go serve()
+	assert.Eventually(t, func() bool { return l.queued.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The queue is full, so the third request is rejected.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Health checks are not limited.
+	rec = httptest.NewRecorder()
+	s.limitConcurrency(l, http.HandlerFunc(s.handleHealthz)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+}
+
+func TestHTTPAgent_LimitConcurrency_QueueTimeout(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	l := newConcurrencyLimiter(ConcurrencyLimitConfig{
+		MaxInFlight:  1,
+		MaxQueued:    1,
+		QueueTimeout: 10 * time.Millisecond,
+	})
+	l.slots <- struct{}{}
+
+	rec := httptest.NewRecorder()
+	s.limitConcurrency(l, http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, int64(0), l.queued.Load())
+}
@@ -0,0 +1,101 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the configuration of Cross-Origin Resource Sharing.
+type CORSConfig struct {
+	// AllowedOrigins is a list of origins allowed to access the agent, e.g.
+	// "https://dashboard.example.com". The "*" value allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods is a list of methods allowed in cross-origin requests.
+	// If empty, GET and POST are allowed.
+	AllowedMethods []string
+	// AllowedHeaders is a list of headers allowed in cross-origin requests.
+	// If empty, Content-Type, Authorization and X-API-Key are allowed.
+	AllowedHeaders []string
+	// MaxAge describes how long the results of a preflight request can be
+	// cached. If zero, it defaults to 10 minutes.
+	MaxAge time.Duration
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", APIKeyHeader}
+	defaultCORSMaxAge  = 10 * time.Minute
+)
+
+// cors wraps the handler to add CORS headers to responses for allowed
+// origins and to answer preflight requests.
+func cors(cfg CORSConfig, h http.Handler) http.Handler {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	maxAge := cfg.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	anyOrigin := false
+	origins := make(map[string]struct{})
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+		}
+		origins[strings.ToLower(strings.TrimSuffix(o, "/"))] = struct{}{}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		_, allowed := origins[strings.ToLower(origin)]
+		if !allowed && !anyOrigin {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+		w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,148 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+// csvColumns are the columns which may be written by the CSV marshaller.
+var csvColumns = map[string]func(p *provider.Price) string{
+	"pair":      func(p *provider.Price) string { return p.Pair.String() },
+	"type":      func(p *provider.Price) string { return p.Type },
+	"base":      func(p *provider.Price) string { return p.Pair.Base },
+	"quote":     func(p *provider.Price) string { return p.Pair.Quote },
+	"price":     func(p *provider.Price) string { return csvFloat(p.Price) },
+	"bid":       func(p *provider.Price) string { return csvFloat(p.Bid) },
+	"ask":       func(p *provider.Price) string { return csvFloat(p.Ask) },
+	"volume":    func(p *provider.Price) string { return csvFloat(p.Volume24h) },
+	"timestamp": func(p *provider.Price) string { return p.Time.In(time.UTC).Format(time.RFC3339Nano) },
+	"sources":   func(p *provider.Price) string { return strconv.Itoa(PriceSources(p)) },
+	"error":     func(p *provider.Price) string { return p.Error },
+}
+
+// DefaultCSVColumns are the columns written by default.
+var DefaultCSVColumns = []string{"pair", "price", "bid", "ask", "volume", "timestamp", "sources"}
+
+// csvItem is a row, or a plain text line if it has no header.
+type csvItem struct {
+	writer io.Writer
+	header []string
+	record []string
+	text   string
+}
+
+// csvMarshaller implements the marshal.Marshaller interface. It writes
+// every price as a row with the configured columns, and every model as a
+// row with its pair. The header is written before the first row written to
+// every writer. Errors are written as plain text.
+//
+// Unlike the marshallers of the marshal package, it forgets items once they
+// are flushed, so it can be flushed repeatedly.
+type csvMarshaller struct {
+	columns []string
+	headers map[io.Writer]bool
+	items   []csvItem
+}
+
+// NewCSVMarshaller returns a marshaller which writes prices as CSV rows with
+// the given columns, or with the DefaultCSVColumns if none are given.
+func NewCSVMarshaller(columns []string) (marshal.Marshaller, error) {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	for _, c := range columns {
+		if _, ok := csvColumns[c]; !ok {
+			return nil, fmt.Errorf("unsupported CSV column: %s", c)
+		}
+	}
+	return &csvMarshaller{columns: columns, headers: make(map[io.Writer]bool)}, nil
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *csvMarshaller) Write(writer io.Writer, item interface{}) error {
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		record := make([]string, len(m.columns))
+		for i, c := range m.columns {
+			record[i] = csvColumns[c](typedItem)
+		}
+		m.items = append(m.items, csvItem{writer: writer, header: m.columns, record: record})
+	case *provider.Model:
+		m.items = append(m.items, csvItem{writer: writer, header: []string{"pair"}, record: []string{typedItem.Pair.String()}})
+	case error:
+		m.items = append(m.items, csvItem{writer: writer, text: fmt.Sprintf("Error: %s", typedItem.Error())})
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *csvMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	for _, i := range items {
+		if i.header == nil {
+			if _, err := io.WriteString(i.writer, i.text+"\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		w := csv.NewWriter(i.writer)
+		if !m.headers[i.writer] {
+			m.headers[i.writer] = true
+			if err := w.Write(i.header); err != nil {
+				return err
+			}
+		}
+		if err := w.Write(i.record); err != nil {
+			return err
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func csvFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// PriceSources returns the number of origin prices without errors from
+// which the price was calculated, or 1 for an origin price.
+func PriceSources(p *provider.Price) int {
+	if len(p.Prices) == 0 {
+		if p.Error != "" {
+			return 0
+		}
+		return 1
+	}
+	n := 0
+	for _, c := range p.Prices {
+		n += PriceSources(c)
+	}
+	return n
+}
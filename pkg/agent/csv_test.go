@@ -0,0 +1,85 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestCSVMarshaller(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := &provider.Price{
+		Type:      "aggregator",
+		Pair:      btcusd,
+		Price:     45242.13,
+		Bid:       45236.308,
+		Ask:       45239.98,
+		Volume24h: 0,
+		Time:      time.Unix(1700000000, 0),
+		Prices: []*provider.Price{
+			{Type: "origin", Pair: btcusd, Price: 45227.05},
+			{Type: "origin", Pair: btcusd, Price: 45242.13},
+			{Type: "origin", Pair: btcusd, Error: "timeout"},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	m, err := NewCSVMarshaller(nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Write(out, price))
+	require.NoError(t, m.Write(errOut, errors.New("failed")))
+	require.NoError(t, m.Flush())
+
+	// The header is written only once, and flushed rows are not written
+	// again.
+	require.NoError(t, m.Write(out, &provider.Price{Pair: provider.Pair{Base: "ETH", Quote: "USD"}, Price: 3501.5, Error: "a, b"}))
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, ""+
+		"pair,price,bid,ask,volume,timestamp,sources\n"+
+		"BTC/USD,45242.13,45236.308,45239.98,0,2023-11-14T22:13:20Z,2\n"+
+		"ETH/USD,3501.5,0,0,0,0001-01-01T00:00:00Z,0\n",
+		out.String(),
+	)
+	assert.Equal(t, "Error: failed\n", errOut.String())
+
+	out.Reset()
+	m, err = NewCSVMarshaller([]string{"pair", "error"})
+	require.NoError(t, err)
+	require.NoError(t, m.Write(out, &provider.Price{Pair: btcusd, Error: "a, b"}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "pair,error\nBTC/USD,\"a, b\"\n", out.String())
+
+	// Models are written with their pairs only.
+	out.Reset()
+	m, err = NewCSVMarshaller(nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Write(out, &provider.Model{Type: "median", Pair: btcusd}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "pair\nBTC/USD\n", out.String())
+
+	_, err = NewCSVMarshaller([]string{"pair", "foo"})
+	assert.Error(t, err)
+}
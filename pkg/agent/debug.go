@@ -0,0 +1,119 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+)
+
+const DebugLoggerTag = "DEBUG_SERVER"
+
+// DebugServerConfig is the configuration for the DebugServer.
+type DebugServerConfig struct {
+	Logger log.Logger
+	// Address is the address the debug server listens on. It should not be
+	// reachable from outside, because the profiling endpoints are not
+	// authenticated.
+	Address string
+}
+
+// DebugServer serves the net/http/pprof profiling endpoints under
+// /debug/pprof/. It is kept separate from the HTTPAgent so that profiling
+// is never exposed on the public API address.
+type DebugServer struct {
+	ctx    context.Context
+	waitCh chan error
+
+	address string
+	server  *http.Server
+	log     log.Logger
+}
+
+// NewDebugServer creates a new instance of the DebugServer.
+func NewDebugServer(cfg DebugServerConfig) (*DebugServer, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("debug server address must not be empty")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &DebugServer{
+		waitCh:  make(chan error),
+		address: cfg.Address,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: defaultReadTimeout,
+		},
+		log: cfg.Logger.WithField("tag", DebugLoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (s *DebugServer) Start(ctx context.Context) error {
+	if s.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	s.log.Debug("Starting")
+	s.ctx = ctx
+
+	ln, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.log.Infof("initializing debug server on %s", ln.Addr())
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.WithError(err).Error("Debug server crashed")
+		}
+	}()
+	go s.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (s *DebugServer) Wait() <-chan error {
+	return s.waitCh
+}
+
+func (s *DebugServer) contextCancelHandler() {
+	defer func() { close(s.waitCh) }()
+	defer s.log.Debug("Stopped")
+	<-s.ctx.Done()
+	// Profiles may take a long time to collect, so they are not waited for.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+	}
+}
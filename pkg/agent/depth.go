@@ -0,0 +1,73 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// fullDepth is the trace depth at which all nested prices are returned.
+const fullDepth = -1
+
+var errInvalidDepth = errors.New("depth must be a non-negative integer")
+
+// depthFromQuery parses the "depth" query parameter. If the parameter is
+// empty, the full trace is returned.
+func depthFromQuery(q url.Values) (int, error) {
+	v := q.Get("depth")
+	if v == "" {
+		return fullDepth, nil
+	}
+	depth, err := strconv.Atoi(v)
+	if err != nil || depth < 0 {
+		return 0, errInvalidDepth
+	}
+	return depth, nil
+}
+
+// depthFromBody returns the trace depth given in a request body. If the
+// depth is not given, the full trace is returned.
+func depthFromBody(depth *int) (int, error) {
+	if depth == nil {
+		return fullDepth, nil
+	}
+	if *depth < 0 {
+		return 0, errInvalidDepth
+	}
+	return *depth, nil
+}
+
+// truncatePrice returns a copy of the price with nested prices limited to
+// the given depth. At depth 0 only the top-level price is returned, at
+// depth 1 also the prices it was calculated from, and so on. A negative
+// depth returns the price unchanged.
+func truncatePrice(p *provider.Price, depth int) *provider.Price {
+	if depth < 0 || p == nil {
+		return p
+	}
+	c := *p
+	c.Prices = nil
+	if depth > 0 {
+		for _, n := range p.Prices {
+			c.Prices = append(c.Prices, truncatePrice(n, depth-1))
+		}
+	}
+	return &c
+}
@@ -0,0 +1,131 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+// testTrace returns a median price calculated from an indirect price, which
+// is in turn calculated from an origin price.
+func testTrace(pair provider.Pair) *provider.Price {
+	origin := testPrice(pair, 42)
+	origin.Type = "origin"
+	indirect := testPrice(pair, 42)
+	indirect.Type = "indirect"
+	indirect.Prices = []*provider.Price{origin}
+	median := testPrice(pair, 42)
+	median.Prices = []*provider.Price{indirect}
+	return median
+}
+
+func traceDepth(p jsonPrice) int {
+	depth := 0
+	for _, n := range p.Prices {
+		if d := traceDepth(n) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+func TestTruncatePrice(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := testTrace(btcusd)
+
+	assert.Same(t, price, truncatePrice(price, fullDepth))
+	assert.Empty(t, truncatePrice(price, 0).Prices)
+	one := truncatePrice(price, 1)
+	require.Len(t, one.Prices, 1)
+	assert.Equal(t, "indirect", one.Prices[0].Type)
+	assert.Empty(t, one.Prices[0].Prices)
+	assert.Equal(t, price, truncatePrice(price, 2))
+
+	// The original price is not modified.
+	require.Len(t, price.Prices, 1)
+	require.Len(t, price.Prices[0].Prices, 1)
+}
+
+func TestHTTPAgent_PriceDepth(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testTrace(btcusd)}, nil)
+	s := newTestAgent(t, p)
+
+	tests := []struct {
+		name  string
+		req   *http.Request
+		code  int
+		depth int
+	}{
+		{name: "full", req: httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil), code: http.StatusOK, depth: 2},
+		{name: "get-0", req: httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD&depth=0", nil), code: http.StatusOK, depth: 0},
+		{name: "get-1", req: httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD&depth=1", nil), code: http.StatusOK, depth: 1},
+		{name: "post-1", req: jsonRequest("/price", `{"pair":"BTC/USD","depth":1}`), code: http.StatusOK, depth: 1},
+		{name: "get-negative", req: httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD&depth=-1", nil), code: http.StatusBadRequest},
+		{name: "get-invalid", req: httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD&depth=all", nil), code: http.StatusBadRequest},
+		{name: "post-negative", req: jsonRequest("/price", `{"pair":"BTC/USD","depth":-1}`), code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handlePrice(rec, tt.req)
+			require.Equal(t, tt.code, rec.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var res jsonPrice
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, tt.depth, traceDepth(res))
+		})
+	}
+}
+
+func TestHTTPAgent_PricesDepth(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testTrace(btcusd)}, nil)
+	s := newTestAgent(t, p)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD&depth=0", nil),
+		jsonRequest("/prices", `{"pairs":["BTC/USD"],"depth":0}`),
+	} {
+		rec := httptest.NewRecorder()
+		s.handlePrices(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res []jsonPrice
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res, 1)
+		assert.Empty(t, res[0].Prices)
+	}
+}
+
+func jsonRequest(target, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
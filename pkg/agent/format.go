@@ -0,0 +1,113 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent/grpc"
+)
+
+// responseFormat is a response format that can be requested with the
+// Accept header.
+type responseFormat struct {
+	contentType string
+	// newMarshaller returns a new marshaller for the format. Marshallers
+	// buffer written items until flushed, so every response needs its own.
+	newMarshaller func() (marshal.Marshaller, error)
+}
+
+// responseFormats maps media types to the supported response formats.
+var responseFormats = map[string]responseFormat{
+	"application/json":     {contentType: "application/json", newMarshaller: marshallerFor(marshal.JSON)},
+	"application/x-ndjson": {contentType: "application/x-ndjson", newMarshaller: marshallerFor(marshal.NDJSON)},
+	"text/csv":             {contentType: "text/csv", newMarshaller: func() (marshal.Marshaller, error) { return NewCSVMarshaller(nil) }},
+	"text/plain":           {contentType: "text/plain; charset=utf-8", newMarshaller: marshallerFor(marshal.Trace)},
+	"application/msgpack":  msgpackFormat,
+	// Other media types used for MessagePack.
+	"application/x-msgpack":   msgpackFormat,
+	"application/vnd.msgpack": msgpackFormat,
+	"application/x-protobuf":  protobufFormat,
+	"application/protobuf":    protobufFormat,
+}
+
+var msgpackFormat = responseFormat{
+	contentType: "application/msgpack",
+	newMarshaller: func() (marshal.Marshaller, error) {
+		return NewMsgpackMarshaller(), nil
+	},
+}
+
+// protobufFormat writes length-delimited Price messages, as defined in the
+// schema served at /gofer.proto.
+var protobufFormat = responseFormat{
+	contentType: "application/x-protobuf",
+	newMarshaller: func() (marshal.Marshaller, error) {
+		return grpc.NewProtobufMarshaller(), nil
+	},
+}
+
+func marshallerFor(format marshal.FormatType) func() (marshal.Marshaller, error) {
+	return func() (marshal.Marshaller, error) {
+		return marshal.NewMarshal(format)
+	}
+}
+
+// negotiateFormat returns the response format preferred by the Accept
+// header. If the header is empty or accepts any media type, nil is
+// returned and the default JSON format should be used. The ok result is
+// false if none of the accepted media types is supported.
+func negotiateFormat(header string) (format *responseFormat, ok bool) {
+	if strings.TrimSpace(header) == "" {
+		return nil, true
+	}
+	var bestQ float64
+	var wildcard bool
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= 0 {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "application/*" {
+			if q > bestQ {
+				format, bestQ, wildcard = nil, q, true
+			}
+			continue
+		}
+		// Versioned vendor media types, e.g. application/vnd.gofer.v1+json,
+		// request JSON.
+		if strings.HasPrefix(mediaType, "application/vnd.gofer.") && strings.HasSuffix(mediaType, "+json") {
+			mediaType = "application/json"
+		}
+		f, supported := responseFormats[mediaType]
+		if supported && q > bestQ {
+			format, bestQ, wildcard = &f, q, false
+		}
+	}
+	return format, format != nil || wildcard
+}
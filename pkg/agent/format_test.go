@@ -0,0 +1,132 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+		ok          bool
+	}{
+		{accept: "", ok: true},
+		{accept: "*/*", ok: true},
+		{accept: "application/json", contentType: "application/json", ok: true},
+		{accept: "application/vnd.gofer.v1+json", contentType: "application/json", ok: true},
+		{accept: "text/csv", contentType: "text/csv", ok: true},
+		{accept: "application/x-ndjson", contentType: "application/x-ndjson", ok: true},
+		{accept: "text/plain", contentType: "text/plain; charset=utf-8", ok: true},
+		{accept: "application/msgpack", contentType: "application/msgpack", ok: true},
+		{accept: "application/x-msgpack", contentType: "application/msgpack", ok: true},
+		{accept: "application/x-protobuf", contentType: "application/x-protobuf", ok: true},
+		{accept: "application/protobuf", contentType: "application/x-protobuf", ok: true},
+		{accept: "text/csv;q=0.5, application/x-ndjson", contentType: "application/x-ndjson", ok: true},
+		{accept: "image/png, */*;q=0.1", ok: true},
+		{accept: "image/png", ok: false},
+		{accept: "text/csv;q=0", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			format, ok := negotiateFormat(tt.accept)
+			assert.Equal(t, tt.ok, ok)
+			if tt.contentType == "" {
+				assert.Nil(t, format)
+				return
+			}
+			require.NotNil(t, format)
+			assert.Equal(t, tt.contentType, format.contentType)
+		})
+	}
+}
+
+func TestHTTPAgent_GetPrices_CSV(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42.5)}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrices(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	// The columns are the same as those of the CSV format of the CLI.
+	assert.Equal(t, strings.Join([]string{
+		"pair,price,bid,ask,volume,timestamp,sources",
+		"BTC/USD,42.5,0,0,0,2023-11-14T22:13:20Z,1",
+		"",
+	}, "\n"), rec.Body.String())
+}
+
+func TestHTTPAgent_GetPrice_NDJSON(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrice(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"))
+	assert.Contains(t, rec.Body.String(), `"price":42`)
+}
+
+func TestHTTPAgent_GetPrice_Protobuf(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrice(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+	var price pb.Price
+	require.NoError(t, protodelim.UnmarshalFrom(bufio.NewReader(rec.Body), &price))
+	assert.Equal(t, "BTC", price.Pair.Base)
+	assert.Equal(t, 42.0, price.Price)
+}
+
+func TestHTTPAgent_NotAcceptable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD", nil)
+	req.Header.Set("Accept", "image/png")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, &mocks.Provider{}).handlePrices(rec, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+}
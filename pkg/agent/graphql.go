@@ -0,0 +1,284 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// graphqlRequest is the request body of a POST request to the GraphQL
+// endpoint.
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type graphqlParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func graphqlParams(m map[string]string) []graphqlParam {
+	params := make([]graphqlParam, 0, len(m))
+	for k, v := range m {
+		params = append(params, graphqlParam{Name: k, Value: v})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+// newGraphQLSchema builds the GraphQL schema used by the /graphql endpoint.
+func (s *HTTPAgent) newGraphQLSchema() (graphql.Schema, error) {
+	paramType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Param",
+		Fields: graphql.Fields{
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	priceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Price",
+		Description: "Price for a single pair, including prices it was calculated from.",
+		Fields: graphql.Fields{
+			"type":  priceField(graphql.String, func(p *provider.Price) any { return p.Type }),
+			"pair":  priceField(graphql.String, func(p *provider.Price) any { return p.Pair.String() }),
+			"base":  priceField(graphql.String, func(p *provider.Price) any { return p.Pair.Base }),
+			"quote": priceField(graphql.String, func(p *provider.Price) any { return p.Pair.Quote }),
+			"price": priceField(graphql.Float, func(p *provider.Price) any { return p.Price }),
+			"bid":   priceField(graphql.Float, func(p *provider.Price) any { return p.Bid }),
+			"ask":   priceField(graphql.Float, func(p *provider.Price) any { return p.Ask }),
+			"vol24h": priceField(graphql.Float, func(p *provider.Price) any {
+				return p.Volume24h
+			}),
+			"ts": priceField(graphql.String, func(p *provider.Price) any {
+				return p.Time.In(time.UTC).Format(time.RFC3339Nano)
+			}),
+			"params": priceField(graphql.NewList(paramType), func(p *provider.Price) any {
+				return graphqlParams(p.Parameters)
+			}),
+			"error": priceField(graphql.String, func(p *provider.Price) any {
+				if p.Error == "" {
+					return nil
+				}
+				return p.Error
+			}),
+		},
+	})
+	priceType.AddFieldConfig("prices", priceField(graphql.NewList(priceType), func(p *provider.Price) any {
+		return p.Prices
+	}))
+	modelType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Model",
+		Description: "Price model describing how the price for a pair is calculated.",
+		Fields: graphql.Fields{
+			"type":  modelField(graphql.String, func(m *provider.Model) any { return m.Type }),
+			"pair":  modelField(graphql.String, func(m *provider.Model) any { return m.Pair.String() }),
+			"base":  modelField(graphql.String, func(m *provider.Model) any { return m.Pair.Base }),
+			"quote": modelField(graphql.String, func(m *provider.Model) any { return m.Pair.Quote }),
+			"params": modelField(graphql.NewList(paramType), func(m *provider.Model) any {
+				return graphqlParams(m.Parameters)
+			}),
+		},
+	})
+	modelType.AddFieldConfig("models", modelField(graphql.NewList(modelType), func(m *provider.Model) any {
+		return m.Models
+	}))
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"price": &graphql.Field{
+				Type: priceType,
+				Args: graphql.FieldConfigArgument{
+					"pair": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(rp graphql.ResolveParams) (any, error) {
+					pair, err := provider.NewPair(rp.Args["pair"].(string))
+					if err != nil {
+						return nil, err
+					}
+					prices, err := s.checkedPrices(pair)
+					if err != nil {
+						return nil, err
+					}
+					return prices[pair], nil
+				},
+			},
+			"prices": &graphql.Field{
+				Type: graphql.NewList(priceType),
+				Args: graphql.FieldConfigArgument{
+					"pairs": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+				},
+				Resolve: func(rp graphql.ResolveParams) (any, error) {
+					pairs, err := graphqlPairsArg(rp.Args["pairs"])
+					if err != nil {
+						return nil, err
+					}
+					prices, err := s.checkedPrices(pairs...)
+					if err != nil {
+						return nil, err
+					}
+					res := make([]*provider.Price, 0, len(prices))
+					for _, p := range prices {
+						res = append(res, p)
+					}
+					sort.Slice(res, func(i, j int) bool { return res[i].Pair.String() < res[j].Pair.String() })
+					return res, nil
+				},
+			},
+			"pairs": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+				Resolve: func(rp graphql.ResolveParams) (any, error) {
+					pairs, err := s.priceProvider.Pairs()
+					if err != nil {
+						return nil, err
+					}
+					res := make([]string, 0, len(pairs))
+					for _, p := range pairs {
+						res = append(res, p.String())
+					}
+					sort.Strings(res)
+					return res, nil
+				},
+			},
+			"models": &graphql.Field{
+				Type: graphql.NewList(modelType),
+				Args: graphql.FieldConfigArgument{
+					"pairs": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+				},
+				Resolve: func(rp graphql.ResolveParams) (any, error) {
+					pairs, err := graphqlPairsArg(rp.Args["pairs"])
+					if err != nil {
+						return nil, err
+					}
+					models, err := s.priceProvider.Models(pairs...)
+					if err != nil {
+						return nil, err
+					}
+					res := make([]*provider.Model, 0, len(models))
+					for _, m := range models {
+						res = append(res, m)
+					}
+					sort.Slice(res, func(i, j int) bool { return res[i].Pair.String() < res[j].Pair.String() })
+					return res, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// handleGraphQL executes GraphQL queries. Queries may be sent either as
+// a JSON body of a POST request or in the "query" parameter of a GET
+// request.
+func (s *HTTPAgent) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != "application/json" {
+			msg := "Content-Type header is not application/json"
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), bodyErrorStatus(err))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	res := graphql.Do(graphql.Params{
+		Schema:         s.graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        r.Context(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.requestLog(r).Errorf("failed to marshal GraphQL response: %v", err)
+	}
+}
+
+// checkedPrices returns prices for the given pairs after they have been
+// verified by the price hook.
+func (s *HTTPAgent) checkedPrices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	prices, err := s.priceProvider.Prices(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.priceHook.Check(prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func graphqlPairsArg(arg any) ([]provider.Pair, error) {
+	list, _ := arg.([]any)
+	var pairs []provider.Pair
+	for _, v := range list {
+		pair, err := provider.NewPair(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+func priceField(t graphql.Output, fn func(*provider.Price) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			if p, ok := rp.Source.(*provider.Price); ok {
+				return fn(p), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func modelField(t graphql.Output, fn func(*provider.Model) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			if m, ok := rp.Source.(*provider.Model); ok {
+				return fn(m), nil
+			}
+			return nil, nil
+		},
+	}
+}
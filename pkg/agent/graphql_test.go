@@ -0,0 +1,79 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_GraphQL(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := testPrice(btcusd, 42)
+	price.Prices = []*provider.Price{testPrice(btcusd, 41)}
+	price.Prices[0].Parameters = map[string]string{"origin": "kraken"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: price}, nil)
+
+	a := newTestAgent(t, p)
+	schema, err := a.newGraphQLSchema()
+	require.NoError(t, err)
+	a.graphqlSchema = schema
+
+	body := `{"query":"query($p: String!) { price(pair: $p) { pair price prices { price params { name value } } } }","variables":{"p":"BTC/USD"}}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	a.handleGraphQL(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"price":{
+		"pair":"BTC/USD",
+		"price":42,
+		"prices":[{"price":41,"params":[{"name":"origin","value":"kraken"}]}]
+	}}}`, rec.Body.String())
+}
+
+func TestHTTPAgent_GraphQL_Get(t *testing.T) {
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{{Base: "ETH", Quote: "USD"}, {Base: "BTC", Quote: "USD"}}, nil)
+
+	a := newTestAgent(t, p)
+	schema, err := a.newGraphQLSchema()
+	require.NoError(t, err)
+	a.graphqlSchema = schema
+
+	rec := httptest.NewRecorder()
+	a.handleGraphQL(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ pairs }"), nil))
+
+	var res struct {
+		Data struct {
+			Pairs []string `json:"pairs"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []string{"BTC/USD", "ETH/USD"}, res.Data.Pairs)
+}
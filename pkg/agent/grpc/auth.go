@@ -0,0 +1,125 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+// methodPaths maps the methods of the Gofer service to the paths of the
+// equivalent HTTP endpoints, which are used to authorize calls.
+var methodPaths = map[string]string{
+	pb.Gofer_GetPrice_FullMethodName:        "/price",
+	pb.Gofer_GetPrices_FullMethodName:       "/prices",
+	pb.Gofer_GetModels_FullMethodName:       "/models",
+	pb.Gofer_SubscribePrices_FullMethodName: "/stream",
+}
+
+func (s *Server) authorizeUnary(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := s.authorizeCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authorizeStream(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := s.authorizeCall(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorizeCall calls the Authorize function of the server with a request
+// for the HTTP endpoint equivalent to the called method.
+func (s *Server) authorizeCall(ctx context.Context, method string) error {
+	if s.authorize == nil {
+		return nil
+	}
+	path, ok := methodPaths[method]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "method %s is not allowed", method)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := info.State
+			r.TLS = &state
+		}
+	}
+	if err := s.authorize(r); err != nil {
+		s.log.
+			WithError(err).
+			WithField("method", method).
+			WithField("remoteAddr", r.RemoteAddr).
+			Debug("Call rejected")
+		return status.Error(accessCode(err), err.Error())
+	}
+	return nil
+}
+
+// accessCode returns the gRPC status code equivalent to the HTTP status
+// of an access error.
+func accessCode(err error) codes.Code {
+	var statusErr interface{ HTTPStatus() int }
+	if !errors.As(err, &statusErr) {
+		return codes.Internal
+	}
+	switch statusErr.HTTPStatus() {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Unavailable
+	}
+}
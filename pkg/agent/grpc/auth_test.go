@@ -0,0 +1,103 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+type accessError int
+
+func (e accessError) Error() string   { return http.StatusText(int(e)) }
+func (e accessError) HTTPStatus() int { return int(e) }
+
+func TestServer_Authorize(t *testing.T) {
+	var got *http.Request
+	s, err := NewServer(ServerConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     nopHook{},
+		Authorize: func(r *http.Request) error {
+			got = r
+			if r.Header.Get("X-API-Key") != "secret" {
+				return accessError(http.StatusUnauthorized)
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}})
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: pb.Gofer_GetPrices_FullMethodName}
+
+	_, err = s.authorizeUnary(ctx, nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NotNil(t, got)
+	assert.Equal(t, "/prices", got.URL.Path)
+	assert.Equal(t, "10.0.0.1:1234", got.RemoteAddr)
+
+	res, err := s.authorizeUnary(metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "secret")), nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res)
+
+	// Unknown methods are never allowed.
+	_, err = s.authorizeUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/unknown/Method"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_Authorize_Stream(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     nopHook{},
+		Authorize: func(r *http.Request) error {
+			assert.Equal(t, "/stream", r.URL.Path)
+			return accessError(http.StatusTooManyRequests)
+		},
+	})
+	require.NoError(t, err)
+	err = s.authorizeStream(nil, &testServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{
+		FullMethod: pb.Gofer_SubscribePrices_FullMethodName,
+	}, func(any, grpc.ServerStream) error { return nil })
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAccessCode(t *testing.T) {
+	assert.Equal(t, codes.PermissionDenied, accessCode(accessError(http.StatusForbidden)))
+	assert.Equal(t, codes.Internal, accessCode(errors.New("failed")))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
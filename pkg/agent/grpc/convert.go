@@ -0,0 +1,88 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+func pairToProto(p provider.Pair) *pb.Pair {
+	return &pb.Pair{Base: p.Base, Quote: p.Quote}
+}
+
+func pairsFromProto(ps []*pb.Pair) ([]provider.Pair, error) {
+	var pairs []provider.Pair
+	for _, p := range ps {
+		pair, err := pairFromProto(p)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+func pairFromProto(p *pb.Pair) (provider.Pair, error) {
+	// Use the same normalization as provider.NewPair.
+	var pair provider.Pair
+	if err := pair.UnmarshalText([]byte(p.GetBase() + "/" + p.GetQuote())); err != nil {
+		return provider.Pair{}, err
+	}
+	if pair.Base == "" || pair.Quote == "" {
+		return provider.Pair{}, fmt.Errorf("invalid pair: %s, expected BASE/QUOTE", pairString(p))
+	}
+	return pair, nil
+}
+
+// PriceToProto converts a provider.Price to its protobuf representation.
+func PriceToProto(p *provider.Price) *pb.Price {
+	var prices []*pb.Price
+	for _, c := range p.Prices {
+		prices = append(prices, PriceToProto(c))
+	}
+	return &pb.Price{
+		Type:       p.Type,
+		Pair:       pairToProto(p.Pair),
+		Price:      p.Price,
+		Bid:        p.Bid,
+		Ask:        p.Ask,
+		Volume24H:  p.Volume24h,
+		Time:       timestamppb.New(p.Time),
+		Parameters: p.Parameters,
+		Prices:     prices,
+		Error:      p.Error,
+	}
+}
+
+// ModelToProto converts a provider.Model to its protobuf representation.
+func ModelToProto(m *provider.Model) *pb.Model {
+	var models []*pb.Model
+	for _, c := range m.Models {
+		models = append(models, ModelToProto(c))
+	}
+	return &pb.Model{
+		Type:       m.Type,
+		Parameters: m.Parameters,
+		Pair:       pairToProto(m.Pair),
+		Models:     models,
+	}
+}
@@ -0,0 +1,94 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+// Schema is the protobuf schema of the messages written by the protobuf
+// marshaller and used by the gRPC service.
+//
+//go:embed pb/gofer.proto
+var Schema []byte
+
+type protobufItem struct {
+	writer io.Writer
+	data   []byte
+}
+
+// protobufMarshaller implements the marshal.Marshaller interface. It writes
+// every item as a length-delimited protobuf message: the size of the
+// message as a varint followed by the message itself. Prices are written as
+// Price messages, with the prices used to calculate them in the prices
+// field, models as Model messages, and errors as Price messages with only
+// the error field set.
+//
+// Items are forgotten once they are flushed, so the marshaller can be
+// flushed repeatedly.
+type protobufMarshaller struct {
+	items []protobufItem
+}
+
+// NewProtobufMarshaller returns a marshaller which writes items as
+// length-delimited protobuf messages.
+func NewProtobufMarshaller() marshal.Marshaller {
+	return &protobufMarshaller{}
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *protobufMarshaller) Write(writer io.Writer, item interface{}) error {
+	var msg proto.Message
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		msg = PriceToProto(typedItem)
+	case *provider.Model:
+		msg = ModelToProto(typedItem)
+	case error:
+		msg = &pb.Price{Error: typedItem.Error()}
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	var buf bytes.Buffer
+	if _, err := protodelim.MarshalTo(&buf, msg); err != nil {
+		return err
+	}
+	m.items = append(m.items, protobufItem{writer: writer, data: buf.Bytes()})
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *protobufMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	for _, i := range items {
+		if _, err := i.writer.Write(i.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
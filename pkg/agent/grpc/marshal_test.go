@@ -0,0 +1,90 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+func TestProtobufMarshaller(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	m := NewProtobufMarshaller()
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf, &provider.Price{
+		Type:  "median",
+		Pair:  btcusd,
+		Price: 42,
+		Time:  ts,
+		Prices: []*provider.Price{
+			{Type: "origin", Pair: btcusd, Price: 42, Time: ts, Parameters: map[string]string{"origin": "a"}},
+		},
+	}))
+	require.NoError(t, m.Write(&buf, errors.New("failed")))
+	assert.Zero(t, buf.Len())
+	require.NoError(t, m.Flush())
+
+	r := bufio.NewReader(&buf)
+	var price, failed pb.Price
+	require.NoError(t, protodelim.UnmarshalFrom(r, &price))
+	require.NoError(t, protodelim.UnmarshalFrom(r, &failed))
+	assert.ErrorIs(t, protodelim.UnmarshalFrom(r, &pb.Price{}), io.EOF)
+
+	assert.Equal(t, "median", price.Type)
+	assert.Equal(t, "BTC", price.Pair.Base)
+	assert.Equal(t, 42.0, price.Price)
+	assert.Equal(t, ts.Unix(), price.Time.AsTime().Unix())
+	require.Len(t, price.Prices, 1)
+	assert.Equal(t, "a", price.Prices[0].Parameters["origin"])
+	assert.Equal(t, "failed", failed.Error)
+
+	// Flushed items are not written again.
+	require.NoError(t, m.Flush())
+	assert.Zero(t, buf.Len())
+}
+
+func TestProtobufMarshaller_Model(t *testing.T) {
+	m := NewProtobufMarshaller()
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf, &provider.Model{
+		Type:   "median",
+		Pair:   btcusd,
+		Models: []*provider.Model{{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "a"}}},
+	}))
+	require.NoError(t, m.Flush())
+
+	var model pb.Model
+	require.NoError(t, protodelim.UnmarshalFrom(bufio.NewReader(&buf), &model))
+	assert.Equal(t, "median", model.Type)
+	require.Len(t, model.Models, 1)
+	assert.Equal(t, "a", model.Models[0].Parameters["origin"])
+}
+
+func TestSchema(t *testing.T) {
+	assert.Contains(t, string(Schema), "message Price {")
+}
@@ -0,0 +1,248 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: gofer.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Gofer_GetPrice_FullMethodName        = "/gofer.v1.Gofer/GetPrice"
+	Gofer_GetPrices_FullMethodName       = "/gofer.v1.Gofer/GetPrices"
+	Gofer_GetModels_FullMethodName       = "/gofer.v1.Gofer/GetModels"
+	Gofer_SubscribePrices_FullMethodName = "/gofer.v1.Gofer/SubscribePrices"
+)
+
+// GoferClient is the client API for Gofer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GoferClient interface {
+	GetPrice(ctx context.Context, in *GetPriceRequest, opts ...grpc.CallOption) (*Price, error)
+	GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error)
+	GetModels(ctx context.Context, in *GetModelsRequest, opts ...grpc.CallOption) (*GetModelsResponse, error)
+	SubscribePrices(ctx context.Context, in *SubscribePricesRequest, opts ...grpc.CallOption) (Gofer_SubscribePricesClient, error)
+}
+
+type goferClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGoferClient(cc grpc.ClientConnInterface) GoferClient {
+	return &goferClient{cc}
+}
+
+func (c *goferClient) GetPrice(ctx context.Context, in *GetPriceRequest, opts ...grpc.CallOption) (*Price, error) {
+	out := new(Price)
+	err := c.cc.Invoke(ctx, Gofer_GetPrice_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goferClient) GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error) {
+	out := new(GetPricesResponse)
+	err := c.cc.Invoke(ctx, Gofer_GetPrices_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goferClient) GetModels(ctx context.Context, in *GetModelsRequest, opts ...grpc.CallOption) (*GetModelsResponse, error) {
+	out := new(GetModelsResponse)
+	err := c.cc.Invoke(ctx, Gofer_GetModels_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goferClient) SubscribePrices(ctx context.Context, in *SubscribePricesRequest, opts ...grpc.CallOption) (Gofer_SubscribePricesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gofer_ServiceDesc.Streams[0], Gofer_SubscribePrices_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &goferSubscribePricesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gofer_SubscribePricesClient interface {
+	Recv() (*Price, error)
+	grpc.ClientStream
+}
+
+type goferSubscribePricesClient struct {
+	grpc.ClientStream
+}
+
+func (x *goferSubscribePricesClient) Recv() (*Price, error) {
+	m := new(Price)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GoferServer is the server API for Gofer service.
+// All implementations must embed UnimplementedGoferServer
+// for forward compatibility
+type GoferServer interface {
+	GetPrice(context.Context, *GetPriceRequest) (*Price, error)
+	GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error)
+	GetModels(context.Context, *GetModelsRequest) (*GetModelsResponse, error)
+	SubscribePrices(*SubscribePricesRequest, Gofer_SubscribePricesServer) error
+	mustEmbedUnimplementedGoferServer()
+}
+
+// UnimplementedGoferServer must be embedded to have forward compatible implementations.
+type UnimplementedGoferServer struct {
+}
+
+func (UnimplementedGoferServer) GetPrice(context.Context, *GetPriceRequest) (*Price, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrice not implemented")
+}
+func (UnimplementedGoferServer) GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrices not implemented")
+}
+func (UnimplementedGoferServer) GetModels(context.Context, *GetModelsRequest) (*GetModelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetModels not implemented")
+}
+func (UnimplementedGoferServer) SubscribePrices(*SubscribePricesRequest, Gofer_SubscribePricesServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePrices not implemented")
+}
+func (UnimplementedGoferServer) mustEmbedUnimplementedGoferServer() {}
+
+// UnsafeGoferServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GoferServer will
+// result in compilation errors.
+type UnsafeGoferServer interface {
+	mustEmbedUnimplementedGoferServer()
+}
+
+func RegisterGoferServer(s grpc.ServiceRegistrar, srv GoferServer) {
+	s.RegisterService(&Gofer_ServiceDesc, srv)
+}
+
+func _Gofer_GetPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoferServer).GetPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gofer_GetPrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoferServer).GetPrice(ctx, req.(*GetPriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gofer_GetPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoferServer).GetPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gofer_GetPrices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoferServer).GetPrices(ctx, req.(*GetPricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gofer_GetModels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetModelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoferServer).GetModels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gofer_GetModels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoferServer).GetModels(ctx, req.(*GetModelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gofer_SubscribePrices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribePricesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoferServer).SubscribePrices(m, &goferSubscribePricesServer{stream})
+}
+
+type Gofer_SubscribePricesServer interface {
+	Send(*Price) error
+	grpc.ServerStream
+}
+
+type goferSubscribePricesServer struct {
+	grpc.ServerStream
+}
+
+func (x *goferSubscribePricesServer) Send(m *Price) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Gofer_ServiceDesc is the grpc.ServiceDesc for Gofer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gofer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gofer.v1.Gofer",
+	HandlerType: (*GoferServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPrice",
+			Handler:    _Gofer_GetPrice_Handler,
+		},
+		{
+			MethodName: "GetPrices",
+			Handler:    _Gofer_GetPrices_Handler,
+		},
+		{
+			MethodName: "GetModels",
+			Handler:    _Gofer_GetModels_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribePrices",
+			Handler:       _Gofer_SubscribePrices_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gofer.proto",
+}
@@ -0,0 +1,308 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package grpc implements a gRPC server exposing the Gofer price provider.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+const LoggerTag = "GRPC_AGENT"
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// ServerConfig is the configuration for the Server.
+type ServerConfig struct {
+	PriceProvider provider.Provider
+	PriceHook     provider.PriceHook
+	Logger        log.Logger
+	// Address is the address the gRPC server listens on.
+	Address string
+	// PriceStream, if set, is used by SubscribePrices to receive price
+	// updates. It must call send for every update of the given pairs until
+	// the context is canceled or send returns an error. If nil,
+	// SubscribePrices is not available.
+	PriceStream func(ctx context.Context, pairs []provider.Pair, send func(*provider.Price) error) error
+	// ShutdownTimeout is the time given to in-flight calls to finish when
+	// the server is stopped. If zero, defaultShutdownTimeout is used.
+	ShutdownTimeout time.Duration
+	// TLSConfig is the TLS configuration of the server. If nil, TLS is
+	// disabled.
+	TLSConfig *tls.Config
+	// Authorize, if set, is called before every call with a request for the
+	// equivalent HTTP endpoint, with the metadata of the call as headers
+	// and the address and TLS state of the client. Calls for which it
+	// returns an error are rejected. An error with the HTTPStatus method is
+	// mapped to the equivalent gRPC status code.
+	Authorize func(r *http.Request) error
+}
+
+// Server is a gRPC server which implements the Gofer service.
+type Server struct {
+	pb.UnimplementedGoferServer
+
+	ctx    context.Context
+	waitCh chan error
+
+	address         string
+	server          *grpc.Server
+	priceProvider   provider.Provider
+	priceHook       provider.PriceHook
+	priceStream     func(context.Context, []provider.Pair, func(*provider.Price) error) error
+	shutdownTimeout time.Duration
+	authorize       func(r *http.Request) error
+	log             log.Logger
+}
+
+// NewServer creates a new instance of the Server.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if cfg.PriceProvider == nil {
+		return nil, errors.New("price provider must not be nil")
+	}
+	if cfg.PriceHook == nil {
+		return nil, errors.New("price hook must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	s := &Server{
+		waitCh:          make(chan error),
+		address:         cfg.Address,
+		priceProvider:   cfg.PriceProvider,
+		priceHook:       cfg.PriceHook,
+		priceStream:     cfg.PriceStream,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		authorize:       cfg.Authorize,
+		log:             cfg.Logger.WithField("tag", LoggerTag),
+	}
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.authorizeUnary),
+		grpc.StreamInterceptor(s.authorizeStream),
+	}
+	if cfg.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLSConfig)))
+	}
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterGoferServer(s.server, s)
+	return s, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (s *Server) Start(ctx context.Context) error {
+	if s.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	s.log.Debug("Starting")
+	s.ctx = ctx
+
+	ln, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.log.Infof("initializing gRPC server on %s", ln.Addr())
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.log.WithError(err).Error("gRPC server crashed")
+		}
+	}()
+	go s.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (s *Server) Wait() <-chan error {
+	return s.waitCh
+}
+
+// GetPrice implements the pb.GoferServer interface.
+func (s *Server) GetPrice(_ context.Context, req *pb.GetPriceRequest) (*pb.Price, error) {
+	if req.GetPair() == nil {
+		return nil, status.Error(codes.InvalidArgument, "pair must not be empty")
+	}
+	pair, err := pairFromProto(req.GetPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	prices, err := s.prices(pair)
+	if err != nil {
+		return nil, err
+	}
+	price, ok := prices[pair]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "price for %s not found", pair)
+	}
+	return PriceToProto(price), nil
+}
+
+// GetPrices implements the pb.GoferServer interface.
+func (s *Server) GetPrices(_ context.Context, req *pb.GetPricesRequest) (*pb.GetPricesResponse, error) {
+	pairs, err := pairsFromProto(req.GetPairs())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	prices, err := s.prices(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetPricesResponse{}
+	for _, price := range sortedPrices(prices) {
+		res.Prices = append(res.Prices, PriceToProto(price))
+	}
+	return res, nil
+}
+
+// GetModels implements the pb.GoferServer interface.
+func (s *Server) GetModels(_ context.Context, req *pb.GetModelsRequest) (*pb.GetModelsResponse, error) {
+	pairs, err := pairsFromProto(req.GetPairs())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	models, err := s.priceProvider.Models(pairs...)
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		s.log.Errorf("failed to get models: %v", err)
+		return nil, status.Error(codes.Unavailable, "failed to get models")
+	}
+	res := &pb.GetModelsResponse{}
+	for _, model := range models {
+		res.Models = append(res.Models, ModelToProto(model))
+	}
+	sort.Slice(res.Models, func(i, j int) bool {
+		return pairString(res.Models[i].Pair) < pairString(res.Models[j].Pair)
+	})
+	return res, nil
+}
+
+// SubscribePrices implements the pb.GoferServer interface. Prices are sent
+// from the price stream shared with the WebSocket and SSE endpoints of the
+// agent, starting with the last known prices.
+func (s *Server) SubscribePrices(req *pb.SubscribePricesRequest, stream pb.Gofer_SubscribePricesServer) error {
+	if s.priceStream == nil {
+		return status.Error(codes.Unimplemented, "price streaming is not available")
+	}
+	pairs, err := pairsFromProto(req.GetPairs())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(pairs) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one pair is required")
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	var sendErr error
+	err = s.priceStream(ctx, pairs, func(price *provider.Price) error {
+		sendErr = stream.Send(PriceToProto(price))
+		return sendErr
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case s.ctx.Err() != nil || err != nil:
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	return nil
+}
+
+func (s *Server) prices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	prices, err := s.priceProvider.Prices(pairs...)
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		s.log.Errorf("failed to get prices: %v", err)
+		return nil, status.Error(codes.Unavailable, "failed to get prices")
+	}
+	if err := s.priceHook.Check(prices); err != nil {
+		s.log.Errorf("failed to check prices: %v", err)
+		return nil, status.Error(codes.Internal, "failed to check prices")
+	}
+	return prices, nil
+}
+
+// contextCancelHandler gracefully stops the server when the context is
+// canceled. In-flight calls are given the shutdown timeout to finish, after
+// which remaining connections are closed.
+func (s *Server) contextCancelHandler() {
+	defer func() { close(s.waitCh) }()
+	<-s.ctx.Done()
+	s.log.Debugf("Shutting down, waiting up to %s for in-flight calls", s.shutdownTimeout)
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	t := time.NewTimer(s.shutdownTimeout)
+	defer t.Stop()
+	select {
+	case <-stopped:
+		s.log.Debug("Stopped")
+	case <-t.C:
+		s.server.Stop()
+		<-stopped
+		s.log.Warn("Stopped before all in-flight calls finished")
+	}
+}
+
+func sortedPrices(prices map[provider.Pair]*provider.Price) []*provider.Price {
+	res := make([]*provider.Price, 0, len(prices))
+	for _, price := range prices {
+		res = append(res, price)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pair.String() < res[j].Pair.String()
+	})
+	return res
+}
+
+func pairString(p *pb.Pair) string {
+	return p.GetBase() + "/" + p.GetQuote()
+}
@@ -0,0 +1,299 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+
+	"gofer-cli/pkg/agent/grpc/pb"
+)
+
+type nopHook struct{}
+
+func (nopHook) Check(map[provider.Pair]*provider.Price) error { return nil }
+
+var (
+	btcusd = provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd = provider.Pair{Base: "ETH", Quote: "USD"}
+)
+
+func newTestServer(t *testing.T, p provider.Provider) *Server {
+	s, err := NewServer(ServerConfig{PriceProvider: p, PriceHook: nopHook{}})
+	require.NoError(t, err)
+	return s
+}
+
+func TestServer_GetPrice(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {
+			Type:  "median",
+			Pair:  btcusd,
+			Price: 42,
+			Time:  ts,
+			Prices: []*provider.Price{
+				{Type: "origin", Pair: btcusd, Price: 42, Time: ts, Parameters: map[string]string{"origin": "a"}},
+			},
+		},
+	}, nil)
+
+	res, err := newTestServer(t, p).GetPrice(context.Background(), &pb.GetPriceRequest{
+		Pair: &pb.Pair{Base: "btc", Quote: "usd"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "BTC", res.Pair.Base)
+	assert.Equal(t, 42.0, res.Price)
+	assert.Equal(t, ts.Unix(), res.Time.AsTime().Unix())
+	require.Len(t, res.Prices, 1)
+	assert.Equal(t, "a", res.Prices[0].Parameters["origin"])
+}
+
+func TestServer_GetPrice_EmptyPair(t *testing.T) {
+	_, err := newTestServer(t, &mocks.Provider{}).GetPrice(context.Background(), &pb.GetPriceRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_GetPrice_InvalidPair(t *testing.T) {
+	for _, pair := range []*pb.Pair{{Base: "BTC"}, {Quote: "USD"}, {Base: "BTC/ETH", Quote: "USD"}} {
+		_, err := newTestServer(t, &mocks.Provider{}).GetPrice(context.Background(), &pb.GetPriceRequest{Pair: pair})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), pair.String())
+	}
+}
+
+func TestServer_GetPrice_NotFound(t *testing.T) {
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", xyzusd).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: xyzusd})
+
+	_, err := newTestServer(t, p).GetPrice(context.Background(), &pb.GetPriceRequest{
+		Pair: &pb.Pair{Base: "XYZ", Quote: "USD"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, graph.ErrPairNotFound{Pair: xyzusd}.Error(), status.Convert(err).Message())
+}
+
+func TestServer_GetModels_NotFound(t *testing.T) {
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models", xyzusd).Return(map[provider.Pair]*provider.Model(nil), graph.ErrPairNotFound{Pair: xyzusd})
+
+	_, err := newTestServer(t, p).GetModels(context.Background(), &pb.GetModelsRequest{
+		Pairs: []*pb.Pair{{Base: "XYZ", Quote: "USD"}},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_GetPrices(t *testing.T) {
+	p := &mocks.Provider{}
+	p.On("Prices", ethusd, btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Price: 42},
+		ethusd: {Pair: ethusd, Price: 21},
+	}, nil)
+
+	res, err := newTestServer(t, p).GetPrices(context.Background(), &pb.GetPricesRequest{
+		Pairs: []*pb.Pair{{Base: "ETH", Quote: "USD"}, {Base: "BTC", Quote: "USD"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Prices, 2)
+	assert.Equal(t, "BTC", res.Prices[0].Pair.Base)
+	assert.Equal(t, "ETH", res.Prices[1].Pair.Base)
+}
+
+func TestServer_GetModels(t *testing.T) {
+	p := &mocks.Provider{}
+	p.On("Models").Return(map[provider.Pair]*provider.Model{
+		btcusd: {Type: "median", Pair: btcusd, Models: []*provider.Model{{Type: "origin", Pair: btcusd}}},
+	}, nil)
+
+	res, err := newTestServer(t, p).GetModels(context.Background(), &pb.GetModelsRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.Models, 1)
+	assert.Equal(t, "median", res.Models[0].Type)
+	require.Len(t, res.Models[0].Models, 1)
+	assert.Equal(t, "origin", res.Models[0].Models[0].Type)
+}
+
+func TestServer_GetModels_Error(t *testing.T) {
+	p := &mocks.Provider{}
+	p.On("Models").Return(map[provider.Pair]*provider.Model(nil), errors.New("upstream secret"))
+
+	_, err := newTestServer(t, p).GetModels(context.Background(), &pb.GetModelsRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.NotContains(t, status.Convert(err).Message(), "upstream secret")
+}
+
+// startTestServer starts a server with a GetPrice call blocked until the
+// returned channel is closed.
+func startTestServer(t *testing.T, shutdownTimeout time.Duration) (*Server, pb.GoferClient, context.CancelFunc, chan struct{}) {
+	release := make(chan struct{})
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Run(func(mock.Arguments) { <-release }).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Pair: btcusd, Price: 42},
+	}, nil)
+	s, client, cancel := serveTestServer(t, ServerConfig{
+		PriceProvider:   p,
+		PriceHook:       nopHook{},
+		ShutdownTimeout: shutdownTimeout,
+	})
+	return s, client, cancel, release
+}
+
+// serveTestServer starts a server with the given config on a free local
+// port and returns a client connected to it.
+func serveTestServer(t *testing.T, cfg ServerConfig) (*Server, pb.GoferClient, context.CancelFunc) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cfg.Address = ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	s, err := NewServer(cfg)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, s.Start(ctx))
+
+	conn, err := grpc.Dial(cfg.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return s, pb.NewGoferClient(conn), cancel
+}
+
+func TestServer_GracefulStop(t *testing.T) {
+	s, client, cancel, release := startTestServer(t, time.Minute)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := client.GetPrice(context.Background(), &pb.GetPriceRequest{Pair: &pb.Pair{Base: "BTC", Quote: "USD"}})
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	// The server must wait for the in-flight call.
+	select {
+	case <-s.Wait():
+		t.Fatal("server stopped before the in-flight call finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-errCh)
+	select {
+	case <-s.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServer_GracefulStop_Timeout(t *testing.T) {
+	s, client, cancel, release := startTestServer(t, 100*time.Millisecond)
+	defer close(release)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := client.GetPrice(context.Background(), &pb.GetPriceRequest{Pair: &pb.Pair{Base: "BTC", Quote: "USD"}})
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-s.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop after the shutdown timeout")
+	}
+	assert.Equal(t, codes.Unavailable, status.Code(<-errCh))
+}
+
+func TestServer_SubscribePrices(t *testing.T) {
+	streamPairs := make(chan []provider.Pair, 1)
+	_, client, _ := serveTestServer(t, ServerConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     nopHook{},
+		PriceStream: func(ctx context.Context, pairs []provider.Pair, send func(*provider.Price) error) error {
+			streamPairs <- pairs
+			for _, price := range []float64{1, 2} {
+				if err := send(&provider.Price{Pair: btcusd, Price: price}); err != nil {
+					return err
+				}
+			}
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.SubscribePrices(ctx, &pb.SubscribePricesRequest{
+		Pairs: []*pb.Pair{{Base: "btc", Quote: "usd"}},
+	})
+	require.NoError(t, err)
+	for _, price := range []float64{1, 2} {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, price, res.Price)
+	}
+	assert.Equal(t, []provider.Pair{btcusd}, <-streamPairs)
+}
+
+func TestServer_SubscribePrices_Shutdown(t *testing.T) {
+	s, client, stop := serveTestServer(t, ServerConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     nopHook{},
+		PriceStream: func(ctx context.Context, _ []provider.Pair, _ func(*provider.Price) error) error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	stream, err := client.SubscribePrices(context.Background(), &pb.SubscribePricesRequest{
+		Pairs: []*pb.Pair{{Base: "BTC", Quote: "USD"}},
+	})
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	// Streams are closed when the server stops, without waiting for the
+	// shutdown timeout.
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	select {
+	case <-s.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServer_SubscribePrices_Unavailable(t *testing.T) {
+	err := newTestServer(t, &mocks.Provider{}).SubscribePrices(&pb.SubscribePricesRequest{}, nil)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
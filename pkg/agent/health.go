@@ -0,0 +1,91 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+)
+
+// ReadinessCheck reports whether a dependency of the agent is ready to
+// serve requests. It returns nil if it is ready.
+type ReadinessCheck func() error
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// handleHealthz reports that the agent process is alive.
+func (s *HTTPAgent) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// handleReadyz reports whether the agent is ready to serve prices. The
+// agent is ready once it is started and all readiness checks pass.
+func (s *HTTPAgent) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	res := healthResponse{Status: "ok", Checks: make(map[string]string)}
+	names := make([]string, 0, len(s.readinessChecks))
+	for name := range s.readinessChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.readinessChecks[name](); err != nil {
+			res.Status = "unavailable"
+			res.Checks[name] = err.Error()
+			continue
+		}
+		res.Checks[name] = "ok"
+	}
+	if res.Status != "ok" {
+		writeHealth(w, http.StatusServiceUnavailable, res)
+		return
+	}
+	writeHealth(w, http.StatusOK, res)
+}
+
+// checkProvider verifies that the price provider is able to list pairs.
+func (s *HTTPAgent) checkProvider() error {
+	pairs, err := s.priceProvider.Pairs()
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		return errors.New("no price models configured")
+	}
+	return nil
+}
+
+// checkStarted verifies that the agent has been started.
+func (s *HTTPAgent) checkStarted() error {
+	if s.ctx == nil {
+		return errors.New("agent is not started")
+	}
+	if s.ctx.Err() != nil {
+		return errors.New("agent is shutting down")
+	}
+	return nil
+}
+
+func writeHealth(w http.ResponseWriter, code int, res healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(res)
+}
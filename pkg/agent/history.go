@@ -0,0 +1,89 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+// PriceHistory provides recent prices kept in memory.
+type PriceHistory interface {
+	// History returns prices of the pair with timestamps between from and
+	// to, inclusive, sorted from the oldest. A zero from or to time means
+	// no bound.
+	History(pair provider.Pair, from, to time.Time) ([]*provider.Price, error)
+}
+
+// handleHistory returns recent prices of the pair given in the "pair" query
+// parameter, optionally limited to the time range given in the "from" and
+// "to" query parameters. The "depth" query parameter limits nested prices.
+func (s *HTTPAgent) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	pair, err := provider.NewPair(q.Get("pair"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseSince(q.Get("from"))
+	if err != nil {
+		http.Error(w, "from must be an RFC 3339 time or a Unix timestamp", http.StatusBadRequest)
+		return
+	}
+	to, err := parseSince(q.Get("to"))
+	if err != nil {
+		http.Error(w, "to must be an RFC 3339 time or a Unix timestamp", http.StatusBadRequest)
+		return
+	}
+	if !to.IsZero() && to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	depth, err := depthFromQuery(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logRequestedPairs(r.Context(), pair)
+
+	prices, err := s.history.History(pair, from, to)
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.requestLog(r).Errorf("failed to get price history: %v", err)
+		http.Error(w, "failed to get price history", http.StatusInternalServerError)
+		return
+	}
+	res := make([]jsonPrice, 0, len(prices))
+	for _, p := range prices {
+		res = append(res, jsonPriceFromGoferPrice(truncatePrice(p, depth)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,111 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+type testHistory map[provider.Pair][]*provider.Price
+
+func (h testHistory) History(pair provider.Pair, from, to time.Time) ([]*provider.Price, error) {
+	prices, ok := h[pair]
+	if !ok {
+		return nil, graph.ErrPairNotFound{Pair: pair}
+	}
+	var res []*provider.Price
+	for _, p := range prices {
+		if (from.IsZero() || !p.Time.Before(from)) && (to.IsZero() || !p.Time.After(to)) {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+func TestHTTPAgent_History(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ts := time.Unix(1700000000, 0)
+	var prices []*provider.Price
+	for i := 0; i < 3; i++ {
+		p := testPrice(btcusd, float64(40+i))
+		p.Time = ts.Add(time.Duration(i) * time.Minute)
+		prices = append(prices, p)
+	}
+	s := newTestAgent(t, &mocks.Provider{})
+	s.history = testHistory{btcusd: prices}
+
+	tests := []struct {
+		query  string
+		code   int
+		prices []float64
+	}{
+		{query: "pair=BTC/USD", code: http.StatusOK, prices: []float64{40, 41, 42}},
+		{query: "pair=BTC/USD&from=1700000060", code: http.StatusOK, prices: []float64{41, 42}},
+		{query: "pair=BTC/USD&to=" + ts.Format(time.RFC3339), code: http.StatusOK, prices: []float64{40}},
+		{query: "pair=BTC/USD&from=1800000000", code: http.StatusOK, prices: []float64{}},
+		{query: "pair=ETH/USD", code: http.StatusNotFound},
+		{query: "pair=BTC", code: http.StatusBadRequest},
+		{query: "pair=BTC/USD&from=yesterday", code: http.StatusBadRequest},
+		{query: "pair=BTC/USD&from=1700000060&to=1700000000", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleHistory(rec, httptest.NewRequest(http.MethodGet, "/history?"+tt.query, nil))
+			require.Equal(t, tt.code, rec.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var res []jsonPrice
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			got := []float64{}
+			for _, p := range res {
+				got = append(got, p.Price)
+			}
+			assert.Equal(t, tt.prices, got)
+		})
+	}
+}
+
+func TestHTTPAgent_History_Error(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.history = errHistory{}
+	rec := httptest.NewRecorder()
+	s.handleHistory(rec, httptest.NewRequest(http.MethodGet, "/history?pair=BTC/USD", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.handleHistory(rec, httptest.NewRequest(http.MethodPost, "/history?pair=BTC/USD", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+type errHistory struct{}
+
+func (errHistory) History(provider.Pair, time.Time, time.Time) ([]*provider.Price, error) {
+	return nil, errors.New("failed")
+}
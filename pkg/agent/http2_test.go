@@ -0,0 +1,95 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+// serveTestAgent initializes the agent and serves it on a random local
+// port. It returns the address of the listener.
+func serveTestAgent(t *testing.T, s *HTTPAgent) string {
+	require.NoError(t, s.initServer())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		if s.certs != nil {
+			_ = s.server.ServeTLS(ln, "", "")
+		} else {
+			_ = s.server.Serve(ln)
+		}
+	}()
+	t.Cleanup(func() { s.server.Close() })
+	return ln.Addr().String()
+}
+
+func TestHTTPAgent_HTTP2_TLS(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.tlsCertFile, s.tlsKeyFile = writeTestCert(t, t.TempDir(), "localhost")
+	addr := serveTestAgent(t, s)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	res, err := client.Get("https://" + addr + "/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 2, res.ProtoMajor)
+}
+
+func TestHTTPAgent_H2C(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.h2c = true
+	addr := serveTestAgent(t, s)
+
+	// HTTP/2 with prior knowledge.
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	res, err := client.Get("http://" + addr + "/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 2, res.ProtoMajor)
+
+	// HTTP/1.1 clients are still served.
+	res, err = http.Get("http://" + addr + "/healthz")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 1, res.ProtoMajor)
+}
+
+func TestHTTPAgent_H2C_TLS(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.h2c = true
+	s.tlsCertFile, s.tlsKeyFile = writeTestCert(t, t.TempDir(), "localhost")
+	assert.Error(t, s.initServer())
+}
@@ -0,0 +1,105 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// NextRefreshFunc returns the time at which the price of the pair is
+// expected to be refreshed, or false if it is not known.
+type NextRefreshFunc func(pair provider.Pair) (time.Time, bool)
+
+// checkNotModified sets the Cache-Control and ETag headers for a response
+// with the given prices, truncated to the given depth, and reports whether the client already has the
+// same response, in which case 304 Not Modified is written and the caller
+// must not write the body.
+//
+// The ETag is derived from the depth and the pairs and timestamps of the
+// prices, so it changes only when prices are refreshed. Responses may be cached until the
+// first of the prices is expected to be refreshed.
+func (s *HTTPAgent) checkNotModified(w http.ResponseWriter, r *http.Request, depth int, prices ...*provider.Price) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if len(prices) == 0 {
+		return false
+	}
+	keys := make([]string, 0, len(prices))
+	for _, p := range prices {
+		keys = append(keys, fmt.Sprintf("%s@%d", p.Pair, p.Time.UnixNano()))
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	// Different representations of the same prices must have different
+	// ETags.
+	_, _ = fmt.Fprintf(h, "%s;%s;%d;", apiVersion(r.Context()), r.Header.Get("Accept"), depth)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(h, "%s;", k)
+	}
+	etag := fmt.Sprintf(`"%x"`, h.Sum64())
+
+	w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(int(s.maxAge(prices...).Seconds())))
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// maxAge returns the time until the first of the prices is expected to be
+// refreshed. It is zero if the refresh time of any of the prices is not
+// known.
+func (s *HTTPAgent) maxAge(prices ...*provider.Price) time.Duration {
+	if s.nextRefresh == nil {
+		return 0
+	}
+	var next time.Time
+	for _, p := range prices {
+		t, ok := s.nextRefresh(p.Pair)
+		if !ok {
+			return 0
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	if d := time.Until(next); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// etagMatches reports whether the If-None-Match header matches the ETag.
+func etagMatches(header, etag string) bool {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "W/")
+		if part == etag || part == "*" {
+			return true
+		}
+	}
+	return false
+}
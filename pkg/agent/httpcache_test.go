@@ -0,0 +1,146 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_ETag(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	s := newTestAgent(t, p)
+	// The max-age is derived from the next refresh of the price, not from
+	// its timestamp.
+	next := time.Now().Add(50 * time.Second)
+	s.nextRefresh = func(pair provider.Pair) (time.Time, bool) {
+		return next, pair == btcusd
+	}
+
+	rec := httptest.NewRecorder()
+	s.handlePrice(rec, httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	maxAge, err := strconv.Atoi(rec.Header().Get("Cache-Control")[len("max-age="):])
+	require.NoError(t, err)
+	assert.InDelta(t, 50, maxAge, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.handlePrice(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// A different representation must not match.
+	req = httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil)
+	req.Header.Set("If-None-Match", etag)
+	req.Header.Set("Accept", "text/csv")
+	rec = httptest.NewRecorder()
+	s.handlePrice(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPAgent_ETag_Depth(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	s := newTestAgent(t, p)
+
+	etags := make(map[string]string)
+	for _, path := range []string{"/price?pair=BTC/USD", "/price?pair=BTC/USD&depth=0", "/prices?pairs=BTC/USD", "/prices?pairs=BTC/USD&depth=0"} {
+		handle := s.handlePrice
+		if strings.HasPrefix(path, "/prices") {
+			handle = s.handlePrices
+		}
+		rec := httptest.NewRecorder()
+		handle(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		etags[path] = rec.Header().Get("ETag")
+	}
+	assert.NotEqual(t, etags["/price?pair=BTC/USD"], etags["/price?pair=BTC/USD&depth=0"])
+	assert.NotEqual(t, etags["/prices?pairs=BTC/USD"], etags["/prices?pairs=BTC/USD&depth=0"])
+
+	// A truncated response must not be revalidated with the ETag of the
+	// full one.
+	req := httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD&depth=0", nil)
+	req.Header.Set("If-None-Match", etags["/price?pair=BTC/USD"])
+	rec := httptest.NewRecorder()
+	s.handlePrice(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPAgent_ETag_Refreshed(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	old := testPrice(btcusd, 42)
+	refreshed := testPrice(btcusd, 43)
+	refreshed.Time = old.Time.Add(time.Minute)
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: old}, nil).Once()
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: refreshed}, nil).Once()
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD", nil))
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, "max-age=0", rec.Header().Get("Cache-Control"))
+
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.handlePrices(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestHTTPAgent_NoCache(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	s := newTestAgent(t, p)
+
+	// The no-cache directive does not make the agent bypass its price
+	// provider.
+	for _, header := range [][2]string{{"Cache-Control", "no-cache"}, {"Pragma", "no-cache"}} {
+		req := httptest.NewRequest(http.MethodGet, "/price?pair=BTC/USD", nil)
+		req.Header.Set(header[0], header[1])
+		rec := httptest.NewRecorder()
+		s.handlePrice(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"price":42`)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`*`, `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+	assert.False(t, etagMatches(``, `"a"`))
+}
@@ -0,0 +1,67 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// InvalidateFunc removes cached prices of the given pairs, or of all cached
+// pairs if none are given, and fetches them again. It returns the pairs
+// whose prices could not be fetched. An error means that nothing was
+// invalidated, e.g. because one of the pairs is not cached.
+type InvalidateFunc func(pairs ...provider.Pair) (failed []provider.Pair, err error)
+
+// invalidateResponse is the response of the /admin/cache/invalidate
+// endpoint.
+type invalidateResponse struct {
+	Failed []string `json:"failed"`
+}
+
+// handleInvalidate invalidates cached prices of the pairs given in the
+// pairs query parameter with the configured InvalidateFunc.
+func (s *HTTPAgent) handleInvalidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pairs, err := pairsFromQuery(r.URL.Query()["pairs"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	failed, err := s.invalidate(pairs...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := invalidateResponse{Failed: make([]string, 0, len(failed))}
+	for _, p := range failed {
+		res.Failed = append(res.Failed, p.String())
+	}
+	sort.Strings(res.Failed)
+	s.requestLog(r).
+		WithField("pairs", len(pairs)).
+		WithField("failed", len(res.Failed)).
+		Info("Cached prices invalidated")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,81 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_Invalidate(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	var invalidated []provider.Pair
+	s := NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider: &mocks.Provider{},
+		Logger:        null.New(),
+		APIKeys:       map[string]string{"admin": "secret"},
+		Invalidate: func(pairs ...provider.Pair) ([]provider.Pair, error) {
+			for _, p := range pairs {
+				if p.Base == "DAI" {
+					return nil, errors.New("unknown pair: DAI/USD")
+				}
+			}
+			invalidated = pairs
+			return []provider.Pair{ethusd}, nil
+		},
+	})
+	require.NoError(t, s.initServer())
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(APIKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/admin/cache/invalidate").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/admin/cache/invalidate?pairs=BTCUSD").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/admin/cache/invalidate?pairs=DAI/USD").Code)
+
+	rec := request(http.MethodPost, "/admin/cache/invalidate?pairs=BTC/USD,ETH/USD")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res invalidateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []string{"ETH/USD"}, res.Failed)
+	assert.Equal(t, []provider.Pair{btcusd, ethusd}, invalidated)
+
+	rec = request(http.MethodPost, "/admin/cache/invalidate")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, invalidated)
+}
+
+func TestHTTPAgent_Invalidate_RequiresAuth(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.invalidate = func(...provider.Pair) ([]provider.Pair, error) { return nil, nil }
+	assert.Error(t, s.initServer())
+}
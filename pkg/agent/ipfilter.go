@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPFilterConfig is the configuration of IP address filtering.
+type IPFilterConfig struct {
+	// Allow is a list of networks allowed to access the agent. If empty,
+	// all networks that are not denied are allowed.
+	Allow []netip.Prefix
+	// Deny is a list of networks denied access to the agent. It takes
+	// precedence over Allow.
+	Deny []netip.Prefix
+	// TrustedProxies is a list of networks of reverse proxies whose
+	// X-Forwarded-For header is trusted. If empty, the header is ignored
+	// and the client address is the address of the connection.
+	TrustedProxies []netip.Prefix
+}
+
+type clientIPCtxKey struct{}
+
+// clientIPFromContext returns the client IP address resolved by the IP
+// filter, or an invalid address if the filter is not enabled.
+func clientIPFromContext(ctx context.Context) netip.Addr {
+	ip, _ := ctx.Value(clientIPCtxKey{}).(netip.Addr)
+	return ip
+}
+
+// filterIP wraps the handler to only allow requests from the configured
+// networks. Health check endpoints are always allowed.
+func (s *HTTPAgent) filterIP(cfg IPFilterConfig, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		ip, ok := clientIP(r, cfg.TrustedProxies)
+		if !ok || !cfg.allowed(ip) {
+			s.requestLog(r).
+				WithField("clientIP", ip.String()).
+				WithField("remoteAddr", r.RemoteAddr).
+				WithField("path", r.URL.Path).
+				Warn("Request from a not allowed address")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPCtxKey{}, ip)))
+	})
+}
+
+// allowed reports whether the given address may access the agent.
+func (c IPFilterConfig) allowed(ip netip.Addr) bool {
+	if containsIP(c.Deny, ip) {
+		return false
+	}
+	return len(c.Allow) == 0 || containsIP(c.Allow, ip)
+}
+
+// clientIP returns the address of the client. If the request comes from a
+// trusted proxy, the X-Forwarded-For header is read from right to left and
+// the first address that does not belong to a trusted proxy is returned.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	ip = ip.Unmap()
+	if !containsIP(trustedProxies, ip) {
+		return ip, true
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		fwd, err := netip.ParseAddr(hop)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		ip = fwd.Unmap()
+		if !containsIP(trustedProxies, ip) {
+			break
+		}
+	}
+	return ip, true
+}
+
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_FilterIP(t *testing.T) {
+	cfg := IPFilterConfig{
+		Allow:          []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		Deny:           []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24")},
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("192.168.0.1/32")},
+	}
+	var ip netip.Addr
+	a := newTestAgent(t, &mocks.Provider{})
+	h := a.filterIP(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip = clientIPFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		path       string
+		remoteAddr string
+		forwarded  string
+		want       int
+		wantIP     string
+	}{
+		{path: "/prices", remoteAddr: "10.0.0.1:1234", want: http.StatusOK, wantIP: "10.0.0.1"},
+		{path: "/prices", remoteAddr: "10.0.1.1:1234", want: http.StatusForbidden},
+		{path: "/prices", remoteAddr: "172.16.0.1:1234", want: http.StatusForbidden},
+		{path: "/healthz", remoteAddr: "172.16.0.1:1234", want: http.StatusOK},
+		// The header is ignored if the request does not come from a trusted proxy.
+		{path: "/prices", remoteAddr: "172.16.0.1:1234", forwarded: "10.0.0.1", want: http.StatusForbidden},
+		{path: "/prices", remoteAddr: "192.168.0.1:1234", forwarded: "10.0.0.1", want: http.StatusOK, wantIP: "10.0.0.1"},
+		// Only the rightmost untrusted address is used, so clients cannot
+		// spoof their address by prepending entries.
+		{path: "/prices", remoteAddr: "192.168.0.1:1234", forwarded: "10.0.0.1, 172.16.0.1", want: http.StatusForbidden},
+		{path: "/prices", remoteAddr: "192.168.0.1:1234", forwarded: "invalid", want: http.StatusForbidden},
+		{path: "/prices", remoteAddr: "[::ffff:10.0.0.2]:1234", want: http.StatusOK, wantIP: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		ip = netip.Addr{}
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, tt.want, rec.Code, "%s from %s via %q", tt.path, tt.remoteAddr, tt.forwarded)
+		if tt.wantIP != "" {
+			assert.Equal(t, tt.wantIP, ip.String())
+		}
+	}
+}
+
+func TestIPFilterConfig_AllowAll(t *testing.T) {
+	cfg := IPFilterConfig{Deny: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	assert.True(t, cfg.allowed(netip.MustParseAddr("172.16.0.1")))
+	assert.False(t, cfg.allowed(netip.MustParseAddr("10.1.2.3")))
+}
@@ -0,0 +1,268 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// maxRPCBatchSize is the maximum number of requests in a batch, so a single
+// HTTP request cannot fan out to an unbounded number of price fetches.
+const maxRPCBatchSize = 100
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string `json:"jsonrpc"`
+	Result  any
+	Error   *rpcError
+	ID      json.RawMessage
+}
+
+// MarshalJSON implements the json.Marshaler interface. It is needed because
+// a successful response must contain the result field even if it is null,
+// while an error response must not contain it.
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *rpcError       `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.JSONRPC, r.Error, r.ID})
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  any             `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{r.JSONRPC, r.Result, r.ID})
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// handleRPC implements a JSON-RPC 2.0 endpoint with the gofer_price,
+// gofer_prices and gofer_models methods. Batch requests are supported.
+func (s *HTTPAgent) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		if status := bodyErrorStatus(err); status != http.StatusBadRequest {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		s.writeRPC(w, rpcResponse{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: rpcParseError, Message: "parse error"},
+			ID:      json.RawMessage("null"),
+		})
+		return
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil || len(batch) == 0 {
+			s.writeRPC(w, invalidRPCRequest())
+			return
+		}
+		if len(batch) > maxRPCBatchSize {
+			s.writeRPC(w, rpcResponse{
+				JSONRPC: "2.0",
+				Error: &rpcError{
+					Code:    rpcInvalidRequest,
+					Message: fmt.Sprintf("batch must not contain more than %d requests", maxRPCBatchSize),
+				},
+				ID: json.RawMessage("null"),
+			})
+			return
+		}
+		res := make([]rpcResponse, 0, len(batch))
+		for _, req := range batch {
+			if resp, ok := s.callRPC(r, req); ok {
+				res = append(res, resp)
+			}
+		}
+		if len(res) == 0 {
+			// A batch containing only notifications has no response.
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.writeRPC(w, res)
+		return
+	}
+	resp, ok := s.callRPC(r, raw)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeRPC(w, resp)
+}
+
+// callRPC executes a single JSON-RPC request. The returned bool is false if
+// the request is a notification, which must not be answered.
+func (s *HTTPAgent) callRPC(r *http.Request, raw json.RawMessage) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return invalidRPCRequest(), true
+	}
+	if req.ID == nil {
+		_, _ = s.execRPC(r, req.Method, req.Params)
+		return rpcResponse{}, false
+	}
+	res, rErr := s.execRPC(r, req.Method, req.Params)
+	return rpcResponse{JSONRPC: "2.0", Result: res, Error: rErr, ID: req.ID}, true
+}
+
+func (s *HTTPAgent) execRPC(r *http.Request, method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "gofer_price":
+		pairs, rErr := rpcPairsParam(params, "pair")
+		if rErr != nil {
+			return nil, rErr
+		}
+		if len(pairs) != 1 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "exactly one pair is required"}
+		}
+		prices, err := s.checkedPrices(pairs[0])
+		if err != nil {
+			return nil, s.rpcProviderError(r, "failed to get prices", err)
+		}
+		price, ok := prices[pairs[0]]
+		if !ok {
+			return nil, nil
+		}
+		return jsonPriceFromGoferPrice(price), nil
+	case "gofer_prices":
+		pairs, rErr := rpcPairsParam(params, "pairs")
+		if rErr != nil {
+			return nil, rErr
+		}
+		prices, err := s.checkedPrices(pairs...)
+		if err != nil {
+			return nil, s.rpcProviderError(r, "failed to get prices", err)
+		}
+		res := make([]jsonPrice, 0, len(prices))
+		for _, p := range prices {
+			res = append(res, jsonPriceFromGoferPrice(p))
+		}
+		sort.Slice(res, func(i, j int) bool {
+			return res[i].Base+"/"+res[i].Quote < res[j].Base+"/"+res[j].Quote
+		})
+		return res, nil
+	case "gofer_models":
+		pairs, rErr := rpcPairsParam(params, "pairs")
+		if rErr != nil {
+			return nil, rErr
+		}
+		models, err := s.sortedModels(pairs...)
+		if err != nil {
+			return nil, s.rpcProviderError(r, "failed to get models", err)
+		}
+		res := make([]jsonModel, 0, len(models))
+		for _, m := range models {
+			res = append(res, jsonModelFromGoferModel(m))
+		}
+		return res, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found"}
+}
+
+// rpcProviderError returns the JSON-RPC error for an error of the price
+// provider. As with the REST endpoints, an unknown pair is reported to the
+// client, while other errors are only logged, because they may contain
+// details of the origins.
+func (s *HTTPAgent) rpcProviderError(r *http.Request, msg string, err error) *rpcError {
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	s.requestLog(r).Errorf("%s: %v", msg, err)
+	return &rpcError{Code: rpcInternalError, Message: msg}
+}
+
+func (s *HTTPAgent) writeRPC(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.log.Errorf("failed to marshal JSON-RPC response: %v", err)
+	}
+}
+
+// rpcPairsParam parses pairs from JSON-RPC params. Params may be given
+// either by position, as a list of pairs, or by name, in which case the
+// pairs are read from the given field. The field may contain a single pair
+// or a list of pairs.
+func rpcPairsParam(params json.RawMessage, name string) ([]provider.Pair, *rpcError) {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil, nil
+	}
+	var pairs []provider.Pair
+	if params[0] == '{' {
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(params, &named); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		field, ok := named[name]
+		if !ok {
+			return nil, nil
+		}
+		var pair provider.Pair
+		if err := json.Unmarshal(field, &pair); err == nil {
+			return []provider.Pair{pair}, nil
+		}
+		params = field
+	}
+	if err := json.Unmarshal(params, &pairs); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return pairs, nil
+}
+
+func invalidRPCRequest() rpcResponse {
+	return rpcResponse{
+		JSONRPC: "2.0",
+		Error:   &rpcError{Code: rpcInvalidRequest, Message: "invalid request"},
+		ID:      json.RawMessage("null"),
+	}
+}
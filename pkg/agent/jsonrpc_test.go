@@ -0,0 +1,121 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_RPC(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model{btcusd: {Type: "median", Pair: btcusd}}, nil)
+
+	tests := []struct {
+		name string
+		req  string
+		want string
+	}{
+		{
+			name: "price-positional",
+			req:  `{"jsonrpc":"2.0","method":"gofer_price","params":["BTC/USD"],"id":1}`,
+			want: `{"jsonrpc":"2.0","id":1,"result":{"type":"median","base":"BTC","quote":"USD","price":42,"bid":0,"ask":0,"vol24h":0,"ts":"2023-11-14T22:13:20Z"}}`,
+		},
+		{
+			name: "models-named",
+			req:  `{"jsonrpc":"2.0","method":"gofer_models","params":{"pairs":["BTC/USD"]},"id":"a"}`,
+			want: `{"jsonrpc":"2.0","id":"a","result":[{"type":"median","base":"BTC","quote":"USD"}]}`,
+		},
+		{
+			name: "method-not-found",
+			req:  `{"jsonrpc":"2.0","method":"foo","id":1}`,
+			want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`,
+		},
+		{
+			name: "parse-error",
+			req:  `{`,
+			want: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		},
+		{
+			name: "batch",
+			req:  `[{"jsonrpc":"2.0","method":"gofer_price","params":{"pair":"BTC/USD"},"id":1},{"jsonrpc":"1.0"}]`,
+			want: `[
+				{"jsonrpc":"2.0","id":1,"result":{"type":"median","base":"BTC","quote":"USD","price":42,"bid":0,"ask":0,"vol24h":0,"ts":"2023-11-14T22:13:20Z"}},
+				{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}
+			]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newTestAgent(t, p).handleRPC(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.req)))
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestHTTPAgent_RPC_Notification(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := `{"jsonrpc":"2.0","method":"foo"}`
+	newTestAgent(t, &mocks.Provider{}).handleRPC(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(req)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestHTTPAgent_RPC_ProviderError(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("GET https://origin.internal/api: timeout"))
+	p.On("Prices", xyzusd).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: xyzusd})
+
+	tests := []struct {
+		pair string
+		want string
+	}{
+		// Errors of origins are not leaked to clients.
+		{pair: "BTC/USD", want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"failed to get prices"}}`},
+		{pair: "XYZ/USD", want: `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"unable to find the XYZ/USD pair"}}`},
+	}
+	for _, tt := range tests {
+		req := fmt.Sprintf(`{"jsonrpc":"2.0","method":"gofer_price","params":[%q],"id":1}`, tt.pair)
+		rec := httptest.NewRecorder()
+		newTestAgent(t, p).handleRPC(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(req)))
+		assert.JSONEq(t, tt.want, rec.Body.String(), tt.pair)
+	}
+}
+
+func TestHTTPAgent_RPC_BatchSize(t *testing.T) {
+	p := &mocks.Provider{}
+	call := `{"jsonrpc":"2.0","method":"gofer_prices","params":[],"id":1}`
+	req := "[" + strings.Repeat(call+",", maxRPCBatchSize) + call + "]"
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handleRPC(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(req)))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch must not contain more than 100 requests"}}`, rec.Body.String())
+	p.AssertNotCalled(t, "Prices")
+}
@@ -0,0 +1,176 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Roles recognized in JWT tokens. The admin role implies the read role.
+const (
+	RoleRead  = "gofer:read"
+	RoleAdmin = "gofer:admin"
+)
+
+// JWTConfig is the configuration of JWT bearer-token authentication.
+// At least one of HMACSecret and JWKSURL must be set.
+type JWTConfig struct {
+	// HMACSecret is the secret used to verify HS256, HS384 and HS512 tokens.
+	HMACSecret []byte
+	// JWKSURL is the URL of a JSON Web Key Set used to verify RSA and ECDSA
+	// tokens.
+	JWKSURL string
+	// Issuer, if set, must match the "iss" claim of the token.
+	Issuer string
+	// Audience, if set, must be present in the "aud" claim of the token.
+	Audience string
+}
+
+// jwtClaims are the claims of the tokens accepted by the agent. Roles may be
+// given either in the "roles" claim or as OAuth2 scopes in the "scope"
+// claim.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+func (c *jwtClaims) hasRole(role string) bool {
+	roles := append(strings.Fields(c.Scope), c.Roles...)
+	for _, r := range roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtClaimsCtxKey struct{}
+
+// jwtAuthenticator verifies JWT bearer tokens.
+type jwtAuthenticator struct {
+	cfg    JWTConfig
+	jwks   *jwksCache
+	parser *jwt.Parser
+}
+
+func newJWTAuthenticator(cfg JWTConfig) (*jwtAuthenticator, error) {
+	if len(cfg.HMACSecret) == 0 && cfg.JWKSURL == "" {
+		return nil, errors.New("either HMAC secret or JWKS URL must be set for JWT authentication")
+	}
+	var methods []string
+	a := &jwtAuthenticator{cfg: cfg}
+	if len(cfg.HMACSecret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if cfg.JWKSURL != "" {
+		methods = append(methods, "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512")
+		a.jwks = newJWKSCache(cfg.JWKSURL)
+	}
+	a.parser = jwt.NewParser(jwt.WithValidMethods(methods))
+	return a, nil
+}
+
+// verify parses the token and verifies its signature and registered claims.
+func (a *jwtAuthenticator) verify(ctx context.Context, token string) (*jwtClaims, error) {
+	claims := &jwtClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			return a.cfg.HMACSecret, nil
+		default:
+			kid, _ := t.Header["kid"].(string)
+			return a.jwks.key(ctx, kid)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if a.cfg.Issuer != "" && !claims.VerifyIssuer(a.cfg.Issuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if a.cfg.Audience != "" && !claims.VerifyAudience(a.cfg.Audience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+	return claims, nil
+}
+
+// requiredRole returns the role required to access the given path. Health
+// check endpoints do not require any role.
+func requiredRole(path string) string {
+	switch {
+	case path == "/healthz" || path == "/readyz":
+		return ""
+	case strings.HasPrefix(trimAPIVersion(path), "/admin/"):
+		return RoleAdmin
+	default:
+		return RoleRead
+	}
+}
+
+// authenticateJWT wraps the handler to only allow requests with a valid
+// bearer token that has the role required for the requested path.
+func (s *HTTPAgent) authenticateJWT(a *jwtAuthenticator, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := requiredRole(r.URL.Path)
+		if role == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			authFailed(r)
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		claims, err := a.verify(r.Context(), token)
+		if err != nil {
+			s.requestLog(r).
+				WithError(err).
+				WithField("remoteAddr", r.RemoteAddr).
+				WithField("path", r.URL.Path).
+				Warn("Invalid bearer token")
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			authFailed(r)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		if !claims.hasRole(role) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, role))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if e := accessLogFromContext(r.Context()); e != nil {
+			e.subject = claims.Subject
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtClaimsCtxKey{}, claims)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
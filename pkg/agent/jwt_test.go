@@ -0,0 +1,126 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func signHMAC(t *testing.T, secret string, claims jwtClaims) string {
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return s
+}
+
+func TestHTTPAgent_AuthenticateJWT(t *testing.T) {
+	auth, err := newJWTAuthenticator(JWTConfig{HMACSecret: []byte("secret"), Issuer: "auth"})
+	require.NoError(t, err)
+	h := newTestAgent(t, &mocks.Provider{}).authenticateJWT(auth, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	reader := signHMAC(t, "secret", jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "auth", ExpiresAt: exp},
+		Scope:            "openid gofer:read",
+	})
+	admin := signHMAC(t, "secret", jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "auth", ExpiresAt: exp},
+		Roles:            []string{RoleAdmin},
+	})
+	expired := signHMAC(t, "secret", jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "auth", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+		Roles:            []string{RoleRead},
+	})
+	wrongIssuer := signHMAC(t, "secret", jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "other"},
+		Roles:            []string{RoleRead},
+	})
+	wrongSecret := signHMAC(t, "other", jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "auth"},
+		Roles:            []string{RoleRead},
+	})
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{path: "/healthz", want: http.StatusOK},
+		{path: "/prices", want: http.StatusUnauthorized},
+		{path: "/prices", token: reader, want: http.StatusOK},
+		{path: "/admin/reload", token: reader, want: http.StatusForbidden},
+		{path: "/admin/reload", token: admin, want: http.StatusOK},
+		{path: "/prices", token: admin, want: http.StatusOK},
+		{path: "/prices", token: expired, want: http.StatusUnauthorized},
+		{path: "/prices", token: wrongIssuer, want: http.StatusUnauthorized},
+		{path: "/prices", token: wrongSecret, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, tt.want, rec.Code, tt.path)
+	}
+}
+
+func TestJWTAuthenticator_JWKS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{{
+			Kid: "key-1",
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	auth, err := newJWTAuthenticator(JWTConfig{JWKSURL: srv.URL})
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwtClaims{Roles: []string{RoleRead}})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	claims, err := auth.verify(httptest.NewRequest(http.MethodGet, "/", nil).Context(), signed)
+	require.NoError(t, err)
+	assert.True(t, claims.hasRole(RoleRead))
+
+	token.Header["kid"] = "key-2"
+	signed, err = token.SignedString(key)
+	require.NoError(t, err)
+	_, err = auth.verify(httptest.NewRequest(http.MethodGet, "/", nil).Context(), signed)
+	assert.Error(t, err)
+}
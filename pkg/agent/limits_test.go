@@ -0,0 +1,71 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestNewHTTPAgent_ServerLimits(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	assert.Equal(t, defaultReadTimeout, s.server.ReadTimeout)
+	assert.Equal(t, defaultWriteTimeout, s.server.WriteTimeout)
+	assert.Equal(t, defaultIdleTimeout, s.server.IdleTimeout)
+	assert.Equal(t, int64(defaultMaxRequestBodySize), s.maxBodySize)
+
+	s = NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider:      &mocks.Provider{},
+		PriceHook:          nopHook{},
+		Logger:             null.New(),
+		ReadTimeout:        time.Second,
+		WriteTimeout:       2 * time.Second,
+		IdleTimeout:        3 * time.Second,
+		MaxHeaderBytes:     4096,
+		MaxRequestBodySize: 16,
+	})
+	assert.Equal(t, time.Second, s.server.ReadTimeout)
+	assert.Equal(t, 2*time.Second, s.server.WriteTimeout)
+	assert.Equal(t, 3*time.Second, s.server.IdleTimeout)
+	assert.Equal(t, 4096, s.server.MaxHeaderBytes)
+	assert.Equal(t, int64(16), s.maxBodySize)
+}
+
+func TestHTTPAgent_RequestBodyTooLarge(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.maxBodySize = 16
+	require.NoError(t, s.initServer())
+
+	for _, path := range []string{"/price", "/prices", "/graphql", "/rpc"} {
+		t.Run(path, func(t *testing.T) {
+			body := `{"pairs":["` + strings.Repeat("A", 64) + `/USD"]}`
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixAddressPrefix is the prefix of addresses of Unix domain sockets,
+// e.g. unix:///var/run/gofer.sock.
+const unixAddressPrefix = "unix://"
+
+const defaultSocketMode fs.FileMode = 0o660
+
+// listen returns a listener for the given address. Addresses with the
+// unix:// prefix are paths of Unix domain sockets, other addresses are TCP
+// addresses. A stale socket file left by a previous process is removed and
+// the permissions of the new socket file are set to mode. The socket file
+// is removed when the listener is closed.
+func listen(address string, mode fs.FileMode) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, unixAddressPrefix)
+	if !ok {
+		if address == "" {
+			address = ":http"
+		}
+		return net.Listen("tcp", address)
+	}
+	if path == "" {
+		return nil, errors.New("empty Unix socket path")
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("unable to set permissions of %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket file at the given path if no
+// process is listening on it. Files that are not sockets are never
+// removed.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%s is already in use", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,147 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofer.sock")
+	s := newTestAgent(t, &mocks.Provider{})
+	s.addresses = []string{unixAddressPrefix + path}
+	s.socketMode = 0o600
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o600), fi.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	res, err := client.Get("http://gofer/healthz")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cancel()
+	select {
+	case <-s.Wait():
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestHTTPAgent_MultipleAddresses(t *testing.T) {
+	// Find a free TCP port.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcpAddr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	path := filepath.Join(t.TempDir(), "gofer.sock")
+	s := newTestAgent(t, &mocks.Provider{})
+	s.addresses = []string{tcpAddr, unixAddressPrefix + path}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	res, err := http.Get("http://" + tcpAddr + "/healthz")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	res, err = client.Get("http://gofer/healthz")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cancel()
+	select {
+	case <-s.Wait():
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+	_, err = net.Dial("tcp", tcpAddr)
+	assert.Error(t, err)
+}
+
+func TestHTTPAgent_ListenError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofer.sock")
+	s := newTestAgent(t, &mocks.Provider{})
+	s.addresses = []string{unixAddressPrefix + path, unixAddressPrefix}
+
+	err := s.Start(context.Background())
+	assert.ErrorContains(t, err, "unable to listen on unix://: empty Unix socket path")
+
+	// Listeners opened before the failure are closed.
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofer.sock")
+
+	// Leave a socket file behind without removing it on close.
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	ln, err = listen(unixAddressPrefix+path, defaultSocketMode)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// A socket that is in use must not be removed.
+	_, err = listen(unixAddressPrefix+path, defaultSocketMode)
+	assert.Error(t, err)
+}
+
+func TestListen_NotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofer.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	_, err := listen(unixAddressPrefix+path, defaultSocketMode)
+	assert.Error(t, err)
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,162 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+const (
+	// defaultLongPollTimeout is the time a long-polling request waits for a
+	// newer price if the client does not specify a timeout.
+	defaultLongPollTimeout = 30 * time.Second
+
+	// maxLongPollTimeout is the maximum time a long-polling request may
+	// wait for a newer price.
+	maxLongPollTimeout = 2 * time.Minute
+)
+
+// handleWaitPrice waits until a price newer than the time given in the
+// "since" query parameter is available for the pair given in the "pair"
+// query parameter. If no newer price is available before the timeout, it
+// responds with 304 Not Modified.
+func (s *HTTPAgent) handleWaitPrice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	pair, err := provider.NewPair(q.Get("pair"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, err := parseSince(q.Get("since"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	timeout, err := parseLongPollTimeout(q.Get("timeout"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	depth, err := depthFromQuery(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logRequestedPairs(r.Context(), pair)
+
+	// Subscribe before checking the current price, so no update published
+	// in between is missed.
+	sub := s.stream.subscribe()
+	defer s.stream.unsubscribe(sub)
+	s.stream.addPairs(sub, pair)
+
+	prices, err := s.priceProvider.Prices(pair)
+	if err == nil {
+		err = s.priceHook.Check(prices)
+	}
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.requestLog(r).Errorf("failed to get prices: %v", err)
+		http.Error(w, "failed to get prices", http.StatusInternalServerError)
+		return
+	}
+	if price, ok := prices[pair]; ok && price.Time.After(since) {
+		s.writeWaitedPrice(w, r, truncatePrice(price, depth))
+		return
+	}
+
+	disableWriteDeadline(w)
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			http.Error(w, "agent is shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		case <-t.C:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case ev := <-sub.ch:
+			if ev.Price.Time.After(since) {
+				s.writeWaitedPrice(w, r, truncatePrice(ev.Price, depth))
+				return
+			}
+		}
+	}
+}
+
+func (s *HTTPAgent) writeWaitedPrice(w http.ResponseWriter, r *http.Request, price *provider.Price) {
+	b, err := json.Marshal(jsonPriceFromGoferPrice(price))
+	if err != nil {
+		s.requestLog(r).Errorf("failed to marshal price: %v", err)
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(b)
+}
+
+// parseSince parses the "since" query parameter, given either in the
+// RFC 3339 format or as a Unix timestamp in seconds. An empty value is the
+// zero time, so any price is newer.
+func parseSince(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, errors.New("since must be an RFC 3339 time or a Unix timestamp")
+	}
+	return t, nil
+}
+
+// parseLongPollTimeout parses the "timeout" query parameter, given in
+// seconds.
+func parseLongPollTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultLongPollTimeout, nil
+	}
+	sec, err := strconv.Atoi(v)
+	if err != nil || sec <= 0 {
+		return 0, errors.New("timeout must be a positive number of seconds")
+	}
+	if d := time.Duration(sec) * time.Second; d < maxLongPollTimeout {
+		return d, nil
+	}
+	return maxLongPollTimeout, nil
+}
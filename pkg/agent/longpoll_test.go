@@ -0,0 +1,109 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_WaitPrice(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ts := time.Unix(1700000000, 0)
+	old := testPrice(btcusd, 42)
+	old.Time = ts
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: old}, nil)
+	s := newTestAgent(t, p)
+	s.ctx = context.Background()
+
+	// A price newer than "since" is returned immediately.
+	rec := httptest.NewRecorder()
+	s.handleWaitPrice(rec, httptest.NewRequest(http.MethodGet, "/prices/wait?pair=BTC/USD&since="+strconv.FormatInt(ts.Unix()-1, 10), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res jsonPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, 42.0, res.Price)
+
+	// Otherwise the request waits for a newer price.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		s.handleWaitPrice(rec, httptest.NewRequest(http.MethodGet, "/prices/wait?pair=BTC/USD&since="+ts.Format(time.RFC3339), nil))
+		done <- rec
+	}()
+	newer := testPrice(btcusd, 43)
+	newer.Time = ts.Add(time.Minute)
+	require.Eventually(t, func() bool {
+		s.stream.publish(map[provider.Pair]*provider.Price{btcusd: newer})
+		select {
+		case rec = <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, 43.0, res.Price)
+}
+
+func TestHTTPAgent_WaitPrice_Timeout(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := testPrice(btcusd, 42)
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: price}, nil)
+	s := newTestAgent(t, p)
+	s.ctx = context.Background()
+
+	rec := httptest.NewRecorder()
+	since := strconv.FormatInt(price.Time.Unix()+1, 10)
+	s.handleWaitPrice(rec, httptest.NewRequest(http.MethodGet, "/prices/wait?pair=BTC/USD&timeout=1&since="+since, nil))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestHTTPAgent_WaitPrice_NotFound(t *testing.T) {
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", xyzusd).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: xyzusd})
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handleWaitPrice(rec, httptest.NewRequest(http.MethodGet, "/prices/wait?pair=XYZ/USD", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), graph.ErrPairNotFound{Pair: xyzusd}.Error())
+}
+
+func TestHTTPAgent_WaitPrice_Invalid(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	for _, q := range []string{"", "pair=BTC/USD&since=yesterday", "pair=BTC/USD&timeout=-1"} {
+		rec := httptest.NewRecorder()
+		s.handleWaitPrice(rec, httptest.NewRequest(http.MethodGet, "/prices/wait?"+q, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
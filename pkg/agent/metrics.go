@@ -0,0 +1,237 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+const metricsNamespace = "gofer_agent"
+
+// metrics holds the Prometheus collectors used by the HTTPAgent. Every
+// agent uses its own registry, so multiple agents may exist in a single
+// process.
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	pairLatency     *prometheus.HistogramVec
+	providerErrors  *prometheus.CounterVec
+	lastPriceTime   *prometheus.GaugeVec
+	lastSuccessTime *prometheus.GaugeVec
+	price           *prometheus.GaugeVec
+}
+
+// newMetrics creates the agent collectors and registers them, together
+// with the extra collectors, in a new registry.
+func newMetrics(extra ...prometheus.Collector) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by handler and status code.",
+		}, []string{"handler", "code"}),
+		pairLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "price_fetch_duration_seconds",
+			Help:      "Time spent fetching prices from the price provider, by pair.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"pair"}),
+		providerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "price_fetch_errors_total",
+			Help:      "Number of failed price fetches, by pair.",
+		}, []string{"pair"}),
+		lastPriceTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_price_timestamp_seconds",
+			Help:      "Timestamp of the last successfully fetched price, by pair.",
+		}, []string{"pair"}),
+		lastSuccessTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Time at which a price was last successfully fetched, by pair.",
+		}, []string{"pair"}),
+		price: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: priceMetric,
+			Help: priceMetricHelp,
+		}, []string{"base", "quote"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.pairLatency,
+		m.providerErrors,
+		m.lastPriceTime,
+		m.lastSuccessTime,
+		m.price,
+	)
+	m.registry.MustRegister(extra...)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrument wraps the handler to count requests.
+func (m *metrics) instrument(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rw, r)
+		m.requests.WithLabelValues(name, strconv.Itoa(rw.status)).Inc()
+	}
+}
+
+// otherPairsLabel is the pair label used for pairs which are not supported
+// by the price provider, so that clients requesting arbitrary pairs cannot
+// create an unbounded number of series.
+const otherPairsLabel = "other"
+
+// pairLabel returns the pair label of the pair, which is the pair itself
+// only if it is one of the known pairs.
+func pairLabel(known map[provider.Pair]bool, pair provider.Pair) string {
+	if !known[pair] {
+		return otherPairsLabel
+	}
+	return pair.String()
+}
+
+// observe records the results of a price fetch. Only the known pairs are
+// used as labels, other pairs are recorded under the otherPairsLabel label
+// and have no price gauges. The price gauges of pairs which failed are
+// removed, so stale prices are not exported as current ones.
+func (m *metrics) observe(
+	known map[provider.Pair]bool,
+	pairs []provider.Pair,
+	prices map[provider.Pair]*provider.Price,
+	err error,
+	d time.Duration,
+) {
+	now := float64(time.Now().Unix())
+	for _, pair := range pairs {
+		m.pairLatency.WithLabelValues(pairLabel(known, pair)).Observe(d.Seconds())
+	}
+	if err != nil {
+		for _, pair := range pairs {
+			m.providerErrors.WithLabelValues(pairLabel(known, pair)).Inc()
+			m.price.DeleteLabelValues(pair.Base, pair.Quote)
+		}
+		return
+	}
+	for pair, price := range prices {
+		if price == nil || price.Error != "" {
+			m.providerErrors.WithLabelValues(pairLabel(known, pair)).Inc()
+			m.price.DeleteLabelValues(pair.Base, pair.Quote)
+			continue
+		}
+		if !known[pair] {
+			continue
+		}
+		m.lastPriceTime.WithLabelValues(pair.String()).Set(float64(price.Time.Unix()))
+		m.lastSuccessTime.WithLabelValues(pair.String()).Set(now)
+		m.price.WithLabelValues(pair.Base, pair.Quote).Set(price.Price)
+	}
+}
+
+// instrumentedProvider is a provider.Provider which records metrics for
+// every price fetch.
+type instrumentedProvider struct {
+	provider.Provider
+	metrics *metrics
+}
+
+// Price implements the provider.Provider interface.
+func (p *instrumentedProvider) Price(pair provider.Pair) (*provider.Price, error) {
+	t := time.Now()
+	price, err := p.Provider.Price(pair)
+	p.metrics.observe(p.knownPairs(), []provider.Pair{pair}, map[provider.Pair]*provider.Price{pair: price}, err, time.Since(t))
+	return price, err
+}
+
+// Prices implements the provider.Provider interface.
+func (p *instrumentedProvider) Prices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	t := time.Now()
+	prices, err := p.Provider.Prices(pairs...)
+	observed := pairs
+	if len(observed) == 0 {
+		for pair := range prices {
+			observed = append(observed, pair)
+		}
+	}
+	p.metrics.observe(p.knownPairs(), observed, prices, err, time.Since(t))
+	return prices, err
+}
+
+// knownPairs returns the pairs supported by the provider. They are read on
+// every fetch, because price models may be reloaded.
+func (p *instrumentedProvider) knownPairs() map[provider.Pair]bool {
+	pairs, err := p.Provider.Pairs()
+	if err != nil {
+		return nil
+	}
+	known := make(map[provider.Pair]bool, len(pairs))
+	for _, pair := range pairs {
+		known[pair] = true
+	}
+	return known
+}
+
+// statusRecorder records the status code written by a handler. It
+// implements http.Flusher and http.Hijacker, which are required by the
+// streaming endpoints.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so it can be used
+// with http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
@@ -0,0 +1,99 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestInstrumentedProvider(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	failed := testPrice(ethusd, 0)
+	failed.Error = "no sources"
+	m := newMetrics()
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{btcusd, ethusd}, nil)
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: testPrice(btcusd, 42),
+		ethusd: failed,
+	}, nil).Once()
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("fail")).Once()
+	for _, pair := range []provider.Pair{{Base: "A", Quote: "B"}, {Base: "C", Quote: "D"}} {
+		p.On("Prices", pair).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: pair}).Once()
+	}
+
+	ip := &instrumentedProvider{Provider: p, metrics: m}
+	_, _ = ip.Prices(btcusd, ethusd)
+	assert.Equal(t, 42.0, testutil.ToFloat64(m.price.WithLabelValues("BTC", "USD")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.price))
+
+	// The price of a pair which failed is removed.
+	_, _ = ip.Prices(btcusd)
+	assert.Equal(t, 0, testutil.CollectAndCount(m.price))
+	// Pairs which are not supported do not create new series.
+	_, _ = ip.Prices(provider.Pair{Base: "A", Quote: "B"})
+	_, _ = ip.Prices(provider.Pair{Base: "C", Quote: "D"})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.providerErrors.WithLabelValues("BTC/USD")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.providerErrors.WithLabelValues("ETH/USD")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.providerErrors.WithLabelValues("other")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.providerErrors))
+	assert.Equal(t, 1700000000.0, testutil.ToFloat64(m.lastPriceTime.WithLabelValues("BTC/USD")))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.price))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.pairLatency))
+}
+
+func TestMetrics_Instrument(t *testing.T) {
+	m := newMetrics()
+	h := m.instrument("/test", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("/test", "400")))
+
+	rec := httptest.NewRecorder()
+	m.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "gofer_agent_http_requests_total")
+}
+
+func TestMetrics_ExtraCollectors(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."})
+	c.Inc()
+	s := NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     nopHook{},
+		Logger:        null.New(),
+		Collectors:    []prometheus.Collector{c},
+	})
+	rec := httptest.NewRecorder()
+	s.metrics.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "test_total 1")
+}
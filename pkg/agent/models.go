@@ -0,0 +1,134 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+type jsonModel struct {
+	Type       string            `json:"type"`
+	Base       string            `json:"base"`
+	Quote      string            `json:"quote"`
+	Parameters map[string]string `json:"params,omitempty"`
+	Models     []jsonModel       `json:"models,omitempty"`
+}
+
+func jsonModelFromGoferModel(m *provider.Model) jsonModel {
+	var models []jsonModel
+	for _, c := range m.Models {
+		models = append(models, jsonModelFromGoferModel(c))
+	}
+	return jsonModel{
+		Type:       m.Type,
+		Base:       m.Pair.Base,
+		Quote:      m.Pair.Quote,
+		Parameters: m.Parameters,
+		Models:     models,
+	}
+}
+
+// sortedModels returns the price models for the given pairs, or for all
+// supported pairs if none are given, sorted by pair.
+func (s *HTTPAgent) sortedModels(pairs ...provider.Pair) ([]*provider.Model, error) {
+	models, err := s.priceProvider.Models(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*provider.Model, 0, len(models))
+	for _, m := range models {
+		res = append(res, m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pair.String() < res[j].Pair.String()
+	})
+	return res, nil
+}
+
+// handleModels returns the price models, including their sources,
+// aggregation methods and parameters, for the pairs given in the "pairs"
+// query parameter, or for all supported pairs if the parameter is empty.
+func (s *HTTPAgent) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format, ok := negotiateFormat(r.Header.Get("Accept"))
+	if !ok || (format != nil && format.contentType == "text/csv") {
+		http.Error(w, "none of the accepted media types is supported", http.StatusNotAcceptable)
+		return
+	}
+	pairs, err := pairsFromQuery(r.URL.Query()["pairs"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logRequestedPairs(r.Context(), pairs...)
+	models, err := s.sortedModels(pairs...)
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.requestLog(r).Errorf("failed to get models: %v", err)
+		http.Error(w, "failed to get models", http.StatusInternalServerError)
+		return
+	}
+
+	// The plain text format renders the same model trees as the "gofer
+	// pairs" command.
+	if format != nil && format.contentType != "application/json" && format.contentType != "application/x-ndjson" {
+		m, err := format.newMarshaller()
+		if err != nil {
+			s.requestLog(r).Errorf("failed to create marshaller: %v", err)
+			http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", format.contentType)
+		for _, model := range models {
+			if mErr := m.Write(w, model); mErr != nil {
+				_ = m.Write(w, mErr)
+			}
+		}
+		if err := m.Flush(); err != nil {
+			s.requestLog(r).Errorf("failed to marshal response: %v", err)
+		}
+		return
+	}
+
+	res := make([]jsonModel, 0, len(models))
+	for _, m := range models {
+		res = append(res, jsonModelFromGoferModel(m))
+	}
+	if format != nil && format.contentType == "application/x-ndjson" {
+		w.Header().Set("Content-Type", format.contentType)
+		enc := json.NewEncoder(w)
+		for _, m := range res {
+			_ = enc.Encode(m)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,99 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func testModel(pair provider.Pair) *provider.Model {
+	return &provider.Model{
+		Type:       "median",
+		Pair:       pair,
+		Parameters: map[string]string{"minimumSuccessfulSources": "1"},
+		Models: []*provider.Model{
+			{Type: "origin", Pair: pair, Parameters: map[string]string{"origin": "bitstamp"}},
+		},
+	}
+}
+
+func TestHTTPAgent_GetModels(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models").Return(map[provider.Pair]*provider.Model{
+		ethusd: testModel(ethusd),
+		btcusd: testModel(btcusd),
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handleModels(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var res []jsonModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res, 2)
+	assert.Equal(t, "BTC", res[0].Base)
+	assert.Equal(t, "ETH", res[1].Base)
+	require.Len(t, res[0].Models, 1)
+	assert.Equal(t, "origin", res[0].Models[0].Type)
+	assert.Equal(t, "bitstamp", res[0].Models[0].Parameters["origin"])
+}
+
+func TestHTTPAgent_GetModels_Trace(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model{btcusd: testModel(btcusd)}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/models?pairs=BTC/USD", nil)
+	req.Header.Set("Accept", "text/plain")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handleModels(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bitstamp")
+}
+
+func TestHTTPAgent_GetModels_NotFound(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model(nil), graph.ErrPairNotFound{Pair: btcusd})
+
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handleModels(rec, httptest.NewRequest(http.MethodGet, "/models?pairs=BTC/USD", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHTTPAgent_GetModels_CSVNotAcceptable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/models", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, &mocks.Provider{}).handleModels(rec, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+}
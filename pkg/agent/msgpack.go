@@ -0,0 +1,87 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+// msgpackHandle encodes timestamps with the MessagePack timestamp extension.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+type msgpackItem struct {
+	writer io.Writer
+	data   []byte
+}
+
+// msgpackMarshaller implements the marshal.Marshaller interface. It writes
+// every item as a separate MessagePack value, so a response with several
+// prices is a stream of values. Prices are maps with the same keys as in
+// the JSON format, with the timestamp encoded with the timestamp extension,
+// models are pair strings, and errors are maps with the error key.
+//
+// Items are forgotten once they are flushed, so the marshaller can be
+// flushed repeatedly.
+type msgpackMarshaller struct {
+	items []msgpackItem
+}
+
+// NewMsgpackMarshaller returns a marshaller which writes items in the
+// MessagePack format.
+func NewMsgpackMarshaller() marshal.Marshaller {
+	return &msgpackMarshaller{}
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *msgpackMarshaller) Write(writer io.Writer, item interface{}) error {
+	var v any
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		v = jsonPriceFromGoferPrice(typedItem)
+	case *provider.Model:
+		v = typedItem.Pair.String()
+	case error:
+		v = struct {
+			Error string `json:"error"`
+		}{Error: typedItem.Error()}
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	var data []byte
+	if err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(v); err != nil {
+		return err
+	}
+	m.items = append(m.items, msgpackItem{writer: writer, data: data})
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *msgpackMarshaller) Flush() error {
+	items := m.items
+	m.items = nil
+	for _, i := range items {
+		if _, err := i.writer.Write(i.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
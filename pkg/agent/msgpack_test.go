@@ -0,0 +1,121 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+// decodeMsgpack decodes all MessagePack values from the reader.
+func decodeMsgpack(t *testing.T, r io.Reader) []any {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	dec := codec.NewDecoder(r, h)
+	var values []any
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return values
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+}
+
+func TestMsgpackMarshaller(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := testPrice(btcusd, 42.5)
+	price.Prices = []*provider.Price{{
+		Type:       "origin",
+		Pair:       btcusd,
+		Price:      42.5,
+		Time:       time.Unix(1700000000, 0),
+		Parameters: map[string]string{"origin": "kraken"},
+	}}
+
+	buf := &bytes.Buffer{}
+	m := NewMsgpackMarshaller()
+	require.NoError(t, m.Write(buf, price))
+	require.NoError(t, m.Write(buf, &provider.Model{Pair: btcusd}))
+	require.NoError(t, m.Write(buf, errors.New("failed")))
+	require.NoError(t, m.Flush())
+	require.NoError(t, m.Flush())
+
+	// Every item is a separate value, and prices have the keys of the JSON
+	// format.
+	values := decodeMsgpack(t, buf)
+	require.Len(t, values, 3)
+	ts := time.Unix(1700000000, 0).UTC()
+	assert.Equal(t, map[any]any{
+		"type":   "median",
+		"base":   "BTC",
+		"quote":  "USD",
+		"price":  42.5,
+		"bid":    0.0,
+		"ask":    0.0,
+		"vol24h": 0.0,
+		"ts":     ts,
+		"prices": []any{map[any]any{
+			"type":   "origin",
+			"base":   "BTC",
+			"quote":  "USD",
+			"price":  42.5,
+			"bid":    0.0,
+			"ask":    0.0,
+			"vol24h": 0.0,
+			"ts":     ts,
+			"params": map[any]any{"origin": "kraken"},
+		}},
+	}, values[0])
+	assert.Equal(t, "BTC/USD", values[1])
+	assert.Equal(t, map[any]any{"error": "failed"}, values[2])
+}
+
+func TestHTTPAgent_GetPrices_Msgpack(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: testPrice(btcusd, 42),
+		ethusd: testPrice(ethusd, 21),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD,ETH/USD", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	rec := httptest.NewRecorder()
+	newTestAgent(t, p).handlePrices(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/msgpack", rec.Header().Get("Content-Type"))
+	values := decodeMsgpack(t, rec.Body)
+	require.Len(t, values, 2)
+	assert.Equal(t, "BTC", values[0].(map[any]any)["base"])
+	assert.Equal(t, 21.0, values[1].(map[any]any)["price"])
+}
@@ -0,0 +1,103 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ClientRule authorizes TLS clients with the given certificate common name
+// to access the agent. If Paths is empty, all paths are allowed, otherwise
+// only the given paths and the paths below them, e.g. the /stream rule
+// allows /stream and /stream/x, but not /streams.
+type ClientRule struct {
+	CommonName string
+	Paths      []string
+}
+
+// ParseClientRule parses a client rule in the format "CN" or
+// "CN=/path1,/path2".
+func ParseClientRule(s string) (ClientRule, error) {
+	cn, paths, hasPaths := strings.Cut(s, "=")
+	cn = strings.TrimSpace(cn)
+	if cn == "" {
+		return ClientRule{}, fmt.Errorf("invalid client rule %q: empty common name", s)
+	}
+	r := ClientRule{CommonName: cn}
+	if hasPaths {
+		for _, p := range strings.Split(paths, ",") {
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(p, "/") {
+				return ClientRule{}, fmt.Errorf("invalid client rule %q: path must start with /", s)
+			}
+			r.Paths = append(r.Paths, p)
+		}
+	}
+	return r, nil
+}
+
+func (r ClientRule) allows(path string) bool {
+	if len(r.Paths) == 0 {
+		return true
+	}
+	for _, p := range r.Paths {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no valid certificates found in the client CA bundle")
+	}
+	return pool, nil
+}
+
+// authorizeClients wraps the handler to only allow requests from TLS
+// clients whose certificate matches one of the rules.
+func authorizeClients(rules []ClientRule, h http.Handler) http.Handler {
+	byCN := make(map[string][]ClientRule)
+	for _, r := range rules {
+		byCN[r.CommonName] = append(byCN[r.CommonName], r)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			authFailed(r)
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, rule := range byCN[cn] {
+			if rule.allows(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+}
@@ -0,0 +1,83 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientRule(t *testing.T) {
+	r, err := ParseClientRule("relayer")
+	require.NoError(t, err)
+	assert.Equal(t, ClientRule{CommonName: "relayer"}, r)
+
+	r, err = ParseClientRule("dashboard=/prices, /stream")
+	require.NoError(t, err)
+	assert.Equal(t, ClientRule{CommonName: "dashboard", Paths: []string{"/prices", "/stream"}}, r)
+
+	_, err = ParseClientRule("=/prices")
+	assert.Error(t, err)
+	_, err = ParseClientRule("dashboard=prices")
+	assert.Error(t, err)
+}
+
+func TestAuthorizeClients(t *testing.T) {
+	h := authorizeClients([]ClientRule{
+		{CommonName: "relayer"},
+		{CommonName: "dashboard", Paths: []string{"/stream"}},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(cn, path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cn != "" {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: cn}},
+			}}}
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("relayer", "/prices"))
+	assert.Equal(t, http.StatusOK, request("dashboard", "/stream"))
+	assert.Equal(t, http.StatusForbidden, request("dashboard", "/prices"))
+	assert.Equal(t, http.StatusForbidden, request("unknown", "/prices"))
+	assert.Equal(t, http.StatusUnauthorized, request("", "/prices"))
+}
+
+func TestClientRule_Allows(t *testing.T) {
+	rule := ClientRule{CommonName: "relayer", Paths: []string{"/price", "/webhooks/"}}
+	assert.True(t, rule.allows("/price"))
+	assert.False(t, rule.allows("/prices"))
+	assert.False(t, rule.allows("/pricesX"))
+	assert.True(t, rule.allows("/webhooks/1"))
+	assert.True(t, rule.allows("/webhooks/"))
+	assert.False(t, rule.allows("/webhooks"))
+	assert.False(t, rule.allows("/webhooksX"))
+
+	rule = ClientRule{CommonName: "relayer", Paths: []string{"/prices"}}
+	assert.True(t, rule.allows("/prices/wait"))
+	assert.False(t, rule.allows("/price"))
+}
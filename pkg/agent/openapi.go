@@ -0,0 +1,76 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"gofer-cli/pkg/agent/grpc"
+)
+
+// openAPITemplate is the OpenAPI 3 description of the agent endpoints.
+// Authentication requirements are added by openAPISpec depending on the
+// agent configuration.
+//
+//go:embed openapi.json
+var openAPITemplate []byte
+
+// openAPISpec returns the OpenAPI document for the agent. Every operation
+// that is not explicitly public requires one of the configured
+// authentication methods.
+func (s *HTTPAgent) openAPISpec() ([]byte, error) {
+	var spec map[string]any
+	if err := json.Unmarshal(openAPITemplate, &spec); err != nil {
+		return nil, err
+	}
+	security := []any{}
+	if len(s.apiKeys) > 0 {
+		security = append(security, map[string]any{"apiKey": []any{}})
+	}
+	if len(s.basicAuth) > 0 {
+		security = append(security, map[string]any{"basicAuth": []any{}})
+	}
+	if s.jwt != nil {
+		security = append(security, map[string]any{"bearerAuth": []any{RoleRead}})
+	}
+	spec["security"] = security
+	return json.Marshal(spec)
+}
+
+// handleOpenAPI serves the OpenAPI document.
+func (s *HTTPAgent) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(s.openAPIDoc)
+}
+
+// handleProtoSchema serves the protobuf schema of the messages written with
+// the application/x-protobuf format and used by the gRPC service.
+func (s *HTTPAgent) handleProtoSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(grpc.Schema)
+}
@@ -0,0 +1,100 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_OpenAPI(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	require.NoError(t, s.initServer())
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI  string                    `json:"openapi"`
+		Paths    map[string]map[string]any `json:"paths"`
+		Security []map[string][]string     `json:"security"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Empty(t, spec.Security)
+	for _, path := range []string{
+		"/price", "/prices", "/models", "/pairs", "/ws", "/stream", "/graphql", "/rpc",
+		"/metrics", "/healthz", "/readyz", "/openapi.json", "/admin/reload", "/admin/cache/invalidate",
+		"/webhooks", "/webhooks/{id}", "/prices/wait", "/history", "/gofer.proto",
+	} {
+		assert.Contains(t, spec.Paths, path)
+	}
+}
+
+func TestHTTPAgent_OpenAPI_Security(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.apiKeys = map[string]string{"test": "secret"}
+	s.basicAuth = map[string]string{"test": "hash"}
+	s.jwt = &JWTConfig{HMACSecret: []byte("secret")}
+
+	doc, err := s.openAPISpec()
+	require.NoError(t, err)
+	var spec struct {
+		Security []map[string][]string `json:"security"`
+	}
+	require.NoError(t, json.Unmarshal(doc, &spec))
+	assert.Equal(t, []map[string][]string{
+		{"apiKey": {}},
+		{"basicAuth": {}},
+		{"bearerAuth": {RoleRead}},
+	}, spec.Security)
+}
+
+func TestOpenAPITemplate_Refs(t *testing.T) {
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal(openAPITemplate, &spec))
+	for _, m := range regexp.MustCompile(`"\$ref":\s*"#/([^"]+)"`).FindAllSubmatch(openAPITemplate, -1) {
+		var node any = spec
+		for _, part := range strings.Split(string(m[1]), "/") {
+			obj, ok := node.(map[string]any)
+			require.True(t, ok, "invalid reference %s", m[1])
+			node, ok = obj[part]
+			require.True(t, ok, "invalid reference %s", m[1])
+		}
+	}
+}
+
+func TestHTTPAgent_ProtoSchema(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	require.NoError(t, s.initServer())
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gofer.proto", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "message Price {")
+}
@@ -0,0 +1,87 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// jsonPair describes a supported asset pair.
+type jsonPair struct {
+	Pair       string   `json:"pair"`
+	Base       string   `json:"base"`
+	Quote      string   `json:"quote"`
+	Type       string   `json:"type"`
+	MinSources int      `json:"minSources,omitempty"`
+	Origins    []string `json:"origins"`
+}
+
+// handlePairs returns the list of supported pairs with their aggregation
+// method, minimum number of sources and the origins used to calculate
+// their prices.
+func (s *HTTPAgent) handlePairs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	models, err := s.sortedModels()
+	if err != nil {
+		s.requestLog(r).Errorf("failed to get models: %v", err)
+		http.Error(w, "failed to get pairs", http.StatusInternalServerError)
+		return
+	}
+	res := make([]jsonPair, 0, len(models))
+	minSources := s.minSources()
+	for _, m := range models {
+		res = append(res, jsonPair{
+			Pair:       m.Pair.String(),
+			Base:       m.Pair.Base,
+			Quote:      m.Pair.Quote,
+			Type:       m.Type,
+			MinSources: minSources[m.Pair],
+			Origins:    modelOrigins(m),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// modelOrigins returns the sorted, unique names of the origins used by the
+// model and its sources.
+func modelOrigins(m *provider.Model) []string {
+	set := map[string]struct{}{}
+	var walk func(*provider.Model)
+	walk = func(m *provider.Model) {
+		if origin, ok := m.Parameters["origin"]; ok && m.Type == "origin" {
+			set[origin] = struct{}{}
+		}
+		for _, c := range m.Models {
+			walk(c)
+		}
+	}
+	walk(m)
+	origins := make([]string, 0, len(set))
+	for origin := range set {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
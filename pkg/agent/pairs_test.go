@@ -0,0 +1,69 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_GetPairs(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethbtc := provider.Pair{Base: "ETH", Quote: "BTC"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models").Return(map[provider.Pair]*provider.Model{
+		btcusd: {
+			Type: "median",
+			Pair: btcusd,
+			Models: []*provider.Model{
+				{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "kraken"}},
+				{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "bitstamp"}},
+			},
+		},
+		ethusd: {
+			Type: "indirect",
+			Pair: ethusd,
+			Models: []*provider.Model{
+				{Type: "origin", Pair: ethbtc, Parameters: map[string]string{"origin": "kraken"}},
+				{Type: "reference", Pair: btcusd, Models: []*provider.Model{
+					{Type: "origin", Pair: btcusd, Parameters: map[string]string{"origin": "bitstamp"}},
+				}},
+			},
+		},
+	}, nil)
+	s := newTestAgent(t, p)
+	s.minSources = func() map[provider.Pair]int { return map[provider.Pair]int{btcusd: 2} }
+
+	rec := httptest.NewRecorder()
+	s.handlePairs(rec, httptest.NewRequest(http.MethodGet, "/pairs", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res []jsonPair
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []jsonPair{
+		{Pair: "BTC/USD", Base: "BTC", Quote: "USD", Type: "median", MinSources: 2, Origins: []string{"bitstamp", "kraken"}},
+		{Pair: "ETH/USD", Base: "ETH", Quote: "USD", Type: "indirect", Origins: []string{"bitstamp", "kraken"}},
+	}, res)
+}
@@ -0,0 +1,176 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+// PartialHeader is set to "true" on responses that contain prices for only
+// some of the requested pairs.
+const PartialHeader = "X-Gofer-Partial"
+
+// jsonPartialPrices is the JSON response of the /prices endpoint when
+// prices for some of the requested pairs could not be obtained. Prices
+// contains a jsonPrice for every pair that succeeded and a jsonPairError
+// for every pair that failed.
+type jsonPartialPrices struct {
+	Partial bool  `json:"partial"`
+	Prices  []any `json:"prices"`
+}
+
+type jsonPairError struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+	Error string `json:"error"`
+}
+
+// jsonPricesError is the JSON response of the /prices endpoint when no
+// price could be obtained. Prices contains a jsonPairError for every pair.
+type jsonPricesError struct {
+	Error  string          `json:"error"`
+	Prices []jsonPairError `json:"prices"`
+}
+
+// pairPrices returns checked prices of the pairs. If the prices cannot be
+// obtained or checked for all pairs at once, every pair is tried
+// separately, so a failing pair does not fail the others. The errors of
+// the pairs that failed are returned with the prices of the other ones.
+// Prices with an error are returned as failed pairs.
+func (s *HTTPAgent) pairPrices(r *http.Request, pairs ...provider.Pair) (map[provider.Pair]*provider.Price, map[provider.Pair]error) {
+	prices, err := s.checkedPrices(pairs...)
+	if err == nil || len(pairs) < 2 {
+		if err != nil {
+			s.requestLog(r).Errorf("failed to get prices: %v", err)
+			return nil, map[provider.Pair]error{pairs[0]: pairError(err)}
+		}
+		return s.priceErrors(r, prices, nil)
+	}
+	s.requestLog(r).Warnf("failed to get prices, trying pairs separately: %v", err)
+	prices = make(map[provider.Pair]*provider.Price, len(pairs))
+	errs := make(map[provider.Pair]error)
+	for _, pair := range pairs {
+		if _, ok := prices[pair]; ok {
+			continue
+		}
+		pp, err := s.checkedPrices(pair)
+		if err != nil {
+			s.requestLog(r).Errorf("failed to get price for %s: %v", pair, err)
+			errs[pair] = pairError(err)
+			continue
+		}
+		if p, ok := pp[pair]; ok {
+			prices[pair] = p
+		}
+	}
+	return s.priceErrors(r, prices, errs)
+}
+
+// priceErrors moves prices with an error to the errors of failed pairs.
+// The given prices map is not modified, as it may be shared by a cache.
+func (s *HTTPAgent) priceErrors(r *http.Request, prices map[provider.Pair]*provider.Price, errs map[provider.Pair]error) (map[provider.Pair]*provider.Price, map[provider.Pair]error) {
+	res := make(map[provider.Pair]*provider.Price, len(prices))
+	for pair, price := range prices {
+		if price.Error == "" {
+			res[pair] = price
+			continue
+		}
+		s.requestLog(r).Errorf("failed to get price for %s: %s", pair, price.Error)
+		if errs == nil {
+			errs = make(map[provider.Pair]error)
+		}
+		errs[pair] = errors.New(price.Error)
+	}
+	return res, errs
+}
+
+// writePartialPrices writes the prices of the pairs that succeeded and the
+// errors of the pairs that failed. In the JSON format, both are written as
+// a single list sorted by pair, together with the "partial" flag. Other
+// formats contain only the prices. Partial responses are not cached.
+func (s *HTTPAgent) writePartialPrices(w http.ResponseWriter, r *http.Request, format *responseFormat, prices []*provider.Price, errs map[provider.Pair]error) {
+	w.Header().Set(PartialHeader, "true")
+	w.Header().Set("Cache-Control", "no-store")
+	if format != nil && format.contentType != "application/json" {
+		s.writePrices(w, r, format, prices...)
+		return
+	}
+	type result struct {
+		pair provider.Pair
+		res  any
+	}
+	results := make([]result, 0, len(prices)+len(errs))
+	for _, p := range prices {
+		results = append(results, result{pair: p.Pair, res: jsonPriceFromGoferPrice(p)})
+	}
+	for pair, err := range errs {
+		results = append(results, result{pair: pair, res: jsonPairError{Base: pair.Base, Quote: pair.Quote, Error: err.Error()}})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].pair.String() < results[j].pair.String()
+	})
+	res := jsonPartialPrices{Partial: true, Prices: make([]any, 0, len(results))}
+	for _, r := range results {
+		res.Prices = append(res.Prices, r.res)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// writePricesError writes the errors of all requested pairs, when no price
+// could be obtained, with the status returned by pricesErrorStatus.
+func (s *HTTPAgent) writePricesError(w http.ResponseWriter, errs map[provider.Pair]error) {
+	res := jsonPricesError{Error: "failed to get prices", Prices: make([]jsonPairError, 0, len(errs))}
+	for pair, err := range errs {
+		res.Prices = append(res.Prices, jsonPairError{Base: pair.Base, Quote: pair.Quote, Error: err.Error()})
+	}
+	sort.Slice(res.Prices, func(i, j int) bool {
+		return res.Prices[i].Base+"/"+res.Prices[i].Quote < res.Prices[j].Base+"/"+res.Prices[j].Quote
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(pricesErrorStatus(errs))
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// pricesErrorStatus returns the status of a response in which no price
+// could be obtained: 404 if none of the pairs is known, 500 otherwise.
+func pricesErrorStatus(errs map[provider.Pair]error) int {
+	for _, err := range errs {
+		var notFound graph.ErrPairNotFound
+		if !errors.As(err, &notFound) {
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusNotFound
+}
+
+// pairError returns the error reported to clients for a pair that failed.
+// Only errors caused by the request are reported as they are; details of
+// other errors are logged instead.
+func pairError(err error) error {
+	var notFound graph.ErrPairNotFound
+	if errors.As(err, &notFound) {
+		return err
+	}
+	return errors.New("failed to get price")
+}
@@ -0,0 +1,162 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_PartialPrices(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", xyzusd, ethusd, btcusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	p.On("Prices", ethusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("origin unavailable"))
+	p.On("Prices", xyzusd).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: xyzusd})
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=XYZ/USD,ETH/USD,BTC/USD", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(PartialHeader))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Empty(t, rec.Header().Get("ETag"))
+
+	var res struct {
+		Partial bool        `json:"partial"`
+		Prices  []jsonPrice `json:"prices"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.True(t, res.Partial)
+	require.Len(t, res.Prices, 3)
+	assert.Equal(t, "BTC", res.Prices[0].Base)
+	assert.Equal(t, 42.0, res.Prices[0].Price)
+	assert.Empty(t, res.Prices[0].Error)
+	assert.Equal(t, "ETH", res.Prices[1].Base)
+	assert.Equal(t, "failed to get price", res.Prices[1].Error)
+	assert.Equal(t, "XYZ", res.Prices[2].Base)
+	assert.Equal(t, graph.ErrPairNotFound{Pair: xyzusd}.Error(), res.Prices[2].Error)
+
+	// Other formats contain only the prices that succeeded.
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=XYZ/USD,ETH/USD,BTC/USD", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	s.handlePrices(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(PartialHeader))
+	var price jsonPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &price))
+	assert.Equal(t, "BTC", price.Base)
+}
+
+func TestHTTPAgent_PartialPrices_AllFailed(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	p.On("Prices", ethusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD,ETH/USD", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get(PartialHeader))
+	assert.JSONEq(t, `{"error":"failed to get prices","prices":[
+		{"base":"BTC","quote":"USD","error":"failed to get price"},
+		{"base":"ETH","quote":"USD","error":"failed to get price"}
+	]}`, rec.Body.String())
+}
+
+func TestHTTPAgent_PartialPrices_AllNotFound(t *testing.T) {
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", xyzusd).Return(map[provider.Pair]*provider.Price(nil), graph.ErrPairNotFound{Pair: xyzusd})
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=XYZ/USD", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"failed to get prices","prices":[
+		{"base":"XYZ","quote":"USD","error":"`+graph.ErrPairNotFound{Pair: xyzusd}.Error()+`"}
+	]}`, rec.Body.String())
+}
+
+func TestHTTPAgent_PartialPrices_PriceError(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	failed := testPrice(ethusd, 0)
+	failed.Error = "not enough sources"
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd, ethusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42), ethusd: failed}, nil)
+	p.On("Prices", ethusd).Return(map[provider.Pair]*provider.Price{ethusd: failed}, nil)
+	s := newTestAgent(t, p)
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD,ETH/USD", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(PartialHeader))
+	var res struct {
+		Partial bool `json:"partial"`
+		Prices  []struct {
+			Base  string `json:"base"`
+			Error string `json:"error"`
+		} `json:"prices"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Prices, 2)
+	assert.Empty(t, res.Prices[0].Error)
+	assert.Equal(t, "ETH", res.Prices[1].Base)
+	assert.Equal(t, "not enough sources", res.Prices[1].Error)
+
+	// A single pair with an error fails the request.
+	rec = httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices?pairs=ETH/USD", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error":"failed to get prices","prices":[
+		{"base":"ETH","quote":"USD","error":"not enough sources"}
+	]}`, rec.Body.String())
+}
+
+func TestHTTPAgent_Prices_NoPairs(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+
+	rec := httptest.NewRecorder()
+	s.handlePrices(rec, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/prices", strings.NewReader(`{"pairs":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	s.handlePrices(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
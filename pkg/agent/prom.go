@@ -0,0 +1,140 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/marshal"
+)
+
+// Name and help of the Prometheus metric with prices, written by the
+// Prometheus marshaller and exported by the agent at /metrics.
+const (
+	priceMetric     = "gofer_price"
+	priceMetricHelp = "Asset price, by base and quote asset."
+)
+
+type promWriter struct {
+	writer io.Writer
+	prices []*provider.Price
+	errors []string
+}
+
+// promMarshaller implements the marshal.Marshaller interface. It writes
+// prices in the Prometheus text exposition format, as samples of the
+// gofer_price gauge labelled with the base and quote assets, with the time
+// of the price as the sample timestamp:
+//
+//	gofer_price{base="ETH",quote="USD"} 3021.4 1700000000000
+//
+// Samples are sorted by pair. Prices with an error and other errors have
+// no value, so they are written as comments, which are ignored by
+// Prometheus.
+//
+// Items are forgotten once they are flushed, so the marshaller can be
+// flushed repeatedly.
+type promMarshaller struct {
+	writers []*promWriter
+}
+
+// NewPrometheusMarshaller returns a marshaller which writes prices in the
+// Prometheus text exposition format.
+func NewPrometheusMarshaller() marshal.Marshaller {
+	return &promMarshaller{}
+}
+
+// Write implements the marshal.Marshaller interface.
+func (m *promMarshaller) Write(writer io.Writer, item interface{}) error {
+	var w *promWriter
+	for _, pw := range m.writers {
+		if pw.writer == writer {
+			w = pw
+			break
+		}
+	}
+	if w == nil {
+		w = &promWriter{writer: writer}
+		m.writers = append(m.writers, w)
+	}
+	switch typedItem := item.(type) {
+	case *provider.Price:
+		if typedItem.Error != "" {
+			w.errors = append(w.errors, typedItem.Pair.String()+": "+typedItem.Error)
+			return nil
+		}
+		w.prices = append(w.prices, typedItem)
+	case error:
+		w.errors = append(w.errors, typedItem.Error())
+	default:
+		return fmt.Errorf("unsupported data type")
+	}
+	return nil
+}
+
+// Flush implements the marshal.Marshaller interface.
+func (m *promMarshaller) Flush() error {
+	writers := m.writers
+	m.writers = nil
+	for _, w := range writers {
+		if _, err := io.WriteString(w.writer, w.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *promWriter) String() string {
+	var sb strings.Builder
+	sort.SliceStable(w.prices, func(i, j int) bool {
+		return w.prices[i].Pair.String() < w.prices[j].Pair.String()
+	})
+	if len(w.prices) > 0 {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", priceMetric, priceMetricHelp)
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", priceMetric)
+	}
+	for _, p := range w.prices {
+		fmt.Fprintf(
+			&sb,
+			"%s{base=%s,quote=%s} %s",
+			priceMetric,
+			promLabelValue(p.Pair.Base),
+			promLabelValue(p.Pair.Quote),
+			strconv.FormatFloat(p.Price, 'f', -1, 64),
+		)
+		if !p.Time.IsZero() {
+			fmt.Fprintf(&sb, " %d", p.Time.UnixMilli())
+		}
+		sb.WriteString("\n")
+	}
+	for _, e := range w.errors {
+		// A comment must not span lines.
+		fmt.Fprintf(&sb, "# error: %s\n", strings.ReplaceAll(e, "\n", " "))
+	}
+	return sb.String()
+}
+
+// promLabelValue returns the quoted label value, with backslashes, double
+// quotes and line feeds escaped as required by the exposition format.
+func promLabelValue(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
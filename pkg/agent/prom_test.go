@@ -0,0 +1,67 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+func TestPrometheusMarshaller(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	failed := testPrice(provider.Pair{Base: "MKR", Quote: "USD"}, 0)
+	failed.Error = "no sources"
+
+	m := NewPrometheusMarshaller()
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf, testPrice(ethusd, 3021.4)))
+	require.NoError(t, m.Write(&buf, testPrice(btcusd, 42)))
+	require.NoError(t, m.Write(&buf, failed))
+	require.NoError(t, m.Write(&buf, errors.New("failed")))
+	assert.Error(t, m.Write(&buf, &provider.Model{Pair: btcusd}))
+	assert.Zero(t, buf.Len())
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, "# HELP gofer_price Asset price, by base and quote asset.\n"+
+		"# TYPE gofer_price gauge\n"+
+		`gofer_price{base="BTC",quote="USD"} 42 1700000000000`+"\n"+
+		`gofer_price{base="ETH",quote="USD"} 3021.4 1700000000000`+"\n"+
+		"# error: MKR/USD: no sources\n"+
+		"# error: failed\n", buf.String())
+
+	// The output must be accepted by Prometheus.
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, families["gofer_price"].GetMetric(), 2)
+	assert.Equal(t, 3021.4, families["gofer_price"].GetMetric()[1].GetGauge().GetValue())
+
+	// Flushed items are not written again.
+	buf.Reset()
+	require.NoError(t, m.Flush())
+	assert.Zero(t, buf.Len())
+}
+
+func TestPromLabelValue(t *testing.T) {
+	assert.Equal(t, `"a\\b\"c\nd"`, promLabelValue("a\\b\"c\nd"))
+}
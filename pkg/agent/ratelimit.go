@@ -0,0 +1,217 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout describes how long a client limiter is kept after
+// the last request from the client.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimitConfig is the configuration of per-client rate limiting. Clients
+// are identified by the API key label if the request was authenticated with
+// an API key, and by the IP address otherwise. If the IP filter is enabled,
+// the client address resolved by the filter is used. Failed authentication
+// attempts are counted against the IP address of the client.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the number of requests per second allowed for a
+	// single client.
+	RequestsPerSecond float64
+	// Burst is the maximum number of requests a client can make at once.
+	// If zero, it defaults to RequestsPerSecond rounded up.
+	Burst int
+}
+
+// rateLimiter limits requests using a token bucket per client.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*clientLimiter
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(cfg.RequestsPerSecond))
+	}
+	return &rateLimiter{
+		limit:    rate.Limit(cfg.RequestsPerSecond),
+		burst:    burst,
+		limiters: make(map[string]*clientLimiter),
+	}
+}
+
+// reserve reserves a token for the given client. It returns zero if the
+// request is allowed, or the time after which the client may retry.
+func (l *rateLimiter) reserve(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	cl, ok := l.limiters[client]
+	if !ok {
+		cl = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[client] = cl
+	}
+	cl.lastSeen = now
+	l.mu.Unlock()
+
+	r := cl.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return time.Second
+	}
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return d
+	}
+	return 0
+}
+
+// delay returns the time after which the given client may make a request,
+// or zero if it may make one now. Unlike reserve, it does not use a token.
+func (l *rateLimiter) delay(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	cl, ok := l.limiters[client]
+	l.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	tokens := cl.limiter.TokensAt(now)
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / float64(l.limit) * float64(time.Second))
+}
+
+// cleanup removes limiters of clients that have been idle for too long.
+func (l *rateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for client, cl := range l.limiters {
+		if now.Sub(cl.lastSeen) > rateLimiterIdleTimeout {
+			delete(l.limiters, client)
+		}
+	}
+}
+
+// run periodically removes idle limiters until the context is canceled.
+func (l *rateLimiter) run(ctx context.Context) {
+	t := time.NewTicker(rateLimiterIdleTimeout)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			l.cleanup(now)
+		}
+	}
+}
+
+// limitRate wraps the handler to reject requests from clients that exceed
+// the rate limit with 429 Too Many Requests. Health check endpoints are not
+// limited.
+func (s *HTTPAgent) limitRate(l *rateLimiter, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		client := rateLimitKey(r)
+		if d := l.reserve(client, time.Now()); d > 0 {
+			s.rejectRate(w, r, client, d)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// limitAuthFailures wraps the authentication layers to count failed
+// authentication attempts against the IP address of the client. Clients
+// which exceed the rate limit with failed attempts are rejected with 429
+// Too Many Requests before their credentials are checked.
+func (s *HTTPAgent) limitAuthFailures(l *rateLimiter, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		client := clientIPKey(r)
+		if d := l.delay(client, time.Now()); d > 0 {
+			s.rejectRate(w, r, client, d)
+			return
+		}
+		failed := func() { l.reserve(client, time.Now()) }
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authFailureCtxKey{}, failed)))
+	})
+}
+
+type authFailureCtxKey struct{}
+
+// authFailed counts a failed authentication attempt of the request against
+// the rate limit of the client, if the rate limit is enabled.
+func authFailed(r *http.Request) {
+	if failed, ok := r.Context().Value(authFailureCtxKey{}).(func()); ok {
+		failed()
+	}
+}
+
+// rejectRate responds with 429 Too Many Requests and the time after which
+// the client may retry.
+func (s *HTTPAgent) rejectRate(w http.ResponseWriter, r *http.Request, client string, d time.Duration) {
+	s.requestLog(r).
+		WithField("client", client).
+		WithField("path", r.URL.Path).
+		Debug("Rate limit exceeded")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+func rateLimitKey(r *http.Request) string {
+	if label := apiKeyLabel(r.Context()); label != "" {
+		return "key:" + label
+	}
+	if user := basicAuthUser(r.Context()); user != "" {
+		return "user:" + user
+	}
+	return clientIPKey(r)
+}
+
+// clientIPKey returns the rate limit key of the IP address of the client.
+func clientIPKey(r *http.Request) string {
+	if ip := clientIPFromContext(r.Context()); ip.IsValid() {
+		return "ip:" + ip.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,102 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{RequestsPerSecond: 1, Burst: 2})
+	now := time.Now()
+
+	assert.Zero(t, l.reserve("a", now))
+	assert.Zero(t, l.reserve("a", now))
+	assert.Equal(t, time.Second, l.reserve("a", now))
+	assert.Zero(t, l.reserve("b", now))
+	assert.Zero(t, l.reserve("a", now.Add(time.Second)))
+
+	l.cleanup(now.Add(time.Second + rateLimiterIdleTimeout + 1))
+	assert.Empty(t, l.limiters)
+}
+
+func TestHTTPAgent_LimitRate(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{RequestsPerSecond: 0.1})
+	h := newTestAgent(t, &mocks.Provider{}).limitRate(l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	request := func(path, addr, label string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = addr
+		if label != "" {
+			req = req.WithContext(context.WithValue(req.Context(), apiKeyLabelCtxKey{}, label))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request("/prices", "10.0.0.1:1000", "").Code)
+	rec := request("/prices", "10.0.0.1:1001", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+
+	// Other clients and health checks are not affected.
+	assert.Equal(t, http.StatusOK, request("/prices", "10.0.0.2:1000", "").Code)
+	assert.Equal(t, http.StatusOK, request("/prices", "10.0.0.1:1000", "relayer").Code)
+	assert.Equal(t, http.StatusOK, request("/healthz", "10.0.0.1:1000", "").Code)
+}
+
+func TestHTTPAgent_LimitAuthFailures(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.apiKeys = map[string]string{"test": "secret"}
+	s.rateLimiter = newRateLimiter(RateLimitConfig{RequestsPerSecond: 0.1, Burst: 3})
+	require.NoError(t, s.initServer())
+
+	request := func(addr, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/prices", nil)
+		req.RemoteAddr = addr
+		req.Header.Set(APIKeyHeader, key)
+		var accessErr *AccessError
+		if errors.As(s.Authorize(req), &accessErr) {
+			return accessErr.HTTPStatus()
+		}
+		return http.StatusOK
+	}
+
+	// Invalid keys are counted against the IP address until it is limited.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1000", "invalid"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1000", "invalid"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1000", "secret"))
+
+	// Other clients are not affected, and valid keys do not count as
+	// failed attempts.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request("10.0.0.2:1000", "secret"))
+	}
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.2:1000", "invalid"))
+}
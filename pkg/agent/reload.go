@@ -0,0 +1,140 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// ReloadFunc re-reads the configuration and swaps in the new price models,
+// e.g. with ReloadableProvider.Swap. The context is canceled when the
+// reload request ends, so services created by the function must not be
+// bound to it.
+type ReloadFunc func(ctx context.Context) error
+
+// ReloadableProvider is a price provider and price hook which delegates to
+// implementations that can be replaced at runtime. It allows the price
+// models and origins to be reloaded without interrupting clients.
+type ReloadableProvider struct {
+	state atomic.Pointer[reloadableState]
+}
+
+type reloadableState struct {
+	provider   provider.Provider
+	hook       provider.PriceHook
+	minSources map[provider.Pair]int
+}
+
+// NewReloadableProvider creates a new ReloadableProvider. The minSources
+// map is the minimum number of sources of each pair, as reported by the
+// /pairs endpoint.
+func NewReloadableProvider(
+	p provider.Provider,
+	hook provider.PriceHook,
+	minSources map[provider.Pair]int,
+) *ReloadableProvider {
+	r := &ReloadableProvider{}
+	r.Swap(p, hook, minSources)
+	return r
+}
+
+// Swap atomically replaces the price provider, price hook and minimum
+// numbers of sources. Requests already in progress use the previous ones.
+func (r *ReloadableProvider) Swap(p provider.Provider, hook provider.PriceHook, minSources map[provider.Pair]int) {
+	r.state.Store(&reloadableState{provider: p, hook: hook, minSources: minSources})
+}
+
+// MinSources returns the minimum number of sources of each pair.
+func (r *ReloadableProvider) MinSources() map[provider.Pair]int {
+	return r.state.Load().minSources
+}
+
+// Models implements the provider.Provider interface.
+func (r *ReloadableProvider) Models(pairs ...provider.Pair) (map[provider.Pair]*provider.Model, error) {
+	return r.state.Load().provider.Models(pairs...)
+}
+
+// Price implements the provider.Provider interface.
+func (r *ReloadableProvider) Price(pair provider.Pair) (*provider.Price, error) {
+	return r.state.Load().provider.Price(pair)
+}
+
+// Prices implements the provider.Provider interface.
+func (r *ReloadableProvider) Prices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	return r.state.Load().provider.Prices(pairs...)
+}
+
+// Pairs implements the provider.Provider interface.
+func (r *ReloadableProvider) Pairs() ([]provider.Pair, error) {
+	return r.state.Load().provider.Pairs()
+}
+
+// Check implements the provider.PriceHook interface.
+func (r *ReloadableProvider) Check(prices map[provider.Pair]*provider.Price) error {
+	return r.state.Load().hook.Check(prices)
+}
+
+// reloadResponse is the response of the /admin/reload endpoint.
+type reloadResponse struct {
+	Pairs []string `json:"pairs"`
+}
+
+// handleReload reloads the configuration with the configured ReloadFunc
+// and responds with the pairs supported after the reload.
+func (s *HTTPAgent) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if err := s.reload(r.Context()); err != nil {
+		s.requestLog(r).WithError(err).Error("Unable to reload configuration")
+		http.Error(w, "reload failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pairs, err := s.priceProvider.Pairs()
+	if err != nil {
+		s.requestLog(r).WithError(err).Error("Unable to list pairs after reload")
+		http.Error(w, "failed to get pairs", http.StatusInternalServerError)
+		return
+	}
+	res := reloadResponse{Pairs: make([]string, 0, len(pairs))}
+	for _, p := range pairs {
+		res.Pairs = append(res.Pairs, p.String())
+	}
+	sort.Strings(res.Pairs)
+	s.requestLog(r).WithField("pairs", len(res.Pairs)).Info("Configuration reloaded")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// checkAdminAuth verifies that the admin endpoints are protected by at
+// least one authentication method.
+func (s *HTTPAgent) checkAdminAuth() error {
+	if len(s.apiKeys) == 0 && len(s.basicAuth) == 0 && s.jwt == nil && len(s.tlsClientRules) == 0 {
+		return errors.New("the admin endpoints require API keys, Basic auth, JWT or client certificate authentication")
+	}
+	return nil
+}
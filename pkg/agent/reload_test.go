@@ -0,0 +1,106 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_Reload(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p1 := &mocks.Provider{}
+	p1.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	p2 := &mocks.Provider{}
+	p2.On("Prices", ethusd).Return(map[provider.Pair]*provider.Price{ethusd: testPrice(ethusd, 21)}, nil)
+	p1.On("Pairs").Return([]provider.Pair{btcusd}, nil)
+	p2.On("Pairs").Return([]provider.Pair{ethusd, btcusd}, nil)
+
+	rp := NewReloadableProvider(p1, nopHook{}, map[provider.Pair]int{btcusd: 1})
+	s := NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider: rp,
+		PriceHook:     rp,
+		Logger:        null.New(),
+		APIKeys:       map[string]string{"admin": "secret"},
+		Reload: func(context.Context) error {
+			rp.Swap(p2, nopHook{}, map[provider.Pair]int{ethusd: 3})
+			return nil
+		},
+	})
+	require.NoError(t, s.initServer())
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(APIKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/price?pair=BTC/USD").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/admin/reload").Code)
+
+	rec := request(http.MethodPost, "/admin/reload")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res reloadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []string{"BTC/USD", "ETH/USD"}, res.Pairs)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/price?pair=ETH/USD").Code)
+	assert.Equal(t, map[provider.Pair]int{ethusd: 3}, s.minSources())
+	p1.AssertExpectations(t)
+	p2.AssertExpectations(t)
+}
+
+func TestHTTPAgent_Reload_Error(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.reload = func(context.Context) error { return errors.New("invalid config") }
+
+	rec := httptest.NewRecorder()
+	s.handleReload(rec, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid config")
+}
+
+func TestHTTPAgent_Reload_RequiresAuth(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.reload = func(context.Context) error { return nil }
+	assert.Error(t, s.initServer())
+}
+
+func TestHTTPAgent_MinSourcesFromHook(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	rp := NewReloadableProvider(&mocks.Provider{}, nopHook{}, map[provider.Pair]int{btcusd: 1})
+	s := NewHTTPAgent(HTTPAgentConfig{
+		PriceProvider: &mocks.Provider{},
+		PriceHook:     rp,
+		Logger:        null.New(),
+	})
+	rp.Swap(&mocks.Provider{}, nopHook{}, map[provider.Pair]int{btcusd: 2})
+	assert.Equal(t, map[provider.Pair]int{btcusd: 2}, s.minSources())
+}
@@ -0,0 +1,84 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+)
+
+// RequestIDHeader is the header used to pass request IDs. An ID sent by the
+// client is reused, otherwise a new one is generated. The ID is returned in
+// the response and added to all log lines related to the request.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID sent by the
+// client. Longer IDs are replaced with generated ones.
+const maxRequestIDLength = 128
+
+type requestIDCtxKey struct{}
+
+// requestID returns the ID of the request, or an empty string if the
+// request has no ID.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// assignRequestID wraps the handler to assign an ID to every request.
+func assignRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)))
+	})
+}
+
+// requestLog returns the logger with the ID of the request, if it has one.
+func (s *HTTPAgent) requestLog(r *http.Request) log.Logger {
+	if id := requestID(r.Context()); id != "" {
+		return s.log.WithField("requestID", id)
+	}
+	return s.log
+}
+
+// validRequestID reports whether a request ID sent by the client may be
+// used. Only short IDs made of printable ASCII characters other than
+// spaces are accepted, so they cannot be used to forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,91 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/callback"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestAssignRequestID(t *testing.T) {
+	var id string
+	h := assignRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = requestID(r.Context())
+	}))
+
+	tests := []struct {
+		header string
+		reused bool
+	}{
+		{header: "", reused: false},
+		{header: "abc-123", reused: true},
+		{header: "with space", reused: false},
+		{header: "line\nbreak", reused: false},
+		{header: strings.Repeat("a", maxRequestIDLength+1), reused: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/prices", nil)
+		if tt.header != "" {
+			req.Header[http.CanonicalHeaderKey(RequestIDHeader)] = []string{tt.header}
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, id, rec.Header().Get(RequestIDHeader))
+		if tt.reused {
+			assert.Equal(t, tt.header, id)
+		} else {
+			assert.Len(t, id, 32, "header %q", tt.header)
+		}
+	}
+}
+
+func TestHTTPAgent_RequestIDInLogs(t *testing.T) {
+	var mu sync.Mutex
+	var entries []log.Fields
+	s := newTestAgent(t, &mocks.Provider{})
+	s.log = callback.New(log.Debug, func(_ log.Level, fields log.Fields, _ string) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, fields)
+	})
+	s.accessLog = true
+	s.apiKeys = map[string]string{"relayer": "secret"}
+	require.NoError(t, s.initServer())
+	entries = nil
+
+	req := httptest.NewRequest(http.MethodGet, "/prices?pairs=BTC/USD", nil)
+	req.Header.Set(APIKeyHeader, "invalid")
+	req.Header.Set(RequestIDHeader, "trace-1")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "trace-1", rec.Header().Get(RequestIDHeader))
+	// The invalid API key warning and the access log entry.
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.Equal(t, "trace-1", e["requestID"])
+	}
+}
@@ -0,0 +1,106 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+// startShutdownTestServer serves the handler through the agent's in-flight
+// tracking and returns the server URL and a function that cancels the
+// agent's context.
+func startShutdownTestServer(t *testing.T, s *HTTPAgent, h http.Handler) (string, context.CancelFunc) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	s.server = &http.Server{Handler: s.trackInFlight(h)}
+	go func() { _ = s.server.Serve(ln) }()
+	go s.contextCancelHandler()
+	return "http://" + ln.Addr().String(), cancel
+}
+
+func TestHTTPAgent_Shutdown_DrainsRequests(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.shutdownTimeout = time.Second
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	url, cancel := startShutdownTestServer(t, s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	resCh := make(chan int, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err != nil {
+			resCh <- 0
+			return
+		}
+		res.Body.Close()
+		resCh <- res.StatusCode
+	}()
+
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-resCh)
+	assert.NoError(t, <-s.Wait())
+	assert.Equal(t, int64(0), s.inFlight.Load())
+}
+
+func TestHTTPAgent_Shutdown_Timeout(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.shutdownTimeout = 50 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	url, cancel := startShutdownTestServer(t, s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go func() {
+		res, err := http.Get(url)
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-s.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("agent did not stop after the shutdown timeout")
+	}
+	assert.Equal(t, int64(1), s.inFlight.Load())
+}
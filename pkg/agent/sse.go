@@ -0,0 +1,94 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// sseRetry is the reconnection time, in milliseconds, suggested to clients.
+const sseRetry = 3000
+
+// handleStream streams price updates for the pairs given in the "pairs"
+// query parameter as Server-Sent Events. Every event carries an ID, so
+// clients that reconnect with the Last-Event-ID header receive the updates
+// they have missed.
+func (s *HTTPAgent) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	pairs, err := pairsFromQuery(r.URL.Query()["pairs"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(pairs) == 0 {
+		http.Error(w, "at least one pair is required", http.StatusBadRequest)
+		return
+	}
+
+	logRequestedPairs(r.Context(), pairs...)
+
+	sub := s.stream.subscribe()
+	defer s.stream.unsubscribe(sub)
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID header", http.StatusBadRequest)
+			return
+		}
+		s.stream.resume(sub, lastID, pairs...)
+	} else {
+		s.stream.addPairs(sub, pairs...)
+	}
+
+	disableWriteDeadline(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-r.Context().Done():
+			return
+		case ev := <-sub.ch:
+			b, err := json.Marshal(jsonPriceFromGoferPrice(ev.Price))
+			if err != nil {
+				s.requestLog(r).Errorf("failed to marshal price: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: price\ndata: %s\n\n", ev.ID, b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
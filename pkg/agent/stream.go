@@ -0,0 +1,287 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+const (
+	// subscriberBufferSize is the number of price updates that may be queued
+	// for a single subscriber before further updates are dropped.
+	subscriberBufferSize = 64
+
+	// streamHistorySize is the number of recent price events kept to allow
+	// clients to resume a stream after reconnecting.
+	streamHistorySize = 256
+)
+
+// priceEvent is a single price update published by the priceStream. IDs are
+// assigned sequentially, starting from 1.
+type priceEvent struct {
+	ID    uint64
+	Price *provider.Price
+}
+
+// priceStream periodically fetches prices for all subscribed pairs and
+// notifies subscribers about prices that have changed since the last
+// refresh.
+type priceStream struct {
+	mu sync.Mutex
+
+	interval      time.Duration
+	updates       <-chan provider.Pair
+	priceProvider provider.Provider
+	priceHook     provider.PriceHook
+	log           log.Logger
+	subscribers   map[*subscriber]struct{}
+	last          map[provider.Pair]priceEvent
+	history       []priceEvent
+	lastID        uint64
+}
+
+// subscriber receives price updates for the pairs it is subscribed to.
+type subscriber struct {
+	pairs map[provider.Pair]struct{}
+	ch    chan priceEvent
+}
+
+func newPriceStream(
+	p provider.Provider,
+	h provider.PriceHook,
+	interval time.Duration,
+	updates <-chan provider.Pair,
+	l log.Logger,
+) *priceStream {
+	return &priceStream{
+		interval:      interval,
+		updates:       updates,
+		priceProvider: p,
+		priceHook:     h,
+		log:           l,
+		subscribers:   make(map[*subscriber]struct{}),
+		last:          make(map[provider.Pair]priceEvent),
+	}
+}
+
+// SubscribePrices subscribes to the price stream shared with the WebSocket
+// and SSE endpoints and calls send for every update of the given pairs,
+// starting with the last known prices. It blocks until the context is
+// canceled, send returns an error or the agent is stopped. The agent must
+// be started.
+func (s *HTTPAgent) SubscribePrices(
+	ctx context.Context,
+	pairs []provider.Pair,
+	send func(*provider.Price) error,
+) error {
+	sub := s.stream.subscribe()
+	defer s.stream.unsubscribe(sub)
+	s.stream.addPairs(sub, pairs...)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return errors.New("agent is stopped")
+		case <-ctx.Done():
+			return nil
+		case ev := <-sub.ch:
+			if err := send(ev.Price); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscribe registers a new subscriber without any pairs.
+func (s *priceStream) subscribe() *subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := &subscriber{
+		pairs: make(map[provider.Pair]struct{}),
+		ch:    make(chan priceEvent, subscriberBufferSize),
+	}
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes the subscriber. The subscriber channel is not closed,
+// so it is safe to call unsubscribe while the channel is being read.
+func (s *priceStream) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, sub)
+}
+
+// addPairs subscribes the subscriber to the given pairs. The last known
+// price for each pair, if any, is delivered immediately.
+func (s *priceStream) addPairs(sub *subscriber, pairs ...provider.Pair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pair := range pairs {
+		if _, ok := sub.pairs[pair]; ok {
+			continue
+		}
+		sub.pairs[pair] = struct{}{}
+		if ev, ok := s.last[pair]; ok {
+			s.send(sub, ev)
+		}
+	}
+}
+
+// resume subscribes the subscriber to the given pairs and replays all
+// events published after the event with the given ID. If the event is no
+// longer in the history, the last known price for each pair is delivered
+// instead, as in addPairs.
+func (s *priceStream) resume(sub *subscriber, lastID uint64, pairs ...provider.Pair) {
+	s.mu.Lock()
+	if len(s.history) == 0 || lastID+1 < s.history[0].ID || lastID > s.lastID {
+		s.mu.Unlock()
+		s.addPairs(sub, pairs...)
+		return
+	}
+	defer s.mu.Unlock()
+	for _, pair := range pairs {
+		sub.pairs[pair] = struct{}{}
+	}
+	for _, ev := range s.history {
+		if _, ok := sub.pairs[ev.Price.Pair]; ok && ev.ID > lastID {
+			s.send(sub, ev)
+		}
+	}
+}
+
+// removePairs unsubscribes the subscriber from the given pairs.
+func (s *priceStream) removePairs(sub *subscriber, pairs ...provider.Pair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pair := range pairs {
+		delete(sub.pairs, pair)
+	}
+}
+
+// run refreshes prices until the context is canceled. Prices are refreshed
+// at the interval and whenever a pair is received from the updates channel.
+func (s *priceStream) run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	updates := s.updates
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.refresh()
+		case pair, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			s.refresh(pair)
+		}
+	}
+}
+
+// refresh fetches prices for the given pairs, or for all subscribed pairs
+// if none are given, and publishes the ones that have changed. Pairs
+// without subscribers are skipped.
+func (s *priceStream) refresh(pairs ...provider.Pair) {
+	pairs = s.subscribedPairs(pairs...)
+	if len(pairs) == 0 {
+		return
+	}
+	prices, err := s.priceProvider.Prices(pairs...)
+	if err != nil {
+		s.log.Errorf("failed to get prices: %v", err)
+		return
+	}
+	if err := s.priceHook.Check(prices); err != nil {
+		s.log.Errorf("failed to check prices: %v", err)
+		return
+	}
+	s.publish(prices)
+}
+
+// publish delivers the given prices to subscribers if they differ from the
+// previously published ones.
+func (s *priceStream) publish(prices map[provider.Pair]*provider.Price) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for pair, price := range prices {
+		if prev, ok := s.last[pair]; ok && !priceChanged(prev.Price, price) {
+			continue
+		}
+		s.lastID++
+		ev := priceEvent{ID: s.lastID, Price: price}
+		s.last[pair] = ev
+		s.history = append(s.history, ev)
+		if len(s.history) > streamHistorySize {
+			s.history = s.history[len(s.history)-streamHistorySize:]
+		}
+		for sub := range s.subscribers {
+			if _, ok := sub.pairs[pair]; ok {
+				s.send(sub, ev)
+			}
+		}
+	}
+}
+
+// send delivers an event to a subscriber without blocking. It must be
+// called with the lock held.
+func (s *priceStream) send(sub *subscriber, ev priceEvent) {
+	select {
+	case sub.ch <- ev:
+	default:
+		s.log.
+			WithField("assetPair", ev.Price.Pair).
+			Warn("Subscriber is too slow, dropping price update")
+	}
+}
+
+// subscribedPairs returns the pairs which have subscribers. If pairs are
+// given, only those of them which have subscribers are returned.
+func (s *priceStream) subscribedPairs(only ...provider.Pair) []provider.Pair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	set := make(map[provider.Pair]struct{})
+	for sub := range s.subscribers {
+		for pair := range sub.pairs {
+			set[pair] = struct{}{}
+		}
+	}
+	if len(only) > 0 {
+		filtered := make(map[provider.Pair]struct{})
+		for _, pair := range only {
+			if _, ok := set[pair]; ok {
+				filtered[pair] = struct{}{}
+			}
+		}
+		set = filtered
+	}
+	pairs := make([]provider.Pair, 0, len(set))
+	for pair := range set {
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+func priceChanged(a, b *provider.Price) bool {
+	return a.Price != b.Price || !a.Time.Equal(b.Time) || a.Error != b.Error
+}
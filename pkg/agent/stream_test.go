@@ -0,0 +1,159 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestPriceStream_Publish(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	s := newPriceStream(&mocks.Provider{}, nopHook{}, time.Second, nil, null.New())
+	sub := s.subscribe()
+	s.addPairs(sub, btcusd)
+
+	p1 := testPrice(btcusd, 1)
+	s.publish(map[provider.Pair]*provider.Price{btcusd: p1, ethusd: testPrice(ethusd, 1)})
+	require.Len(t, sub.ch, 1)
+	assert.Equal(t, p1, (<-sub.ch).Price)
+
+	// Unchanged prices are not published again.
+	s.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 1)})
+	assert.Len(t, sub.ch, 0)
+
+	p2 := testPrice(btcusd, 2)
+	s.publish(map[provider.Pair]*provider.Price{btcusd: p2})
+	require.Len(t, sub.ch, 1)
+	assert.Equal(t, p2, (<-sub.ch).Price)
+
+	// Subscribing to a pair with a known price delivers it immediately.
+	s.addPairs(sub, ethusd)
+	require.Len(t, sub.ch, 1)
+	assert.Equal(t, ethusd, (<-sub.ch).Price.Pair)
+
+	s.removePairs(sub, btcusd)
+	s.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 3)})
+	assert.Len(t, sub.ch, 0)
+}
+
+func TestPriceStream_Refresh(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 1)}, nil)
+	s := newPriceStream(p, nopHook{}, time.Second, nil, null.New())
+
+	// Nothing is fetched without subscribers.
+	s.refresh()
+	p.AssertNotCalled(t, "Prices", btcusd)
+
+	sub := s.subscribe()
+	s.addPairs(sub, btcusd)
+	s.refresh()
+	require.Len(t, sub.ch, 1)
+	p.AssertExpectations(t)
+
+	s.unsubscribe(sub)
+	assert.Empty(t, s.subscribedPairs())
+}
+
+func TestPriceStream_Updates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 1)}, nil)
+	updates := make(chan provider.Pair)
+	s := newPriceStream(p, nopHook{}, time.Hour, updates, null.New())
+	sub := s.subscribe()
+	s.addPairs(sub, btcusd)
+	go s.run(ctx)
+
+	// Only updated pairs with subscribers are fetched, without waiting for
+	// the interval.
+	updates <- ethusd
+	updates <- btcusd
+	select {
+	case ev := <-sub.ch:
+		assert.Equal(t, btcusd, ev.Price.Pair)
+	case <-time.After(time.Second):
+		t.Fatal("no price event")
+	}
+	p.AssertNotCalled(t, "Prices", ethusd)
+}
+
+func TestPriceStream_Resume(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	s := newPriceStream(&mocks.Provider{}, nopHook{}, time.Second, nil, null.New())
+	s.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 1)})
+	s.publish(map[provider.Pair]*provider.Price{ethusd: testPrice(ethusd, 1)})
+	s.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 2)})
+
+	// Events after ID 1 are replayed for subscribed pairs only.
+	sub := s.subscribe()
+	s.resume(sub, 1, btcusd)
+	require.Len(t, sub.ch, 1)
+	ev := <-sub.ch
+	assert.Equal(t, uint64(3), ev.ID)
+	assert.Equal(t, 2.0, ev.Price.Price)
+
+	// Unknown IDs fall back to the last known price.
+	sub = s.subscribe()
+	s.resume(sub, 100, btcusd, ethusd)
+	assert.Len(t, sub.ch, 2)
+}
+
+func TestHTTPAgent_SubscribePrices(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	s := newTestAgent(t, &mocks.Provider{})
+	s.ctx = context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.stream.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 1)})
+
+	recv := make(chan *provider.Price)
+	errCh := make(chan error)
+	go func() {
+		errCh <- s.SubscribePrices(ctx, []provider.Pair{btcusd}, func(price *provider.Price) error {
+			recv <- price
+			return nil
+		})
+	}()
+
+	// The last known price is sent first, followed by published updates.
+	assert.Equal(t, 1.0, (<-recv).Price)
+	s.stream.publish(map[provider.Pair]*provider.Price{
+		btcusd: testPrice(btcusd, 2),
+		ethusd: testPrice(ethusd, 1),
+	})
+	assert.Equal(t, 2.0, (<-recv).Price)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
@@ -0,0 +1,130 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+)
+
+// certReloadInterval describes how often certificate files are checked for
+// changes.
+const certReloadInterval = time.Minute
+
+// certReloader keeps a TLS certificate loaded from files and reloads it
+// when the files change or when the process receives SIGHUP.
+type certReloader struct {
+	mu sync.RWMutex
+
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+	log      log.Logger
+}
+
+func newCertReloader(certFile, keyFile string, l log.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, log: l}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is used as the tls.Config.GetCertificate callback.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload loads the certificate from files. The previous certificate is kept
+// if loading fails.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	modTime, _ := r.filesModTime()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// changed reports whether any of the certificate files was modified since
+// the certificate was last loaded.
+func (r *certReloader) changed() bool {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return modTime.After(r.modTime)
+}
+
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// watch reloads the certificate on SIGHUP or when the files change, until
+// the context is canceled.
+func (r *certReloader) watch(ctx context.Context) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+	t := time.NewTicker(certReloadInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hupCh:
+			r.tryReload()
+		case <-t.C:
+			if r.changed() {
+				r.tryReload()
+			}
+		}
+	}
+}
+
+func (r *certReloader) tryReload() {
+	if err := r.reload(); err != nil {
+		r.log.WithError(err).Error("Unable to reload TLS certificate")
+		return
+	}
+	r.log.Info("TLS certificate reloaded")
+}
@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIVersionHeader is the header with the API version used to serve a
+// request. Clients may send it to select the version served by the
+// unversioned paths.
+const APIVersionHeader = "X-Gofer-API-Version"
+
+// defaultAPIVersion is the version served by the unversioned paths when a
+// client does not request a specific version. It is kept at v1 so that
+// existing integrations are not affected when a new version is added.
+const defaultAPIVersion = "v1"
+
+// apiVersions lists the supported API versions. Every API endpoint is
+// available under the /<version>/ prefix of each of these versions.
+var apiVersions = []string{"v1"}
+
+type apiVersionCtxKey struct{}
+
+// apiVersion returns the API version of the request. Handlers use it to
+// keep the response schema of older versions unchanged.
+func apiVersion(ctx context.Context) string {
+	if v, ok := ctx.Value(apiVersionCtxKey{}).(string); ok {
+		return v
+	}
+	return defaultAPIVersion
+}
+
+// handleAPI registers the handler for the given path under every API
+// version prefix, and at the unversioned path, where the version is
+// negotiated with the client.
+func (s *HTTPAgent) handleAPI(path string, h http.HandlerFunc) {
+	for _, v := range apiVersions {
+		s.handle("/"+v+path, withAPIVersion(v, h))
+	}
+	s.handle(path, negotiateAPIVersion(h))
+}
+
+// withAPIVersion wraps the handler to serve the given API version.
+func withAPIVersion(v string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, v)
+		h(w, r.WithContext(context.WithValue(r.Context(), apiVersionCtxKey{}, v)))
+	}
+}
+
+// negotiateAPIVersion wraps the handler to serve the API version requested
+// by the client, or the default one. Requests for unsupported versions are
+// rejected with 406 Not Acceptable.
+func negotiateAPIVersion(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := requestedAPIVersion(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotAcceptable)
+			return
+		}
+		withAPIVersion(v, h)(w, r)
+	}
+}
+
+// requestedAPIVersion returns the API version requested in the
+// X-Gofer-API-Version header or with a vendor media type in the Accept
+// header, e.g. application/vnd.gofer.v1+json. If no version is requested,
+// the default version is returned.
+func requestedAPIVersion(r *http.Request) (string, error) {
+	v := r.Header.Get(APIVersionHeader)
+	if v == "" {
+		for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+			mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if rest, ok := strings.CutPrefix(strings.ToLower(mediaType), "application/vnd.gofer."); ok {
+				v, _, _ = strings.Cut(rest, "+")
+				break
+			}
+		}
+	}
+	if v == "" {
+		return defaultAPIVersion, nil
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	for _, supported := range apiVersions {
+		if v == supported {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported API version: %s", v)
+}
+
+// trimAPIVersion removes the API version prefix from the path.
+func trimAPIVersion(path string) string {
+	for _, v := range apiVersions {
+		if rest, ok := strings.CutPrefix(path, "/"+v+"/"); ok {
+			return "/" + rest
+		}
+	}
+	return path
+}
@@ -0,0 +1,74 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestHTTPAgent_APIVersion(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 42)}, nil)
+	s := newTestAgent(t, p)
+	require.NoError(t, s.initServer())
+
+	tests := []struct {
+		name    string
+		path    string
+		header  http.Header
+		code    int
+		version string
+	}{
+		{name: "versioned", path: "/v1/price", code: http.StatusOK, version: "v1"},
+		{name: "unversioned", path: "/price", code: http.StatusOK, version: "v1"},
+		{name: "header", path: "/price", header: http.Header{APIVersionHeader: {"1"}}, code: http.StatusOK, version: "v1"},
+		{
+			name:    "accept",
+			path:    "/price",
+			header:  http.Header{"Accept": {"text/html, application/vnd.gofer.v1+json"}},
+			code:    http.StatusOK,
+			version: "v1",
+		},
+		{name: "unsupported", path: "/price", header: http.Header{APIVersionHeader: {"v9"}}, code: http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path+"?pair=BTC/USD", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v[0])
+			}
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.version, rec.Header().Get(APIVersionHeader))
+		})
+	}
+}
+
+func TestTrimAPIVersion(t *testing.T) {
+	assert.Equal(t, "/price", trimAPIVersion("/v1/price"))
+	assert.Equal(t, "/price", trimAPIVersion("/price"))
+	assert.Equal(t, "/v2/price", trimAPIVersion("/v2/price"))
+}
@@ -0,0 +1,460 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+const (
+	// WebhookIDHeader is the header with the ID of the webhook in price
+	// notifications.
+	WebhookIDHeader = "X-Gofer-Webhook-ID"
+	// WebhookSignatureHeader is the header with the HMAC-SHA256 signature
+	// of the notification body, in the "sha256=<hex>" format.
+	WebhookSignatureHeader = "X-Gofer-Signature"
+	// WebhookTokenHeader is the header with the token returned on
+	// registration, which is required to remove the webhook.
+	WebhookTokenHeader = "X-Gofer-Webhook-Token"
+)
+
+const (
+	defaultWebhookMaxRetries       = 3
+	defaultWebhookRetryBackoff     = time.Second
+	defaultWebhookTimeout          = 10 * time.Second
+	defaultWebhookMaxSubscriptions = 100
+)
+
+// WebhookConfig is the configuration of webhook subscriptions. Clients may
+// register callback URLs for pairs; the agent then POSTs the price to the
+// URL whenever a refreshed price deviates from the last delivered one by
+// more than the threshold given by the client.
+type WebhookConfig struct {
+	// Secret is the key used to sign notifications with HMAC-SHA256.
+	Secret []byte
+	// MaxRetries is the number of times a failed delivery is retried. If
+	// zero, defaultWebhookMaxRetries is used.
+	MaxRetries int
+	// RetryBackoff is the time to wait before the first retry. It is
+	// doubled after every retry. If zero, defaultWebhookRetryBackoff is
+	// used.
+	RetryBackoff time.Duration
+	// Timeout is the timeout of a single delivery. If zero,
+	// defaultWebhookTimeout is used.
+	Timeout time.Duration
+	// MaxSubscriptions is the maximum number of registered webhooks. If
+	// zero, defaultWebhookMaxSubscriptions is used.
+	MaxSubscriptions int
+	// AllowPrivateURLs allows webhook URLs with loopback, private and
+	// link-local addresses. By default, they are rejected, so clients
+	// cannot make the agent send requests to internal services.
+	AllowPrivateURLs bool
+}
+
+var (
+	errWebhookNotFound = errors.New("webhook not found")
+	errWebhookToken    = errors.New("invalid webhook token")
+)
+
+// webhookRequest is the request body used to register a webhook.
+type webhookRequest struct {
+	URL   string          `json:"url"`
+	Pairs []provider.Pair `json:"pairs"`
+	// Deviation is the minimum change of the price, in percent, required
+	// to send a notification. If zero, every change is sent.
+	Deviation float64 `json:"deviation"`
+}
+
+// jsonWebhook is the representation of a registered webhook. The URL and
+// the token are returned only on registration.
+type jsonWebhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url,omitempty"`
+	Token     string   `json:"token,omitempty"`
+	Pairs     []string `json:"pairs"`
+	Deviation float64  `json:"deviation"`
+}
+
+// webhook is a registered webhook subscription.
+type webhook struct {
+	id        string
+	token     string
+	url       string
+	pairs     []provider.Pair
+	deviation float64
+	sub       *subscriber
+	cancel    context.CancelFunc
+}
+
+func (h *webhook) json() jsonWebhook {
+	pairs := make([]string, 0, len(h.pairs))
+	for _, p := range h.pairs {
+		pairs = append(pairs, p.String())
+	}
+	return jsonWebhook{ID: h.id, Pairs: pairs, Deviation: h.deviation}
+}
+
+// webhookManager keeps registered webhooks and delivers price updates
+// from the price stream to them.
+type webhookManager struct {
+	mu sync.Mutex
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	cfg      WebhookConfig
+	stream   *priceStream
+	client   *http.Client
+	log      log.Logger
+	webhooks map[string]*webhook
+}
+
+func newWebhookManager(cfg WebhookConfig, stream *priceStream, l log.Logger) *webhookManager {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultWebhookMaxRetries
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = defaultWebhookRetryBackoff
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.MaxSubscriptions == 0 {
+		cfg.MaxSubscriptions = defaultWebhookMaxSubscriptions
+	}
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateURLs {
+		// Addresses are checked again when connecting, because the host
+		// may resolve to a different address than on registration, and
+		// the receiver may redirect the request.
+		dialer.Control = checkWebhookAddr
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookManager{
+		ctx:      ctx,
+		cancel:   cancel,
+		cfg:      cfg,
+		stream:   stream,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		log:      l,
+		webhooks: make(map[string]*webhook),
+	}
+}
+
+// register adds a new webhook and starts delivering notifications to it.
+func (m *webhookManager) register(rawURL string, pairs []provider.Pair, deviation float64) (*webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.webhooks) >= m.cfg.MaxSubscriptions {
+		return nil, errors.New("too many webhooks")
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	h := &webhook{
+		id:        newRequestID(),
+		token:     newRequestID(),
+		url:       rawURL,
+		pairs:     pairs,
+		deviation: deviation,
+		sub:       m.stream.subscribe(),
+		cancel:    cancel,
+	}
+	m.webhooks[h.id] = h
+	go m.deliver(ctx, h)
+	m.stream.addPairs(h.sub, pairs...)
+	return h, nil
+}
+
+// unregister removes the webhook with the given ID, if the token matches
+// the one returned on registration.
+func (m *webhookManager) unregister(id, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.webhooks[id]
+	if !ok {
+		return errWebhookNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return errWebhookToken
+	}
+	delete(m.webhooks, id)
+	m.stream.unsubscribe(h.sub)
+	h.cancel()
+	return nil
+}
+
+// list returns the registered webhooks sorted by ID.
+func (m *webhookManager) list() []jsonWebhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]jsonWebhook, 0, len(m.webhooks))
+	for _, h := range m.webhooks {
+		res = append(res, h.json())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// close stops all deliveries.
+func (m *webhookManager) close() {
+	m.cancel()
+}
+
+// deliver sends price updates to the webhook until the context is
+// canceled. Updates that deviate from the last delivered price by less
+// than the webhook threshold are skipped.
+func (m *webhookManager) deliver(ctx context.Context, h *webhook) {
+	last := make(map[provider.Pair]float64)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-h.sub.ch:
+			price := ev.Price
+			if price.Error != "" {
+				continue
+			}
+			if prev, ok := last[price.Pair]; ok && !deviates(prev, price.Price, h.deviation) {
+				continue
+			}
+			if err := m.send(ctx, h, price); err != nil {
+				m.log.
+					WithError(err).
+					WithField("webhook", h.id).
+					WithField("assetPair", price.Pair).
+					Warn("Unable to deliver webhook notification")
+				continue
+			}
+			last[price.Pair] = price.Price
+		}
+	}
+}
+
+// send POSTs the price to the webhook URL, retrying failed deliveries with
+// an exponential backoff.
+func (m *webhookManager) send(ctx context.Context, h *webhook, price *provider.Price) error {
+	body, err := json.Marshal(jsonPriceFromGoferPrice(price))
+	if err != nil {
+		return err
+	}
+	backoff := m.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = m.post(ctx, h, body)
+		if err == nil || attempt >= m.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (m *webhookManager) post(ctx context.Context, h *webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, h.id)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhook(m.cfg.Secret, body))
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the hex encoded HMAC-SHA256 of the body.
+func signWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deviates reports whether the price differs from the previous one by at
+// least the given percentage. Any change is reported if the threshold is
+// zero.
+func deviates(prev, price, threshold float64) bool {
+	if prev == price {
+		return false
+	}
+	if threshold == 0 || prev == 0 {
+		return true
+	}
+	return math.Abs(price-prev)/math.Abs(prev)*100 >= threshold
+}
+
+// handleWebhooks registers and lists webhooks.
+func (s *HTTPAgent) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.webhooks.list())
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "Content-Type header is not application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		var req webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), bodyErrorStatus(err))
+			return
+		}
+		if err := s.webhooks.validateURL(r.Context(), req.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Pairs) == 0 {
+			http.Error(w, "at least one pair is required", http.StatusBadRequest)
+			return
+		}
+		if req.Deviation < 0 {
+			http.Error(w, "deviation must not be negative", http.StatusBadRequest)
+			return
+		}
+		logRequestedPairs(r.Context(), req.Pairs...)
+		_, err := s.priceProvider.Models(req.Pairs...)
+		var notFound graph.ErrPairNotFound
+		if errors.As(err, &notFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			s.requestLog(r).Errorf("failed to get models: %v", err)
+			http.Error(w, "failed to verify pairs", http.StatusInternalServerError)
+			return
+		}
+		h, err := s.webhooks.register(req.URL, req.Pairs, req.Deviation)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		s.requestLog(r).
+			WithField("webhook", h.id).
+			WithField("url", h.url).
+			Info("Webhook registered")
+		res := h.json()
+		res.URL = h.url
+		res.Token = h.token
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(res)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhook removes the webhook with the ID given in the path. The
+// token returned on registration must be given in the WebhookTokenHeader.
+func (s *HTTPAgent) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(trimAPIVersion(r.URL.Path), "/webhooks/")
+	switch err := s.webhooks.unregister(id, r.Header.Get(WebhookTokenHeader)); {
+	case errors.Is(err, errWebhookNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	s.requestLog(r).WithField("webhook", id).Info("Webhook removed")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateURL verifies that the URL is an absolute http or https URL and,
+// unless private URLs are allowed, that its host resolves only to public
+// addresses.
+func (m *webhookManager) validateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	if m.cfg.AllowPrivateURLs {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("unable to resolve the URL host %q", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return errors.New("URL must not point to a loopback, private or link-local address")
+		}
+	}
+	return nil
+}
+
+// checkWebhookAddr is used as the net.Dialer Control function to refuse
+// connections to addresses which are not public.
+func checkWebhookAddr(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", address)
+	}
+	return nil
+}
+
+// publicIP reports whether the IP is not a loopback, private, link-local,
+// multicast or unspecified address.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,226 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+type webhookCall struct {
+	header http.Header
+	body   []byte
+}
+
+// newWebhookReceiver starts a server that records notifications. The
+// status function returns the response status of every call.
+func newWebhookReceiver(t *testing.T, status func(n int) int) (string, <-chan webhookCall) {
+	calls := make(chan webhookCall, 16)
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n++
+		code := status(n)
+		if code == http.StatusOK {
+			calls <- webhookCall{header: r.Header, body: body}
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, calls
+}
+
+func newWebhookTestAgent(t *testing.T, p *mocks.Provider) *HTTPAgent {
+	s := newTestAgent(t, p)
+	s.webhooks = newWebhookManager(WebhookConfig{
+		Secret:           []byte("secret"),
+		RetryBackoff:     time.Millisecond,
+		AllowPrivateURLs: true, // The receivers listen on the loopback interface.
+	}, s.stream, s.log)
+	t.Cleanup(s.webhooks.close)
+	require.NoError(t, s.initServer())
+	return s
+}
+
+func registerWebhook(t *testing.T, s *HTTPAgent, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func receiveWebhook(t *testing.T, calls <-chan webhookCall) webhookCall {
+	select {
+	case c := <-calls:
+		return c
+	case <-time.After(time.Second):
+		require.Fail(t, "webhook notification not received")
+		return webhookCall{}
+	}
+}
+
+func TestHTTPAgent_Webhooks(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model{btcusd: {Pair: btcusd}}, nil)
+	s := newWebhookTestAgent(t, p)
+	url, calls := newWebhookReceiver(t, func(int) int { return http.StatusOK })
+
+	rec := registerWebhook(t, s, `{"url":"`+url+`","pairs":["BTC/USD"],"deviation":1}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var h jsonWebhook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+	assert.Equal(t, url, h.URL)
+	assert.NotEmpty(t, h.Token)
+	assert.Equal(t, []string{"BTC/USD"}, h.Pairs)
+
+	// The first price is always delivered.
+	s.stream.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 100)})
+	c := receiveWebhook(t, calls)
+	assert.Equal(t, h.ID, c.header.Get(WebhookIDHeader))
+	assert.Equal(t, "sha256="+signWebhook([]byte("secret"), c.body), c.header.Get(WebhookSignatureHeader))
+	var price jsonPrice
+	require.NoError(t, json.Unmarshal(c.body, &price))
+	assert.Equal(t, 100.0, price.Price)
+
+	// Changes below the threshold are skipped.
+	s.stream.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 100.5)})
+	s.stream.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 101)})
+	c = receiveWebhook(t, calls)
+	require.NoError(t, json.Unmarshal(c.body, &price))
+	assert.Equal(t, 101.0, price.Price)
+
+	// Listing webhooks does not reveal their URLs and tokens.
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+	var list []jsonWebhook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []jsonWebhook{{ID: h.ID, Pairs: h.Pairs, Deviation: h.Deviation}}, list)
+
+	// Removing webhooks requires the token.
+	deleteWebhook := func(id, token string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/webhooks/"+id, nil)
+		if token != "" {
+			req.Header.Set(WebhookTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusForbidden, deleteWebhook(h.ID, ""))
+	assert.Equal(t, http.StatusForbidden, deleteWebhook(h.ID, "invalid"))
+	assert.Equal(t, http.StatusNoContent, deleteWebhook(h.ID, h.Token))
+	assert.Equal(t, http.StatusNotFound, deleteWebhook(h.ID, h.Token))
+}
+
+func TestHTTPAgent_Webhooks_Retry(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model{btcusd: {Pair: btcusd}}, nil)
+	s := newWebhookTestAgent(t, p)
+	url, calls := newWebhookReceiver(t, func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+
+	require.Equal(t, http.StatusCreated, registerWebhook(t, s, `{"url":"`+url+`","pairs":["BTC/USD"]}`).Code)
+	s.stream.publish(map[provider.Pair]*provider.Price{btcusd: testPrice(btcusd, 100)})
+	receiveWebhook(t, calls)
+}
+
+func TestHTTPAgent_Webhooks_Invalid(t *testing.T) {
+	p := &mocks.Provider{}
+	s := newWebhookTestAgent(t, p)
+
+	tests := []string{
+		`{"url":"ftp://example.com","pairs":["BTC/USD"]}`,
+		`{"url":"/relative","pairs":["BTC/USD"]}`,
+		`{"url":"http://example.com","pairs":[]}`,
+		`{"url":"http://example.com","pairs":["BTC/USD"],"deviation":-1}`,
+		`{"url":"http://example.com","pairs":["BTCUSD"]}`,
+	}
+	for _, body := range tests {
+		assert.Equal(t, http.StatusBadRequest, registerWebhook(t, s, body).Code, body)
+	}
+}
+
+func TestHTTPAgent_Webhooks_PrivateURL(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.webhooks = newWebhookManager(WebhookConfig{Secret: []byte("secret")}, s.stream, s.log)
+	t.Cleanup(s.webhooks.close)
+	require.NoError(t, s.initServer())
+
+	tests := []string{
+		"http://127.0.0.1:8080",
+		"http://localhost",
+		"http://10.0.0.1",
+		"http://192.168.1.1",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]",
+		"http://[fe80::1]",
+		"http://0.0.0.0",
+	}
+	for _, url := range tests {
+		rec := registerWebhook(t, s, `{"url":"`+url+`","pairs":["BTC/USD"]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, url)
+	}
+
+	// Addresses are checked again when notifications are sent.
+	url, _ := newWebhookReceiver(t, func(int) int { return http.StatusOK })
+	err := s.webhooks.post(context.Background(), &webhook{id: "test", url: url}, []byte(`{}`))
+	assert.ErrorContains(t, err, "is not allowed")
+}
+
+func TestPublicIP(t *testing.T) {
+	assert.True(t, publicIP(net.ParseIP("1.1.1.1")))
+	assert.True(t, publicIP(net.ParseIP("2606:4700::1111")))
+	assert.False(t, publicIP(net.ParseIP("127.0.0.1")))
+	assert.False(t, publicIP(net.ParseIP("172.16.0.1")))
+	assert.False(t, publicIP(net.ParseIP("169.254.169.254")))
+	assert.False(t, publicIP(net.ParseIP("::ffff:10.0.0.1")))
+	assert.False(t, publicIP(net.ParseIP("fd00::1")))
+	assert.False(t, publicIP(net.ParseIP("::")))
+}
+
+func TestHTTPAgent_Webhooks_RequireSecret(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.webhooks = newWebhookManager(WebhookConfig{}, s.stream, s.log)
+	assert.Error(t, s.initServer())
+}
+
+func TestDeviates(t *testing.T) {
+	assert.False(t, deviates(100, 100, 0))
+	assert.True(t, deviates(100, 100.1, 0))
+	assert.False(t, deviates(100, 100.9, 1))
+	assert.True(t, deviates(100, 99, 1))
+	assert.True(t, deviates(0, 1, 1))
+}
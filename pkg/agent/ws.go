@@ -0,0 +1,121 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+const (
+	wsSubscribe   = "subscribe"
+	wsUnsubscribe = "unsubscribe"
+)
+
+// wsReadLimit is the maximum size, in bytes, of a message sent by a
+// WebSocket client. Clients only send subscribe and unsubscribe messages,
+// so this leaves room for a few hundred pairs.
+const wsReadLimit = 8 << 10
+
+// wsRequest is a message sent by a WebSocket client to change the list of
+// subscribed pairs.
+type wsRequest struct {
+	Type  string          `json:"type"`
+	Pairs []provider.Pair `json:"pairs"`
+}
+
+type wsError struct {
+	Error string `json:"error"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// handleWebSocket streams price updates to WebSocket clients. Clients
+// control which pairs they receive by sending subscribe and unsubscribe
+// messages, e.g. {"type":"subscribe","pairs":["BTC/USD"]}.
+func (s *HTTPAgent) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.requestLog(r).Errorf("failed to upgrade WebSocket connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// The server timeouts still apply to the hijacked connection, but
+	// WebSocket connections are long-lived.
+	_ = conn.SetReadDeadline(time.Time{})
+	_ = conn.SetWriteDeadline(time.Time{})
+	conn.SetReadLimit(wsReadLimit)
+
+	sub := s.stream.subscribe()
+	defer s.stream.unsubscribe(sub)
+
+	// Errors are sent from the reader goroutine, but gorilla/websocket
+	// allows only one concurrent writer, so they go through the channel.
+	errCh := make(chan string, 1)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				if _, ok := err.(*websocket.CloseError); !ok {
+					s.requestLog(r).Debugf("WebSocket read failed: %v", err)
+				}
+				return
+			}
+			switch req.Type {
+			case wsSubscribe:
+				s.stream.addPairs(sub, req.Pairs...)
+			case wsUnsubscribe:
+				s.stream.removePairs(sub, req.Pairs...)
+			default:
+				select {
+				case errCh <- "unknown message type: " + req.Type:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			_ = conn.WriteMessage(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+			)
+			return
+		case <-doneCh:
+			return
+		case msg := <-errCh:
+			if err := conn.WriteJSON(wsError{Error: msg}); err != nil {
+				return
+			}
+		case ev := <-sub.ch:
+			if err := conn.WriteJSON(jsonPriceFromGoferPrice(ev.Price)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func dialTestWebSocket(t *testing.T, s *HTTPAgent) *websocket.Conn {
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestHTTPAgent_WebSocket_UnknownMessage(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.ctx = context.Background()
+	conn := dialTestWebSocket(t, s)
+
+	require.NoError(t, conn.WriteJSON(wsRequest{Type: "foo"}))
+	var res wsError
+	require.NoError(t, conn.ReadJSON(&res))
+	assert.Equal(t, "unknown message type: foo", res.Error)
+}
+
+func TestHTTPAgent_WebSocket_ReadLimit(t *testing.T) {
+	s := newTestAgent(t, &mocks.Provider{})
+	s.ctx = context.Background()
+	conn := dialTestWebSocket(t, s)
+
+	msg := `{"type":"subscribe","pairs":["` + strings.Repeat("A", wsReadLimit) + `/USD"]}`
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "unexpected error: %v", err)
+}
@@ -18,16 +18,68 @@ package prices
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
-	"github.com/chronicleprotocol/oracle-suite/pkg/price/median"
 	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
 )
 
 const LoggerTag = "PRICE_CACHE"
 
+// ErrUnknownPair is returned for pairs that are not kept in the Cache.
+var ErrUnknownPair = errors.New("unknown pair")
+
+// ErrNoPrice is returned for pairs whose price has not been fetched yet.
+var ErrNoPrice = errors.New("price not fetched yet")
+
+// ErrPriceTooOld is returned for pairs whose price was fetched longer ago
+// than the maximum age of cached prices.
+var ErrPriceTooOld = errors.New("price too old")
+
+// ErrUpdateTimeout is returned for pairs whose price was not fetched within
+// the pair timeout.
+var ErrUpdateTimeout = errors.New("price update timed out")
+
+// CachedPrice is a price kept in the Cache.
+type CachedPrice struct {
+	*provider.Price
+
+	// FetchedAt is the time at which the price was fetched.
+	FetchedAt time.Time
+
+	// Stale is true if the price was fetched longer ago than the TTL, it
+	// was loaded from the Store and has not been refreshed since, or the
+	// last refresh failed. Stale prices may still be used, but they will
+	// not be refreshed until the next successful update.
+	Stale bool
+
+	// RefreshError is the error of the last refresh, if it failed and the
+	// last good price is returned instead.
+	RefreshError string
+
+	// Failures is the number of consecutive failed refreshes of the price.
+	Failures int
+}
+
+// refreshFailure describes consecutive failed refreshes of a pair.
+type refreshFailure struct {
+	count int
+	err   error
+}
+
+type cacheEntry struct {
+	price     *provider.Price
+	fetchedAt time.Time
+	restored  bool
+}
+
 // Cache is a service which periodically fetches prices and keeps them in cache.
 type Cache struct {
 	ctx    context.Context
@@ -37,20 +89,126 @@ type Cache struct {
 	priceProvider provider.Provider
 	pairs         []provider.Pair
 	log           log.Logger
-	prices        map[provider.Pair]provider.Price
+	ttl           time.Duration
+	maxAge        time.Duration
+	store         Store
+	warmUpTimeout time.Duration
+	spread        bool
+	jitter        time.Duration
+	historySize   int
+	snapshotPath  string
+	deviation     float64
+	freshDefault  time.Duration
+	freshPairs    map[provider.Pair]time.Duration
+	onDemand      *onDemandPairs
+	pairTimeout   time.Duration
+	workers       chan struct{}
+	warm          atomic.Bool
+	metrics       *cacheMetrics
+	events        *eventBus
+
+	// routines tracks the goroutines which update prices, so the Cache is
+	// stopped only after they finish.
+	routines sync.WaitGroup
+
+	// pairsMu guards onDemand. The mu lock must not be acquired while
+	// pairsMu is held.
+	pairsMu sync.RWMutex
+
+	mu        sync.RWMutex
+	prices    map[provider.Pair]cacheEntry
+	history   map[provider.Pair]*priceRing
+	published map[provider.Pair]*provider.Price
+	failures  map[provider.Pair]refreshFailure
 }
 
 // Config is the configuration for the Cache.
 type Config struct {
-	// Pairs is a list supported pairs in the format "QUOTE/BASE".
+	// Pairs is a list supported pairs in the format "QUOTE/BASE". Patterns,
+	// such as "*/USD", are expanded against the pairs of the PriceProvider,
+	// see ExpandPairs.
 	Pairs []string
 
 	// PriceProvider is a price provider which is used to fetch prices.
 	PriceProvider provider.Provider
 
-	// Interval describes how often we should send prices to the network.
+	// Interval describes how often prices are fetched.
 	Interval *timeutil.Ticker
 
+	// TTL is the time after which a cached price is stale. Defaults to the
+	// duration of the Interval.
+	TTL time.Duration
+
+	// MaxAge is the time after which a cached price is too old to be
+	// returned. If zero, cached prices are returned regardless of their age.
+	MaxAge time.Duration
+
+	// Store, if set, persists cached prices. Prices are loaded from it when
+	// the Cache is started and are stale until they are refreshed, unless
+	// the Store is a SharedStore. The Store is closed when the Cache is
+	// stopped.
+	Store Store
+
+	// WarmUpTimeout, if set, enables fetching prices of all pairs as soon
+	// as the Cache is started. The Cache is not ready until the prices are
+	// fetched, or until the timeout elapses.
+	WarmUpTimeout time.Duration
+
+	// Spread, if true, spreads updates of pairs evenly over the Interval,
+	// instead of updating all pairs at once.
+	Spread bool
+
+	// Jitter, if set, delays the update of every pair by a random time up
+	// to the Jitter, so multiple Caches with the same Interval do not send
+	// requests to the origins at the same time. It must be shorter than the
+	// Interval.
+	Jitter time.Duration
+
+	// HistorySize is the number of recent prices kept for every pair, which
+	// are returned by the History method. If zero, no history is kept.
+	HistorySize int
+
+	// SnapshotPath, if set, is the path of the file to which cached prices
+	// and their history are written when the Cache is stopped. The snapshot
+	// is loaded when the Cache is started, and its prices are stale until
+	// they are refreshed. Prices loaded from the Store take precedence.
+	SnapshotPath string
+
+	// DeviationThreshold, if set, is the percentage by which a price must
+	// move since the last published price of the pair to be published to
+	// subscribers and logged. Smaller changes are still cached. If zero,
+	// every update is published.
+	DeviationThreshold float64
+
+	// Freshness, if set, is the maximum age of prices reported by the
+	// origins, measured from the timestamp of the price rather than the time
+	// it was fetched. Outdated prices are not cached, and the CachedProvider
+	// returns them with an error, so a stuck origin does not silently keep
+	// serving the same price. If zero, the age of prices is not checked.
+	Freshness time.Duration
+
+	// PairFreshness overrides the Freshness for individual pairs, in the
+	// format "BASE/QUOTE". A zero value disables the check for the pair.
+	PairFreshness map[string]time.Duration
+
+	// MaxOnDemandPairs, if set, allows pairs which are not listed in Pairs
+	// to be admitted to the Cache when their prices are fetched on demand
+	// by the CachedProvider. They are then refreshed like the other pairs.
+	// If more pairs are admitted, the least recently requested ones are
+	// evicted.
+	MaxOnDemandPairs int
+
+	// Workers is the maximum number of pairs updated at the same time, so
+	// a slow origin does not delay updates of all other pairs. Defaults to
+	// 1, which updates pairs one by one.
+	Workers int
+
+	// PairTimeout, if set, is the maximum time to fetch the price of a single
+	// pair. If the Provider does not return the price in time, the update
+	// fails and the price is discarded once it is returned. If zero, the
+	// update waits for the Provider.
+	PairTimeout time.Duration
+
 	// Logger is a current logger interface used by the Cache.
 	Logger log.Logger
 }
@@ -60,19 +218,90 @@ func New(cfg Config) (*Cache, error) {
 	if cfg.PriceProvider == nil {
 		return nil, errors.New("price provider must not be nil")
 	}
+	if cfg.Interval == nil {
+		return nil, errors.New("interval must not be nil")
+	}
+	if cfg.TTL < 0 || cfg.MaxAge < 0 || cfg.WarmUpTimeout < 0 || cfg.Jitter < 0 || cfg.HistorySize < 0 || cfg.DeviationThreshold < 0 {
+		return nil, errors.New("TTL, max age, warm-up timeout, jitter, history size and deviation threshold must not be negative")
+	}
+	if cfg.Jitter > 0 && cfg.Interval.Duration() > 0 && cfg.Jitter >= cfg.Interval.Duration() {
+		return nil, errors.New("jitter must be shorter than the interval")
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = cfg.Interval.Duration()
+	}
+	if cfg.MaxAge != 0 && cfg.MaxAge < cfg.TTL {
+		return nil, errors.New("max age must not be less than TTL")
+	}
 	if cfg.Logger == nil {
 		cfg.Logger = null.New()
 	}
-	pairs, err := provider.NewPairs(cfg.Pairs...)
+	var available []provider.Pair
+	if HasWildcards(cfg.Pairs...) {
+		ps, err := cfg.PriceProvider.Pairs()
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand pairs: %w", err)
+		}
+		available = ps
+	}
+	pairs, err := ExpandPairs(cfg.Pairs, available)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Freshness < 0 {
+		return nil, errors.New("freshness must not be negative")
+	}
+	pairFreshness := make(map[provider.Pair]time.Duration, len(cfg.PairFreshness))
+	for s, d := range cfg.PairFreshness {
+		pair, err := provider.NewPair(s)
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("freshness of %s must not be negative", pair)
+		}
+		pairFreshness[pair] = d
+	}
+	if cfg.MaxOnDemandPairs < 0 {
+		return nil, errors.New("max on-demand pairs must not be negative")
+	}
+	if cfg.Workers < 0 || cfg.PairTimeout < 0 {
+		return nil, errors.New("workers and pair timeout must not be negative")
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = 1
+	}
+	var onDemand *onDemandPairs
+	if cfg.MaxOnDemandPairs > 0 {
+		onDemand = newOnDemandPairs(cfg.MaxOnDemandPairs)
+	}
+	logger := cfg.Logger.WithField("tag", LoggerTag)
 	g := &Cache{
 		waitCh:        make(chan error),
 		priceProvider: cfg.PriceProvider,
 		interval:      cfg.Interval,
 		pairs:         pairs,
-		log:           cfg.Logger.WithField("tag", LoggerTag),
+		log:           logger,
+		ttl:           cfg.TTL,
+		maxAge:        cfg.MaxAge,
+		store:         cfg.Store,
+		warmUpTimeout: cfg.WarmUpTimeout,
+		spread:        cfg.Spread,
+		jitter:        cfg.Jitter,
+		historySize:   cfg.HistorySize,
+		snapshotPath:  cfg.SnapshotPath,
+		deviation:     cfg.DeviationThreshold,
+		freshDefault:  cfg.Freshness,
+		freshPairs:    pairFreshness,
+		onDemand:      onDemand,
+		pairTimeout:   cfg.PairTimeout,
+		workers:       make(chan struct{}, cfg.Workers),
+		metrics:       newCacheMetrics(),
+		events:        newEventBus(logger),
+		prices:        make(map[provider.Pair]cacheEntry),
+		history:       make(map[provider.Pair]*priceRing),
+		published:     make(map[provider.Pair]*provider.Price),
+		failures:      make(map[provider.Pair]refreshFailure),
 	}
 	return g, nil
 }
@@ -87,7 +316,22 @@ func (g *Cache) Start(ctx context.Context) error {
 	}
 	g.log.Debug("Starting")
 	g.ctx = ctx
+	if g.snapshotPath != "" {
+		if err := g.loadSnapshot(); err != nil {
+			g.log.WithError(err).Warn("Unable to load snapshot")
+		}
+	}
+	if g.store != nil {
+		g.restore()
+	}
 	g.interval.Start(g.ctx)
+	if g.warmUpTimeout > 0 {
+		g.routines.Add(1)
+		go g.warmUpRoutine()
+	} else {
+		g.warm.Store(true)
+	}
+	g.routines.Add(1)
 	go g.broadcasterRoutine()
 	go g.contextCancelHandler()
 	return nil
@@ -98,50 +342,464 @@ func (g *Cache) Wait() <-chan error {
 	return g.waitCh
 }
 
-// update sends price for single pair to the network. This method uses
-// current price from the Provider, so it must be updated beforehand.
-func (g *Cache) update(pair provider.Pair) error {
-	var err error
+// Ready returns an error until the Cache is started and, if the warm-up
+// is enabled, the warm-up has finished.
+func (g *Cache) Ready() error {
+	if !g.warm.Load() {
+		return errors.New("price cache is warming up")
+	}
+	return nil
+}
 
-	// Create price.
-	tick, err := g.priceProvider.Price(pair)
+// Price returns the cached price of the pair. An error is returned if the
+// pair is not kept in the Cache, its price has not been fetched yet, the
+// price is older than the maximum age, or it is outdated.
+//
+// Calls for pairs kept in the Cache are counted as hits or misses.
+func (g *Cache) Price(pair provider.Pair) (*CachedPrice, error) {
+	if !g.hasPair(pair) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPair, pair)
+	}
+	g.touch(pair)
+	price, err := g.price(pair)
+	if err != nil {
+		g.metrics.misses.WithLabelValues(pair.String()).Inc()
+		return nil, err
+	}
+	g.metrics.hits.WithLabelValues(pair.String()).Inc()
+	return price, nil
+}
+
+func (g *Cache) price(pair provider.Pair) (*CachedPrice, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	e, ok := g.prices[pair]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrice, pair)
+	}
+	now := time.Now()
+	price, ok := g.cachedPrice(pair, e, now)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPriceTooOld, pair)
+	}
+	if err := g.outdated(pair, e.price, now); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// NextRefresh returns the time at which the price of the pair is expected
+// to be refreshed, one interval after it was fetched. It returns false if
+// the pair has no cached price.
+func (g *Cache) NextRefresh(pair provider.Pair) (time.Time, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	e, ok := g.prices[pair]
+	if !ok || g.interval.Duration() <= 0 {
+		return time.Time{}, false
+	}
+	return e.fetchedAt.Add(g.interval.Duration()), true
+}
+
+// Prices returns all cached prices. Pairs whose price has not been fetched
+// yet, is older than the maximum age or is outdated are omitted.
+func (g *Cache) Prices() map[provider.Pair]*CachedPrice {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := time.Now()
+	prices := make(map[provider.Pair]*CachedPrice, len(g.prices))
+	for pair, e := range g.prices {
+		if g.outdated(pair, e.price, now) != nil {
+			continue
+		}
+		if price, ok := g.cachedPrice(pair, e, now); ok {
+			prices[pair] = price
+		}
+	}
+	return prices
+}
+
+// cachedPrice returns the entry of the pair as a CachedPrice, or false if
+// the entry is too old to be returned. The caller must hold the lock.
+func (g *Cache) cachedPrice(pair provider.Pair, e cacheEntry, now time.Time) (*CachedPrice, bool) {
+	age := now.Sub(e.fetchedAt)
+	if g.maxAge > 0 && age > g.maxAge {
+		return nil, false
+	}
+	p := &CachedPrice{Price: e.price, FetchedAt: e.fetchedAt, Stale: e.restored || age > g.ttl}
+	if f, ok := g.failures[pair]; ok {
+		p.Stale = true
+		p.RefreshError = f.err.Error()
+		p.Failures = f.count
+	}
+	return p, true
+}
+
+func (g *Cache) hasPair(pair provider.Pair) bool {
+	for _, p := range g.pairs {
+		if p.Equal(pair) {
+			return true
+		}
+	}
+	if g.onDemand == nil {
+		return false
+	}
+	g.pairsMu.RLock()
+	defer g.pairsMu.RUnlock()
+	return g.onDemand.has(pair)
+}
+
+// allPairs returns the configured pairs followed by the pairs admitted on
+// demand.
+func (g *Cache) allPairs() []provider.Pair {
+	if g.onDemand == nil {
+		return g.pairs
+	}
+	g.pairsMu.RLock()
+	defer g.pairsMu.RUnlock()
+	return append(append([]provider.Pair{}, g.pairs...), g.onDemand.pairs()...)
+}
+
+// Invalidate removes cached prices of the given pairs, or of all pairs if
+// none are given, and fetches them again from the Provider immediately,
+// without using prices stored by other Caches. It returns the pairs whose
+// prices could not be fetched; they are fetched again on the next tick.
+// If any of the pairs is not kept in the Cache, nothing is invalidated.
+func (g *Cache) Invalidate(pairs ...provider.Pair) ([]provider.Pair, error) {
+	for _, pair := range pairs {
+		if !g.hasPair(pair) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPair, pair)
+		}
+	}
+	if len(pairs) == 0 {
+		pairs = g.allPairs()
+	}
+	g.mu.Lock()
+	for _, pair := range pairs {
+		delete(g.prices, pair)
+		delete(g.published, pair)
+	}
+	g.mu.Unlock()
+	for _, pair := range pairs {
+		g.log.WithField("assetPair", pair).Info("Price invalidated")
+	}
+	return g.updatePairs(pairs, true), nil
+}
+
+// update fetches the price for a single pair from the Provider and stores
+// it in the Cache. If the price cannot be fetched, the previously cached
+// price is kept. Unless force is true, a fresh price from the SharedStore
+// is used instead.
+func (g *Cache) update(pair provider.Pair, force bool) error {
+	if s, ok := g.store.(SharedStore); ok && !force {
+		if done := g.updateShared(s, pair); done {
+			return nil
+		}
+	}
+	price, err := g.fetch(pair)
 	if err != nil {
 		return err
 	}
-	if tick.Error != "" {
-		return errors.New(tick.Error)
+	if price.Error != "" {
+		return errors.New(price.Error)
 	}
-	price := &median.Price{Wat: pair.Base + pair.Quote, Age: tick.Time}
-	price.SetFloat64Price(tick.Price)
+	if err := g.outdated(pair, price, time.Now()); err != nil {
+		return err
+	}
+	g.put(pair, price)
+	return nil
+}
 
-	return err
+// fetch fetches the price of the pair from the Provider, waiting at most
+// the pair timeout, if it is set. A price returned after the timeout is
+// discarded.
+func (g *Cache) fetch(pair provider.Pair) (*provider.Price, error) {
+	if g.pairTimeout == 0 {
+		return g.priceProvider.Price(pair)
+	}
+	type result struct {
+		price *provider.Price
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		price, err := g.priceProvider.Price(pair)
+		ch <- result{price: price, err: err}
+	}()
+	t := time.NewTimer(g.pairTimeout)
+	defer t.Stop()
+	select {
+	case r := <-ch:
+		return r.price, r.err
+	case <-t.C:
+		return nil, fmt.Errorf("%w after %s", ErrUpdateTimeout, g.pairTimeout)
+	}
+}
+
+// put adds the price to the Cache, and saves it in the Store, if the pair
+// is kept in the Cache.
+func (g *Cache) put(pair provider.Pair, price *provider.Price) {
+	if !g.hasPair(pair) {
+		return
+	}
+	e := cacheEntry{price: price, fetchedAt: time.Now()}
+	g.mu.Lock()
+	g.prices[pair] = e
+	delete(g.failures, pair)
+	g.record(pair, price)
+	g.mu.Unlock()
+	g.publish(pair, &CachedPrice{Price: price, FetchedAt: e.fetchedAt})
+	if g.store != nil {
+		if err := g.store.Save(&CachedPrice{Price: price, FetchedAt: e.fetchedAt}); err != nil {
+			g.log.
+				WithField("assetPair", pair).
+				WithError(err).
+				Warn("Unable to store price")
+		}
+	}
+}
+
+// updateShared uses the price of the pair stored in the SharedStore if it
+// is fresh, or if another Cache has claimed the pair to fetch it. It
+// reports whether the price must not be fetched. If the SharedStore is
+// unavailable, the price is fetched.
+func (g *Cache) updateShared(s SharedStore, pair provider.Pair) bool {
+	shared, err := s.Get(pair)
+	if err != nil {
+		g.log.WithField("assetPair", pair).WithError(err).Warn("Unable to get shared price")
+		return false
+	}
+	if shared != nil && time.Since(shared.FetchedAt) <= g.ttl {
+		g.putShared(pair, shared)
+		return true
+	}
+	claimed, err := s.Claim(pair)
+	if err != nil {
+		g.log.WithField("assetPair", pair).WithError(err).Warn("Unable to claim shared price")
+		return false
+	}
+	if !claimed && shared != nil {
+		g.putShared(pair, shared)
+	}
+	return !claimed
+}
+
+// putShared adds the price fetched by another Cache, unless the cached
+// price is newer.
+func (g *Cache) putShared(pair provider.Pair, price *CachedPrice) {
+	if !g.hasPair(pair) {
+		return
+	}
+	g.mu.Lock()
+	if e, ok := g.prices[pair]; ok && !e.restored && !e.fetchedAt.Before(price.FetchedAt) {
+		g.mu.Unlock()
+		return
+	}
+	g.prices[pair] = cacheEntry{price: price.Price, fetchedAt: price.FetchedAt}
+	delete(g.failures, pair)
+	g.record(pair, price.Price)
+	g.mu.Unlock()
+	g.publish(pair, &CachedPrice{Price: price.Price, FetchedAt: price.FetchedAt})
+}
+
+// restore loads prices from the Store. Prices of pairs which are not kept
+// in the Cache are ignored. If the prices cannot be loaded, the Cache
+// starts empty.
+func (g *Cache) restore() {
+	prices, err := g.store.Load()
+	if err != nil {
+		g.log.WithError(err).Warn("Unable to load stored prices")
+		return
+	}
+	// Prices in a shared store may have been refreshed by another Cache
+	// just now, so their staleness depends only on their age.
+	_, shared := g.store.(SharedStore)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for pair, p := range prices {
+		if !g.hasPair(pair) {
+			continue
+		}
+		g.prices[pair] = cacheEntry{price: p.Price, fetchedAt: p.FetchedAt, restored: !shared}
+	}
+	g.log.WithField("count", len(g.prices)).Info("Loaded stored prices")
 }
 
 func (g *Cache) broadcasterRoutine() {
+	defer g.routines.Done()
 	for {
 		select {
 		case <-g.ctx.Done():
 			return
 		case <-g.interval.TickCh():
-			// Send prices to the network.
-			for _, pair := range g.pairs {
-				if err := g.update(pair); err != nil {
-					g.log.
-						WithField("assetPair", pair).
-						WithError(err).
-						Warn("Unable to update price")
-					continue
-				}
-				g.log.
-					WithField("assetPair", pair).
-					Info("Price update")
+			g.refresh()
+		}
+	}
+}
+
+// refresh updates prices of all pairs. If the spread or jitter is
+// configured, every pair is updated after its own delay, so the requests
+// sent to the origins are spread over the interval. A pair whose update
+// is due waits for a free worker.
+func (g *Cache) refresh() {
+	if !g.spread && g.jitter == 0 {
+		g.updateAll()
+		return
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	start := time.Now()
+	for _, s := range g.schedule() {
+		if d := time.Until(start.Add(s.delay)); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-g.ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
 			}
 		}
+		select {
+		case <-g.ctx.Done():
+			return
+		case g.workers <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(pair provider.Pair) {
+			defer wg.Done()
+			defer func() { <-g.workers }()
+			g.updatePair(pair, false)
+		}(s.pair)
+	}
+}
+
+type scheduledUpdate struct {
+	pair  provider.Pair
+	delay time.Duration
+}
+
+// schedule returns the delays after which pairs are updated, sorted from
+// the shortest. With the spread, pairs are updated at even steps within the
+// interval. The jitter adds a random delay to every pair, which differs
+// between updates and between Caches.
+func (g *Cache) schedule() []scheduledUpdate {
+	pairs := g.allPairs()
+	res := make([]scheduledUpdate, len(pairs))
+	var step time.Duration
+	if g.spread && len(pairs) > 0 {
+		step = g.interval.Duration() / time.Duration(len(pairs))
+	}
+	for i, pair := range pairs {
+		delay := time.Duration(i) * step
+		if g.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(g.jitter)))
+		}
+		res[i] = scheduledUpdate{pair: pair, delay: delay}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].delay < res[j].delay
+	})
+	return res
+}
+
+// updateAll updates prices of all pairs and returns the pairs which could
+// not be updated.
+func (g *Cache) updateAll() []provider.Pair {
+	return g.updatePairs(g.allPairs(), false)
+}
+
+// updatePairs updates prices of the pairs, using at most as many goroutines
+// as there are workers, and returns the pairs which could not be updated,
+// in the given order.
+func (g *Cache) updatePairs(pairs []provider.Pair, force bool) (failed []provider.Pair) {
+	ok := make([]bool, len(pairs))
+	var wg sync.WaitGroup
+	for i, pair := range pairs {
+		g.workers <- struct{}{}
+		wg.Add(1)
+		go func(i int, pair provider.Pair) {
+			defer wg.Done()
+			defer func() { <-g.workers }()
+			ok[i] = g.updatePair(pair, force)
+		}(i, pair)
+	}
+	wg.Wait()
+	for i, pair := range pairs {
+		if !ok[i] {
+			failed = append(failed, pair)
+		}
+	}
+	return failed
+}
+
+// updatePair updates the price of the pair and reports whether it was
+// updated.
+func (g *Cache) updatePair(pair provider.Pair, force bool) bool {
+	err := g.update(pair, force)
+	g.mu.Lock()
+	if err != nil {
+		g.failures[pair] = refreshFailure{count: g.failures[pair].count + 1, err: err}
+	} else {
+		delete(g.failures, pair)
+	}
+	g.mu.Unlock()
+	if err != nil {
+		g.log.
+			WithField("assetPair", pair).
+			WithError(err).
+			Warn("Unable to update price")
+		g.metrics.refreshes.WithLabelValues(pair.String(), "failure").Inc()
+		return false
+	}
+	g.metrics.refreshes.WithLabelValues(pair.String(), "success").Inc()
+	return true
+}
+
+// warmUpRoutine fetches prices of all pairs once, without waiting for the
+// first tick of the interval. The Cache becomes ready when all prices are
+// fetched or the warm-up times out. Prices are still fetched after the
+// warm-up times out.
+func (g *Cache) warmUpRoutine() {
+	defer g.routines.Done()
+	defer g.warm.Store(true)
+	done := make(chan []provider.Pair, 1)
+	g.routines.Add(1)
+	go func() {
+		defer g.routines.Done()
+		done <- g.updateAll()
+	}()
+	t := time.NewTimer(g.warmUpTimeout)
+	defer t.Stop()
+	select {
+	case <-g.ctx.Done():
+	case failed := <-done:
+		if len(failed) > 0 {
+			g.log.WithField("pairs", failed).Warn("Warm-up finished without some prices")
+			return
+		}
+		g.log.Info("Warm-up finished")
+	case <-t.C:
+		g.log.Warn("Warm-up timed out")
 	}
 }
 
+// contextCancelHandler stops the Cache when the context is canceled. It
+// waits for prices which are being fetched, so they are included in the
+// snapshot and are not saved to a closed Store.
 func (g *Cache) contextCancelHandler() {
 	defer func() { close(g.waitCh) }()
 	defer g.log.Debug("Stopped")
 	<-g.ctx.Done()
+	g.routines.Wait()
+	g.events.close()
+	if g.snapshotPath != "" {
+		if err := g.writeSnapshot(); err != nil {
+			g.log.WithError(err).Warn("Unable to write snapshot")
+		}
+	}
+	if g.store != nil {
+		if err := g.store.Close(); err != nil {
+			g.log.WithError(err).Warn("Unable to close the price store")
+		}
+	}
 }
@@ -0,0 +1,444 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	btcPrice := &provider.Price{Type: "median", Pair: btcusd, Price: 42, Time: time.Unix(1700000000, 0)}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(btcPrice, nil)
+	p.On("Price", ethusd).Return((*provider.Price)(nil), errors.New("failed"))
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+
+	// Prices are not available before the first update.
+	_, err = c.Price(btcusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+	assert.Empty(t, c.Prices())
+
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		return len(c.Prices()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	price, err := c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, btcPrice, price.Price)
+	assert.False(t, price.Stale)
+	assert.WithinDuration(t, time.Now(), price.FetchedAt, time.Second)
+	require.Contains(t, c.Prices(), btcusd)
+	assert.Equal(t, btcPrice, c.Prices()[btcusd].Price)
+
+	// The price of a pair that failed to update is not available.
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	_, err = c.Price(provider.Pair{Base: "XYZ", Quote: "USD"})
+	assert.ErrorIs(t, err, ErrUnknownPair)
+
+	cancel()
+	<-c.Wait()
+}
+
+func TestCache_Staleness(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	btcPrice := &provider.Price{Type: "median", Pair: btcusd, Price: 42}
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: &mocks.Provider{},
+		Interval:      timeutil.NewTicker(time.Hour),
+		TTL:           time.Minute,
+		MaxAge:        time.Hour,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		age   time.Duration
+		stale bool
+		err   error
+	}{
+		{age: 0, stale: false},
+		{age: 2 * time.Minute, stale: true},
+		{age: 2 * time.Hour, err: ErrPriceTooOld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.age.String(), func(t *testing.T) {
+			c.prices[btcusd] = cacheEntry{price: btcPrice, fetchedAt: time.Now().Add(-tt.age)}
+			price, err := c.Price(btcusd)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Empty(t, c.Prices())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.stale, price.Stale)
+			assert.Equal(t, tt.stale, c.Prices()[btcusd].Stale)
+		})
+	}
+}
+
+func TestCache_NextRefresh(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: &mocks.Provider{},
+		Interval:      timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, ok := c.NextRefresh(btcusd)
+	assert.False(t, ok)
+
+	fetchedAt := time.Now().Add(-10 * time.Second)
+	c.prices[btcusd] = cacheEntry{price: &provider.Price{Pair: btcusd, Price: 42}, fetchedAt: fetchedAt}
+	next, ok := c.NextRefresh(btcusd)
+	require.True(t, ok)
+	assert.Equal(t, fetchedAt.Add(time.Minute), next)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no-provider":       {Interval: timeutil.NewTicker(time.Minute)},
+		"no-interval":       {PriceProvider: &mocks.Provider{}},
+		"negative-ttl":      {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), TTL: -1},
+		"max-age-below-ttl": {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), MaxAge: time.Second},
+		"negative-jitter":   {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), Jitter: -1},
+		"jitter-too-long":   {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), Jitter: time.Minute},
+		"negative-workers":  {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), Workers: -1},
+		"negative-timeout":  {PriceProvider: &mocks.Provider{}, Interval: timeutil.NewTicker(time.Minute), PairTimeout: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCache_WarmUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	fetch := make(chan struct{})
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).
+		Run(func(mock.Arguments) { <-fetch }).
+		Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		WarmUpTimeout: time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Error(t, c.Ready())
+	require.NoError(t, c.Start(ctx))
+
+	// The cache is not ready until prices are fetched, without waiting
+	// for the first tick.
+	assert.Error(t, c.Ready())
+	close(fetch)
+	require.Eventually(t, func() bool {
+		return c.Ready() == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = c.Price(btcusd)
+	assert.NoError(t, err)
+}
+
+func TestCache_WarmUpTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).
+		Run(func(mock.Arguments) { <-ctx.Done() }).
+		Return((*provider.Price)(nil), errors.New("canceled"))
+
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		WarmUpTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	require.Eventually(t, func() bool {
+		return c.Ready() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCache_Schedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		spread bool
+		jitter time.Duration
+	}{
+		{name: "spread", spread: true},
+		{name: "jitter", jitter: 10 * time.Second},
+		{name: "spread-and-jitter", spread: true, jitter: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(Config{
+				Pairs:         []string{"BTC/USD", "ETH/USD", "DAI/USD", "MKR/USD"},
+				PriceProvider: &mocks.Provider{},
+				Interval:      timeutil.NewTicker(time.Minute),
+				Spread:        tt.spread,
+				Jitter:        tt.jitter,
+			})
+			require.NoError(t, err)
+
+			s := c.schedule()
+			require.Len(t, s, 4)
+			pairs := map[provider.Pair]bool{}
+			for i, u := range s {
+				pairs[u.pair] = true
+				if i > 0 {
+					assert.GreaterOrEqual(t, u.delay, s[i-1].delay)
+				}
+				min := time.Duration(0)
+				if tt.spread {
+					// Every pair is delayed at least by its step.
+					min = time.Duration(indexOf(c.pairs, u.pair)) * 15 * time.Second
+				}
+				assert.GreaterOrEqual(t, u.delay, min)
+				assert.Less(t, u.delay, min+tt.jitter+time.Nanosecond)
+			}
+			assert.Len(t, pairs, 4)
+		})
+	}
+}
+
+func TestCache_SpreadRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21}, nil)
+
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		Spread:        true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	go c.interval.Tick()
+
+	// The first pair is updated immediately, the second one only after
+	// half of the interval.
+	require.Eventually(t, func() bool {
+		_, err := c.Price(btcusd)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+}
+
+func indexOf(pairs []provider.Pair, pair provider.Pair) int {
+	for i, p := range pairs {
+		if p == pair {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil).Once()
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 43}, nil)
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21}, nil).Once()
+	p.On("Price", ethusd).Return((*provider.Price)(nil), errors.New("failed"))
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		return len(c.Prices()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// Nothing is invalidated if one of the pairs is unknown.
+	_, err = c.Invalidate(btcusd, provider.Pair{Base: "XYZ", Quote: "USD"})
+	assert.ErrorIs(t, err, ErrUnknownPair)
+	assert.Len(t, c.Prices(), 2)
+
+	failed, err := c.Invalidate(btcusd)
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	price, err := c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 43.0, price.Price.Price)
+
+	// A price that cannot be fetched again is removed.
+	failed, err = c.Invalidate()
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{ethusd}, failed)
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+}
+
+func TestCache_LastGoodValue(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{
+		Type:       "median",
+		Pair:       btcusd,
+		Price:      42,
+		Parameters: map[string]string{"method": "median"},
+	}, nil).Once()
+	p.On("Price", btcusd).Return((*provider.Price)(nil), errors.New("origin unavailable")).Twice()
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 43}, nil).Once()
+
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// After failed refreshes, the last good price is served as stale with
+	// the error of the last refresh.
+	c.updateAll()
+	c.updateAll()
+	c.updateAll()
+	price, err := c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, price.Price.Price)
+	assert.True(t, price.Stale)
+	assert.Equal(t, "origin unavailable", price.RefreshError)
+	assert.Equal(t, 2, price.Failures)
+
+	served, err := NewCachedProvider(c).Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, served.Price)
+	assert.Equal(t, map[string]string{
+		"method":          "median",
+		"stale":           "true",
+		"refreshError":    "origin unavailable",
+		"refreshFailures": "2",
+	}, served.Parameters)
+	assert.Equal(t, map[string]string{"method": "median"}, price.Parameters)
+
+	// A successful refresh resets the failures.
+	c.updateAll()
+	price, err = c.Price(btcusd)
+	require.NoError(t, err)
+	assert.False(t, price.Stale)
+	assert.Empty(t, price.RefreshError)
+	assert.Zero(t, price.Failures)
+}
+
+func TestCache_Workers(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	unblock := make(chan time.Time)
+	p := &mocks.Provider{}
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21}, nil).WaitUntil(unblock)
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+
+	c, err := New(Config{
+		Pairs:         []string{"ETH/USD", "BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		Workers:       2,
+	})
+	require.NoError(t, err)
+
+	// A slow pair does not delay updates of the other pairs.
+	done := make(chan []provider.Pair, 1)
+	go func() { done <- c.updateAll() }()
+	require.Eventually(t, func() bool {
+		_, err := c.Price(btcusd)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	close(unblock)
+	assert.Empty(t, <-done)
+	assert.Len(t, c.Prices(), 2)
+}
+
+func TestCache_PairTimeout(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	unblock := make(chan time.Time)
+	defer close(unblock)
+	p := &mocks.Provider{}
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21}, nil).WaitUntil(unblock)
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+
+	c, err := New(Config{
+		Pairs:         []string{"ETH/USD", "BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		PairTimeout:   50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []provider.Pair{ethusd}, c.updateAll())
+	_, err = c.Price(btcusd)
+	assert.NoError(t, err)
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+	c.mu.RLock()
+	assert.ErrorIs(t, c.failures[ethusd].err, ErrUpdateTimeout)
+	c.mu.RUnlock()
+}
@@ -0,0 +1,133 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"sync"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// ConcurrentProvider is a price provider which splits requests for many
+// pairs into batches which are sent to another price provider at the same
+// time. Origins fetch prices of many pairs one after another, so batches
+// are fetched faster. Pairs are split into as many batches as the
+// concurrency allows, so origins which fetch many pairs in one request
+// still do so within a batch.
+type ConcurrentProvider struct {
+	provider    provider.Provider
+	concurrency int
+}
+
+// NewConcurrentProvider creates a new ConcurrentProvider which sends at
+// most concurrency requests to the given price provider at the same time.
+func NewConcurrentProvider(p provider.Provider, concurrency int) *ConcurrentProvider {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ConcurrentProvider{provider: p, concurrency: concurrency}
+}
+
+// Models implements the provider.Provider interface.
+func (c *ConcurrentProvider) Models(pairs ...provider.Pair) (map[provider.Pair]*provider.Model, error) {
+	if len(pairs) == 0 {
+		var err error
+		if pairs, err = c.provider.Pairs(); err != nil {
+			return nil, err
+		}
+	}
+	return concurrently(c.batches(pairs), c.provider.Models)
+}
+
+// Price implements the provider.Provider interface.
+func (c *ConcurrentProvider) Price(pair provider.Pair) (*provider.Price, error) {
+	return c.provider.Price(pair)
+}
+
+// Prices implements the provider.Provider interface.
+func (c *ConcurrentProvider) Prices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	if len(pairs) == 0 {
+		var err error
+		if pairs, err = c.provider.Pairs(); err != nil {
+			return nil, err
+		}
+	}
+	return concurrently(c.batches(pairs), c.provider.Prices)
+}
+
+// Pairs implements the provider.Provider interface.
+func (c *ConcurrentProvider) Pairs() ([]provider.Pair, error) {
+	return c.provider.Pairs()
+}
+
+// batches splits the pairs into at most as many batches as the
+// concurrency, keeping the order of the pairs.
+func (c *ConcurrentProvider) batches(pairs []provider.Pair) [][]provider.Pair {
+	n := c.concurrency
+	if n > len(pairs) {
+		n = len(pairs)
+	}
+	batches := make([][]provider.Pair, 0, n)
+	from := 0
+	for i := 0; i < n; i++ {
+		// The first len(pairs)%n batches have one more pair.
+		to := from + len(pairs)/n
+		if i < len(pairs)%n {
+			to++
+		}
+		batches = append(batches, pairs[from:to])
+		from = to
+	}
+	return batches
+}
+
+// concurrently calls fn with every batch at the same time and merges the
+// results. If any call fails, the first error is returned.
+func concurrently[T any](batches [][]provider.Pair, fn func(...provider.Pair) (map[provider.Pair]T, error)) (map[provider.Pair]T, error) {
+	if len(batches) == 1 {
+		return fn(batches[0]...)
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	res := make(map[provider.Pair]T)
+	for _, b := range batches {
+		b := b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := fn(b...)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			for pair, v := range r {
+				res[pair] = v
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return res, nil
+}
@@ -0,0 +1,103 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+)
+
+func TestConcurrentProvider_Batches(t *testing.T) {
+	var pairs []provider.Pair
+	for _, s := range []string{"A/X", "B/X", "C/X", "D/X", "E/X"} {
+		p, _ := provider.NewPair(s)
+		pairs = append(pairs, p)
+	}
+	sizes := func(batches [][]provider.Pair) (res []int) {
+		for _, b := range batches {
+			res = append(res, len(b))
+		}
+		return res
+	}
+	assert.Equal(t, []int{5}, sizes(NewConcurrentProvider(nil, 0).batches(pairs)))
+	assert.Equal(t, []int{3, 2}, sizes(NewConcurrentProvider(nil, 2).batches(pairs)))
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, sizes(NewConcurrentProvider(nil, 10).batches(pairs)))
+	assert.Empty(t, NewConcurrentProvider(nil, 4).batches(nil))
+
+	// Pairs keep their order.
+	var all []provider.Pair
+	for _, b := range NewConcurrentProvider(nil, 3).batches(pairs) {
+		all = append(all, b...)
+	}
+	assert.Equal(t, pairs, all)
+}
+
+func TestConcurrentProvider_Prices(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	mkrusd := provider.Pair{Base: "MKR", Quote: "USD"}
+
+	// Every batch is fetched at the same time, so each call waits until
+	// all of them have started.
+	var started int32
+	wait := func(mock.Arguments) {
+		atomic.AddInt32(&started, 1)
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&started) < 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{btcusd, ethusd, mkrusd}, nil)
+	for _, pair := range []provider.Pair{btcusd, ethusd, mkrusd} {
+		p.On("Prices", pair).Run(wait).Return(map[provider.Pair]*provider.Price{
+			pair: {Pair: pair, Price: 1},
+		}, nil).Once()
+	}
+
+	prices, err := NewConcurrentProvider(p, 3).Prices()
+	require.NoError(t, err)
+	assert.Len(t, prices, 3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&started))
+	p.AssertExpectations(t)
+}
+
+func TestConcurrentProvider_Error(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{btcusd: {Pair: btcusd}}, nil)
+	p.On("Prices", ethusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	p.On("Models", btcusd).Return(map[provider.Pair]*provider.Model{btcusd: {Pair: btcusd}}, nil)
+	p.On("Models", ethusd).Return(map[provider.Pair]*provider.Model{ethusd: {Pair: ethusd}}, nil)
+
+	c := NewConcurrentProvider(p, 2)
+	_, err := c.Prices(btcusd, ethusd)
+	assert.EqualError(t, err, "failed")
+
+	models, err := c.Models(btcusd, ethusd)
+	require.NoError(t, err)
+	assert.Len(t, models, 2)
+}
@@ -0,0 +1,166 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"math"
+	"sync"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// eventBufferSize is the number of events that may be queued for a single
+// Subscription before further events are dropped.
+const eventBufferSize = 64
+
+// PriceEvent is published when the price of a pair is updated in the Cache.
+type PriceEvent struct {
+	Pair  provider.Pair
+	Price *CachedPrice
+}
+
+// Subscription receives events about updated prices. It must be closed when
+// it is no longer used.
+type Subscription struct {
+	bus   *eventBus
+	pairs map[provider.Pair]struct{}
+	ch    chan PriceEvent
+}
+
+// Events returns the channel of price events. The channel is closed when
+// the Subscription is closed or the Cache is stopped. Events are not queued indefinitely: if the
+// subscriber is too slow to receive them, further events are dropped.
+func (s *Subscription) Events() <-chan PriceEvent {
+	return s.ch
+}
+
+// Close stops the Subscription and closes its events channel.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// eventBus delivers price events to subscriptions.
+type eventBus struct {
+	mu     sync.Mutex
+	log    log.Logger
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func newEventBus(l log.Logger) *eventBus {
+	return &eventBus{log: l, subs: make(map[*Subscription]struct{})}
+}
+
+func (b *eventBus) subscribe(pairs ...provider.Pair) *Subscription {
+	s := &Subscription{
+		bus:   b,
+		pairs: make(map[provider.Pair]struct{}, len(pairs)),
+		ch:    make(chan PriceEvent, eventBufferSize),
+	}
+	for _, pair := range pairs {
+		s.pairs[pair] = struct{}{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *eventBus) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	close(s.ch)
+}
+
+// close closes all subscriptions. Subscriptions created afterwards are
+// closed immediately.
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		close(s.ch)
+	}
+	b.subs = make(map[*Subscription]struct{})
+	b.closed = true
+}
+
+// publish delivers the event to all subscriptions of its pair without
+// blocking.
+func (b *eventBus) publish(ev PriceEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if _, ok := s.pairs[ev.Pair]; len(s.pairs) > 0 && !ok {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			b.log.
+				WithField("assetPair", ev.Pair).
+				Warn("Subscriber is too slow, dropping price event")
+		}
+	}
+}
+
+// publish logs the updated price of the pair and publishes it to
+// subscribers, unless it has moved by less than the deviation threshold
+// since the last published price.
+func (g *Cache) publish(pair provider.Pair, price *CachedPrice) {
+	g.mu.Lock()
+	last, ok := g.published[pair]
+	if ok && !g.deviates(last, price.Price) {
+		g.mu.Unlock()
+		g.log.
+			WithField("assetPair", pair).
+			Debug("Price update below deviation threshold")
+		return
+	}
+	g.published[pair] = price.Price
+	g.mu.Unlock()
+	g.log.
+		WithField("assetPair", pair).
+		Info("Price update")
+	g.events.publish(PriceEvent{Pair: pair, Price: price})
+}
+
+// deviates reports whether the price p moved by at least the deviation
+// threshold since the price last. Prices with errors and prices following
+// a zero price always deviate.
+func (g *Cache) deviates(last, p *provider.Price) bool {
+	if g.deviation == 0 || last.Price == 0 || last.Error != "" || p.Error != "" {
+		return true
+	}
+	return math.Abs(p.Price-last.Price)/math.Abs(last.Price)*100 >= g.deviation
+}
+
+// Subscribe returns a Subscription to updates of the given pairs, or of all
+// pairs if none are given. An event is published every time a price is
+// fetched, or a price fetched by another Cache is loaded from the
+// SharedStore, even if the price has not changed, unless the deviation
+// threshold is set.
+func (g *Cache) Subscribe(pairs ...provider.Pair) *Subscription {
+	return g.events.subscribe(pairs...)
+}
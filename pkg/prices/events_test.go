@@ -0,0 +1,160 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCache_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21}, nil)
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+
+	all := c.Subscribe()
+	eth := c.Subscribe(ethusd)
+	closed := c.Subscribe()
+	closed.Close()
+	closed.Close()
+	_, ok := <-closed.Events()
+	assert.False(t, ok)
+
+	ticker.Tick()
+	received := map[provider.Pair]float64{}
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-all.Events():
+			received[ev.Pair] = ev.Price.Price.Price
+		case <-time.After(time.Second):
+			t.Fatal("no price event")
+		}
+	}
+	assert.Equal(t, map[provider.Pair]float64{btcusd: 42, ethusd: 21}, received)
+
+	// Only events of subscribed pairs are delivered.
+	select {
+	case ev := <-eth.Events():
+		assert.Equal(t, ethusd, ev.Pair)
+		assert.WithinDuration(t, time.Now(), ev.Price.FetchedAt, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("no price event")
+	}
+	assert.Len(t, eth.Events(), 0)
+
+	// Subscriptions are closed when the cache is stopped.
+	cancel()
+	<-c.Wait()
+	_, ok = <-eth.Events()
+	assert.False(t, ok)
+	_, ok = <-c.Subscribe().Events()
+	assert.False(t, ok)
+}
+
+func TestEventBus_DropsSlowSubscribers(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	b := newEventBus(null.New())
+	s := b.subscribe()
+	for i := 0; i < eventBufferSize+10; i++ {
+		b.publish(PriceEvent{Pair: btcusd})
+	}
+	assert.Len(t, s.Events(), eventBufferSize)
+}
+
+func TestCache_DeviationThreshold(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	for _, price := range []float64{100, 100.5, 101, 100.2} {
+		p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: price}, nil).Once()
+	}
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:              []string{"BTC/USD"},
+		PriceProvider:      p,
+		Interval:           ticker,
+		DeviationThreshold: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	sub := c.Subscribe()
+
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+	}
+	require.Eventually(t, func() bool {
+		price, err := c.Price(btcusd)
+		return err == nil && price.Price.Price == 100.2
+	}, time.Second, 10*time.Millisecond)
+
+	// The first price is always published. Changes below 1% are cached but
+	// not published; the deviation is measured from the last published
+	// price.
+	var published []float64
+	for len(sub.Events()) > 0 {
+		published = append(published, (<-sub.Events()).Price.Price.Price)
+	}
+	assert.Equal(t, []float64{100, 101}, published)
+
+	// Invalidated prices are always published.
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 101}, nil).Once()
+	_, err = c.Invalidate(btcusd)
+	require.NoError(t, err)
+	require.Len(t, sub.Events(), 1)
+}
+
+func TestDeviates(t *testing.T) {
+	c := &Cache{deviation: 1}
+	tests := []struct {
+		last, price *provider.Price
+		want        bool
+	}{
+		{last: &provider.Price{Price: 100}, price: &provider.Price{Price: 100.99}, want: false},
+		{last: &provider.Price{Price: 100}, price: &provider.Price{Price: 99}, want: true},
+		{last: &provider.Price{Price: 0}, price: &provider.Price{Price: 0}, want: true},
+		{last: &provider.Price{Price: 100, Error: "failed"}, price: &provider.Price{Price: 100}, want: true},
+		{last: &provider.Price{Price: 100}, price: &provider.Price{Price: 100, Error: "failed"}, want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, c.deviates(tt.last, tt.price))
+	}
+}
@@ -0,0 +1,63 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// ErrPriceOutdated is returned for pairs whose price was reported by the
+// origins longer ago than the freshness bound of the pair.
+var ErrPriceOutdated = errors.New("price outdated")
+
+// freshness returns the freshness bound of the pair, or zero if the age of
+// its prices is not checked.
+func (g *Cache) freshness(pair provider.Pair) time.Duration {
+	if d, ok := g.freshPairs[pair]; ok {
+		return d
+	}
+	return g.freshDefault
+}
+
+// outdated returns an error if the timestamp of the price is older than
+// the freshness bound of its pair. Prices without a timestamp are never
+// outdated.
+func (g *Cache) outdated(pair provider.Pair, p *provider.Price, now time.Time) error {
+	bound := g.freshness(pair)
+	if bound == 0 || p.Time.IsZero() {
+		return nil
+	}
+	if age := now.Sub(p.Time); age > bound {
+		return fmt.Errorf("%w: %s was updated %s ago", ErrPriceOutdated, pair, age.Truncate(time.Second))
+	}
+	return nil
+}
+
+// markOutdated returns a copy of the price with the error set if the price
+// is outdated, or the price itself otherwise.
+func (g *Cache) markOutdated(pair provider.Pair, p *provider.Price) *provider.Price {
+	err := g.outdated(pair, p, time.Now())
+	if err == nil || p.Error != "" {
+		return p
+	}
+	marked := *p
+	marked.Error = err.Error()
+	return &marked
+}
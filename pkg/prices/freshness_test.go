@@ -0,0 +1,114 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCache_Freshness(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	daiusd := provider.Pair{Base: "DAI", Quote: "USD"}
+	old := time.Now().Add(-time.Hour)
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42, Time: old}, nil)
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 21, Time: old}, nil)
+	p.On("Price", daiusd).Return(&provider.Price{Type: "median", Pair: daiusd, Price: 1, Time: time.Now()}, nil)
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price{
+		btcusd: {Type: "median", Pair: btcusd, Price: 42, Time: old},
+	}, nil)
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD", "DAI/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+		Freshness:     time.Minute,
+		PairFreshness: map[string]time.Duration{"ETH/USD": 2 * time.Hour},
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		return len(c.Prices()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The outdated price is not cached, the per-pair bound of ETH/USD
+	// allows older prices.
+	_, err = c.Price(btcusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+	assert.Contains(t, c.Prices(), ethusd)
+	assert.Contains(t, c.Prices(), daiusd)
+
+	// Prices fetched on demand are marked with an error.
+	price, err := NewCachedProvider(c).Price(btcusd)
+	require.NoError(t, err)
+	assert.Contains(t, price.Error, ErrPriceOutdated.Error())
+	prices, err := NewCachedProvider(c).Prices(btcusd, daiusd)
+	require.NoError(t, err)
+	assert.Contains(t, prices[btcusd].Error, ErrPriceOutdated.Error())
+	assert.Empty(t, prices[daiusd].Error)
+	_, err = c.Price(btcusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+}
+
+func TestCache_FreshnessOfCachedPrice(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: &mocks.Provider{},
+		Interval:      timeutil.NewTicker(time.Hour),
+		Freshness:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	// A cached price becomes outdated when its origins stop updating it.
+	c.prices[btcusd] = cacheEntry{
+		price:     &provider.Price{Pair: btcusd, Price: 42, Time: time.Now().Add(-2 * time.Minute)},
+		fetchedAt: time.Now(),
+	}
+	_, err = c.Price(btcusd)
+	assert.ErrorIs(t, err, ErrPriceOutdated)
+	assert.Empty(t, c.Prices())
+}
+
+func TestNew_InvalidFreshness(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"negative":      {Freshness: -1},
+		"negative-pair": {PairFreshness: map[string]time.Duration{"BTC/USD": -1}},
+		"invalid-pair":  {PairFreshness: map[string]time.Duration{"BTCUSD": time.Minute}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.PriceProvider = &mocks.Provider{}
+			cfg.Interval = timeutil.NewTicker(time.Minute)
+			_, err := New(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+)
+
+// priceRing is a fixed-size buffer of the most recent prices of a pair.
+// When it is full, adding a price overwrites the oldest one.
+type priceRing struct {
+	prices []*provider.Price
+	start  int
+	len    int
+}
+
+func newPriceRing(size int) *priceRing {
+	return &priceRing{prices: make([]*provider.Price, size)}
+}
+
+// add appends the price, overwriting the oldest one if the ring is full.
+func (r *priceRing) add(p *provider.Price) {
+	r.prices[(r.start+r.len)%len(r.prices)] = p
+	if r.len < len(r.prices) {
+		r.len++
+		return
+	}
+	r.start = (r.start + 1) % len(r.prices)
+}
+
+// all returns the prices in the ring, from the oldest.
+func (r *priceRing) all() []*provider.Price {
+	res := make([]*provider.Price, r.len)
+	for i := range res {
+		res[i] = r.prices[(r.start+i)%len(r.prices)]
+	}
+	return res
+}
+
+// History returns the recent prices of the pair kept in the Cache, with
+// timestamps between from and to, inclusive, sorted from the oldest.
+// A zero from or to time means no bound. It implements the
+// agent.PriceHistory interface, so pairs which are not kept in the Cache
+// are reported as graph.ErrPairNotFound.
+func (g *Cache) History(pair provider.Pair, from, to time.Time) ([]*provider.Price, error) {
+	if !g.hasPair(pair) {
+		return nil, graph.ErrPairNotFound{Pair: pair}
+	}
+	g.mu.RLock()
+	r, ok := g.history[pair]
+	var prices []*provider.Price
+	if ok {
+		prices = r.all()
+	}
+	g.mu.RUnlock()
+	res := make([]*provider.Price, 0, len(prices))
+	for _, p := range prices {
+		if !from.IsZero() && p.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && p.Time.After(to) {
+			continue
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// record adds the price to the history of the pair, if the history is
+// enabled. The caller must hold the lock.
+func (g *Cache) record(pair provider.Pair, p *provider.Price) {
+	if g.historySize == 0 {
+		return
+	}
+	r, ok := g.history[pair]
+	if !ok {
+		r = newPriceRing(g.historySize)
+		g.history[pair] = r
+	}
+	r.add(p)
+}
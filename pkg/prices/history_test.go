@@ -0,0 +1,91 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestPriceRing(t *testing.T) {
+	r := newPriceRing(3)
+	assert.Empty(t, r.all())
+
+	var prices []*provider.Price
+	for i := 0; i < 5; i++ {
+		p := &provider.Price{Price: float64(i)}
+		prices = append(prices, p)
+		r.add(p)
+	}
+	// Only the three most recent prices are kept, from the oldest.
+	assert.Equal(t, prices[2:], r.all())
+}
+
+func TestCache_History(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	start := time.Unix(1700000000, 0)
+	p := &mocks.Provider{}
+	for i := 0; i < 4; i++ {
+		p.On("Price", btcusd).Return(&provider.Price{
+			Type:  "median",
+			Pair:  btcusd,
+			Price: float64(i),
+			Time:  start.Add(time.Duration(i) * time.Minute),
+		}, nil).Once()
+	}
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+		HistorySize:   3,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+
+	history, err := c.History(btcusd, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+	}
+	require.Eventually(t, func() bool {
+		history, err := c.History(btcusd, time.Time{}, time.Time{})
+		return err == nil && len(history) == 3 && history[2].Price == 3
+	}, time.Second, 10*time.Millisecond)
+
+	history, err = c.History(btcusd, start.Add(2*time.Minute), start.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 2.0, history[0].Price)
+
+	_, err = c.History(provider.Pair{Base: "XYZ", Quote: "USD"}, time.Time{}, time.Time{})
+	assert.ErrorAs(t, err, &graph.ErrPairNotFound{})
+}
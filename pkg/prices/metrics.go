@@ -0,0 +1,102 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "gofer_cache"
+
+// cacheMetrics holds the Prometheus collectors of the Cache. The collectors
+// are not registered; the Cache implements the prometheus.Collector
+// interface, so it can be registered in any registry.
+type cacheMetrics struct {
+	hits      *prometheus.CounterVec
+	misses    *prometheus.CounterVec
+	refreshes *prometheus.CounterVec
+	priceAge  *prometheus.Desc
+	failures  *prometheus.Desc
+}
+
+func newCacheMetrics() *cacheMetrics {
+	return &cacheMetrics{
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "hits_total",
+			Help:      "Number of prices served from the cache, by pair.",
+		}, []string{"pair"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "misses_total",
+			Help:      "Number of requested prices not available in the cache, by pair.",
+		}, []string{"pair"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "refreshes_total",
+			Help:      "Number of periodic price refreshes, by pair and result.",
+		}, []string{"pair", "result"}),
+		priceAge: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "price_age_seconds"),
+			"Time since the cached price was fetched, by pair.",
+			[]string{"pair"},
+			nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "consecutive_failures"),
+			"Number of consecutive failed refreshes, by pair. Reset by a successful refresh.",
+			[]string{"pair"},
+			nil,
+		),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (g *Cache) Describe(ch chan<- *prometheus.Desc) {
+	g.metrics.hits.Describe(ch)
+	g.metrics.misses.Describe(ch)
+	g.metrics.refreshes.Describe(ch)
+	ch <- g.metrics.priceAge
+	ch <- g.metrics.failures
+}
+
+// Collect implements the prometheus.Collector interface.
+func (g *Cache) Collect(ch chan<- prometheus.Metric) {
+	g.metrics.hits.Collect(ch)
+	g.metrics.misses.Collect(ch)
+	g.metrics.refreshes.Collect(ch)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := time.Now()
+	for pair, e := range g.prices {
+		ch <- prometheus.MustNewConstMetric(
+			g.metrics.priceAge,
+			prometheus.GaugeValue,
+			now.Sub(e.fetchedAt).Seconds(),
+			pair.String(),
+		)
+	}
+	for _, pair := range g.allPairs() {
+		ch <- prometheus.MustNewConstMetric(
+			g.metrics.failures,
+			prometheus.GaugeValue,
+			float64(g.failures[pair].count),
+			pair.String(),
+		)
+	}
+}
@@ -0,0 +1,76 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCache_Metrics(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil)
+	p.On("Price", ethusd).Return((*provider.Price)(nil), errors.New("failed"))
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+
+	c.updateAll()
+	_, _ = c.Price(btcusd)
+	_, _ = c.Price(btcusd)
+	_, _ = c.Price(ethusd)
+	_, _ = c.Price(provider.Pair{Base: "XYZ", Quote: "USD"})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.metrics.hits.WithLabelValues("BTC/USD")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.metrics.misses.WithLabelValues("ETH/USD")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.metrics.refreshes.WithLabelValues("BTC/USD", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.metrics.refreshes.WithLabelValues("ETH/USD", "failure")))
+
+	// Unknown pairs are not counted.
+	assert.Equal(t, 2, testutil.CollectAndCount(c, "gofer_cache_hits_total", "gofer_cache_misses_total"))
+
+	// The age is reported for every cached price.
+	assert.Equal(t, 1, testutil.CollectAndCount(c, "gofer_cache_price_age_seconds"))
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP gofer_cache_refreshes_total Number of periodic price refreshes, by pair and result.
+# TYPE gofer_cache_refreshes_total counter
+gofer_cache_refreshes_total{pair="BTC/USD",result="success"} 1
+gofer_cache_refreshes_total{pair="ETH/USD",result="failure"} 1
+`), "gofer_cache_refreshes_total"))
+
+	c.updateAll()
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP gofer_cache_consecutive_failures Number of consecutive failed refreshes, by pair. Reset by a successful refresh.
+# TYPE gofer_cache_consecutive_failures gauge
+gofer_cache_consecutive_failures{pair="BTC/USD"} 0
+gofer_cache_consecutive_failures{pair="ETH/USD"} 2
+`), "gofer_cache_consecutive_failures"))
+}
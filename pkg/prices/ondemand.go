@@ -0,0 +1,118 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"container/list"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// onDemandPairs is the set of pairs admitted to the Cache on demand, ordered
+// from the most recently requested. It is not safe for concurrent use.
+type onDemandPairs struct {
+	max   int
+	order *list.List
+	elems map[provider.Pair]*list.Element
+}
+
+func newOnDemandPairs(max int) *onDemandPairs {
+	return &onDemandPairs{
+		max:   max,
+		order: list.New(),
+		elems: make(map[provider.Pair]*list.Element),
+	}
+}
+
+// has reports whether the pair is in the set.
+func (o *onDemandPairs) has(pair provider.Pair) bool {
+	_, ok := o.elems[pair]
+	return ok
+}
+
+// touch marks the pair as the most recently requested one, if it is in the
+// set.
+func (o *onDemandPairs) touch(pair provider.Pair) {
+	if e, ok := o.elems[pair]; ok {
+		o.order.MoveToFront(e)
+	}
+}
+
+// add adds the pair as the most recently requested one and returns the
+// least recently requested pairs removed to keep the size of the set
+// within the limit.
+func (o *onDemandPairs) add(pair provider.Pair) (evicted []provider.Pair) {
+	if o.has(pair) {
+		o.touch(pair)
+		return nil
+	}
+	o.elems[pair] = o.order.PushFront(pair)
+	for o.order.Len() > o.max {
+		e := o.order.Back()
+		p := o.order.Remove(e).(provider.Pair)
+		delete(o.elems, p)
+		evicted = append(evicted, p)
+	}
+	return evicted
+}
+
+// pairs returns the pairs in the set, from the most recently requested.
+func (o *onDemandPairs) pairs() []provider.Pair {
+	res := make([]provider.Pair, 0, o.order.Len())
+	for e := o.order.Front(); e != nil; e = e.Next() {
+		res = append(res, e.Value.(provider.Pair))
+	}
+	return res
+}
+
+// admit adds the pair to the pairs refreshed by the Cache, if pairs may be
+// admitted on demand and the pair is not kept in the Cache yet. If there
+// are too many such pairs, the least recently requested ones are evicted
+// from the Cache together with their prices.
+func (g *Cache) admit(pair provider.Pair) {
+	if g.onDemand == nil || g.hasPair(pair) {
+		return
+	}
+	g.pairsMu.Lock()
+	evicted := g.onDemand.add(pair)
+	g.pairsMu.Unlock()
+	g.log.WithField("assetPair", pair).Info("Pair admitted to the cache")
+	if len(evicted) == 0 {
+		return
+	}
+	g.mu.Lock()
+	for _, p := range evicted {
+		delete(g.prices, p)
+		delete(g.history, p)
+		delete(g.published, p)
+		delete(g.failures, p)
+	}
+	g.mu.Unlock()
+	for _, p := range evicted {
+		g.log.WithField("assetPair", p).Info("Pair evicted from the cache")
+	}
+}
+
+// touch marks the pair as recently requested, so it is not evicted before
+// pairs which were requested earlier.
+func (g *Cache) touch(pair provider.Pair) {
+	if g.onDemand == nil {
+		return
+	}
+	g.pairsMu.Lock()
+	g.onDemand.touch(pair)
+	g.pairsMu.Unlock()
+}
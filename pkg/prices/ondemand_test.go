@@ -0,0 +1,115 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestOnDemandPairs(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	daiusd := provider.Pair{Base: "DAI", Quote: "USD"}
+	o := newOnDemandPairs(2)
+	assert.Empty(t, o.add(btcusd))
+	assert.Empty(t, o.add(ethusd))
+	o.touch(btcusd)
+	assert.Equal(t, []provider.Pair{btcusd, ethusd}, o.pairs())
+
+	// The least recently requested pair is evicted.
+	assert.Equal(t, []provider.Pair{ethusd}, o.add(daiusd))
+	assert.Equal(t, []provider.Pair{daiusd, btcusd}, o.pairs())
+	assert.False(t, o.has(ethusd))
+	assert.Empty(t, o.add(btcusd))
+	assert.Equal(t, []provider.Pair{btcusd, daiusd}, o.pairs())
+}
+
+func TestCache_OnDemandPairs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	daiusd := provider.Pair{Base: "DAI", Quote: "USD"}
+	p := &mocks.Provider{}
+	for _, pair := range []provider.Pair{btcusd, ethusd, daiusd} {
+		p.On("Price", pair).Return(&provider.Price{Type: "median", Pair: pair, Price: 1}, nil)
+	}
+	p.On("Prices", daiusd).Return(map[provider.Pair]*provider.Price{
+		daiusd: {Type: "median", Pair: daiusd, Price: 1},
+	}, nil)
+
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:            []string{"BTC/USD"},
+		PriceProvider:    p,
+		Interval:         ticker,
+		MaxOnDemandPairs: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	cp := NewCachedProvider(c)
+
+	// A pair fetched on demand is admitted and served from the cache.
+	_, err = cp.Price(ethusd)
+	require.NoError(t, err)
+	_, err = c.Price(ethusd)
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{btcusd, ethusd}, c.allPairs())
+
+	// Admitting another pair evicts the previous one with its price, but
+	// never the configured pairs.
+	_, err = cp.Prices(daiusd)
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{btcusd, daiusd}, c.allPairs())
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrUnknownPair)
+
+	// Admitted pairs are refreshed.
+	admitted, err := c.Price(daiusd)
+	require.NoError(t, err)
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		refreshed, err := c.Price(daiusd)
+		return err == nil && refreshed.FetchedAt.After(admitted.FetchedAt)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCache_OnDemandPairsDisabled(t *testing.T) {
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", ethusd).Return(&provider.Price{Type: "median", Pair: ethusd, Price: 1}, nil)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = NewCachedProvider(c).Price(ethusd)
+	require.NoError(t, err)
+	_, err = c.Price(ethusd)
+	assert.ErrorIs(t, err, ErrUnknownPair)
+}
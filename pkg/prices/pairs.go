@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// HasWildcards reports whether any of the pairs is a pattern, such as
+// "*/USD" or "BTC/*".
+func HasWildcards(pairs ...string) bool {
+	for _, p := range pairs {
+		if strings.ContainsAny(p, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandPairs returns the pairs in the format "BASE/QUOTE", with patterns
+// replaced by the available pairs they match. The base and the quote of a
+// pattern are matched separately, with the syntax of path.Match, e.g.
+// "*/USD" matches all pairs quoted in USD. Pairs matched by a pattern are
+// sorted, and every pair is returned only once. An error is returned if
+// a pattern does not match any pair.
+func ExpandPairs(pairs []string, available []provider.Pair) ([]provider.Pair, error) {
+	var res []provider.Pair
+	seen := make(map[provider.Pair]bool)
+	add := func(p provider.Pair) {
+		if !seen[p] {
+			seen[p] = true
+			res = append(res, p)
+		}
+	}
+	for _, s := range pairs {
+		pattern, err := provider.NewPair(s)
+		if err != nil {
+			return nil, err
+		}
+		if !HasWildcards(s) {
+			add(pattern)
+			continue
+		}
+		matched, err := matchPairs(pattern, available)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no pairs match %s", s)
+		}
+		for _, p := range matched {
+			add(p)
+		}
+	}
+	return res, nil
+}
+
+// matchPairs returns the available pairs which match the pattern, sorted.
+func matchPairs(pattern provider.Pair, available []provider.Pair) ([]provider.Pair, error) {
+	var res []provider.Pair
+	for _, p := range available {
+		base, err := path.Match(pattern.Base, p.Base)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pair pattern %s: %w", pattern, err)
+		}
+		quote, err := path.Match(pattern.Quote, p.Quote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pair pattern %s: %w", pattern, err)
+		}
+		if base && quote {
+			res = append(res, p)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].String() < res[j].String()
+	})
+	return res, nil
+}
+
+// ReadPairs reads pairs, or patterns, written one per line. Empty lines are
+// skipped, and "#" starts a comment which lasts until the end of the line.
+func ReadPairs(r io.Reader) ([]string, error) {
+	var res []string
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, err := provider.NewPair(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		res = append(res, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
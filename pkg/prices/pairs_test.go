@@ -0,0 +1,92 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestExpandPairs(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	btceth := provider.Pair{Base: "BTC", Quote: "ETH"}
+	daieur := provider.Pair{Base: "DAI", Quote: "EUR"}
+	available := []provider.Pair{ethusd, btcusd, btceth, daieur}
+	tests := []struct {
+		pairs   []string
+		want    []provider.Pair
+		wantErr bool
+	}{
+		{pairs: []string{"BTC/USD", "XYZ/USD"}, want: []provider.Pair{btcusd, {Base: "XYZ", Quote: "USD"}}},
+		{pairs: []string{"*/USD"}, want: []provider.Pair{btcusd, ethusd}},
+		{pairs: []string{"btc/*"}, want: []provider.Pair{btceth, btcusd}},
+		{pairs: []string{"DAI/EUR", "*/*"}, want: []provider.Pair{daieur, btceth, btcusd, ethusd}},
+		{pairs: []string{"E?H/USD"}, want: []provider.Pair{ethusd}},
+		{pairs: []string{"*/GBP"}, wantErr: true},
+		{pairs: []string{"[/USD"}, wantErr: true},
+		{pairs: []string{"BTCUSD"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pairs[0], func(t *testing.T) {
+			pairs, err := ExpandPairs(tt.pairs, available)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pairs)
+		})
+	}
+}
+
+func TestReadPairs(t *testing.T) {
+	pairs, err := ReadPairs(strings.NewReader("" +
+		"# Pairs used by the automation.\n" +
+		"BTC/USD\n" +
+		"\n" +
+		"  eth/usd  # Lowercase symbols are allowed.\n" +
+		"*/EUR\n",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BTC/USD", "eth/usd", "*/EUR"}, pairs)
+
+	_, err = ReadPairs(strings.NewReader("BTC/USD\nETHUSD\n"))
+	assert.EqualError(t, err, "line 2: invalid pair format: ETHUSD, expected BASE/QUOTE")
+}
+
+func TestNew_Wildcards(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Pairs").Return([]provider.Pair{ethusd, btcusd, {Base: "BTC", Quote: "ETH"}}, nil)
+
+	c, err := New(Config{
+		Pairs:         []string{"*/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Pair{btcusd, ethusd}, c.pairs)
+}
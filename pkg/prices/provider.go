@@ -0,0 +1,167 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"strconv"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// CachedProvider is a price provider which serves prices from the Cache.
+// Prices of pairs which are not cached, or whose cached price is too old,
+// are fetched from the price provider of the Cache and stored in it.
+// If the last refresh of a cached price failed, the last good price is
+// returned with the "stale", "refreshError" and "refreshFailures"
+// parameters. Fetched prices which are outdated are returned with an error and are not
+// stored. If the Cache allows pairs on demand, pairs which are not cached
+// are admitted to it when they are fetched, except in the cache-only mode.
+//
+// Models and pairs are always returned by the price provider of the Cache.
+type CachedProvider struct {
+	cache     *Cache
+	cacheOnly bool
+}
+
+// NewCachedProvider creates a new CachedProvider which serves prices from
+// the given Cache.
+func NewCachedProvider(cache *Cache) *CachedProvider {
+	return &CachedProvider{cache: cache}
+}
+
+// NewCacheOnlyProvider creates a new CachedProvider which serves prices
+// only from the given Cache. Prices which are not available in the Cache
+// are not fetched; an error is returned instead. The time needed to return
+// prices does not depend on the origins.
+func NewCacheOnlyProvider(cache *Cache) *CachedProvider {
+	return &CachedProvider{cache: cache, cacheOnly: true}
+}
+
+// Models implements the provider.Provider interface.
+func (c *CachedProvider) Models(pairs ...provider.Pair) (map[provider.Pair]*provider.Model, error) {
+	return c.cache.priceProvider.Models(pairs...)
+}
+
+// Price implements the provider.Provider interface.
+func (c *CachedProvider) Price(pair provider.Pair) (*provider.Price, error) {
+	p, err := c.cache.Price(pair)
+	if err == nil {
+		return annotate(p), nil
+	}
+	if c.cacheOnly {
+		return nil, err
+	}
+	price, err := c.cache.priceProvider.Price(pair)
+	if err != nil {
+		return nil, err
+	}
+	price = c.cache.markOutdated(pair, price)
+	if price.Error == "" {
+		c.cache.admit(pair)
+		c.cache.put(pair, price)
+	}
+	return price, nil
+}
+
+// Prices implements the provider.Provider interface. If no pairs are given,
+// prices of all pairs are fetched from the price provider of the Cache, or,
+// if only the Cache is used, all cached prices are returned.
+func (c *CachedProvider) Prices(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	if len(pairs) == 0 {
+		if c.cacheOnly {
+			return c.cached(), nil
+		}
+		return c.fetch()
+	}
+	prices := make(map[provider.Pair]*provider.Price, len(pairs))
+	var missing []provider.Pair
+	for _, pair := range pairs {
+		p, err := c.cache.Price(pair)
+		if err == nil {
+			prices[pair] = annotate(p)
+			continue
+		}
+		if c.cacheOnly {
+			return nil, err
+		}
+		missing = append(missing, pair)
+	}
+	if len(missing) == 0 {
+		return prices, nil
+	}
+	fetched, err := c.fetch(missing...)
+	if err != nil {
+		return nil, err
+	}
+	for pair, price := range fetched {
+		prices[pair] = price
+	}
+	return prices, nil
+}
+
+// Pairs implements the provider.Provider interface.
+func (c *CachedProvider) Pairs() ([]provider.Pair, error) {
+	return c.cache.priceProvider.Pairs()
+}
+
+// cached returns all prices available in the Cache.
+func (c *CachedProvider) cached() map[provider.Pair]*provider.Price {
+	prices := make(map[provider.Pair]*provider.Price)
+	for pair, p := range c.cache.Prices() {
+		prices[pair] = annotate(p)
+	}
+	return prices
+}
+
+// fetch fetches prices from the price provider of the Cache and stores
+// the ones without errors in the Cache. Outdated prices are marked with
+// an error. Explicitly requested pairs are admitted to the Cache, if it
+// allows pairs on demand.
+func (c *CachedProvider) fetch(pairs ...provider.Pair) (map[provider.Pair]*provider.Price, error) {
+	prices, err := c.cache.priceProvider.Prices(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	for pair, price := range prices {
+		price = c.cache.markOutdated(pair, price)
+		prices[pair] = price
+		if price.Error == "" {
+			if len(pairs) > 0 {
+				c.cache.admit(pair)
+			}
+			c.cache.put(pair, price)
+		}
+	}
+	return prices, nil
+}
+
+// annotate returns the cached price. If the last refresh of the price
+// failed, a copy of the price is returned with parameters describing the
+// failure.
+func annotate(p *CachedPrice) *provider.Price {
+	if p.Failures == 0 {
+		return p.Price
+	}
+	a := *p.Price
+	a.Parameters = make(map[string]string, len(p.Parameters)+3)
+	for k, v := range p.Parameters {
+		a.Parameters[k] = v
+	}
+	a.Parameters["stale"] = "true"
+	a.Parameters["refreshError"] = p.RefreshError
+	a.Parameters["refreshFailures"] = strconv.Itoa(p.Failures)
+	return &a
+}
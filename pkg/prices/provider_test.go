@@ -0,0 +1,130 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCachedProvider(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	xyzusd := provider.Pair{Base: "XYZ", Quote: "USD"}
+	cached := &provider.Price{Type: "median", Pair: btcusd, Price: 42}
+	live := &provider.Price{Type: "median", Pair: ethusd, Price: 2}
+	other := &provider.Price{Type: "median", Pair: xyzusd, Price: 1}
+
+	p := &mocks.Provider{}
+	p.On("Prices", ethusd, xyzusd).Return(map[provider.Pair]*provider.Price{ethusd: live, xyzusd: other}, nil).Once()
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Minute),
+		MaxAge:        time.Hour,
+	})
+	require.NoError(t, err)
+	c.put(btcusd, cached)
+	cp := NewCachedProvider(c)
+
+	// Cached prices are served from the cache, the other ones are fetched.
+	prices, err := cp.Prices(btcusd, ethusd, xyzusd)
+	require.NoError(t, err)
+	assert.Equal(t, map[provider.Pair]*provider.Price{btcusd: cached, ethusd: live, xyzusd: other}, prices)
+
+	// Fetched prices of cached pairs are stored in the cache.
+	price, err := cp.Price(ethusd)
+	require.NoError(t, err)
+	assert.Same(t, live, price)
+	_, err = c.Price(xyzusd)
+	assert.ErrorIs(t, err, ErrUnknownPair)
+
+	// Prices too old to be served from the cache are fetched again.
+	c.prices[btcusd] = cacheEntry{price: cached, fetchedAt: time.Now().Add(-2 * time.Hour)}
+	fresh := &provider.Price{Type: "median", Pair: btcusd, Price: 43}
+	p.On("Price", btcusd).Return(fresh, nil).Once()
+	price, err = cp.Price(btcusd)
+	require.NoError(t, err)
+	assert.Same(t, fresh, price)
+	p.AssertExpectations(t)
+}
+
+func TestCachedProvider_Error(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Pair: btcusd, Error: "no sources"}, nil)
+	p.On("Prices", btcusd).Return(map[provider.Pair]*provider.Price(nil), errors.New("failed"))
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+	cp := NewCachedProvider(c)
+
+	// Prices with errors are returned, but not cached.
+	price, err := cp.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, "no sources", price.Error)
+	assert.Empty(t, c.Prices())
+
+	_, err = cp.Prices(btcusd)
+	assert.Error(t, err)
+}
+
+func TestCacheOnlyProvider(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	cached := &provider.Price{Type: "median", Pair: btcusd, Price: 42}
+
+	// The price provider of the cache must not be called.
+	p := &mocks.Provider{}
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD", "ETH/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+	c.put(btcusd, cached)
+	cp := NewCacheOnlyProvider(c)
+
+	price, err := cp.Price(btcusd)
+	require.NoError(t, err)
+	assert.Same(t, cached, price)
+	_, err = cp.Price(ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	prices, err := cp.Prices(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, map[provider.Pair]*provider.Price{btcusd: cached}, prices)
+	_, err = cp.Prices(btcusd, ethusd)
+	assert.ErrorIs(t, err, ErrNoPrice)
+	_, err = cp.Prices(provider.Pair{Base: "XYZ", Quote: "USD"})
+	assert.ErrorIs(t, err, ErrUnknownPair)
+
+	prices, err = cp.Prices()
+	require.NoError(t, err)
+	assert.Equal(t, map[provider.Pair]*provider.Price{btcusd: cached}, prices)
+	p.AssertNotCalled(t, "Price", ethusd)
+}
@@ -0,0 +1,230 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// SharedStore is a Store shared by multiple Caches, e.g. by replicas of the
+// agent. Before fetching a price, the Cache uses a price already stored by
+// another Cache if it is fresh, and otherwise claims the pair, so only one
+// Cache fetches it from the origins.
+type SharedStore interface {
+	Store
+
+	// Get returns the stored price of the pair, or nil if there is none.
+	Get(pair provider.Pair) (*CachedPrice, error)
+
+	// Claim reports whether the caller may fetch the price of the pair. It
+	// returns false if the pair was claimed by another Cache recently.
+	Claim(pair provider.Pair) (bool, error)
+}
+
+const (
+	defaultRedisKeyPrefix = "gofer:"
+	defaultRedisLockTTL   = 30 * time.Second
+	defaultRedisTimeout   = 5 * time.Second
+	redisSaveRetries      = 3
+)
+
+// RedisStoreConfig is the configuration of the RedisStore.
+type RedisStoreConfig struct {
+	// Addr is the address of the Redis server, in the host:port format.
+	Addr string
+
+	// Password and DB are used to select the Redis database.
+	Password string
+	DB       int
+
+	// KeyPrefix is prepended to all keys. Defaults to "gofer:".
+	KeyPrefix string
+
+	// TTL is the time after which stored prices are removed. If zero,
+	// prices are kept until they are replaced.
+	TTL time.Duration
+
+	// LockTTL is the time for which a claimed pair cannot be claimed by
+	// another Cache. It should be longer than the time needed to fetch a
+	// price. Defaults to 30 seconds.
+	LockTTL time.Duration
+
+	// Timeout limits the time of every Redis operation. Defaults to 5
+	// seconds.
+	Timeout time.Duration
+}
+
+// RedisStore is a SharedStore which keeps prices in Redis.
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	lockTTL   time.Duration
+	timeout   time.Duration
+}
+
+// NewRedisStore connects to the Redis server.
+func NewRedisStore(cfg RedisStoreConfig) (*RedisStore, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("redis address must not be empty")
+	}
+	if cfg.TTL < 0 || cfg.LockTTL < 0 || cfg.Timeout < 0 {
+		return nil, errors.New("redis TTL, lock TTL and timeout must not be negative")
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = defaultRedisKeyPrefix
+	}
+	if cfg.LockTTL == 0 {
+		cfg.LockTTL = defaultRedisLockTTL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultRedisTimeout
+	}
+	s := &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		keyPrefix: cfg.KeyPrefix,
+		ttl:       cfg.TTL,
+		lockTTL:   cfg.LockTTL,
+		timeout:   cfg.Timeout,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		_ = s.client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load implements the Store interface.
+func (s *RedisStore) Load() (map[provider.Pair]*CachedPrice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	prices := make(map[provider.Pair]*CachedPrice)
+	iter := s.client.Scan(ctx, 0, s.keyPrefix+"price:*", 0).Iterator()
+	for iter.Next(ctx) {
+		price, err := get(ctx, s.client, iter.Val())
+		if err != nil {
+			return nil, err
+		}
+		if price != nil {
+			prices[price.Pair] = price
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// Save implements the Store interface. The price is not saved if a price
+// fetched later is already stored, e.g. by another Cache.
+func (s *RedisStore) Save(price *CachedPrice) error {
+	b, err := json.Marshal(storedPriceFromCachedPrice(price))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	key := s.priceKey(price.Pair)
+	// The stored price is compared and replaced in a transaction, which
+	// fails if the key is modified in the meantime, in which case the
+	// comparison is retried.
+	save := func(tx *redis.Tx) error {
+		stored, err := get(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if stored != nil && !stored.FetchedAt.Before(price.FetchedAt) {
+			return nil
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, b, s.ttl)
+			return nil
+		})
+		return err
+	}
+	for i := 0; i < redisSaveRetries; i++ {
+		err = s.client.Watch(ctx, save, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// Delete implements the Store interface.
+func (s *RedisStore) Delete(pairs ...provider.Pair) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	keys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		keys = append(keys, s.priceKey(pair))
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// Get implements the SharedStore interface.
+func (s *RedisStore) Get(pair provider.Pair) (*CachedPrice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return get(ctx, s.client, s.priceKey(pair))
+}
+
+// Claim implements the SharedStore interface.
+func (s *RedisStore) Claim(pair provider.Pair) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return s.client.SetNX(ctx, s.keyPrefix+"lock:"+pair.String(), 1, s.lockTTL).Result()
+}
+
+// Close implements the Store interface.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) priceKey(pair provider.Pair) string {
+	return s.keyPrefix + "price:" + pair.String()
+}
+
+func get(ctx context.Context, c redis.Cmdable, key string) (*CachedPrice, error) {
+	b, err := c.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sp storedPrice
+	if err := json.Unmarshal(b, &sp); err != nil {
+		return nil, err
+	}
+	return sp.cachedPrice(), nil
+}
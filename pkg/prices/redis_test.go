@@ -0,0 +1,171 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func newTestRedisStore(t *testing.T, m *miniredis.Miniredis) *RedisStore {
+	s, err := NewRedisStore(RedisStoreConfig{
+		Addr:      m.Addr(),
+		KeyPrefix: "test:",
+		TTL:       time.Hour,
+		LockTTL:   time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestRedisStore(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestRedisStore(t, m)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	fetchedAt := time.Unix(1700000000, 0).UTC()
+	price := &CachedPrice{
+		Price:     &provider.Price{Type: "median", Pair: btcusd, Price: 42, Time: fetchedAt},
+		FetchedAt: fetchedAt,
+	}
+
+	stored, err := s.Get(btcusd)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	require.NoError(t, s.Save(price))
+	stored, err = s.Get(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, price, stored)
+	assert.True(t, m.Exists("test:price:BTC/USD"))
+	assert.Equal(t, time.Hour, m.TTL("test:price:BTC/USD"))
+
+	// An older price does not replace a newer one.
+	older := &CachedPrice{
+		Price:     &provider.Price{Type: "median", Pair: btcusd, Price: 41},
+		FetchedAt: fetchedAt.Add(-time.Minute),
+	}
+	require.NoError(t, s.Save(older))
+	prices, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[provider.Pair]*CachedPrice{btcusd: price}, prices)
+
+	// A pair can be claimed only once until the lock expires.
+	claimed, err := s.Claim(btcusd)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = s.Claim(btcusd)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	m.FastForward(time.Minute)
+	claimed, err = s.Claim(btcusd)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	require.NoError(t, s.Delete(btcusd))
+	assert.False(t, m.Exists("test:price:BTC/USD"))
+}
+
+func TestCache_SharedStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := miniredis.RunT(t)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p1 := &mocks.Provider{}
+	p1.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil).Once()
+	p2 := &mocks.Provider{}
+
+	newCache := func(p provider.Provider) (*Cache, *timeutil.Ticker) {
+		ticker := timeutil.NewTicker(time.Hour)
+		c, err := New(Config{
+			Pairs:         []string{"BTC/USD"},
+			PriceProvider: p,
+			Interval:      ticker,
+			Store:         newTestRedisStore(t, m),
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Start(ctx))
+		return c, ticker
+	}
+	c1, t1 := newCache(p1)
+	c2, t2 := newCache(p2)
+
+	// The first cache fetches the price, the second one uses the shared
+	// price without fetching it.
+	t1.Tick()
+	require.Eventually(t, func() bool {
+		_, err := c1.Price(btcusd)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	t2.Tick()
+	require.Eventually(t, func() bool {
+		_, err := c2.Price(btcusd)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	price, err := c2.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, price.Price.Price)
+	assert.False(t, price.Stale)
+	p1.AssertExpectations(t)
+	p2.AssertNotCalled(t, "Price", btcusd)
+}
+
+func TestCache_InvalidateSharedStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := miniredis.RunT(t)
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42}, nil).Once()
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 43}, nil).Once()
+
+	store := newTestRedisStore(t, m)
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+		Store:         store,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		_, err := c.Price(btcusd)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	// The price is fetched again even though the shared price is fresh,
+	// and the shared price is replaced.
+	failed, err := c.Invalidate(btcusd)
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	shared, err := store.Get(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 43.0, shared.Price.Price)
+	p.AssertExpectations(t)
+}
@@ -0,0 +1,158 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// snapshot is the JSON representation of the Cache written to the
+// snapshot file.
+type snapshot struct {
+	Pairs []snapshotPair `json:"pairs"`
+}
+
+type snapshotPair struct {
+	Pair    string        `json:"pair"`
+	Price   *storedPrice  `json:"price,omitempty"`
+	History []storedPrice `json:"history,omitempty"`
+}
+
+// SnapshotPair is the cached price of a pair and its history stored in
+// a snapshot file.
+type SnapshotPair struct {
+	Pair provider.Pair
+
+	// Price is the cached price, or nil if only the history is stored.
+	Price *CachedPrice
+
+	// History is the list of recent prices, from the oldest.
+	History []*provider.Price
+}
+
+// ReadSnapshot reads the pairs stored in the snapshot file. Invalid pairs
+// are ignored. A missing file is not an error.
+func ReadSnapshot(path string) ([]SnapshotPair, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapshot %s: %w", path, err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	var pairs []SnapshotPair
+	for _, sp := range s.Pairs {
+		pair, err := provider.NewPair(sp.Pair)
+		if err != nil {
+			continue
+		}
+		p := SnapshotPair{Pair: pair}
+		if sp.Price != nil {
+			p.Price = sp.Price.cachedPrice()
+		}
+		for _, h := range sp.History {
+			p.History = append(p.History, h.price())
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// WriteSnapshot writes the pairs to the snapshot file. Pairs without
+// a price and history are skipped. The file is replaced atomically, so
+// a failed write does not corrupt the previous snapshot.
+func WriteSnapshot(path string, pairs []SnapshotPair) error {
+	var s snapshot
+	for _, p := range pairs {
+		sp := snapshotPair{Pair: p.Pair.String()}
+		if p.Price != nil {
+			price := storedPriceFromCachedPrice(p.Price)
+			sp.Price = &price
+		}
+		for _, h := range p.History {
+			sp.History = append(sp.History, storedPriceFromPrice(h))
+		}
+		if sp.Price != nil || len(sp.History) > 0 {
+			s.Pairs = append(s.Pairs, sp)
+		}
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("unable to write snapshot %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("unable to write snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeSnapshot writes cached prices and their history to the snapshot
+// file.
+func (g *Cache) writeSnapshot() error {
+	var pairs []SnapshotPair
+	g.mu.RLock()
+	for _, pair := range g.allPairs() {
+		p := SnapshotPair{Pair: pair}
+		if e, ok := g.prices[pair]; ok {
+			p.Price = &CachedPrice{Price: e.price, FetchedAt: e.fetchedAt}
+		}
+		if r, ok := g.history[pair]; ok {
+			p.History = r.all()
+		}
+		pairs = append(pairs, p)
+	}
+	g.mu.RUnlock()
+	return WriteSnapshot(g.snapshotPath, pairs)
+}
+
+// loadSnapshot loads cached prices and their history from the snapshot
+// file. Loaded prices are stale until they are refreshed, and pairs which
+// are not kept in the Cache are ignored. A missing file is not an error.
+func (g *Cache) loadSnapshot() error {
+	pairs, err := ReadSnapshot(g.snapshotPath)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, sp := range pairs {
+		if !g.hasPair(sp.Pair) {
+			continue
+		}
+		if sp.Price != nil {
+			g.prices[sp.Pair] = cacheEntry{price: sp.Price.Price, fetchedAt: sp.Price.FetchedAt, restored: true}
+		}
+		for _, p := range sp.History {
+			g.record(sp.Pair, p)
+		}
+	}
+	g.log.WithField("count", len(g.prices)).Info("Loaded snapshot")
+	return nil
+}
@@ -0,0 +1,179 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestCache_Snapshot(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	start := time.Unix(1700000000, 0).UTC()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	p := &mocks.Provider{}
+	for i := 0; i < 2; i++ {
+		p.On("Price", btcusd).Return(&provider.Price{
+			Type:  "median",
+			Pair:  btcusd,
+			Price: float64(i),
+			Time:  start.Add(time.Duration(i) * time.Minute),
+		}, nil).Once()
+	}
+
+	newCache := func(ctx context.Context) (*Cache, *timeutil.Ticker) {
+		ticker := timeutil.NewTicker(time.Hour)
+		c, err := New(Config{
+			Pairs:         []string{"BTC/USD"},
+			PriceProvider: p,
+			Interval:      ticker,
+			HistorySize:   10,
+			SnapshotPath:  path,
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Start(ctx))
+		return c, ticker
+	}
+
+	// The snapshot is written when the cache is stopped.
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	c1, ticker := newCache(ctx1)
+	ticker.Tick()
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		history, _ := c1.History(btcusd, time.Time{}, time.Time{})
+		return len(history) == 2
+	}, time.Second, 10*time.Millisecond)
+	cancel1()
+	<-c1.Wait()
+	require.FileExists(t, path)
+
+	// The restarted cache serves the last price as stale, and keeps its
+	// history.
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	c2, _ := newCache(ctx2)
+	price, err := c2.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, price.Price.Price)
+	assert.True(t, price.Stale)
+	history, err := c2.History(btcusd, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 0.0, history[0].Price)
+	assert.Equal(t, start, history[0].Time)
+}
+
+func TestCache_InvalidSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(path, []byte("invalid"), 0o600))
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: &mocks.Provider{},
+		Interval:      timeutil.NewTicker(time.Hour),
+		SnapshotPath:  path,
+	})
+	require.NoError(t, err)
+
+	// An invalid snapshot is ignored and the cache starts empty.
+	require.NoError(t, c.Start(ctx))
+	assert.Empty(t, c.Prices())
+}
+
+func TestSnapshotFile(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ts := time.Unix(1700000000, 0).UTC()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	// A missing file has no pairs.
+	pairs, err := ReadSnapshot(path)
+	require.NoError(t, err)
+	assert.Empty(t, pairs)
+
+	// Pairs without a price and history are not written.
+	want := []SnapshotPair{{
+		Pair:    btcusd,
+		Price:   &CachedPrice{Price: &provider.Price{Type: "median", Pair: btcusd, Price: 42, Time: ts}, FetchedAt: ts},
+		History: []*provider.Price{{Type: "median", Pair: btcusd, Price: 41, Time: ts.Add(-time.Minute)}},
+	}}
+	require.NoError(t, WriteSnapshot(path, append(want, SnapshotPair{Pair: provider.Pair{Base: "ETH", Quote: "USD"}})))
+	pairs, err = ReadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, pairs)
+}
+
+func TestCache_StopWaitsForUpdates(t *testing.T) {
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Run(func(mock.Arguments) {
+		close(fetching)
+		<-release
+	}).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 42, Time: time.Now()}, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      timeutil.NewTicker(time.Hour),
+		WarmUpTimeout: time.Millisecond,
+		SnapshotPath:  path,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	<-fetching
+	cancel()
+
+	// The cache is not stopped while the price is being fetched.
+	select {
+	case <-c.Wait():
+		t.Fatal("cache stopped before the update finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-c.Wait()
+
+	// The fetched price is included in the snapshot.
+	c, err = New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: &mocks.Provider{},
+		Interval:      timeutil.NewTicker(time.Hour),
+		SnapshotPath:  path,
+	})
+	require.NoError(t, err)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Start(ctx))
+	price, err := c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, price.Price.Price)
+}
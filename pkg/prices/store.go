@@ -0,0 +1,184 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+)
+
+// Store persists cached prices, so the Cache does not start empty after
+// a restart.
+type Store interface {
+	// Load returns all stored prices.
+	Load() (map[provider.Pair]*CachedPrice, error)
+
+	// Save stores the price of the pair, replacing the previous one.
+	Save(price *CachedPrice) error
+
+	// Delete removes the stored prices of the pairs. Pairs which are not
+	// stored are ignored.
+	Delete(pairs ...provider.Pair) error
+
+	// Close closes the store.
+	Close() error
+}
+
+var boltBucket = []byte("prices")
+
+// BoltStore is a Store which keeps prices in a BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens the BoltDB file at the given path, creating it if it
+// does not exist. Only one process may open the file at a time.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open price store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Load implements the Store interface.
+func (s *BoltStore) Load() (map[provider.Pair]*CachedPrice, error) {
+	prices := make(map[provider.Pair]*CachedPrice)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, v []byte) error {
+			var sp storedPrice
+			if err := json.Unmarshal(v, &sp); err != nil {
+				return fmt.Errorf("invalid stored price of %s: %w", k, err)
+			}
+			price := sp.cachedPrice()
+			prices[price.Pair] = price
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// Save implements the Store interface.
+func (s *BoltStore) Save(price *CachedPrice) error {
+	b, err := json.Marshal(storedPriceFromCachedPrice(price))
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(price.Pair.String()), b)
+	})
+}
+
+// Delete implements the Store interface.
+func (s *BoltStore) Delete(pairs ...provider.Pair) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for _, pair := range pairs {
+			if err := b.Delete([]byte(pair.String())); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close implements the Store interface.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// storedPrice is the JSON representation of a stored price. The
+// provider.Pair type cannot be marshaled to JSON and back, so prices are
+// converted to this type first.
+type storedPrice struct {
+	Type       string            `json:"type"`
+	Base       string            `json:"base"`
+	Quote      string            `json:"quote"`
+	Price      float64           `json:"price"`
+	Bid        float64           `json:"bid"`
+	Ask        float64           `json:"ask"`
+	Volume24h  float64           `json:"vol24h"`
+	Time       time.Time         `json:"ts"`
+	Parameters map[string]string `json:"params,omitempty"`
+	Prices     []storedPrice     `json:"prices,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	FetchedAt  time.Time         `json:"fetchedAt,omitempty"`
+}
+
+func storedPriceFromCachedPrice(p *CachedPrice) storedPrice {
+	sp := storedPriceFromPrice(p.Price)
+	sp.FetchedAt = p.FetchedAt
+	return sp
+}
+
+func storedPriceFromPrice(p *provider.Price) storedPrice {
+	var prices []storedPrice
+	for _, c := range p.Prices {
+		prices = append(prices, storedPriceFromPrice(c))
+	}
+	return storedPrice{
+		Type:       p.Type,
+		Base:       p.Pair.Base,
+		Quote:      p.Pair.Quote,
+		Price:      p.Price,
+		Bid:        p.Bid,
+		Ask:        p.Ask,
+		Volume24h:  p.Volume24h,
+		Time:       p.Time,
+		Parameters: p.Parameters,
+		Prices:     prices,
+		Error:      p.Error,
+	}
+}
+
+func (sp storedPrice) cachedPrice() *CachedPrice {
+	return &CachedPrice{Price: sp.price(), FetchedAt: sp.FetchedAt}
+}
+
+func (sp storedPrice) price() *provider.Price {
+	var prices []*provider.Price
+	for _, c := range sp.Prices {
+		prices = append(prices, c.price())
+	}
+	return &provider.Price{
+		Type:       sp.Type,
+		Parameters: sp.Parameters,
+		Pair:       provider.Pair{Base: sp.Base, Quote: sp.Quote},
+		Price:      sp.Price,
+		Bid:        sp.Bid,
+		Ask:        sp.Ask,
+		Volume24h:  sp.Volume24h,
+		Time:       sp.Time,
+		Prices:     prices,
+		Error:      sp.Error,
+	}
+}
@@ -0,0 +1,127 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prices
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider"
+	"github.com/chronicleprotocol/oracle-suite/pkg/price/provider/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.db")
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	price := &CachedPrice{
+		Price: &provider.Price{
+			Type:       "median",
+			Pair:       btcusd,
+			Price:      42,
+			Time:       time.Unix(1700000000, 0).UTC(),
+			Parameters: map[string]string{"minimumSuccessfulSources": "1"},
+			Prices: []*provider.Price{{
+				Type:  "origin",
+				Pair:  btcusd,
+				Price: 42,
+				Time:  time.Unix(1700000000, 0).UTC(),
+			}},
+		},
+		FetchedAt: time.Unix(1700000010, 0).UTC(),
+	}
+
+	s, err := NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Save(price))
+	require.NoError(t, s.Close())
+
+	s, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+	prices, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[provider.Pair]*CachedPrice{btcusd: price}, prices)
+
+	// Pairs which are not stored are ignored.
+	require.NoError(t, s.Delete(btcusd, provider.Pair{Base: "ETH", Quote: "USD"}))
+	prices, err = s.Load()
+	require.NoError(t, err)
+	assert.Empty(t, prices)
+}
+
+func TestCache_Store(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "prices.db")
+	btcusd := provider.Pair{Base: "BTC", Quote: "USD"}
+	ethusd := provider.Pair{Base: "ETH", Quote: "USD"}
+	fetchedAt := time.Now().Add(-time.Second).UTC()
+	s, err := NewBoltStore(path)
+	require.NoError(t, err)
+	for _, pair := range []provider.Pair{btcusd, ethusd} {
+		require.NoError(t, s.Save(&CachedPrice{
+			Price:     &provider.Price{Type: "median", Pair: pair, Price: 42},
+			FetchedAt: fetchedAt,
+		}))
+	}
+
+	p := &mocks.Provider{}
+	p.On("Price", btcusd).Return(&provider.Price{Type: "median", Pair: btcusd, Price: 43}, nil)
+	ticker := timeutil.NewTicker(time.Hour)
+	c, err := New(Config{
+		Pairs:         []string{"BTC/USD"},
+		PriceProvider: p,
+		Interval:      ticker,
+		Store:         s,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+
+	// Stored prices are stale until they are refreshed. Prices of pairs
+	// that are not cached are ignored.
+	price, err := c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, price.Price.Price)
+	assert.True(t, price.Stale)
+	assert.Len(t, c.Prices(), 1)
+
+	ticker.Tick()
+	require.Eventually(t, func() bool {
+		price, err := c.Price(btcusd)
+		return err == nil && !price.Stale
+	}, time.Second, 10*time.Millisecond)
+	price, err = c.Price(btcusd)
+	require.NoError(t, err)
+	assert.Equal(t, 43.0, price.Price.Price)
+
+	// The store is closed when the cache is stopped, and contains the
+	// refreshed price.
+	cancel()
+	<-c.Wait()
+	s, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+	prices, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 43.0, prices[btcusd].Price.Price)
+}